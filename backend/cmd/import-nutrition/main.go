@@ -0,0 +1,65 @@
+// Command import-nutrition loads a food composition table, such as the Indonesian TKPI,
+// from a CSV file into the nutrition reference database. Foods are matched on their
+// source and code, so importing a newer edition of a table updates it in place.
+//
+//	go run ./cmd/import-nutrition -file tkpi.csv              # import
+//	go run ./cmd/import-nutrition -file tkpi.csv -dry-run     # only parse and report
+//	go run ./cmd/import-nutrition -file foods.csv -source custom
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/nutrition"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+func main() {
+	path := flag.String("file", "", "CSV file to import")
+	source := flag.String("source", "tkpi", "name of the table the foods come from")
+	dryRun := flag.Bool("dry-run", false, "parse the file and report without importing")
+	flag.Parse()
+
+	if *path == "" || *source == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", *path, err)
+	}
+	defer file.Close()
+
+	foods, skipped, err := nutrition.ParseCSV(file, *source)
+	if err != nil {
+		log.Fatalf("Error parsing %s: %v", *path, err)
+	}
+	for _, reason := range skipped {
+		log.Printf("skipped %s", reason)
+	}
+	log.Printf("%d food(s) parsed, %d row(s) skipped", len(foods), len(skipped))
+
+	if *dryRun || len(foods) == 0 {
+		return
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Error loading .env file: %v", err)
+	}
+
+	config.InitDB()
+	defer config.CloseDB()
+
+	inserted, updated, err := repository.NewNutritionRepository().UpsertNutritionFoods(foods)
+	if err != nil {
+		log.Fatalf("Error importing foods: %v", err)
+	}
+	log.Printf("%d food(s) added, %d updated", inserted, updated)
+}
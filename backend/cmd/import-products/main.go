@@ -0,0 +1,112 @@
+// Command import-products loads packaged foods from the Open Food Facts CSV export
+// (en.openfoodfacts.org.products.csv, optionally gzipped) into the product table used
+// for barcode lookups. Products are matched on their barcode, so importing a newer dump
+// updates them in place. The full dump is large; -country keeps only products sold in
+// one country.
+//
+//	go run ./cmd/import-products -file en.openfoodfacts.org.products.csv.gz -country en:indonesia
+//	go run ./cmd/import-products -file products.csv -dry-run   # only parse and report
+package main
+
+import (
+	"compress/gzip"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/nutrition"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+// batchSize is the number of products imported per transaction
+const batchSize = 1000
+
+func main() {
+	path := flag.String("file", "", "Open Food Facts CSV export to import")
+	country := flag.String("country", "", "only import products sold in this country, e.g. en:indonesia")
+	dryRun := flag.Bool("dry-run", false, "parse the file and report without importing")
+	flag.Parse()
+
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", *path, err)
+	}
+	defer file.Close()
+
+	var input io.Reader = file
+	if strings.HasSuffix(*path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			log.Fatalf("Error opening %s: %v", *path, err)
+		}
+		defer gz.Close()
+		input = gz
+	}
+
+	reader, err := nutrition.NewProductReader(input, *country)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", *path, err)
+	}
+
+	var nutritionRepo *repository.NutritionRepository
+	if !*dryRun {
+		// Load environment variables
+		if err := godotenv.Load(); err != nil {
+			log.Printf("Warning: Error loading .env file: %v", err)
+		}
+
+		config.InitDB()
+		defer config.CloseDB()
+
+		nutritionRepo = repository.NewNutritionRepository()
+	}
+
+	var parsed, inserted, updated int
+	batch := make([]models.FoodProduct, 0, batchSize)
+
+	flush := func() {
+		if nutritionRepo != nil && len(batch) > 0 {
+			batchInserted, batchUpdated, err := nutritionRepo.UpsertFoodProducts(batch)
+			if err != nil {
+				log.Fatalf("Error importing products: %v", err)
+			}
+			inserted += batchInserted
+			updated += batchUpdated
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		product, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Error reading %s: %v", *path, err)
+		}
+
+		parsed++
+		batch = append(batch, *product)
+		if len(batch) == batchSize {
+			flush()
+		}
+	}
+	flush()
+
+	log.Printf("%d product(s) parsed, %d skipped", parsed, reader.Skipped)
+	if nutritionRepo != nil {
+		log.Printf("%d product(s) added, %d updated", inserted, updated)
+	}
+}
@@ -0,0 +1,97 @@
+// Command purge-accounts permanently deletes the accounts whose deletion grace period has
+// ended. Personal data is deleted and the users row is anonymized, one account per
+// transaction, which also queues the account's uploaded photos for deletion. The queued
+// photos are then removed from storage; photos that fail stay queued and are retried on
+// the next run. Run it from a daily scheduled job.
+//
+//	go run ./cmd/purge-accounts            # purge due accounts
+//	go run ./cmd/purge-accounts -dry-run   # only list them and the queued photos
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/repository"
+	"github.com/habdil/sigap-app/backend/storage"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "list the accounts due for deletion and the photos pending deletion without deleting them")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Error loading .env file: %v", err)
+	}
+
+	config.InitDB()
+	defer config.CloseDB()
+
+	accountRepo := repository.NewAccountRepository()
+	store := storage.New()
+
+	userIDs, err := accountRepo.GetAccountsDueForDeletion(time.Now().UTC())
+	if err != nil {
+		log.Fatalf("Error listing accounts due for deletion: %v", err)
+	}
+
+	failed := 0
+	for _, userID := range userIDs {
+		if *dryRun {
+			log.Printf("user %d: due for deletion", userID)
+			continue
+		}
+
+		if err := accountRepo.PurgeAccount(userID); err != nil {
+			if errors.Is(err, repository.ErrDeletionNotScheduled) {
+				log.Printf("user %d: deletion cancelled, skipped", userID)
+				continue
+			}
+			log.Printf("user %d: failed to purge: %v", userID, err)
+			failed++
+			continue
+		}
+		log.Printf("user %d: purged", userID)
+	}
+
+	// Includes the photos of earlier runs that could not be deleted
+	photoKeys, err := accountRepo.GetPendingPhotoDeletions()
+	if err != nil {
+		log.Fatalf("Error listing photos pending deletion: %v", err)
+	}
+
+	photosFailed := 0
+	for _, key := range photoKeys {
+		if *dryRun {
+			log.Printf("photo %s: pending deletion", key)
+			continue
+		}
+
+		if err := store.Delete(context.Background(), key); err != nil {
+			log.Printf("photo %s: failed to delete, kept for the next run: %v", key, err)
+			photosFailed++
+			continue
+		}
+		if err := accountRepo.CompletePhotoDeletion(key); err != nil {
+			// Deleting the object again on the next run is harmless
+			log.Printf("photo %s: deleted, but failed to dequeue: %v", key, err)
+			photosFailed++
+		}
+	}
+
+	log.Printf("%d account(s) due for deletion, %d failed; %d photo(s) pending deletion, %d failed",
+		len(userIDs), failed, len(photoKeys), photosFailed)
+	if failed > 0 || photosFailed > 0 {
+		// Non-zero exit lets a scheduled job alert on failures
+		config.CloseDB()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,65 @@
+// Command reconcile-coins compares every user's coin balance with their transaction
+// ledger and reports the drift. With -fix the balances are reset to the ledger total.
+//
+//	go run ./cmd/reconcile-coins        # report only
+//	go run ./cmd/reconcile-coins -fix   # report and repair
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "reset drifted balances to the ledger total")
+	flag.Parse()
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: Error loading .env file: %v", err)
+	}
+
+	config.InitDB()
+	defer config.CloseDB()
+
+	coinRepo := repository.NewCoinRepository()
+
+	drifts, err := coinRepo.FindBalanceDrift()
+	if err != nil {
+		log.Fatalf("Error checking coin balances: %v", err)
+	}
+
+	if len(drifts) == 0 {
+		log.Println("All coin balances match the ledger")
+		return
+	}
+
+	for _, drift := range drifts {
+		log.Printf("user %d: stored balance %d, ledger balance %d (drift %+d)",
+			drift.UserID, drift.StoredBalance, drift.LedgerBalance, drift.StoredBalance-drift.LedgerBalance)
+
+		if !*fix {
+			continue
+		}
+
+		balance, err := coinRepo.ReconcileBalance(drift.UserID)
+		if err != nil {
+			log.Printf("user %d: failed to reconcile: %v", drift.UserID, err)
+			continue
+		}
+		log.Printf("user %d: balance reset to %d", drift.UserID, balance)
+	}
+
+	log.Printf("%d user(s) with drifted balances", len(drifts))
+	if !*fix {
+		// Non-zero exit lets a scheduled job alert on drift
+		config.CloseDB()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/repository"
+	"github.com/habdil/sigap-app/backend/services"
+)
+
+// AccountController handles the personal data export and account deletion endpoints
+type AccountController struct {
+	accountService *services.AccountService
+}
+
+// NewAccountController creates a new AccountController
+func NewAccountController() *AccountController {
+	return &AccountController{
+		accountService: services.NewAccountService(),
+	}
+}
+
+// ExportData handles downloading all of the user's personal data as a ZIP archive
+func (c *AccountController) ExportData(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	archive, err := c.accountService.ExportData(userID.(int))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("sigap-data-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusOK, "application/zip", archive)
+}
+
+// DeleteAccount handles scheduling the account for deletion after the grace period
+func (c *AccountController) DeleteAccount(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	deletion, err := c.accountService.RequestDeletion(userID.(int))
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, deletion)
+}
+
+// GetDeletion handles reporting whether the account is scheduled for deletion
+func (c *AccountController) GetDeletion(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	deletion, err := c.accountService.GetDeletion(userID.(int))
+	if err != nil {
+		if errors.Is(err, repository.ErrAccountNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deletion)
+}
+
+// CancelDeletion handles cancelling a scheduled account deletion during the grace period
+func (c *AccountController) CancelDeletion(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := c.accountService.CancelDeletion(userID.(int)); err != nil {
+		if errors.Is(err, repository.ErrDeletionNotScheduled) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
@@ -2,23 +2,29 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
 	"github.com/habdil/sigap-app/backend/services"
 )
 
 // ActivityController handles activity endpoints
 type ActivityController struct {
 	activityService *services.ActivityService
+	streakService   *services.StreakService
 }
 
 // NewActivityController creates a new instance of ActivityController
 func NewActivityController() *ActivityController {
 	return &ActivityController{
 		activityService: services.NewActivityService(),
+		streakService:   services.NewStreakService(),
 	}
 }
 
@@ -42,6 +48,10 @@ func (c *ActivityController) LogActivity(ctx *gin.Context) {
 	// Log the activity
 	log, err := c.activityService.LogActivity(userID.(int), &req)
 	if err != nil {
+		if errors.Is(err, services.ErrUnknownActivityType) || errors.Is(err, services.ErrFutureActivity) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -50,6 +60,88 @@ func (c *ActivityController) LogActivity(ctx *gin.Context) {
 	ctx.JSON(http.StatusCreated, log)
 }
 
+// ImportActivity creates an activity from an uploaded GPX, TCX or FIT file
+func (c *ActivityController) ImportActivity(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, services.MaxActivityFileSize+1<<20)
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if fileHeader.Size > services.MaxActivityFileSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file is too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Import the activity
+	response, err := c.activityService.ImportActivity(userID.(int), fileHeader.Filename, data, ctx.PostForm("activity_type"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidActivityFile), errors.Is(err, services.ErrUnknownActivityType),
+			errors.Is(err, services.ErrFutureActivity):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrTrackAlreadyImported):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// GetActivityTrack gets the imported route and splits of an activity
+func (c *ActivityController) GetActivityTrack(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	activityID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid activity ID"})
+		return
+	}
+
+	track, err := c.activityService.GetActivityTrack(userID.(int), activityID)
+	if err != nil {
+		if errors.Is(err, repository.ErrTrackNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusOK, track)
+}
+
 // GetUserActivities gets activities for a user
 func (c *ActivityController) GetUserActivities(ctx *gin.Context) {
 	// Get user ID from context (set by auth middleware)
@@ -59,15 +151,142 @@ func (c *ActivityController) GetUserActivities(ctx *gin.Context) {
 		return
 	}
 
+	query, opts, ok := bindListQuery(ctx, false)
+	if !ok {
+		return
+	}
+
 	// Get activities
-	activities, err := c.activityService.GetUserActivities(userID.(int))
+	activities, nextCursor, err := c.activityService.GetUserActivities(userID.(int), opts)
+	if err != nil {
+		ctx.JSON(listErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the response
+	respondList(ctx, query, activities, nextCursor)
+}
+
+// GetActivity gets a single activity of the user
+func (c *ActivityController) GetActivity(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	activityID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid activity ID"})
+		return
+	}
+
+	activity, err := c.activityService.GetActivity(userID.(int), activityID)
+	if err != nil {
+		if errors.Is(err, repository.ErrActivityNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusOK, activity)
+}
+
+// UpdateActivity edits an activity and adjusts the coins it earned
+func (c *ActivityController) UpdateActivity(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	activityID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid activity ID"})
+		return
+	}
+
+	var req models.ActivityLogUpdateRequest
+
+	// Bind the request body
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update the activity
+	activity, err := c.activityService.UpdateActivity(userID.(int), activityID, &req)
+	if err != nil {
+		ctx.JSON(activityErrorStatus(err), gin.H{"error": activityErrorMessage(err)})
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusOK, activity)
+}
+
+// DeleteActivity deletes an activity and takes back the coins it earned
+func (c *ActivityController) DeleteActivity(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	activityID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid activity ID"})
+		return
+	}
+
+	if err := c.activityService.DeleteActivity(userID.(int), activityID); err != nil {
+		ctx.JSON(activityErrorStatus(err), gin.H{"error": activityErrorMessage(err)})
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusOK, gin.H{"message": "Activity deleted successfully"})
+}
+
+// activityErrorStatus maps activity update and delete errors to HTTP status codes
+func activityErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrActivityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUnknownActivityType):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrInsufficientCoins):
+		// The coins this activity earned have already been spent
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// activityErrorMessage returns the client facing message for activity update and delete errors
+func activityErrorMessage(err error) string {
+	if errors.Is(err, repository.ErrInsufficientCoins) {
+		return "coins earned from this activity have already been spent"
+	}
+	return err.Error()
+}
+
+// GetActivityTypes lists the activity types that can be logged
+func (c *ActivityController) GetActivityTypes(ctx *gin.Context) {
+	types, err := c.activityService.GetActivityTypes()
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Return the response
-	ctx.JSON(http.StatusOK, activities)
+	ctx.JSON(http.StatusOK, types)
 }
 
 // GetRecommendedActivities gets recommended activities for a user
@@ -89,3 +308,135 @@ func (c *ActivityController) GetRecommendedActivities(ctx *gin.Context) {
 	// Return the response
 	ctx.JSON(http.StatusOK, recommendations)
 }
+
+// GetStreak gets the user's daily and weekly activity streaks
+func (c *ActivityController) GetStreak(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Get streak
+	streak, err := c.streakService.GetStreak(userID.(int))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusOK, streak)
+}
+
+// PurchaseStreakFreeze buys a streak freeze day with coins
+func (c *ActivityController) PurchaseStreakFreeze(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Buy the freeze
+	freeze, err := c.streakService.PurchaseFreeze(userID.(int))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInsufficientCoins):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient coins"})
+		case errors.Is(err, repository.ErrFreezeAlreadyOwned):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusCreated, freeze)
+}
+
+// GetActivityStats gets aggregated activity statistics for a date range
+func (c *ActivityController) GetActivityStats(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var query models.ActivityStatsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get stats
+	stats, err := c.activityService.GetActivityStats(userID.(int), &query)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStatsQuery) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusOK, stats)
+}
+
+// GetActivityFlags lists flagged activity logs for admin review
+func (c *ActivityController) GetActivityFlags(ctx *gin.Context) {
+	query, opts, ok := bindListQuery(ctx, false)
+	if !ok {
+		return
+	}
+
+	flags, nextCursor, err := c.activityService.GetActivityFlags(opts)
+	if err != nil {
+		ctx.JSON(listErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the response
+	respondList(ctx, query, flags, nextCursor)
+}
+
+// ReviewActivityFlag approves or rejects a flagged activity log
+func (c *ActivityController) ReviewActivityFlag(ctx *gin.Context) {
+	// Get the admin's user ID from context (role checked by RequireRole)
+	adminID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	flagID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid flag ID"})
+		return
+	}
+
+	var req models.ActivityFlagReviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flag, err := c.activityService.ReviewActivityFlag(adminID.(int), flagID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrFlagNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrFlagAlreadyReviewed):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusOK, flag)
+}
@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
 	"github.com/habdil/sigap-app/backend/services"
 )
 
@@ -41,7 +44,11 @@ func (c *AssessmentController) SubmitAssessment(ctx *gin.Context) {
 	// Submit the assessment
 	response, err := c.assessmentService.SubmitAssessment(userID.(int), &req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidAnswers) || errors.Is(err, repository.ErrQuestionnaireNotFound) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -49,6 +56,32 @@ func (c *AssessmentController) SubmitAssessment(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+// GetQuestionnaire handles retrieving the assessment questionnaire, the current version
+// unless a version is given
+func (c *AssessmentController) GetQuestionnaire(ctx *gin.Context) {
+	version := 0
+	if v := ctx.Query("version"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid questionnaire version"})
+			return
+		}
+		version = parsed
+	}
+
+	questionnaire, err := c.assessmentService.GetQuestionnaire(version)
+	if err != nil {
+		if errors.Is(err, repository.ErrQuestionnaireNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, questionnaire)
+}
+
 // GetLatestAssessment handles retrieving the latest assessment for a user
 func (c *AssessmentController) GetLatestAssessment(ctx *gin.Context) {
 	// Get user ID from context (set by auth middleware)
@@ -78,18 +111,23 @@ func (c *AssessmentController) GetAssessmentHistory(ctx *gin.Context) {
 		return
 	}
 
+	query, opts, ok := bindListQuery(ctx, false)
+	if !ok {
+		return
+	}
+
 	// Get the assessment history
-	history, err := c.assessmentService.GetAssessmentHistory(userID.(int))
+	history, nextCursor, err := c.assessmentService.GetAssessmentHistory(userID.(int), opts)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(listErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	// Return the history
-	ctx.JSON(http.StatusOK, history)
+	respondList(ctx, query, history, nextCursor)
 }
 
-// CheckAssessmentStatus mengecek apakah user perlu mengisi assessment
+// CheckAssessmentStatus mengecek apakah user perlu mengisi assessment dan kapan assessment berikutnya
 func (c *AssessmentController) CheckAssessmentStatus(ctx *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userID, exists := ctx.Get("userID")
@@ -99,14 +137,34 @@ func (c *AssessmentController) CheckAssessmentStatus(ctx *gin.Context) {
 	}
 
 	// Cek status assessment
-	needsAssessment, err := c.assessmentService.CheckAssessmentStatus(userID.(int))
+	status, err := c.assessmentService.GetAssessmentStatus(userID.(int))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Return response
-	ctx.JSON(http.StatusOK, gin.H{
-		"needs_assessment": needsAssessment,
-	})
+	ctx.JSON(http.StatusOK, status)
+}
+
+// GetAssessmentTrend handles comparing the latest risk assessment result with the previous one
+func (c *AssessmentController) GetAssessmentTrend(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	trend, err := c.assessmentService.GetAssessmentTrend(userID.(int))
+	if err != nil {
+		if errors.Is(err, repository.ErrAssessmentResultNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, trend)
 }
@@ -1,11 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
 	"github.com/habdil/sigap-app/backend/services"
 )
 
@@ -84,6 +86,77 @@ func (c *AuthController) GoogleLogin(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response)
 }
 
+// RefreshToken exchanges a refresh token for a new access and refresh token pair
+func (c *AuthController) RefreshToken(ctx *gin.Context) {
+	var req models.RefreshTokenRequest
+
+	// Bind the request body
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Rotate the refresh token
+	response, err := c.authService.RefreshToken(req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRefreshTokenNotFound),
+			errors.Is(err, repository.ErrRefreshTokenExpired),
+			errors.Is(err, repository.ErrRefreshTokenReused):
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return the new tokens
+	ctx.JSON(http.StatusOK, response)
+}
+
+// Logout revokes the session of the given refresh token
+func (c *AuthController) Logout(ctx *gin.Context) {
+	var req models.RefreshTokenRequest
+
+	// Bind the request body
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Revoke the session
+	if err := c.authService.Logout(req.RefreshToken); err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return success
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// LogoutAll revokes every session of the current user
+func (c *AuthController) LogoutAll(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Revoke all sessions
+	if err := c.authService.LogoutAll(userID.(int)); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return success
+	ctx.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
+}
+
 // GetCurrentUser gets the current user profile
 func (c *AuthController) GetCurrentUser(ctx *gin.Context) {
 	// Get user ID from context (set by auth middleware)
@@ -117,6 +190,10 @@ func (c *AuthController) SupabaseAuth(ctx *gin.Context) {
 	// Proses autentikasi
 	response, err := c.authService.SupabaseAuth(req)
 	if err != nil {
+		if errors.Is(err, services.ErrUnverifiedEmailLink) {
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -89,15 +89,25 @@ func (c *ChatbotController) GetConversations(ctx *gin.Context) {
 		return
 	}
 
+	_, opts, ok := bindListQuery(ctx, false)
+	if !ok {
+		return
+	}
+
 	// Get the conversations
-	conversations, err := c.chatbotService.GetUserConversations(userID.(int))
+	conversations, nextCursor, err := c.chatbotService.GetUserConversations(userID.(int), opts)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(listErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	// Return the response
-	ctx.JSON(http.StatusOK, gin.H{"conversations": conversations})
+	response := gin.H{"conversations": conversations}
+	if nextCursor != "" {
+		response["next_cursor"] = nextCursor
+		response["has_more"] = true
+	}
+	ctx.JSON(http.StatusOK, response)
 }
 
 // SendMessage handles sending a message to a conversation
@@ -145,6 +155,68 @@ func (c *ChatbotController) SendMessage(ctx *gin.Context) {
 	})
 }
 
+// StreamMessage handles sending a message and streams the bot reply as Server-Sent Events.
+// POST reads the message from the JSON body, GET from the "content" query parameter so
+// that EventSource clients can use it. Events: user_message, token, done and error.
+func (c *ChatbotController) StreamMessage(ctx *gin.Context) {
+	// Get user ID from context
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Get conversation ID from URL
+	conversationID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid conversation ID"})
+		return
+	}
+
+	var req models.ChatMessageRequest
+	if ctx.Request.Method == http.MethodGet {
+		req.Content = ctx.Query("content")
+		if req.Content == "" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "content query parameter is required"})
+			return
+		}
+	} else if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Save the user message before opening the stream so errors can still be reported as JSON
+	userMessage, err := c.chatbotService.AddUserMessage(userID.(int), conversationID, req.Content)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	ctx.Status(http.StatusOK)
+
+	ctx.SSEvent("user_message", userMessage)
+	ctx.Writer.Flush()
+
+	// The request context is cancelled when the client disconnects, which stops the model stream
+	botMessage, err := c.chatbotService.StreamBotResponse(ctx.Request.Context(), userID.(int), conversationID, userMessage, func(chunk string) error {
+		ctx.SSEvent("token", gin.H{"text": chunk})
+		ctx.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		ctx.SSEvent("error", gin.H{"error": err.Error()})
+		ctx.Writer.Flush()
+		return
+	}
+
+	ctx.SSEvent("done", gin.H{"bot_message": botMessage})
+	ctx.Writer.Flush()
+}
+
 // controllers/chatbot_controller.go (lanjutan)
 // GetMessages handles retrieving all messages for a conversation
 func (c *ChatbotController) GetMessages(ctx *gin.Context) {
@@ -162,16 +234,24 @@ func (c *ChatbotController) GetMessages(ctx *gin.Context) {
 		return
 	}
 
+	// Messages are oldest first unless sort=desc is given
+	_, opts, ok := bindListQuery(ctx, true)
+	if !ok {
+		return
+	}
+
 	// Get the messages
-	messages, err := c.chatbotService.GetMessages(conversationID, userID.(int))
+	messages, nextCursor, err := c.chatbotService.GetMessages(conversationID, userID.(int), opts)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		ctx.JSON(listErrorStatus(err, http.StatusNotFound), gin.H{"error": err.Error()})
 		return
 	}
 
 	// Return the response
 	ctx.JSON(http.StatusOK, models.MessagesResponse{
-		Messages: messages,
+		Messages:   messages,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
 	})
 }
 
@@ -2,11 +2,14 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/habdil/sigap-app/backend/middlewares"
 	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
 	"github.com/habdil/sigap-app/backend/services"
 )
 
@@ -42,32 +45,34 @@ func (c *CoinController) GetUserCoins(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, coins)
 }
 
-// AddCoins handles adding coins to a user's balance (admin only)
-func (c *CoinController) AddCoins(ctx *gin.Context) {
-	// Get user ID from context
-	userID, exists := ctx.Get("userID")
+// GrantCoins handles an admin crediting coins to a user
+func (c *CoinController) GrantCoins(ctx *gin.Context) {
+	// Get the admin's user ID from context (role checked by RequireRole)
+	adminID, exists := ctx.Get("userID")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	// Only admin or system can add coins directly
-	// You may want to add role check here
-
-	var req models.AddCoinsRequest
+	var req models.GrantCoinsRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Add coins
-	if err := c.coinService.AddCoins(userID.(int), &req); err != nil {
+	// Grant coins
+	grant, err := c.coinService.GrantCoins(adminID.(int), &req)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Return success
-	ctx.JSON(http.StatusOK, gin.H{"message": "Coins added successfully"})
+	// Return the grant
+	ctx.JSON(http.StatusCreated, grant)
 }
 
 // SpendCoins handles spending coins from a user's balance
@@ -86,7 +91,7 @@ func (c *CoinController) SpendCoins(ctx *gin.Context) {
 	}
 
 	// Spend coins
-	if err := c.coinService.SpendCoins(userID.(int), &req); err != nil {
+	if err := c.coinService.SpendCoins(userID.(int), &req, ctx.GetHeader(middlewares.IdempotencyKeyHeader)); err != nil {
 		if err.Error() == "insufficient coins" {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient coins"})
 			return
@@ -108,13 +113,18 @@ func (c *CoinController) GetTransactionHistory(ctx *gin.Context) {
 		return
 	}
 
+	query, opts, ok := bindListQuery(ctx, false)
+	if !ok {
+		return
+	}
+
 	// Get the transaction history
-	transactions, err := c.coinService.GetTransactionHistory(userID.(int))
+	transactions, nextCursor, err := c.coinService.GetTransactionHistory(userID.(int), opts)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(listErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	// Return the transactions
-	ctx.JSON(http.StatusOK, transactions)
+	respondList(ctx, query, transactions, nextCursor)
 }
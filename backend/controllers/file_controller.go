@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/storage"
+)
+
+// FileController serves files of the local storage backend through signed URLs
+type FileController struct {
+	storage *storage.Local
+}
+
+// NewFileController creates a new FileController
+func NewFileController(local *storage.Local) *FileController {
+	return &FileController{storage: local}
+}
+
+// GetFile handles downloading a stored file. The link's expiry and signature take the
+// place of a login, so links can be used directly in image views.
+func (c *FileController) GetFile(ctx *gin.Context) {
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+
+	if err := c.storage.Verify(key, ctx.Query("expires"), ctx.Query("signature")); err != nil {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	object, err := c.storage.Get(ctx.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.Header("Cache-Control", "private, max-age=3600")
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Data(http.StatusOK, object.ContentType, object.Data)
+}
@@ -2,11 +2,16 @@
 package controllers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/nutrition"
+	"github.com/habdil/sigap-app/backend/repository"
 	"github.com/habdil/sigap-app/backend/services"
 )
 
@@ -42,6 +47,100 @@ func (c *FoodController) LogFood(ctx *gin.Context) {
 	// Log the food
 	log, err := c.foodService.LogFood(userID.(int), &req)
 	if err != nil {
+		if errors.Is(err, repository.ErrNutritionFoodNotFound) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusCreated, log)
+}
+
+// LogBarcode handles the creation of a food log for a packaged food scanned by its barcode
+func (c *FoodController) LogBarcode(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.BarcodeLogRequest
+
+	// Bind the request body
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Look up the product and log it
+	log, err := c.foodService.LogBarcode(userID.(int), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, nutrition.ErrInvalidBarcode):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrFoodProductNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusCreated, log)
+}
+
+// UploadFoodPhoto handles the creation of a food log from an uploaded photo (multipart
+// field photo, with optional food_name and notes)
+func (c *FoodController) UploadFoodPhoto(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, services.MaxFoodPhotoSize+1<<20)
+
+	fileHeader, err := ctx.FormFile("photo")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "photo is required"})
+		return
+	}
+	if fileHeader.Size > services.MaxFoodPhotoSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "photo is too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	photo, err := io.ReadAll(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req := models.FoodLogRequest{
+		FoodName: ctx.PostForm("food_name"),
+		Notes:    ctx.PostForm("notes"),
+	}
+
+	// Log the food with its photo
+	log, err := c.foodService.LogFoodWithPhoto(userID.(int), &req, photo)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFoodPhoto) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -70,6 +169,10 @@ func (c *FoodController) AnalyzeFood(ctx *gin.Context) {
 	// Analyze the food
 	analysis, err := c.foodService.AnalyzeFood(userID.(int), &req)
 	if err != nil {
+		if errors.Is(err, services.ErrNoFoodPhoto) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -78,6 +181,74 @@ func (c *FoodController) AnalyzeFood(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, analysis)
 }
 
+// CorrectFoodItem handles a user's correction of the name or portion of a detected item
+func (c *FoodController) CorrectFoodItem(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	foodLogID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid food log ID"})
+		return
+	}
+	itemID, err := strconv.Atoi(ctx.Param("itemId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid item ID"})
+		return
+	}
+
+	var req models.FoodItemCorrection
+
+	// Bind the request body
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Correct the item and recompute the totals
+	analysis, err := c.foodService.CorrectFoodItem(userID.(int), foodLogID, itemID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidFoodItemCorrection):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrFoodLogNotFound), errors.Is(err, repository.ErrFoodItemNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusOK, analysis)
+}
+
+// SearchFoods searches the nutrition reference database (query parameters q and limit)
+func (c *FoodController) SearchFoods(ctx *gin.Context) {
+	limit := 0
+	if value := ctx.Query("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+
+	foods, err := c.foodService.SearchFoods(ctx.Query("q"), limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the response
+	ctx.JSON(http.StatusOK, foods)
+}
+
 // GetUserFoodLogs gets food logs for a user
 func (c *FoodController) GetUserFoodLogs(ctx *gin.Context) {
 	// Get user ID from context (set by auth middleware)
@@ -87,13 +258,18 @@ func (c *FoodController) GetUserFoodLogs(ctx *gin.Context) {
 		return
 	}
 
+	query, opts, ok := bindListQuery(ctx, false)
+	if !ok {
+		return
+	}
+
 	// Get food logs
-	logs, err := c.foodService.GetUserFoodLogs(userID.(int))
+	logs, nextCursor, err := c.foodService.GetUserFoodLogs(userID.(int), opts)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(listErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	// Return the response
-	ctx.JSON(http.StatusOK, logs)
+	respondList(ctx, query, logs, nextCursor)
 }
@@ -0,0 +1,57 @@
+// controllers/pagination.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+// bindListQuery parses the shared list parameters (limit, cursor, from, to, type, sort).
+// It writes a 400 response and returns false when they are invalid.
+func bindListQuery(ctx *gin.Context, defaultAscending bool) (models.ListQuery, models.ListOptions, bool) {
+	var query models.ListQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return query, models.ListOptions{}, false
+	}
+
+	opts, err := query.Options(defaultAscending)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return query, opts, false
+	}
+
+	return query, opts, true
+}
+
+// respondList returns a page with its next cursor when the client asked for pagination,
+// otherwise the plain array older clients expect
+func respondList[T any](ctx *gin.Context, query models.ListQuery, items []T, nextCursor string) {
+	if items == nil {
+		items = []T{}
+	}
+
+	if !query.Paginated() {
+		ctx.JSON(http.StatusOK, items)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, models.Page[T]{
+		Data:       items,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
+	})
+}
+
+// listErrorStatus maps an invalid filter to 400 and anything else to the given status
+func listErrorStatus(err error, status int) int {
+	if errors.Is(err, repository.ErrInvalidListFilter) {
+		return http.StatusBadRequest
+	}
+	return status
+}
@@ -68,3 +68,34 @@ func (c *ProfileController) GetProfile(ctx *gin.Context) {
 	// Return the profile
 	ctx.JSON(http.StatusOK, profile)
 }
+
+// UpdateTimezone handles changing the user's timezone
+func (c *ProfileController) UpdateTimezone(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.TimezoneUpdateRequest
+
+	// Bind the request body
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Update the timezone
+	if err := c.profileService.UpdateTimezone(userID.(int), req.Timezone); err != nil {
+		if err.Error() == "invalid timezone" {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return success
+	ctx.JSON(http.StatusOK, gin.H{"message": "Timezone updated successfully"})
+}
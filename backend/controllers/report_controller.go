@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/services"
+)
+
+// defaultReportDays is the period covered by a report when the client does not choose one
+const defaultReportDays = 90
+
+// ReportController handles report endpoints
+type ReportController struct {
+	reportService *services.ReportService
+}
+
+// NewReportController creates a new ReportController
+func NewReportController() *ReportController {
+	return &ReportController{
+		reportService: services.NewReportService(),
+	}
+}
+
+// GetHealthReport handles downloading the health report as a PDF. The period is set with
+// from and to (YYYY-MM-DD or RFC 3339, inclusive) and defaults to the last 90 days.
+func (c *ReportController) GetHealthReport(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	query := models.ListQuery{From: ctx.Query("from"), To: ctx.Query("to")}
+	opts, err := query.Options(true)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if opts.To != nil {
+		to = *opts.To
+	}
+	from := to.AddDate(0, 0, -defaultReportDays)
+	if opts.From != nil {
+		from = *opts.From
+	}
+
+	report, err := c.reportService.HealthReportPDF(userID.(int), from, to)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReportPeriod) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("sigap-health-report-%s.pdf", to.AddDate(0, 0, -1).Format("2006-01-02"))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusOK, "application/pdf", report)
+}
@@ -0,0 +1,108 @@
+// controllers/reward_controller.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/repository"
+	"github.com/habdil/sigap-app/backend/services"
+)
+
+// RewardController handles the rewards catalog and redemption endpoints
+type RewardController struct {
+	rewardService *services.RewardService
+}
+
+// NewRewardController creates a new RewardController
+func NewRewardController() *RewardController {
+	return &RewardController{
+		rewardService: services.NewRewardService(),
+	}
+}
+
+// GetRewards handles listing the rewards that can currently be redeemed
+func (c *RewardController) GetRewards(ctx *gin.Context) {
+	rewards, err := c.rewardService.GetAvailableRewards()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rewards)
+}
+
+// GetReward handles retrieving a single reward
+func (c *RewardController) GetReward(ctx *gin.Context) {
+	rewardID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid reward ID"})
+		return
+	}
+
+	reward, err := c.rewardService.GetReward(rewardID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRewardNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reward)
+}
+
+// RedeemReward handles buying a reward with coins
+func (c *RewardController) RedeemReward(ctx *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rewardID, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid reward ID"})
+		return
+	}
+
+	response, err := c.rewardService.RedeemReward(userID.(int), rewardID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRewardNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrInsufficientCoins):
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient coins"})
+		case errors.Is(err, repository.ErrRewardUnavailable), errors.Is(err, repository.ErrRewardOutOfStock):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, response)
+}
+
+// GetMyRewards handles listing the rewards the user has redeemed
+func (c *RewardController) GetMyRewards(ctx *gin.Context) {
+	// Get user ID from context
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	redemptions, err := c.rewardService.GetUserRedemptions(userID.(int))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, redemptions)
+}
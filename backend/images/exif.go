@@ -0,0 +1,89 @@
+// images/exif.go
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// gpsIFDTag is the IFD0 tag pointing to the GPS block
+const gpsIFDTag = 0x8825
+
+// exifTypeSizes are the sizes in bytes of the TIFF field types, by type number
+var exifTypeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+var errBadExif = errors.New("malformed EXIF data")
+
+// clearGPS blanks the GPS block of TIFF-structured EXIF data in place. The block is
+// zeroed so it reads as an empty IFD; offsets elsewhere in the data stay valid.
+func clearGPS(tiff []byte) error {
+	if len(tiff) < 8 {
+		return errBadExif
+	}
+
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(tiff, []byte("II")):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(tiff, []byte("MM")):
+		order = binary.BigEndian
+	default:
+		return errBadExif
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return errBadExif
+	}
+
+	ifd0 := order.Uint32(tiff[4:])
+	entries, err := ifdEntries(tiff, ifd0, order)
+	if err != nil {
+		return err
+	}
+
+	for i := uint32(0); i < entries; i++ {
+		entry := ifd0 + 2 + 12*i
+		if order.Uint16(tiff[entry:]) != gpsIFDTag {
+			continue
+		}
+
+		gps := order.Uint32(tiff[entry+8:])
+		gpsEntries, err := ifdEntries(tiff, gps, order)
+		if err != nil {
+			return err
+		}
+
+		// Zero the values stored outside the entries first, then the entries and the
+		// next-IFD pointer, leaving an IFD with no entries
+		for j := uint32(0); j < gpsEntries; j++ {
+			gpsEntry := gps + 2 + 12*j
+			size := exifTypeSizes[order.Uint16(tiff[gpsEntry+2:])] * order.Uint32(tiff[gpsEntry+4:])
+			if size <= 4 {
+				continue
+			}
+			offset := order.Uint32(tiff[gpsEntry+8:])
+			if uint64(offset)+uint64(size) > uint64(len(tiff)) {
+				return errBadExif
+			}
+			clear(tiff[offset : offset+size])
+		}
+		clear(tiff[gps : gps+2+12*gpsEntries+4])
+	}
+
+	return nil
+}
+
+// ifdEntries returns the number of entries of the IFD at offset after checking that the
+// entries and the next-IFD pointer lie within the data
+func ifdEntries(tiff []byte, offset uint32, order binary.ByteOrder) (uint32, error) {
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return 0, errBadExif
+	}
+	entries := uint32(order.Uint16(tiff[offset:]))
+	if uint64(offset)+2+12*uint64(entries)+4 > uint64(len(tiff)) {
+		return 0, errBadExif
+	}
+	return entries, nil
+}
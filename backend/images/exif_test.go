@@ -0,0 +1,117 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// testGPSIFD is where testEXIF puts the GPS block. Everything from there to the end of
+// the data is GPS: the block followed by the latitude and longitude values it points to.
+const testGPSIFD = 38
+
+// testEXIF returns TIFF-structured EXIF data with an orientation in IFD0 and a GPS block
+// holding a position in Jakarta, with the coordinates stored outside the entries
+func testEXIF(order binary.ByteOrder) []byte {
+	var b bytes.Buffer
+	if order == binary.LittleEndian {
+		b.WriteString("II")
+	} else {
+		b.WriteString("MM")
+	}
+	write := func(values ...interface{}) {
+		for _, value := range values {
+			binary.Write(&b, order, value)
+		}
+	}
+	entry := func(tag uint16, fieldType uint16, count uint32, value uint32) {
+		write(tag, fieldType, count, value)
+	}
+	inlineASCII := func(tag uint16, char byte) {
+		write(tag, uint16(2), uint32(2))
+		b.Write([]byte{char, 0, 0, 0})
+	}
+
+	write(uint16(42), uint32(8))
+
+	// IFD0 at 8: orientation and the GPS pointer
+	write(uint16(2))
+	write(uint16(0x0112), uint16(3), uint32(1), uint16(1), uint16(0))
+	entry(gpsIFDTag, 4, 1, testGPSIFD)
+	write(uint32(0))
+
+	// GPS IFD at 38, values from 92
+	write(uint16(4))
+	inlineASCII(0x0001, 'S')
+	entry(0x0002, 5, 3, 92)
+	inlineASCII(0x0003, 'E')
+	entry(0x0004, 5, 3, 116)
+	write(uint32(0))
+
+	write(uint32(6), uint32(1), uint32(12), uint32(1), uint32(3456), uint32(100))
+	write(uint32(106), uint32(1), uint32(49), uint32(1), uint32(0), uint32(1))
+
+	return b.Bytes()
+}
+
+// cleanedTestEXIF is testEXIF with the GPS block cleared
+func cleanedTestEXIF(order binary.ByteOrder) []byte {
+	exif := testEXIF(order)
+	clear(exif[testGPSIFD:])
+	return exif
+}
+
+func TestClearGPS(t *testing.T) {
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			exif := testEXIF(order)
+			if err := clearGPS(exif); err != nil {
+				t.Fatalf("clearGPS: %v", err)
+			}
+			if want := cleanedTestEXIF(order); !bytes.Equal(exif, want) {
+				t.Errorf("cleared EXIF\n% x\nwant\n% x", exif, want)
+			}
+
+			// The GPS pointer now leads to an empty IFD
+			if entries, err := ifdEntries(exif, testGPSIFD, order); err != nil || entries != 0 {
+				t.Errorf("GPS IFD has %d entries (%v), want 0", entries, err)
+			}
+		})
+	}
+}
+
+func TestClearGPSWithoutGPSBlock(t *testing.T) {
+	exif := testEXIF(binary.LittleEndian)
+	// Turn the GPS pointer into an unrelated tag
+	binary.LittleEndian.PutUint16(exif[22:], 0x010f)
+	want := append([]byte(nil), exif...)
+
+	if err := clearGPS(exif); err != nil {
+		t.Fatalf("clearGPS: %v", err)
+	}
+	if !bytes.Equal(exif, want) {
+		t.Error("EXIF without a GPS block was changed")
+	}
+}
+
+func TestClearGPSRejectsMalformedData(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func([]byte) []byte
+	}{
+		{"too short", func(exif []byte) []byte { return exif[:6] }},
+		{"unknown byte order", func(exif []byte) []byte { exif[0], exif[1] = 'X', 'X'; return exif }},
+		{"wrong magic number", func(exif []byte) []byte { exif[2] = 43; return exif }},
+		{"IFD0 beyond the data", func(exif []byte) []byte { binary.LittleEndian.PutUint32(exif[4:], 1000); return exif }},
+		{"GPS block beyond the data", func(exif []byte) []byte { binary.LittleEndian.PutUint32(exif[30:], 1000); return exif }},
+		{"GPS values beyond the data", func(exif []byte) []byte { return exif[:100] }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := clearGPS(tt.modify(testEXIF(binary.LittleEndian))); !errors.Is(err, errBadExif) {
+				t.Errorf("got error %v, want errBadExif", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// images/images.go
+package images
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Supported image types
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypeWebP = "image/webp"
+)
+
+var (
+	// ErrUnsupportedType is returned for files that are not JPEG, PNG or WebP images
+	ErrUnsupportedType = errors.New("unsupported image type: expected JPEG, PNG or WebP")
+	// ErrCorrupt is returned for images whose structure cannot be read
+	ErrCorrupt = errors.New("corrupt image file")
+)
+
+// extensions maps the supported types to the file extension they are stored with
+var extensions = map[string]string{
+	TypeJPEG: ".jpg",
+	TypePNG:  ".png",
+	TypeWebP: ".webp",
+}
+
+// Detect returns the type of an image from its content, ignoring whatever type the
+// client claimed
+func Detect(data []byte) (string, error) {
+	mimeType := http.DetectContentType(data)
+	if _, ok := extensions[mimeType]; !ok {
+		return "", ErrUnsupportedType
+	}
+	return mimeType, nil
+}
+
+// Extension returns the file extension of a supported image type
+func Extension(mimeType string) string {
+	return extensions[mimeType]
+}
+
+// StripLocation removes the location metadata a camera or phone may have written into
+// an image: the GPS block of the EXIF data and any XMP packet. The pixels and the rest
+// of the EXIF data, such as the orientation, are kept.
+func StripLocation(data []byte, mimeType string) ([]byte, error) {
+	switch mimeType {
+	case TypeJPEG:
+		return stripJPEG(data)
+	case TypePNG:
+		return stripPNG(data)
+	case TypeWebP:
+		return stripWebP(data)
+	default:
+		return nil, ErrUnsupportedType
+	}
+}
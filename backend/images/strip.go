@@ -0,0 +1,222 @@
+// images/strip.go
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+var (
+	exifHeader         = []byte("Exif\x00\x00")
+	xmpHeader          = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	xmpExtensionHeader = []byte("http://ns.adobe.com/xmp/extension/\x00")
+	pngSignature       = []byte("\x89PNG\r\n\x1a\n")
+)
+
+// cleanExif returns a copy of EXIF data with the GPS block cleared, or false if the data
+// cannot be read and should be dropped instead. A leading "Exif" header is allowed.
+func cleanExif(exif []byte) ([]byte, bool) {
+	cleaned := append([]byte(nil), exif...)
+	tiff := cleaned
+	if bytes.HasPrefix(tiff, exifHeader) {
+		tiff = tiff[len(exifHeader):]
+	}
+	if err := clearGPS(tiff); err != nil {
+		return nil, false
+	}
+	return cleaned, true
+}
+
+// stripJPEG walks the segments before the image data, clearing the GPS block of the EXIF
+// segment and dropping XMP segments
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, ErrCorrupt
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, 0xd8)
+	pos := 2
+
+	for {
+		// A marker is 0xff and a code, optionally preceded by more 0xff fill bytes
+		if pos >= len(data) || data[pos] != 0xff {
+			return nil, ErrCorrupt
+		}
+		for pos < len(data) && data[pos] == 0xff {
+			pos++
+		}
+		if pos >= len(data) {
+			return nil, ErrCorrupt
+		}
+		marker := data[pos]
+		pos++
+
+		switch {
+		case marker == 0xd9: // end of image
+			return append(out, 0xff, marker), nil
+		case marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7): // markers without a length
+			out = append(out, 0xff, marker)
+			continue
+		}
+
+		if pos+2 > len(data) {
+			return nil, ErrCorrupt
+		}
+		length := int(binary.BigEndian.Uint16(data[pos:]))
+		if length < 2 || pos+length > len(data) {
+			return nil, ErrCorrupt
+		}
+		payload := data[pos+2 : pos+length]
+		pos += length
+
+		segment := func(payload []byte) []byte {
+			header := []byte{0xff, marker, 0, 0}
+			binary.BigEndian.PutUint16(header[2:], uint16(len(payload)+2))
+			return append(header, payload...)
+		}
+
+		// Start of scan: the compressed image data follows up to the end of the file.
+		// Metadata segments always come before it.
+		if marker == 0xda {
+			out = append(out, segment(payload)...)
+			return append(out, data[pos:]...), nil
+		}
+
+		if marker == 0xe1 {
+			switch {
+			case bytes.HasPrefix(payload, exifHeader):
+				cleaned, ok := cleanExif(payload)
+				if ok {
+					out = append(out, segment(cleaned)...)
+				}
+				continue
+			case bytes.HasPrefix(payload, xmpHeader), bytes.HasPrefix(payload, xmpExtensionHeader):
+				continue
+			}
+		}
+
+		out = append(out, segment(payload)...)
+	}
+}
+
+// stripPNG clears the GPS block of the eXIf chunk and drops text chunks carrying XMP or
+// raw EXIF profiles
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrCorrupt
+	}
+
+	out := append([]byte(nil), pngSignature...)
+	pos := len(pngSignature)
+
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, ErrCorrupt
+		}
+		length := binary.BigEndian.Uint32(data[pos:])
+		end := uint64(pos) + 12 + uint64(length)
+		if end > uint64(len(data)) {
+			return nil, ErrCorrupt
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		body := data[pos+8 : end-4]
+		chunk := data[pos:end]
+		pos = int(end)
+
+		switch chunkType {
+		case "eXIf":
+			cleaned, ok := cleanExif(body)
+			if ok {
+				out = append(out, pngChunk(chunkType, cleaned)...)
+			}
+			continue
+		case "tEXt", "zTXt", "iTXt":
+			keyword, _, _ := bytes.Cut(body, []byte{0})
+			if string(keyword) == "XML:com.adobe.xmp" || bytes.HasPrefix(keyword, []byte("Raw profile type")) {
+				continue
+			}
+		}
+
+		out = append(out, chunk...)
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	return out, nil
+}
+
+func pngChunk(chunkType string, body []byte) []byte {
+	chunk := make([]byte, 8, 12+len(body))
+	binary.BigEndian.PutUint32(chunk, uint32(len(body)))
+	copy(chunk[4:], chunkType)
+	chunk = append(chunk, body...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// stripWebP clears the GPS block of the EXIF chunk and drops the XMP chunk, updating
+// the VP8X feature flags and the RIFF size to match
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrCorrupt
+	}
+
+	const (
+		exifFlag = 0x08
+		xmpFlag  = 0x04
+	)
+
+	out := append([]byte(nil), data[:12]...)
+	pos := 12
+	vp8x := -1
+	var dropped byte
+
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, ErrCorrupt
+		}
+		fourCC := string(data[pos : pos+4])
+		size := binary.LittleEndian.Uint32(data[pos+4:])
+		bodyEnd := uint64(pos) + 8 + uint64(size)
+		if bodyEnd > uint64(len(data)) {
+			return nil, ErrCorrupt
+		}
+		// Chunks are padded to an even size; tolerate a missing pad byte at the end
+		end := bodyEnd + uint64(size&1)
+		if end > uint64(len(data)) {
+			end = bodyEnd
+		}
+		body := data[pos+8 : bodyEnd]
+		chunk := data[pos:end]
+		pos = int(end)
+
+		switch fourCC {
+		case "EXIF":
+			cleaned, ok := cleanExif(body)
+			if !ok {
+				dropped |= exifFlag
+				continue
+			}
+			out = append(out, chunk[:8]...)
+			out = append(out, cleaned...)
+			out = append(out, chunk[8+len(body):]...)
+			continue
+		case "XMP ":
+			dropped |= xmpFlag
+			continue
+		case "VP8X":
+			vp8x = len(out)
+		}
+
+		out = append(out, chunk...)
+	}
+
+	if vp8x >= 0 && len(out) > vp8x+8 {
+		out[vp8x+8] &^= dropped
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+
+	return out, nil
+}
@@ -0,0 +1,323 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testXMP is an XMP packet carrying the position
+var testXMP = []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">` +
+	`<rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/" exif:GPSLatitude="6,12.3456S" exif:GPSLongitude="106,49.0E"/>` +
+	`</rdf:RDF></x:xmpmeta>`)
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{uint8(x * 32), uint8(y * 32), 128, 255})
+		}
+	}
+	return img
+}
+
+// jpegSegment returns a JPEG marker segment
+func jpegSegment(marker byte, payload []byte) []byte {
+	segment := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// findJPEGSegment returns the payload of the first segment with the marker whose payload
+// starts with the prefix
+func findJPEGSegment(data []byte, marker byte, prefix []byte) ([]byte, bool) {
+	for pos := 2; pos+4 <= len(data) && data[pos] == 0xff && data[pos+1] != 0xda; {
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		payload := data[pos+4 : pos+2+length]
+		if data[pos+1] == marker && bytes.HasPrefix(payload, prefix) {
+			return payload, true
+		}
+		pos += 2 + length
+	}
+	return nil, false
+}
+
+func TestStripJPEG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	// Insert EXIF and XMP segments after the start of image marker, as cameras do
+	var fixture bytes.Buffer
+	fixture.Write(encoded.Bytes()[:2])
+	fixture.Write(jpegSegment(0xe1, append(append([]byte(nil), exifHeader...), testEXIF(binary.BigEndian)...)))
+	fixture.Write(jpegSegment(0xe1, append(append([]byte(nil), xmpHeader...), testXMP...)))
+	fixture.Write(encoded.Bytes()[2:])
+
+	stripped, err := StripLocation(fixture.Bytes(), TypeJPEG)
+	if err != nil {
+		t.Fatalf("StripLocation: %v", err)
+	}
+
+	exif, ok := findJPEGSegment(stripped, 0xe1, exifHeader)
+	if !ok {
+		t.Fatal("EXIF segment dropped")
+	}
+	if want := cleanedTestEXIF(binary.BigEndian); !bytes.Equal(exif[len(exifHeader):], want) {
+		t.Error("GPS block left in the EXIF segment")
+	}
+	if _, ok := findJPEGSegment(stripped, 0xe1, xmpHeader); ok || bytes.Contains(stripped, []byte("GPSLatitude")) {
+		t.Error("XMP segment left in the image")
+	}
+
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped image does not decode: %v", err)
+	}
+	// The image data itself is unchanged
+	if !bytes.HasSuffix(stripped, encoded.Bytes()[2:]) {
+		t.Error("image data changed")
+	}
+}
+
+func TestStripJPEGDropsUnreadableEXIF(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	var fixture bytes.Buffer
+	fixture.Write(encoded.Bytes()[:2])
+	fixture.Write(jpegSegment(0xe1, append(append([]byte(nil), exifHeader...), testEXIF(binary.BigEndian)[:100]...)))
+	fixture.Write(encoded.Bytes()[2:])
+
+	stripped, err := StripLocation(fixture.Bytes(), TypeJPEG)
+	if err != nil {
+		t.Fatalf("StripLocation: %v", err)
+	}
+	if _, ok := findJPEGSegment(stripped, 0xe1, exifHeader); ok {
+		t.Error("unreadable EXIF segment kept")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped image does not decode: %v", err)
+	}
+}
+
+func TestStripJPEGRejectsCorruptImages(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := jpeg.Encode(&encoded, testImage(), nil); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data := encoded.Bytes()
+
+	for name, corrupt := range map[string][]byte{
+		"no start of image":   data[2:],
+		"truncated segment":   data[:20],
+		"segment beyond data": append(append([]byte(nil), data[:2]...), 0xff, 0xe1, 0xff, 0xff),
+	} {
+		if _, err := stripJPEG(corrupt); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: got error %v, want ErrCorrupt", name, err)
+		}
+	}
+}
+
+// pngChunks returns the chunks of a PNG file by type, in order
+func pngChunks(t *testing.T, data []byte) ([]string, map[string][][]byte) {
+	t.Helper()
+
+	var order []string
+	bodies := make(map[string][][]byte)
+	for pos := len(pngSignature); pos < len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		order = append(order, chunkType)
+		bodies[chunkType] = append(bodies[chunkType], data[pos+8:pos+8+length])
+		pos += 12 + length
+	}
+	return order, bodies
+}
+
+func TestStripPNG(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testImage()); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	// Insert the metadata chunks after IHDR, which is 25 bytes after the signature
+	ihdrEnd := len(pngSignature) + 25
+	var fixture bytes.Buffer
+	fixture.Write(encoded.Bytes()[:ihdrEnd])
+	fixture.Write(pngChunk("eXIf", testEXIF(binary.LittleEndian)))
+	fixture.Write(pngChunk("iTXt", append([]byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00"), testXMP...)))
+	fixture.Write(pngChunk("zTXt", []byte("Raw profile type exif\x00\x00compressed")))
+	fixture.Write(pngChunk("tEXt", []byte("Comment\x00lunch")))
+	fixture.Write(encoded.Bytes()[ihdrEnd:])
+
+	stripped, err := StripLocation(fixture.Bytes(), TypePNG)
+	if err != nil {
+		t.Fatalf("StripLocation: %v", err)
+	}
+
+	order, chunks := pngChunks(t, stripped)
+	if len(chunks["eXIf"]) != 1 || !bytes.Equal(chunks["eXIf"][0], cleanedTestEXIF(binary.LittleEndian)) {
+		t.Error("GPS block left in the eXIf chunk")
+	}
+	if len(chunks["iTXt"]) != 0 || len(chunks["zTXt"]) != 0 || bytes.Contains(stripped, []byte("GPSLatitude")) {
+		t.Errorf("metadata chunks left in the image: %v", order)
+	}
+	if len(chunks["tEXt"]) != 1 {
+		t.Errorf("other text chunks dropped: %v", order)
+	}
+	if order[len(order)-1] != "IEND" {
+		t.Errorf("chunks %v do not end with IEND", order)
+	}
+
+	// The decoder checks the CRC of every chunk, including the rewritten eXIf
+	if _, err := png.Decode(bytes.NewReader(stripped)); err != nil {
+		t.Errorf("stripped image does not decode: %v", err)
+	}
+}
+
+func TestStripPNGRejectsCorruptImages(t *testing.T) {
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, testImage()); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	data := encoded.Bytes()
+
+	for name, corrupt := range map[string][]byte{
+		"no signature":    data[1:],
+		"truncated chunk": data[:len(pngSignature)+20],
+	} {
+		if _, err := stripPNG(corrupt); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: got error %v, want ErrCorrupt", name, err)
+		}
+	}
+}
+
+// riffChunk returns a RIFF chunk, padded to an even size
+func riffChunk(fourCC string, body []byte) []byte {
+	chunk := []byte(fourCC)
+	chunk = binary.LittleEndian.AppendUint32(chunk, uint32(len(body)))
+	chunk = append(chunk, body...)
+	if len(body)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// testWebPImage is the VP8L bitstream of a 1x1 lossless image
+var testWebPImage = []byte{0x2f, 0x00, 0x00, 0x00, 0x00, 0x07, 0x10, 0x11, 0x11, 0x88, 0x88, 0xfe, 0x07, 0x00}
+
+// webpChunks returns the chunks of a WebP file by FourCC, checking the RIFF structure
+func webpChunks(t *testing.T, data []byte) ([]string, map[string][]byte) {
+	t.Helper()
+
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		t.Fatal("not a WebP file")
+	}
+	if size := binary.LittleEndian.Uint32(data[4:]); int(size) != len(data)-8 {
+		t.Errorf("RIFF size %d, want %d", size, len(data)-8)
+	}
+
+	var order []string
+	bodies := make(map[string][]byte)
+	pos := 12
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			t.Fatalf("truncated chunk header at %d", pos)
+		}
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		if pos+8+size > len(data) {
+			t.Fatalf("chunk %q runs past the file", fourCC)
+		}
+		order = append(order, fourCC)
+		bodies[fourCC] = data[pos+8 : pos+8+size]
+		pos += 8 + size + size%2
+	}
+	if pos != len(data) {
+		t.Errorf("chunks end at %d, file at %d", pos, len(data))
+	}
+	return order, bodies
+}
+
+func TestStripWebP(t *testing.T) {
+	// VP8X with the EXIF and XMP flags set and a 1x1 canvas
+	vp8x := []byte{0x0c, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	// An odd size, so the EXIF chunk is padded
+	exif := append(testEXIF(binary.LittleEndian), 0)
+
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	body.Write(riffChunk("VP8X", vp8x))
+	body.Write(riffChunk("VP8L", testWebPImage))
+	body.Write(riffChunk("EXIF", exif))
+	body.Write(riffChunk("XMP ", testXMP))
+	fixture := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(body.Len()))...)
+	fixture = append(fixture, body.Bytes()...)
+
+	stripped, err := StripLocation(fixture, TypeWebP)
+	if err != nil {
+		t.Fatalf("StripLocation: %v", err)
+	}
+
+	// The standard library has no WebP decoder; check the container is still well formed
+	// and the image chunk is untouched
+	order, chunks := webpChunks(t, stripped)
+	if want := []string{"VP8X", "VP8L", "EXIF"}; len(order) != len(want) || order[0] != want[0] || order[1] != want[1] || order[2] != want[2] {
+		t.Errorf("chunks %v, want %v", order, want)
+	}
+	if flags := chunks["VP8X"][0]; flags != 0x08 {
+		t.Errorf("VP8X flags %#x, want only the EXIF flag", flags)
+	}
+	if !bytes.Equal(chunks["VP8L"], testWebPImage) {
+		t.Error("image data changed")
+	}
+	if want := append(cleanedTestEXIF(binary.LittleEndian), 0); !bytes.Equal(chunks["EXIF"], want) {
+		t.Error("GPS block left in the EXIF chunk")
+	}
+	if bytes.Contains(stripped, []byte("GPSLatitude")) {
+		t.Error("XMP left in the image")
+	}
+}
+
+func TestStripWebPDropsUnreadableEXIF(t *testing.T) {
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	body.Write(riffChunk("VP8X", []byte{0x08, 0, 0, 0, 0, 0, 0, 0, 0, 0}))
+	body.Write(riffChunk("VP8L", testWebPImage))
+	body.Write(riffChunk("EXIF", []byte("not EXIF")))
+	fixture := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(body.Len()))...)
+	fixture = append(fixture, body.Bytes()...)
+
+	stripped, err := StripLocation(fixture, TypeWebP)
+	if err != nil {
+		t.Fatalf("StripLocation: %v", err)
+	}
+
+	order, chunks := webpChunks(t, stripped)
+	if _, ok := chunks["EXIF"]; ok {
+		t.Errorf("unreadable EXIF chunk kept: %v", order)
+	}
+	if flags := chunks["VP8X"][0]; flags != 0 {
+		t.Errorf("VP8X flags %#x, want none", flags)
+	}
+}
+
+func TestStripWebPRejectsCorruptImages(t *testing.T) {
+	for name, corrupt := range map[string][]byte{
+		"not RIFF":          []byte("RIFX\x04\x00\x00\x00WEBP"),
+		"not WebP":          []byte("RIFF\x04\x00\x00\x00WAVE"),
+		"chunk beyond data": append([]byte("RIFF\x10\x00\x00\x00WEBPVP8L"), 0xff, 0, 0, 0),
+	} {
+		if _, err := stripWebP(corrupt); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: got error %v, want ErrCorrupt", name, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// llm/config.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Provider identifiers accepted by LLM_PROVIDER
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderFake   = "fake"
+)
+
+// Features that use a language model. Each can be pointed at its own provider and model.
+const (
+	FeatureAssessment = "assessment"
+	FeatureFood       = "food"
+	FeatureChatbot    = "chatbot"
+)
+
+// Config holds the connection settings of a provider
+type Config struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+	Timeout time.Duration
+}
+
+// featureDefaults keeps the models and timeouts each feature used before providers were pluggable
+var featureDefaults = map[string]struct {
+	geminiModel string
+	timeout     time.Duration
+}{
+	FeatureAssessment: {geminiModel: "gemini-2.0-flash", timeout: 10 * time.Second},
+	FeatureFood:       {geminiModel: "gemini-1.5-flash", timeout: 30 * time.Second},
+	FeatureChatbot:    {geminiModel: "gemini-1.5-flash", timeout: 20 * time.Second},
+}
+
+// NewForFeature returns the provider configured for a feature.
+//
+// The provider is read from LLM_PROVIDER_<FEATURE>, then LLM_PROVIDER (default "gemini").
+// The model is read from LLM_MODEL_<FEATURE>, then GEMINI_MODEL / OPENAI_MODEL.
+// The timeout is read from LLM_TIMEOUT_<FEATURE>, then LLM_TIMEOUT.
+func NewForFeature(feature string) Provider {
+	defaults, ok := featureDefaults[feature]
+	if !ok {
+		defaults = featureDefaults[FeatureChatbot]
+	}
+
+	name := strings.ToLower(featureEnv("LLM_PROVIDER", feature))
+	if name == "" {
+		name = ProviderGemini
+	}
+
+	timeout := defaults.timeout
+	if raw := featureEnv("LLM_TIMEOUT", feature); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			log.Printf("Warning: invalid LLM timeout %q for %s, using %s", raw, feature, timeout)
+		} else {
+			timeout = parsed
+		}
+	}
+
+	model := os.Getenv("LLM_MODEL_" + strings.ToUpper(feature))
+
+	switch name {
+	case ProviderGemini:
+		if model == "" {
+			model = envOrDefault("GEMINI_MODEL", defaults.geminiModel)
+		}
+		return NewGemini(Config{
+			APIKey:  os.Getenv("GEMINI_API_KEY"),
+			BaseURL: os.Getenv("GEMINI_BASE_URL"),
+			Model:   model,
+			Timeout: timeout,
+		})
+	case ProviderOpenAI:
+		if model == "" {
+			model = envOrDefault("OPENAI_MODEL", "gpt-4o-mini")
+		}
+		return NewOpenAI(Config{
+			APIKey:  os.Getenv("OPENAI_API_KEY"),
+			BaseURL: os.Getenv("OPENAI_BASE_URL"),
+			Model:   model,
+			Timeout: timeout,
+		})
+	case ProviderFake:
+		return NewFake()
+	default:
+		log.Printf("Warning: unknown LLM provider %q for %s", name, feature)
+		return &unavailable{err: fmt.Errorf("unknown LLM provider %q", name)}
+	}
+}
+
+// featureEnv reads KEY_<FEATURE> and falls back to KEY
+func featureEnv(key, feature string) string {
+	if value := os.Getenv(key + "_" + strings.ToUpper(feature)); value != "" {
+		return value
+	}
+	return os.Getenv(key)
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// unavailable is returned for a misconfigured provider so callers fall back gracefully
+type unavailable struct {
+	err error
+}
+
+func (u *unavailable) Name() string {
+	return "unavailable"
+}
+
+func (u *unavailable) GenerateText(ctx context.Context, prompt string, opts Options) (string, error) {
+	return "", u.err
+}
+
+func (u *unavailable) GenerateVision(ctx context.Context, prompt string, image Image, opts Options) (string, error) {
+	return "", u.err
+}
+
+func (u *unavailable) Chat(ctx context.Context, system string, history []Message, opts Options) (string, error) {
+	return "", u.err
+}
+
+func (u *unavailable) ChatStream(ctx context.Context, system string, history []Message, opts Options, onChunk func(chunk string) error) (string, error) {
+	return "", u.err
+}
@@ -0,0 +1,135 @@
+// llm/fake.go
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fake is a deterministic provider for tests and offline development.
+// Each method returns its configured response, or a canned answer derived
+// from the input when the response is empty, and records the prompts it received.
+type Fake struct {
+	TextResponse   string
+	VisionResponse string
+	ChatResponse   string
+	Err            error
+
+	// StreamDelay is the pause between words sent by ChatStream
+	StreamDelay time.Duration
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+// NewFake creates a fake provider with canned responses
+func NewFake() *Fake {
+	return &Fake{}
+}
+
+// Name returns the provider identifier
+func (f *Fake) Name() string {
+	return ProviderFake
+}
+
+// Prompts returns every prompt or last chat message received so far
+func (f *Fake) Prompts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]string(nil), f.prompts...)
+}
+
+// GenerateText returns TextResponse, or a risk assessment JSON derived from the prompt
+func (f *Fake) GenerateText(ctx context.Context, prompt string, opts Options) (string, error) {
+	f.record(prompt)
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if f.TextResponse != "" {
+		return f.TextResponse, nil
+	}
+
+	return fmt.Sprintf(`{"risk_percentage": %d, "risk_factors": ["Sedentary lifestyle", "Irregular sleep patterns", "Dietary habits"], "recommendations": ["Exercise at least 30 minutes daily", "Keep a regular sleep schedule", "Eat more fruits and vegetables"]}`,
+		fakeHash(prompt)%100), nil
+}
+
+// GenerateVision returns VisionResponse, or a per-item nutrition JSON derived from the image
+func (f *Fake) GenerateVision(ctx context.Context, prompt string, image Image, opts Options) (string, error) {
+	f.record(prompt)
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if f.VisionResponse != "" {
+		return f.VisionResponse, nil
+	}
+
+	h := fakeHash(string(image.Data))
+	return fmt.Sprintf(`{"items": [`+
+		`{"name": "Nasi putih", "portion_grams": %d, "calories": %d, "protein_grams": %d, "carbs_grams": %d, "fat_grams": 0.5, "fiber_grams": 0.6, `+
+		`"sugar_grams": 0.1, "sodium_mg": 2, "saturated_fat_grams": 0.1, "cholesterol_mg": 0, "confidence": 0.9}, `+
+		`{"name": "Ayam goreng", "portion_grams": %d, "calories": %d, "protein_grams": %d, "carbs_grams": 5, "fat_grams": %d, "fiber_grams": %d, `+
+		`"sugar_grams": 0.5, "sodium_mg": %d, "saturated_fat_grams": %d, "cholesterol_mg": %d, "confidence": 0.8}`+
+		`], "healthiness_score": %d}`,
+		100+h%100, 130+h%130, 3+h%3, 28+h%30, 60+h%80, 150+h%200, 10+h%20, 5+h%15, h%3,
+		300+h%500, 2+h%4, 60+h%60, 1+h%10), nil
+}
+
+// Chat returns ChatResponse, or echoes the last message
+func (f *Fake) Chat(ctx context.Context, system string, history []Message, opts Options) (string, error) {
+	last := ""
+	if len(history) > 0 {
+		last = history[len(history)-1].Content
+	}
+	f.record(last)
+	if f.Err != nil {
+		return "", f.Err
+	}
+	if f.ChatResponse != "" {
+		return f.ChatResponse, nil
+	}
+
+	return fmt.Sprintf("This is an offline response to: %s. Remember to stay active, eat well and rest enough.", last), nil
+}
+
+// ChatStream returns the same reply as Chat, delivered one word at a time
+func (f *Fake) ChatStream(ctx context.Context, system string, history []Message, opts Options, onChunk func(chunk string) error) (string, error) {
+	reply, err := f.Chat(ctx, system, history, opts)
+	if err != nil {
+		return "", err
+	}
+
+	var sent strings.Builder
+	for i, word := range strings.SplitAfter(reply, " ") {
+		if err := ctx.Err(); err != nil {
+			return sent.String(), err
+		}
+		if i > 0 && f.StreamDelay > 0 {
+			time.Sleep(f.StreamDelay)
+		}
+
+		sent.WriteString(word)
+		if err := onChunk(word); err != nil {
+			return sent.String(), err
+		}
+	}
+
+	return sent.String(), nil
+}
+
+func (f *Fake) record(prompt string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.prompts = append(f.prompts, prompt)
+}
+
+func fakeHash(s string) int {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return int(h.Sum32() & 0x7fffffff)
+}
@@ -0,0 +1,295 @@
+// llm/gemini.go
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultGeminiBaseURL is the public Gemini REST endpoint
+const DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// Gemini talks to the Google Gemini generateContent API
+type Gemini struct {
+	apiKey  string
+	baseURL string
+	model   string
+	client  *http.Client
+	// streamClient has no overall timeout; timeout bounds the wait for the first byte
+	// and the gaps between chunks of a streamed reply
+	streamClient *http.Client
+	timeout      time.Duration
+}
+
+// NewGemini creates a Gemini provider from the given configuration
+func NewGemini(cfg Config) *Gemini {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultGeminiBaseURL
+	}
+
+	return &Gemini{
+		apiKey:  cfg.APIKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   cfg.Model,
+		client:  &http.Client{Timeout: cfg.Timeout},
+
+		streamClient: newStreamClient(cfg.Timeout),
+		timeout:      cfg.Timeout,
+	}
+}
+
+type geminiPart struct {
+	Text       string            `json:"text,omitempty"`
+	InlineData *geminiInlineData `json:"inlineData,omitempty"`
+}
+
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     float64 `json:"temperature,omitempty"`
+	TopP            float64 `json:"topP,omitempty"`
+	TopK            int     `json:"topK,omitempty"`
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+}
+
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	SafetySettings    []geminiSafetySetting   `json:"safetySettings,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []geminiPart `json:"parts"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback,omitempty"`
+}
+
+// geminiSafetySettings blocks medium and above for every harm category
+var geminiSafetySettings = []geminiSafetySetting{
+	{Category: "HARM_CATEGORY_HARASSMENT", Threshold: "BLOCK_MEDIUM_AND_ABOVE"},
+	{Category: "HARM_CATEGORY_HATE_SPEECH", Threshold: "BLOCK_MEDIUM_AND_ABOVE"},
+	{Category: "HARM_CATEGORY_SEXUALLY_EXPLICIT", Threshold: "BLOCK_MEDIUM_AND_ABOVE"},
+	{Category: "HARM_CATEGORY_DANGEROUS_CONTENT", Threshold: "BLOCK_MEDIUM_AND_ABOVE"},
+}
+
+// Name returns the provider identifier
+func (g *Gemini) Name() string {
+	return ProviderGemini
+}
+
+// Model returns the configured model name
+func (g *Gemini) Model() string {
+	return g.model
+}
+
+// GenerateText sends a single text prompt to Gemini
+func (g *Gemini) GenerateText(ctx context.Context, prompt string, opts Options) (string, error) {
+	return g.generate(ctx, geminiRequest{
+		Contents: []geminiContent{
+			{Role: "user", Parts: []geminiPart{{Text: prompt}}},
+		},
+		GenerationConfig: g.generationConfig(opts),
+	})
+}
+
+// GenerateVision sends a text prompt with an inline image to Gemini
+func (g *Gemini) GenerateVision(ctx context.Context, prompt string, image Image, opts Options) (string, error) {
+	return g.generate(ctx, geminiRequest{
+		Contents: []geminiContent{
+			{
+				Role: "user",
+				Parts: []geminiPart{
+					{Text: prompt},
+					{InlineData: &geminiInlineData{
+						MimeType: image.MimeType,
+						Data:     base64.StdEncoding.EncodeToString(image.Data),
+					}},
+				},
+			},
+		},
+		GenerationConfig: g.generationConfig(opts),
+	})
+}
+
+// Chat sends the system prompt and conversation history to Gemini
+func (g *Gemini) Chat(ctx context.Context, system string, history []Message, opts Options) (string, error) {
+	return g.generate(ctx, g.chatRequest(system, history, opts))
+}
+
+// ChatStream streams the reply from Gemini using streamGenerateContent
+func (g *Gemini) ChatStream(ctx context.Context, system string, history []Message, opts Options, onChunk func(chunk string) error) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := g.post(ctx, g.streamClient, "streamGenerateContent?alt=sse", g.chatRequest(system, history, opts))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("gemini API returned status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	body := newStreamBody(resp.Body, g.timeout, cancel)
+	defer body.stop()
+
+	var full strings.Builder
+	err = readSSE(body, func(data string) error {
+		var parsed geminiResponse
+		if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+			return fmt.Errorf("failed to parse Gemini stream event: %v", err)
+		}
+
+		chunk, err := parsed.text()
+		if errors.Is(err, ErrEmptyResponse) {
+			return nil // Final events may only carry the finish reason
+		}
+		if err != nil {
+			return err
+		}
+
+		full.WriteString(chunk)
+		return onChunk(chunk)
+	})
+	if err != nil {
+		return full.String(), err
+	}
+
+	if full.Len() == 0 {
+		return "", ErrEmptyResponse
+	}
+
+	return full.String(), nil
+}
+
+// chatRequest builds the request body shared by Chat and ChatStream
+func (g *Gemini) chatRequest(system string, history []Message, opts Options) geminiRequest {
+	contents := make([]geminiContent, 0, len(history))
+	for _, msg := range history {
+		role := "user"
+		if msg.Role == RoleAssistant {
+			role = "model" // Gemini calls the assistant role "model"
+		}
+		contents = append(contents, geminiContent{Role: role, Parts: []geminiPart{{Text: msg.Content}}})
+	}
+
+	req := geminiRequest{
+		Contents:         contents,
+		SafetySettings:   geminiSafetySettings,
+		GenerationConfig: g.generationConfig(opts),
+	}
+	if system != "" {
+		req.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+	}
+
+	return req
+}
+
+func (g *Gemini) generationConfig(opts Options) *geminiGenerationConfig {
+	if opts == (Options{}) {
+		return nil
+	}
+
+	return &geminiGenerationConfig{
+		Temperature:     opts.Temperature,
+		TopP:            opts.TopP,
+		TopK:            opts.TopK,
+		MaxOutputTokens: opts.MaxOutputTokens,
+	}
+}
+
+// generate posts the request to generateContent and extracts the first text part
+func (g *Gemini) generate(ctx context.Context, body geminiRequest) (string, error) {
+	resp, err := g.post(ctx, g.client, "generateContent", body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gemini API returned status code %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var parsed geminiResponse
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", fmt.Errorf("failed to parse Gemini response: %v", err)
+	}
+
+	return parsed.text()
+}
+
+// post sends the JSON body to the given model method
+func (g *Gemini) post(ctx context.Context, client *http.Client, method string, body geminiRequest) (*http.Response, error) {
+	if g.apiKey == "" {
+		return nil, errors.New("GEMINI_API_KEY environment variable is not set")
+	}
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/models/%s:%s", g.baseURL, g.model, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", g.apiKey)
+
+	return client.Do(req)
+}
+
+// text returns the first text part of the first candidate
+func (r *geminiResponse) text() (string, error) {
+	if len(r.Candidates) == 0 {
+		if r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+			return "", fmt.Errorf("prompt blocked by Gemini: %s", r.PromptFeedback.BlockReason)
+		}
+		return "", fmt.Errorf("no candidates in response: %w", ErrEmptyResponse)
+	}
+
+	for _, part := range r.Candidates[0].Content.Parts {
+		if part.Text != "" {
+			return part.Text, nil
+		}
+	}
+
+	return "", ErrEmptyResponse
+}
@@ -0,0 +1,110 @@
+// llm/llm.go
+package llm
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Role identifies the author of a chat message
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Message is a single turn in a chat history
+type Message struct {
+	Role    Role
+	Content string
+}
+
+// Image is an inline image passed to a vision model
+type Image struct {
+	MimeType string
+	Data     []byte
+}
+
+// Options tunes a single generation call. Zero values mean "use the provider default".
+type Options struct {
+	Temperature     float64
+	TopP            float64
+	TopK            int
+	MaxOutputTokens int
+}
+
+// Provider is implemented by every language model backend used by the services
+type Provider interface {
+	// Name returns the provider identifier (e.g. "gemini", "openai", "fake")
+	Name() string
+
+	// GenerateText sends a single prompt and returns the generated text
+	GenerateText(ctx context.Context, prompt string, opts Options) (string, error)
+
+	// GenerateVision sends a prompt together with an image and returns the generated text
+	GenerateVision(ctx context.Context, prompt string, image Image, opts Options) (string, error)
+
+	// Chat sends a system prompt and the conversation history, the last message being
+	// the one to answer, and returns the assistant reply
+	Chat(ctx context.Context, system string, history []Message, opts Options) (string, error)
+
+	// ChatStream works like Chat but calls onChunk with each piece of text as it is
+	// generated. It returns the text received so far, also when the stream is interrupted.
+	ChatStream(ctx context.Context, system string, history []Message, opts Options, onChunk func(chunk string) error) (string, error)
+}
+
+// ModelID identifies the provider and model that produced an answer, e.g.
+// "gemini/gemini-2.0-flash", for recording alongside stored results
+func ModelID(p Provider) string {
+	if m, ok := p.(interface{ Model() string }); ok && m.Model() != "" {
+		return p.Name() + "/" + m.Model()
+	}
+	return p.Name()
+}
+
+// ErrEmptyResponse is returned when a provider answers without any text
+var ErrEmptyResponse = errors.New("no text found in response")
+
+// ExtractJSON strips markdown code fences and surrounding prose from a model answer
+// and returns the outermost JSON object. The text is returned trimmed as-is when no
+// object can be found.
+func ExtractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.ReplaceAll(text, "```json", "")
+	text = strings.ReplaceAll(text, "```", "")
+
+	jsonStart := strings.Index(text, "{")
+	jsonEnd := strings.LastIndex(text, "}")
+	if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
+		return text[jsonStart : jsonEnd+1]
+	}
+
+	return strings.TrimSpace(text)
+}
+
+// readSSE reads a Server-Sent Events body and calls onData with the payload of every
+// "data:" line until the body ends or onData returns an error
+func readSSE(body io.Reader, onData func(data string) error) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		if err := onData(strings.TrimSpace(strings.TrimPrefix(line, "data:"))); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// errStreamDone stops readSSE when a provider signals the end of the stream
+var errStreamDone = errors.New("stream done")
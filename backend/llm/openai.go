@@ -0,0 +1,256 @@
+// llm/openai.go
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOpenAIBaseURL is the public OpenAI REST endpoint
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAI talks to any server implementing the OpenAI chat completions API,
+// including local stand-ins such as Ollama, llama.cpp or LM Studio
+type OpenAI struct {
+	apiKey  string
+	baseURL string
+	model   string
+	client  *http.Client
+	// streamClient has no overall timeout; timeout bounds the wait for the first byte
+	// and the gaps between chunks of a streamed reply
+	streamClient *http.Client
+	timeout      time.Duration
+}
+
+// NewOpenAI creates an OpenAI-compatible provider from the given configuration
+func NewOpenAI(cfg Config) *OpenAI {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+
+	return &OpenAI{
+		apiKey:  cfg.APIKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   cfg.Model,
+		client:  &http.Client{Timeout: cfg.Timeout},
+
+		streamClient: newStreamClient(cfg.Timeout),
+		timeout:      cfg.Timeout,
+	}
+}
+
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // string or []openAIContentPart
+}
+
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Temperature float64         `json:"temperature,omitempty"`
+	TopP        float64         `json:"top_p,omitempty"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+}
+
+type openAIStreamRequest struct {
+	openAIRequest
+	Stream bool `json:"stream"`
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+// Name returns the provider identifier
+func (o *OpenAI) Name() string {
+	return ProviderOpenAI
+}
+
+// Model returns the configured model name
+func (o *OpenAI) Model() string {
+	return o.model
+}
+
+// GenerateText sends a single user prompt
+func (o *OpenAI) GenerateText(ctx context.Context, prompt string, opts Options) (string, error) {
+	return o.complete(ctx, []openAIMessage{
+		{Role: "user", Content: prompt},
+	}, opts)
+}
+
+// GenerateVision sends a user prompt with the image embedded as a data URI
+func (o *OpenAI) GenerateVision(ctx context.Context, prompt string, image Image, opts Options) (string, error) {
+	dataURI := fmt.Sprintf("data:%s;base64,%s", image.MimeType, base64.StdEncoding.EncodeToString(image.Data))
+
+	return o.complete(ctx, []openAIMessage{
+		{
+			Role: "user",
+			Content: []openAIContentPart{
+				{Type: "text", Text: prompt},
+				{Type: "image_url", ImageURL: &openAIImageURL{URL: dataURI}},
+			},
+		},
+	}, opts)
+}
+
+// Chat sends the system prompt followed by the conversation history
+func (o *OpenAI) Chat(ctx context.Context, system string, history []Message, opts Options) (string, error) {
+	return o.complete(ctx, o.chatMessages(system, history), opts)
+}
+
+// ChatStream streams the reply using the chat completions stream mode
+func (o *OpenAI) ChatStream(ctx context.Context, system string, history []Message, opts Options, onChunk func(chunk string) error) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resp, err := o.post(ctx, o.streamClient, openAIStreamRequest{
+		openAIRequest: o.request(o.chatMessages(system, history), opts),
+		Stream:        true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("openai API returned status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	body := newStreamBody(resp.Body, o.timeout, cancel)
+	defer body.stop()
+
+	var full strings.Builder
+	err = readSSE(body, func(data string) error {
+		if data == "[DONE]" {
+			return errStreamDone
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("failed to parse OpenAI stream event: %v", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			return nil
+		}
+
+		full.WriteString(chunk.Choices[0].Delta.Content)
+		return onChunk(chunk.Choices[0].Delta.Content)
+	})
+	if err != nil && !errors.Is(err, errStreamDone) {
+		return full.String(), err
+	}
+
+	if full.Len() == 0 {
+		return "", ErrEmptyResponse
+	}
+
+	return full.String(), nil
+}
+
+// chatMessages converts the history into OpenAI messages
+func (o *OpenAI) chatMessages(system string, history []Message) []openAIMessage {
+	messages := make([]openAIMessage, 0, len(history)+1)
+	if system != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: system})
+	}
+	for _, msg := range history {
+		role := "user"
+		if msg.Role == RoleAssistant {
+			role = "assistant"
+		}
+		messages = append(messages, openAIMessage{Role: role, Content: msg.Content})
+	}
+
+	return messages
+}
+
+func (o *OpenAI) request(messages []openAIMessage, opts Options) openAIRequest {
+	return openAIRequest{
+		Model:       o.model,
+		Messages:    messages,
+		Temperature: opts.Temperature,
+		TopP:        opts.TopP,
+		MaxTokens:   opts.MaxOutputTokens,
+	}
+}
+
+// complete posts to /chat/completions and returns the first choice
+func (o *OpenAI) complete(ctx context.Context, messages []openAIMessage, opts Options) (string, error) {
+	resp, err := o.post(ctx, o.client, o.request(messages, opts))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("openai API returned status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	var parsed openAIResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", fmt.Errorf("failed to parse OpenAI response: %v", err)
+	}
+
+	if len(parsed.Choices) == 0 || parsed.Choices[0].Message.Content == "" {
+		return "", ErrEmptyResponse
+	}
+
+	return parsed.Choices[0].Message.Content, nil
+}
+
+// post sends the JSON body to the chat completions endpoint
+func (o *OpenAI) post(ctx context.Context, client *http.Client, body interface{}) (*http.Response, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/chat/completions", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Local OpenAI-compatible servers usually do not require a key
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	return client.Do(req)
+}
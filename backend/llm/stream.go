@@ -0,0 +1,62 @@
+// llm/stream.go
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStreamStalled is returned when a streamed reply stops sending data
+var ErrStreamStalled = errors.New("stream stalled")
+
+// newStreamClient returns the client used for streamed replies. http.Client.Timeout
+// covers reading the whole body and would cut off long replies mid-stream, so the
+// timeout only bounds the wait for the response headers; streamBody bounds the gaps
+// between chunks.
+func newStreamClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	return &http.Client{Transport: transport}
+}
+
+// streamBody cancels the request when no data arrives for the idle timeout. The request
+// must have been made with the context whose cancel function is passed in.
+type streamBody struct {
+	body    io.Reader
+	timeout time.Duration
+	timer   *time.Timer
+	stalled atomic.Bool
+}
+
+func newStreamBody(body io.Reader, timeout time.Duration, cancel context.CancelFunc) *streamBody {
+	s := &streamBody{body: body, timeout: timeout}
+	if timeout > 0 {
+		s.timer = time.AfterFunc(timeout, func() {
+			s.stalled.Store(true)
+			cancel()
+		})
+	}
+	return s
+}
+
+func (s *streamBody) Read(p []byte) (int, error) {
+	n, err := s.body.Read(p)
+	if s.stalled.Load() {
+		return n, ErrStreamStalled
+	}
+	if n > 0 && s.timer != nil {
+		s.timer.Reset(s.timeout)
+	}
+	return n, err
+}
+
+// stop releases the idle timer
+func (s *streamBody) stop() {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
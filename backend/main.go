@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	_ "time/tzdata" // Timezone database for user streaks on hosts without one
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -39,7 +40,12 @@ func main() {
 	routes.SetupActivityRoutes(router)
 	routes.SetupFoodRoutes(router)
 	routes.SetupCoinRoutes(router)
+	routes.SetupRewardRoutes(router)
 	routes.SetupChatbotRoutes(router)
+	routes.SetupReportRoutes(router)
+	routes.SetupAccountRoutes(router)
+	routes.SetupFileRoutes(router)
+	routes.SetupAdminRoutes(router)
 
 	// Add health check endpoint
 	router.GET("/health", func(c *gin.Context) {
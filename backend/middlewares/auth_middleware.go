@@ -6,11 +6,14 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/habdil/sigap-app/backend/repository"
 	"github.com/habdil/sigap-app/backend/utils"
 )
 
 // AuthMiddleware authenticates the user from the JWT token
 func AuthMiddleware() gin.HandlerFunc {
+	sessionRepo := repository.NewRefreshTokenRepository()
+
 	return func(c *gin.Context) {
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -38,6 +41,19 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Reject access tokens whose session was logged out or revoked
+		active, err := sessionRepo.IsSessionActive(claims.UserID, claims.SessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify session"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked"})
+			c.Abort()
+			return
+		}
+
 		// Set the user ID in the context
 		c.Set("userID", claims.UserID)
 
@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header clients use to make retries safe
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	idempotencyKeyTTL       = 24 * time.Hour
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency replays the stored response when a request is retried with the same
+// Idempotency-Key header, so retried writes are applied only once. Requests without
+// the header are processed normally. It must run after AuthMiddleware.
+func Idempotency() gin.HandlerFunc {
+	idempotencyRepo := repository.NewIdempotencyRepository()
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key header is too long"})
+			c.Abort()
+			return
+		}
+
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+
+		// Fingerprint the request so a key cannot be reused for a different request
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		record, reserved, err := idempotencyRepo.Reserve(userID.(int), key, requestHash, idempotencyKeyTTL)
+		if err != nil {
+			// Only a key released by a concurrent request is a conflict the client can retry
+			if errors.Is(err, repository.ErrIdempotencyKeyReleased) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			} else {
+				log.Printf("Error reserving idempotency key: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process Idempotency-Key"})
+			}
+			c.Abort()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case record.RequestHash != requestHash:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used for a different request"})
+			case record.StatusCode == nil:
+				c.JSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still being processed"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(*record.StatusCode, record.ContentType, record.ResponseBody)
+			}
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		// Server errors are not stored so the client can retry them
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			if err := idempotencyRepo.Release(record.ID); err != nil {
+				log.Printf("Error releasing idempotency key: %v", err)
+			}
+			return
+		}
+
+		if err := idempotencyRepo.Complete(record.ID, status, recorder.body.Bytes(), recorder.Header().Get("Content-Type")); err != nil {
+			log.Printf("Error storing idempotent response: %v", err)
+		}
+	}
+}
+
+// responseRecorder keeps a copy of the response body written by the handler
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+// RequireRole only lets through users with one of the given roles.
+// It must run after AuthMiddleware. The role is read from the database on every
+// request so that a demotion takes effect immediately.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	userRepo := repository.NewUserRepository()
+
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			c.Abort()
+			return
+		}
+
+		role, err := userRepo.GetUserRole(userID.(int))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify user role"})
+			c.Abort()
+			return
+		}
+
+		for _, allowed := range roles {
+			if role == allowed {
+				// Set the role in the context
+				c.Set("userRole", role)
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		c.Abort()
+	}
+}
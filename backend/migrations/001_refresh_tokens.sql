@@ -0,0 +1,15 @@
+-- Rotating refresh tokens. Every login starts a token family (one per device session);
+-- each refresh revokes the presented token and issues its replacement in the same family.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id          SERIAL PRIMARY KEY,
+    user_id     INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id   TEXT         NOT NULL,
+    token_hash  TEXT         NOT NULL UNIQUE,
+    expires_at  TIMESTAMP(3) NOT NULL,
+    created_at  TIMESTAMP(3) NOT NULL DEFAULT NOW(),
+    revoked_at  TIMESTAMP(3),
+    replaced_by INTEGER      REFERENCES refresh_tokens(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
@@ -0,0 +1,30 @@
+-- Rewards catalog that users can redeem with their coins
+CREATE TABLE IF NOT EXISTS reward_items (
+    id                    SERIAL PRIMARY KEY,
+    name                  TEXT         NOT NULL,
+    description           TEXT,
+    image_url             TEXT,
+    price_coins           INTEGER      NOT NULL CHECK (price_coins > 0),
+    stock                 INTEGER      NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    valid_from            TIMESTAMP(3),
+    valid_until           TIMESTAMP(3),
+    voucher_validity_days INTEGER,
+    is_active             BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at            TIMESTAMP(3) NOT NULL DEFAULT NOW(),
+    updated_at            TIMESTAMP(3) NOT NULL DEFAULT NOW()
+);
+
+-- One row per redeemed item; the code is what the user shows to claim the reward
+CREATE TABLE IF NOT EXISTS reward_redemptions (
+    id              SERIAL PRIMARY KEY,
+    user_id         INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reward_item_id  INTEGER      NOT NULL REFERENCES reward_items(id),
+    coins_spent     INTEGER      NOT NULL,
+    redemption_code TEXT         NOT NULL UNIQUE,
+    status          TEXT         NOT NULL DEFAULT 'issued',
+    expires_at      TIMESTAMP(3),
+    used_at         TIMESTAMP(3),
+    created_at      TIMESTAMP(3) NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reward_redemptions_user_id ON reward_redemptions (user_id);
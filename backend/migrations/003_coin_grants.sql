@@ -0,0 +1,12 @@
+-- Manual coin credits made by an admin. Each grant is the source reference of
+-- its coin_transactions row, so every credit can be traced back to who made it and why.
+CREATE TABLE IF NOT EXISTS coin_grants (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    granted_by INTEGER      NOT NULL REFERENCES users(id),
+    amount     INTEGER      NOT NULL CHECK (amount > 0),
+    reason     TEXT         NOT NULL,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_coin_grants_user_id ON coin_grants (user_id);
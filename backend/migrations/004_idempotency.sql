@@ -0,0 +1,19 @@
+-- Ledger level idempotency: a credit or debit carrying a key is applied at most once per user
+ALTER TABLE coin_transactions ADD COLUMN IF NOT EXISTS idempotency_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coin_transactions_idempotency_key
+    ON coin_transactions (user_id, idempotency_key);
+
+-- Responses of requests sent with an Idempotency-Key header, replayed when the client retries.
+-- A row without status_code is a request that is still being processed.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id            SERIAL PRIMARY KEY,
+    user_id       INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key           TEXT         NOT NULL,
+    request_hash  TEXT         NOT NULL,
+    status_code   INTEGER,
+    response_body BYTEA,
+    content_type  TEXT,
+    created_at    TIMESTAMP(3) NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, key)
+);
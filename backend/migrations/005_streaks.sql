@@ -0,0 +1,29 @@
+-- Streaks are counted in calendar days of the user's own timezone
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'Asia/Jakarta';
+
+-- Freeze days bought with coins. An unused freeze covers one missed day of a daily streak.
+CREATE TABLE IF NOT EXISTS streak_freezes (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    coins_spent  INTEGER      NOT NULL,
+    purchased_on DATE         NOT NULL,
+    used_on      DATE,
+    created_at   TIMESTAMP(3) NOT NULL DEFAULT NOW()
+);
+
+-- A user can hold only one unused freeze at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_streak_freezes_unused
+    ON streak_freezes (user_id) WHERE used_on IS NULL;
+
+-- Milestone bonuses already paid out. A streak is identified by the day (or week) it started,
+-- so each milestone is paid once per streak. Each row is the source of its StreakBonus credit.
+CREATE TABLE IF NOT EXISTS streak_bonuses (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind         TEXT         NOT NULL, -- 'daily' or 'weekly'
+    streak_start DATE         NOT NULL,
+    milestone    INTEGER      NOT NULL,
+    coins        INTEGER      NOT NULL,
+    created_at   TIMESTAMP(3) NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, kind, streak_start, milestone)
+);
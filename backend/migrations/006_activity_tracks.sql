@@ -0,0 +1,23 @@
+-- GPS tracks imported from GPX, TCX and FIT files. The row is reserved before the
+-- activity is created, so activity_id is NULL only while an import is in progress.
+CREATE TABLE IF NOT EXISTS activity_tracks (
+    id               SERIAL PRIMARY KEY,
+    user_id          INTEGER          NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    activity_id      INTEGER          UNIQUE REFERENCES activity_logs(id) ON DELETE CASCADE,
+    source_format    TEXT             NOT NULL, -- 'gpx', 'tcx' or 'fit'
+    sport            TEXT,
+    file_hash        TEXT             NOT NULL, -- SHA-256 of the uploaded file
+    started_at       TIMESTAMP(3)     NOT NULL,
+    ended_at         TIMESTAMP(3)     NOT NULL,
+    distance_km      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    duration_seconds INTEGER          NOT NULL DEFAULT 0,
+    avg_pace         DOUBLE PRECISION,
+    elevation_gain_m DOUBLE PRECISION NOT NULL DEFAULT 0,
+    heart_rate_avg   INTEGER,
+    heart_rate_max   INTEGER,
+    points           JSONB            NOT NULL DEFAULT '[]',
+    splits           JSONB            NOT NULL DEFAULT '[]',
+    created_at       TIMESTAMP(3)     NOT NULL DEFAULT NOW(),
+    -- The same file cannot be imported twice
+    UNIQUE (user_id, file_hash)
+);
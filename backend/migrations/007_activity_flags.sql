@@ -0,0 +1,17 @@
+-- Activity logs that failed the plausibility checks. Coins of a flagged log are capped
+-- or withheld; withheld coins are credited if an admin approves the log on review.
+CREATE TABLE IF NOT EXISTS activity_flags (
+    id             SERIAL PRIMARY KEY,
+    activity_id    INTEGER      NOT NULL UNIQUE REFERENCES activity_logs(id) ON DELETE CASCADE,
+    user_id        INTEGER      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reasons        TEXT[]       NOT NULL,
+    action         TEXT         NOT NULL, -- 'capped' or 'withheld'
+    coins_withheld INTEGER      NOT NULL DEFAULT 0,
+    status         TEXT         NOT NULL DEFAULT 'pending', -- 'pending', 'approved' or 'rejected'
+    reviewed_by    INTEGER      REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at    TIMESTAMP(3),
+    created_at     TIMESTAMP(3) NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMP(3) NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_flags_status ON activity_flags (status, created_at);
@@ -0,0 +1,41 @@
+-- Registry of activity types used to compute calories and coins. New types can be
+-- added here without a redeploy; the API picks them up within a few minutes.
+CREATE TABLE IF NOT EXISTS activity_types (
+    id               SERIAL PRIMARY KEY,
+    name             TEXT             NOT NULL UNIQUE, -- value stored in activity_logs.activity_type
+    labels           JSONB            NOT NULL DEFAULT '{}', -- display names by language, e.g. {"en": "Running", "id": "Lari"}
+    met              DOUBLE PRECISION NOT NULL, -- MET used when the speed is unknown
+    met_curve        JSONB            NOT NULL DEFAULT '[]', -- [{"speed_kmh": 8, "met": 8.3}, ...] ascending by speed
+    coins_per_minute DOUBLE PRECISION NOT NULL,
+    bonus_tiers      JSONB            NOT NULL DEFAULT '[]', -- [{"min_minutes": 30, "coins": 3}, ...]
+    uses_distance    BOOLEAN          NOT NULL DEFAULT FALSE,
+    max_speed_kmh    DOUBLE PRECISION, -- fastest plausible average speed, NULL to skip the check
+    sort_order       INTEGER          NOT NULL DEFAULT 0,
+    is_active        BOOLEAN          NOT NULL DEFAULT TRUE,
+    created_at       TIMESTAMP(3)     NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMP(3)     NOT NULL DEFAULT NOW()
+);
+
+-- MET values follow the Compendium of Physical Activities
+INSERT INTO activity_types (name, labels, met, met_curve, coins_per_minute, bonus_tiers, uses_distance, max_speed_kmh, sort_order)
+VALUES
+    ('Jogging', '{"en": "Jogging", "id": "Jogging"}', 7.0, '[]', 0.4,
+     '[{"min_minutes": 30, "coins": 3}, {"min_minutes": 45, "coins": 5}, {"min_minutes": 60, "coins": 10}]', TRUE, 25, 1),
+    ('Running', '{"en": "Running", "id": "Lari"}', 9.8,
+     '[{"speed_kmh": 6.4, "met": 6.0}, {"speed_kmh": 8.0, "met": 8.3}, {"speed_kmh": 9.7, "met": 9.8}, {"speed_kmh": 11.3, "met": 11.0}, {"speed_kmh": 12.9, "met": 11.8}, {"speed_kmh": 14.5, "met": 12.8}, {"speed_kmh": 16.1, "met": 14.5}, {"speed_kmh": 17.7, "met": 16.0}, {"speed_kmh": 19.3, "met": 19.0}]',
+     0.5, '[{"min_minutes": 30, "coins": 3}, {"min_minutes": 45, "coins": 5}, {"min_minutes": 60, "coins": 10}]', TRUE, 25, 2),
+    ('Yoga', '{"en": "Yoga", "id": "Yoga"}', 3.0, '[]', 0.3,
+     '[{"min_minutes": 30, "coins": 3}, {"min_minutes": 45, "coins": 5}, {"min_minutes": 60, "coins": 10}]', FALSE, NULL, 3),
+    ('Badminton', '{"en": "Badminton", "id": "Bulu Tangkis"}', 5.5, '[]', 0.4,
+     '[{"min_minutes": 30, "coins": 3}, {"min_minutes": 45, "coins": 5}, {"min_minutes": 60, "coins": 10}]', FALSE, NULL, 4),
+    ('Walking', '{"en": "Walking", "id": "Jalan Kaki"}', 3.5,
+     '[{"speed_kmh": 3.2, "met": 2.8}, {"speed_kmh": 4.8, "met": 3.5}, {"speed_kmh": 5.6, "met": 4.3}, {"speed_kmh": 6.4, "met": 5.0}, {"speed_kmh": 7.2, "met": 7.0}]',
+     0.2, '[{"min_minutes": 30, "coins": 2}, {"min_minutes": 60, "coins": 5}]', TRUE, 10, 5),
+    ('Hiking', '{"en": "Hiking", "id": "Mendaki"}', 6.0, '[]', 0.4,
+     '[{"min_minutes": 30, "coins": 3}, {"min_minutes": 60, "coins": 10}]', TRUE, 10, 6),
+    ('Cycling', '{"en": "Cycling", "id": "Bersepeda"}', 7.5,
+     '[{"speed_kmh": 16, "met": 4.0}, {"speed_kmh": 19, "met": 6.8}, {"speed_kmh": 22, "met": 8.0}, {"speed_kmh": 25, "met": 10.0}, {"speed_kmh": 30, "met": 12.0}, {"speed_kmh": 32, "met": 15.8}]',
+     0.3, '[{"min_minutes": 30, "coins": 3}, {"min_minutes": 45, "coins": 5}, {"min_minutes": 60, "coins": 10}]', TRUE, 60, 7),
+    ('Swimming', '{"en": "Swimming", "id": "Berenang"}', 6.0, '[]', 0.5,
+     '[{"min_minutes": 30, "coins": 3}, {"min_minutes": 45, "coins": 5}, {"min_minutes": 60, "coins": 10}]', TRUE, 8, 8)
+ON CONFLICT (name) DO NOTHING;
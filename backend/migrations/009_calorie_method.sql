@@ -0,0 +1,3 @@
+-- How calories_burned was obtained: 'heart_rate' (Keytel equations), 'met' or 'manual'.
+-- NULL for activities logged before the column existed.
+ALTER TABLE activity_logs ADD COLUMN IF NOT EXISTS calorie_method TEXT;
@@ -0,0 +1,10 @@
+-- Which path produced each risk percentage and why.
+-- source is 'llm' or 'rule_engine'; model_version is the LLM (e.g. 'gemini/gemini-2.0-flash')
+-- or the rule engine version. The rule engine always runs, so rule_score,
+-- rule_model_version and its per-factor contributions are stored for every new result.
+-- All columns are NULL for results stored before they existed.
+ALTER TABLE risk_assessment_results ADD COLUMN IF NOT EXISTS source TEXT;
+ALTER TABLE risk_assessment_results ADD COLUMN IF NOT EXISTS model_version TEXT;
+ALTER TABLE risk_assessment_results ADD COLUMN IF NOT EXISTS rule_score INTEGER;
+ALTER TABLE risk_assessment_results ADD COLUMN IF NOT EXISTS rule_model_version TEXT;
+ALTER TABLE risk_assessment_results ADD COLUMN IF NOT EXISTS contributions JSONB;
@@ -0,0 +1,80 @@
+-- Versions of the assessment questionnaire. The current questionnaire is the highest
+-- active version. Released versions are never edited; add a new version instead, so
+-- stored answers keep their meaning.
+CREATE TABLE IF NOT EXISTS assessment_questionnaires (
+    id         SERIAL PRIMARY KEY,
+    version    INTEGER      NOT NULL UNIQUE,
+    questions  JSONB        NOT NULL, -- [{"key", "topic", "text", "required", "options": [{"value", "label", "description"}]}]
+    is_active  BOOLEAN      NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP(3) NOT NULL DEFAULT NOW()
+);
+
+-- Version 1 is the original four-question assessment
+INSERT INTO assessment_questionnaires (version, questions, is_active)
+VALUES (1, '[
+    {"key": "screen_time_hours", "topic": "Screen time", "text": "How many hours a day do you spend in front of a screen?", "required": true, "options": [
+        {"value": 1, "label": "About 1 hour", "description": "just an hour"},
+        {"value": 2, "label": "2-4 hours", "description": "around 2-4 hours"},
+        {"value": 3, "label": "5-8 hours", "description": "around 5-8 hours"},
+        {"value": 4, "label": "9 hours or more", "description": "more than 9 hours per day"}]},
+    {"key": "exercise_hours", "topic": "Exercise", "text": "How many hours a week do you exercise?", "required": true, "options": [
+        {"value": 1, "label": "About 1 hour", "description": "just an hour"},
+        {"value": 2, "label": "2-4 hours", "description": "around 2-4 hours"},
+        {"value": 3, "label": "5-8 hours", "description": "around 5-8 hours"},
+        {"value": 4, "label": "9 hours or more", "description": "more than 9 hours per day"}]},
+    {"key": "late_night_frequency", "topic": "Late night habits", "text": "How often do you stay up late?", "required": true, "options": [
+        {"value": 1, "label": "Never", "description": "never"},
+        {"value": 2, "label": "Once a week", "description": "once a week when tomorrow is a vacation day"},
+        {"value": 3, "label": "2-4 times a week", "description": "about 2-4 times a week"},
+        {"value": 4, "label": "Every day", "description": "every day without pause and continuously"}]},
+    {"key": "diet_quality", "topic": "Diet", "text": "How would you describe your diet?", "required": true, "options": [
+        {"value": 1, "label": "Regular, with vegetables and fruit", "description": "a regular diet with plenty of vegetables and fruit"},
+        {"value": 2, "label": "A little messy", "description": "a little messy but still consuming vegetables"},
+        {"value": 3, "label": "Messy, sometimes fast food", "description": "messy diet and sometimes eat fast and high-fat foods"},
+        {"value": 4, "label": "Mostly junk food", "description": "no vegetables, no fruits, only eat something like junk food"}]}
+]', TRUE)
+ON CONFLICT (version) DO NOTHING;
+
+-- Version 2 adds smoking, blood pressure, family history and diabetes
+INSERT INTO assessment_questionnaires (version, questions, is_active)
+SELECT 2, questions || '[
+    {"key": "smoking", "topic": "Smoking", "text": "Do you smoke?", "required": true, "options": [
+        {"value": 1, "label": "Never", "description": "has never smoked"},
+        {"value": 2, "label": "I quit", "description": "a former smoker"},
+        {"value": 3, "label": "Sometimes", "description": "smokes occasionally"},
+        {"value": 4, "label": "Every day", "description": "smokes every day"}]},
+    {"key": "blood_pressure", "topic": "Blood pressure", "text": "What was your last blood pressure reading?", "required": true, "options": [
+        {"value": 1, "label": "Normal", "description": "normal blood pressure"},
+        {"value": 2, "label": "Slightly high", "description": "slightly elevated blood pressure (120-139 systolic)"},
+        {"value": 3, "label": "High or on medication", "description": "high blood pressure (140 systolic or more) or takes blood pressure medication"},
+        {"value": 4, "label": "I don''t know", "description": "does not know their blood pressure"}]},
+    {"key": "family_history", "topic": "Family history", "text": "Has a parent or sibling had a stroke?", "required": true, "options": [
+        {"value": 1, "label": "No", "description": "no parent or sibling has had a stroke"},
+        {"value": 2, "label": "Yes", "description": "a parent or sibling has had a stroke"},
+        {"value": 3, "label": "I don''t know", "description": "does not know the family history of stroke"}]},
+    {"key": "diabetes", "topic": "Diabetes", "text": "Have you been told you have diabetes?", "required": true, "options": [
+        {"value": 1, "label": "No", "description": "no diabetes"},
+        {"value": 2, "label": "Prediabetes", "description": "prediabetes (borderline blood sugar)"},
+        {"value": 3, "label": "Yes", "description": "diagnosed with diabetes"}]}
+]'::jsonb, TRUE
+FROM assessment_questionnaires WHERE version = 1
+ON CONFLICT (version) DO NOTHING;
+
+-- Submissions record the questionnaire version and every answer by question key.
+-- Earlier submissions were made with version 1.
+ALTER TABLE user_assessments ADD COLUMN IF NOT EXISTS questionnaire_version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE user_assessments ADD COLUMN IF NOT EXISTS answers JSONB;
+
+UPDATE user_assessments
+SET answers = jsonb_build_object(
+    'screen_time_hours', screen_time_hours,
+    'exercise_hours', exercise_hours,
+    'late_night_frequency', late_night_frequency,
+    'diet_quality', diet_quality)
+WHERE answers IS NULL;
+
+-- Later questionnaire versions may drop one of the original questions
+ALTER TABLE user_assessments ALTER COLUMN screen_time_hours DROP NOT NULL;
+ALTER TABLE user_assessments ALTER COLUMN exercise_hours DROP NOT NULL;
+ALTER TABLE user_assessments ALTER COLUMN late_night_frequency DROP NOT NULL;
+ALTER TABLE user_assessments ALTER COLUMN diet_quality DROP NOT NULL;
@@ -0,0 +1,3 @@
+-- Weight at the time of each assessment, so a large weight change since can prompt a
+-- reassessment. NULL for assessments taken before it was recorded or without a weight.
+ALTER TABLE user_assessments ADD COLUMN IF NOT EXISTS weight_kg DOUBLE PRECISION;
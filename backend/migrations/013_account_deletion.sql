@@ -0,0 +1,9 @@
+-- Account deletion is scheduled with a grace period during which the user can cancel it.
+-- Once purged the users row stays behind as an anonymous tombstone so the coin ledger,
+-- grants and redemptions still balance.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMP(3);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_scheduled_at TIMESTAMP(3);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP(3);
+
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at
+    ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Photos uploaded with a food log are kept in object storage under photo_key. photo_url
+-- still holds links to photos hosted elsewhere by older clients; uploaded photos are
+-- served through signed URLs generated on every read.
+ALTER TABLE food_logs ADD COLUMN IF NOT EXISTS photo_key TEXT;
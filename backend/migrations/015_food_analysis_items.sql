@@ -0,0 +1,21 @@
+-- Every component the vision model recognizes on a plate is stored as its own item with
+-- an estimated portion and nutrients. The totals in food_analysis are the sums over the
+-- items and are recomputed when the user corrects an item.
+CREATE TABLE IF NOT EXISTS food_analysis_items (
+    id               SERIAL PRIMARY KEY,
+    food_analysis_id INTEGER          NOT NULL REFERENCES food_analysis(id) ON DELETE CASCADE,
+    position         INTEGER          NOT NULL, -- order in which the model listed the item
+    name             TEXT             NOT NULL,
+    portion_grams    DOUBLE PRECISION NOT NULL,
+    calories         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    protein_grams    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    carbs_grams      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fat_grams        DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fiber_grams      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    confidence       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    corrected_at     TIMESTAMP(3), -- set when the user corrected the name or portion
+    created_at       TIMESTAMP(3)     NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_food_analysis_items_analysis
+    ON food_analysis_items (food_analysis_id, position);
@@ -0,0 +1,32 @@
+-- Nutrition reference database for logging food without a photo. Rows are imported from
+-- a food composition table such as TKPI with cmd/import-nutrition; nutrients are per
+-- 100 g edible portion.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS nutrition_foods (
+    id                    SERIAL PRIMARY KEY,
+    source                TEXT             NOT NULL,
+    source_code           TEXT             NOT NULL,
+    name                  TEXT             NOT NULL,
+    name_en               TEXT,
+    category              TEXT,
+    energy_kcal           DOUBLE PRECISION NOT NULL,
+    protein_grams         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fat_grams             DOUBLE PRECISION NOT NULL DEFAULT 0,
+    carbs_grams           DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fiber_grams           DOUBLE PRECISION NOT NULL DEFAULT 0,
+    default_serving_grams DOUBLE PRECISION NOT NULL DEFAULT 100,
+    created_at            TIMESTAMP(3)     NOT NULL DEFAULT NOW(),
+    updated_at            TIMESTAMP(3)     NOT NULL DEFAULT NOW(),
+    UNIQUE (source, source_code)
+);
+
+-- Prefix search uses the pattern index, fuzzy search the trigram index
+CREATE INDEX IF NOT EXISTS idx_nutrition_foods_name_prefix
+    ON nutrition_foods (lower(name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_nutrition_foods_name_trgm
+    ON nutrition_foods USING gin (lower(name) gin_trgm_ops);
+
+-- Food logs created from a reference food keep the food and the serving eaten
+ALTER TABLE food_logs ADD COLUMN IF NOT EXISTS nutrition_food_id INTEGER REFERENCES nutrition_foods(id) ON DELETE SET NULL;
+ALTER TABLE food_logs ADD COLUMN IF NOT EXISTS serving_grams DOUBLE PRECISION;
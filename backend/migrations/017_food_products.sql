@@ -0,0 +1,29 @@
+-- Packaged foods looked up by barcode. Rows are imported from an Open Food Facts dump
+-- with cmd/import-products; barcodes are stored as GTIN-13 (UPC-A codes get a leading
+-- zero) and nutrients are per 100 g.
+CREATE TABLE IF NOT EXISTS food_products (
+    id             SERIAL PRIMARY KEY,
+    barcode        TEXT             NOT NULL UNIQUE,
+    name           TEXT             NOT NULL,
+    brand          TEXT,
+    serving_grams  DOUBLE PRECISION, -- size of one serving as printed on the package
+    energy_kcal    DOUBLE PRECISION NOT NULL,
+    protein_grams  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fat_grams      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    carbs_grams    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    fiber_grams    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    sugar_grams    DOUBLE PRECISION,
+    sodium_mg      DOUBLE PRECISION,
+    created_at     TIMESTAMP(3)     NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMP(3)     NOT NULL DEFAULT NOW()
+);
+
+-- Food logs created from a scanned product keep the product
+ALTER TABLE food_logs ADD COLUMN IF NOT EXISTS food_product_id INTEGER REFERENCES food_products(id) ON DELETE SET NULL;
+
+-- Sodium and sugar of an analysis and its items. They are NULL for analyses that did
+-- not estimate them.
+ALTER TABLE food_analysis ADD COLUMN IF NOT EXISTS sodium_mg DOUBLE PRECISION;
+ALTER TABLE food_analysis ADD COLUMN IF NOT EXISTS sugar_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS sodium_mg DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS sugar_grams DOUBLE PRECISION;
@@ -0,0 +1,12 @@
+-- Saturated fat and cholesterol of analyses, their items and packaged products, next to
+-- the sodium and sugar added in 017. They are NULL where they were not estimated. The
+-- daily sums are checked against WHO limits in the food log response.
+ALTER TABLE food_analysis ADD COLUMN IF NOT EXISTS saturated_fat_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis ADD COLUMN IF NOT EXISTS cholesterol_mg DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS saturated_fat_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS cholesterol_mg DOUBLE PRECISION;
+ALTER TABLE food_products ADD COLUMN IF NOT EXISTS saturated_fat_grams DOUBLE PRECISION;
+ALTER TABLE food_products ADD COLUMN IF NOT EXISTS cholesterol_mg DOUBLE PRECISION;
+
+-- Daily sums are taken over a user's logs of one day
+CREATE INDEX IF NOT EXISTS idx_food_logs_user_date ON food_logs (user_id, log_date);
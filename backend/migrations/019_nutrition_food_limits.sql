@@ -0,0 +1,7 @@
+-- Sodium, sugar, saturated fat and cholesterol of reference foods per 100 g, so foods
+-- logged from the reference database count towards the daily limits added in 018. They
+-- are NULL where the food composition table has no value.
+ALTER TABLE nutrition_foods ADD COLUMN IF NOT EXISTS sodium_mg DOUBLE PRECISION;
+ALTER TABLE nutrition_foods ADD COLUMN IF NOT EXISTS sugar_grams DOUBLE PRECISION;
+ALTER TABLE nutrition_foods ADD COLUMN IF NOT EXISTS saturated_fat_grams DOUBLE PRECISION;
+ALTER TABLE nutrition_foods ADD COLUMN IF NOT EXISTS cholesterol_mg DOUBLE PRECISION;
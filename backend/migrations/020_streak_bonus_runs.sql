@@ -0,0 +1,20 @@
+-- Milestone bonuses belong to the run of active days (or weeks) they were reached in,
+-- identified by reached_on rather than by the start date, which moves when the first
+-- day is deleted or a backdated activity is imported. Existing rows get the day the
+-- milestone fell on when the streak started on streak_start.
+ALTER TABLE streak_bonuses ADD COLUMN IF NOT EXISTS reached_on DATE;
+UPDATE streak_bonuses
+SET reached_on = streak_start + CASE WHEN kind = 'weekly' THEN (milestone - 1) * 7 ELSE milestone - 1 END
+WHERE reached_on IS NULL;
+ALTER TABLE streak_bonuses ALTER COLUMN reached_on SET NOT NULL;
+
+-- A bonus whose run no longer reaches the milestone is reversed by a compensating row
+-- with negative coins, which is the source of the StreakBonusReversal debit
+ALTER TABLE streak_bonuses ADD COLUMN IF NOT EXISTS reverses_id INTEGER REFERENCES streak_bonuses(id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_streak_bonuses_reverses
+    ON streak_bonuses (reverses_id) WHERE reverses_id IS NOT NULL;
+
+-- A milestone can be paid again once its bonus is reversed, so the start date no longer
+-- identifies a bonus. Payments are serialized per user instead.
+ALTER TABLE streak_bonuses DROP CONSTRAINT IF EXISTS streak_bonuses_user_id_kind_streak_start_milestone_key;
+CREATE INDEX IF NOT EXISTS idx_streak_bonuses_user ON streak_bonuses (user_id, kind, milestone);
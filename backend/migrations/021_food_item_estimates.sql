@@ -0,0 +1,34 @@
+-- The portion and nutrients of an item as the model (or the reference table) first
+-- estimated them. Corrections always scale from these, so shrinking a portion and
+-- restoring it gives back the original values instead of compounding the rounding.
+-- Existing items start from their current values.
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_portion_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_calories DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_protein_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_carbs_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_fat_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_fiber_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_sugar_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_sodium_mg DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_saturated_fat_grams DOUBLE PRECISION;
+ALTER TABLE food_analysis_items ADD COLUMN IF NOT EXISTS estimated_cholesterol_mg DOUBLE PRECISION;
+
+UPDATE food_analysis_items
+SET estimated_portion_grams = portion_grams,
+    estimated_calories = calories,
+    estimated_protein_grams = protein_grams,
+    estimated_carbs_grams = carbs_grams,
+    estimated_fat_grams = fat_grams,
+    estimated_fiber_grams = fiber_grams,
+    estimated_sugar_grams = sugar_grams,
+    estimated_sodium_mg = sodium_mg,
+    estimated_saturated_fat_grams = saturated_fat_grams,
+    estimated_cholesterol_mg = cholesterol_mg
+WHERE estimated_portion_grams IS NULL;
+
+ALTER TABLE food_analysis_items ALTER COLUMN estimated_portion_grams SET NOT NULL;
+ALTER TABLE food_analysis_items ALTER COLUMN estimated_calories SET NOT NULL;
+ALTER TABLE food_analysis_items ALTER COLUMN estimated_protein_grams SET NOT NULL;
+ALTER TABLE food_analysis_items ALTER COLUMN estimated_carbs_grams SET NOT NULL;
+ALTER TABLE food_analysis_items ALTER COLUMN estimated_fat_grams SET NOT NULL;
+ALTER TABLE food_analysis_items ALTER COLUMN estimated_fiber_grams SET NOT NULL;
@@ -0,0 +1,8 @@
+-- Photos of purged accounts waiting to be deleted from storage. The purge records them in
+-- its transaction and cmd/purge-accounts removes a row only once the object is deleted, so
+-- a failed delete is retried on the next run.
+CREATE TABLE IF NOT EXISTS pending_photo_deletions (
+    photo_key TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
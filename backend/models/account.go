@@ -0,0 +1,18 @@
+// models/account.go
+package models
+
+import "time"
+
+// AccountDeletion tells whether the account is scheduled for deletion. The account and its
+// data are purged at ScheduledAt unless the user cancels before then.
+type AccountDeletion struct {
+	Scheduled   bool       `json:"scheduled"`
+	RequestedAt *time.Time `json:"requested_at,omitempty"`
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+}
+
+// ExportTable is one table of a personal data export, with every row as a JSON object
+type ExportTable struct {
+	Name string
+	Rows []map[string]any
+}
@@ -19,8 +19,19 @@ type ActivityLog struct {
 	AvgPace          float64     `json:"avg_pace,omitempty"`
 	CoinsEarned      int         `json:"coins_earned"`
 	MusicPlayed      string      `json:"music_played,omitempty"`
+	CalorieMethod    string      `json:"calorie_method,omitempty"`
+
+	// Flag is set when the log failed the plausibility checks
+	Flag *ActivityFlag `json:"flag,omitempty"`
 }
 
+// How the calories of an activity log were obtained
+const (
+	CalorieMethodHeartRate = "heart_rate"
+	CalorieMethodMET       = "met"
+	CalorieMethodManual    = "manual"
+)
+
 // ActivityLogRequest represents the request to create an activity log
 type ActivityLogRequest struct {
 	ActivityType     string      `json:"activity_type" binding:"required"`
@@ -33,6 +44,11 @@ type ActivityLogRequest struct {
 	LocationData     interface{} `json:"location_data,omitempty"`
 	AvgPace          float64     `json:"avg_pace,omitempty"`
 	MusicPlayed      string      `json:"music_played,omitempty"`
+
+	// ActivityDate is set for imported activities; logged activities use the current time
+	ActivityDate *time.Time `json:"-"`
+	// CalorieMethod is set by the service to record how CaloriesBurned was obtained
+	CalorieMethod string `json:"-"`
 }
 
 // ActivityRecommendation represents an AI-generated activity recommendation
@@ -45,3 +61,18 @@ type ActivityRecommendation struct {
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
 }
+
+// ActivityLogUpdateRequest represents a partial update of an activity log.
+// Only the fields present in the request are changed.
+type ActivityLogUpdateRequest struct {
+	ActivityType     *string     `json:"activity_type,omitempty" binding:"omitempty,min=1"`
+	DurationMinutes  *int        `json:"duration_minutes,omitempty" binding:"omitempty,min=1"`
+	DistanceKM       *float64    `json:"distance_km,omitempty" binding:"omitempty,min=0"`
+	CaloriesBurned   *int        `json:"calories_burned,omitempty" binding:"omitempty,min=0"`
+	HeartRateAvg     *int        `json:"heart_rate_avg,omitempty" binding:"omitempty,min=0"`
+	Notes            *string     `json:"notes,omitempty"`
+	WeatherCondition *string     `json:"weather_condition,omitempty"`
+	LocationData     interface{} `json:"location_data,omitempty"`
+	AvgPace          *float64    `json:"avg_pace,omitempty" binding:"omitempty,min=0"`
+	MusicPlayed      *string     `json:"music_played,omitempty"`
+}
@@ -0,0 +1,33 @@
+// models/activity_flag.go
+package models
+
+import "time"
+
+// Flag reasons recorded for implausible activity logs
+const (
+	FlagDurationTooLong      = "duration_too_long"
+	FlagPaceTooFast          = "pace_too_fast"
+	FlagPaceInconsistent     = "pace_inconsistent"
+	FlagOverlappingSession   = "overlapping_session"
+	FlagImplausibleHeartRate = "implausible_heart_rate"
+)
+
+// ActivityFlag records why an activity log was flagged and what happened to its coins
+type ActivityFlag struct {
+	ID            int        `json:"id"`
+	ActivityID    int        `json:"activity_id"`
+	UserID        int        `json:"user_id"`
+	Reasons       []string   `json:"reasons"`
+	Action        string     `json:"action"`
+	CoinsWithheld int        `json:"coins_withheld"`
+	Status        string     `json:"status"`
+	ReviewedBy    *int       `json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ActivityFlagReviewRequest represents an admin decision on a flagged activity
+type ActivityFlagReviewRequest struct {
+	Status string `json:"status" binding:"required,oneof=approved rejected"`
+}
@@ -0,0 +1,85 @@
+// models/activity_stats.go
+package models
+
+import "time"
+
+// ActivityStatsQuery represents the query parameters of the activity statistics endpoint
+type ActivityStatsQuery struct {
+	From     string `form:"from"`     // YYYY-MM-DD, defaults to 29 days before To
+	To       string `form:"to"`       // YYYY-MM-DD, defaults to today
+	Period   string `form:"period"`   // "day", "week" or "month", defaults to "day"
+	Timezone string `form:"timezone"` // IANA name, defaults to the user's timezone
+}
+
+// ActivityTotals represents aggregated activity figures
+type ActivityTotals struct {
+	Sessions   int     `json:"sessions"`
+	Minutes    int     `json:"minutes"`
+	DistanceKM float64 `json:"distance_km"`
+	Calories   int     `json:"calories"`
+	Coins      int     `json:"coins"`
+}
+
+// ActivityTypeTotals represents aggregated figures for one activity type
+type ActivityTypeTotals struct {
+	ActivityType string `json:"activity_type"`
+	ActivityTotals
+}
+
+// ActivityStatsBucket represents the aggregates of one day, week or month
+type ActivityStatsBucket struct {
+	PeriodStart string `json:"period_start"`
+	ActivityTotals
+	ByType []ActivityTypeTotals `json:"by_type"`
+}
+
+// PersonalBest represents the activity that holds a personal record
+type PersonalBest struct {
+	ActivityID      int       `json:"activity_id"`
+	ActivityType    string    `json:"activity_type"`
+	DurationMinutes int       `json:"duration_minutes"`
+	DistanceKM      float64   `json:"distance_km,omitempty"`
+	AvgPace         float64   `json:"avg_pace,omitempty"`
+	ActivityDate    time.Time `json:"activity_date"`
+}
+
+// PersonalBests represents the user's all-time records
+type PersonalBests struct {
+	LongestRun     *PersonalBest `json:"longest_run,omitempty"`     // Longest Running distance
+	LongestSession *PersonalBest `json:"longest_session,omitempty"` // Longest duration of any type
+	FastestPace    *PersonalBest `json:"fastest_pace,omitempty"`    // Lowest avg_pace (minutes per km)
+}
+
+// WeekOverWeek compares the current week so far with the same days of the previous week
+type WeekOverWeek struct {
+	WeekStart         string         `json:"week_start"`
+	ThisWeek          ActivityTotals `json:"this_week"`
+	LastWeek          ActivityTotals `json:"last_week"`
+	SessionsDelta     int            `json:"sessions_delta"`
+	MinutesDelta      int            `json:"minutes_delta"`
+	DistanceKMDelta   float64        `json:"distance_km_delta"`
+	CaloriesDelta     int            `json:"calories_delta"`
+	MinutesChangePct  *float64       `json:"minutes_change_pct"` // nil when last week had no activity
+	DistanceChangePct *float64       `json:"distance_change_pct"`
+	CaloriesChangePct *float64       `json:"calories_change_pct"`
+}
+
+// ActivityStats represents the response of the activity statistics endpoint
+type ActivityStats struct {
+	Timezone      string                `json:"timezone"`
+	Period        string                `json:"period"`
+	From          string                `json:"from"`
+	To            string                `json:"to"`
+	Totals        ActivityTotals        `json:"totals"`
+	ByType        []ActivityTypeTotals  `json:"by_type"`
+	Buckets       []ActivityStatsBucket `json:"buckets"`
+	PersonalBests PersonalBests         `json:"personal_bests"`
+	WeekOverWeek  WeekOverWeek          `json:"week_over_week"`
+}
+
+// ActivityAggregate is one row of activity totals grouped by period and activity type
+type ActivityAggregate struct {
+	PeriodStart  time.Time
+	ActivityType string
+	ActivityTotals
+}
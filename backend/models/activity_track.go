@@ -0,0 +1,49 @@
+// models/activity_track.go
+package models
+
+import "time"
+
+// TrackPoint is a single recorded position of a GPS track
+type TrackPoint struct {
+	Time      time.Time `json:"time"`
+	Lat       float64   `json:"lat"`
+	Lon       float64   `json:"lon"`
+	Elevation *float64  `json:"ele,omitempty"`
+	HeartRate int       `json:"hr,omitempty"`
+}
+
+// TrackSplit is the summary of one kilometre of a track. The last split may be shorter.
+type TrackSplit struct {
+	Index           int     `json:"index"`
+	DistanceKM      float64 `json:"distance_km"`
+	DurationSeconds int     `json:"duration_seconds"`
+	AvgPace         float64 `json:"avg_pace"`
+	ElevationGainM  float64 `json:"elevation_gain_m"`
+}
+
+// ActivityTrack is a GPS track imported from a GPX, TCX or FIT file
+type ActivityTrack struct {
+	ID              int          `json:"id"`
+	UserID          int          `json:"user_id"`
+	ActivityID      *int         `json:"activity_id"`
+	SourceFormat    string       `json:"source_format"`
+	Sport           string       `json:"sport,omitempty"`
+	FileHash        string       `json:"-"`
+	StartedAt       time.Time    `json:"started_at"`
+	EndedAt         time.Time    `json:"ended_at"`
+	DistanceKM      float64      `json:"distance_km"`
+	DurationSeconds int          `json:"duration_seconds"`
+	AvgPace         float64      `json:"avg_pace,omitempty"`
+	ElevationGainM  float64      `json:"elevation_gain_m"`
+	HeartRateAvg    int          `json:"heart_rate_avg,omitempty"`
+	HeartRateMax    int          `json:"heart_rate_max,omitempty"`
+	Points          []TrackPoint `json:"points"`
+	Splits          []TrackSplit `json:"splits"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+// ActivityImportResponse is returned after importing an activity file
+type ActivityImportResponse struct {
+	Activity *ActivityLog   `json:"activity"`
+	Track    *ActivityTrack `json:"track"`
+}
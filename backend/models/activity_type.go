@@ -0,0 +1,33 @@
+// models/activity_type.go
+package models
+
+import "time"
+
+// METPoint is one point of a speed-dependent MET curve
+type METPoint struct {
+	SpeedKMH float64 `json:"speed_kmh"`
+	MET      float64 `json:"met"`
+}
+
+// CoinBonusTier is a one-off coin bonus for sessions of at least MinMinutes
+type CoinBonusTier struct {
+	MinMinutes int `json:"min_minutes"`
+	Coins      int `json:"coins"`
+}
+
+// ActivityType describes how an activity type earns calories and coins
+type ActivityType struct {
+	ID             int               `json:"id"`
+	Name           string            `json:"name"`
+	Labels         map[string]string `json:"labels"`
+	MET            float64           `json:"met"`
+	METCurve       []METPoint        `json:"met_curve"`
+	CoinsPerMinute float64           `json:"coins_per_minute"`
+	BonusTiers     []CoinBonusTier   `json:"bonus_tiers"`
+	UsesDistance   bool              `json:"uses_distance"`
+	MaxSpeedKMH    *float64          `json:"max_speed_kmh,omitempty"`
+	SortOrder      int               `json:"sort_order"`
+	IsActive       bool              `json:"is_active"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+}
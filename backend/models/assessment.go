@@ -2,35 +2,65 @@ package models
 
 import "time"
 
-// UserAssessment represents a user's health assessment
+// UserAssessment represents a user's health assessment. Answers holds every answer by
+// question key; the four original questions are also kept in their own fields.
 type UserAssessment struct {
-	ID                 int       `json:"id"`
-	UserID             int       `json:"user_id"`
-	ScreenTimeHours    int       `json:"screen_time_hours"`    // Question 1
-	ExerciseHours      int       `json:"exercise_hours"`       // Question 2
-	LateNightFrequency int       `json:"late_night_frequency"` // Question 3
-	DietQuality        int       `json:"diet_quality"`         // Question 4
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	ID                   int            `json:"id"`
+	UserID               int            `json:"user_id"`
+	QuestionnaireVersion int            `json:"questionnaire_version"`
+	Answers              map[string]int `json:"answers"`
+	ScreenTimeHours      int            `json:"screen_time_hours"`    // Question 1
+	ExerciseHours        int            `json:"exercise_hours"`       // Question 2
+	LateNightFrequency   int            `json:"late_night_frequency"` // Question 3
+	DietQuality          int            `json:"diet_quality"`         // Question 4
+	WeightKG             float64        `json:"weight_kg,omitempty"`  // weight when the assessment was taken
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
 }
 
-// RiskAssessmentResult represents the result of a risk assessment
+// Sources of a risk percentage
+const (
+	RiskSourceLLM        = "llm"
+	RiskSourceRuleEngine = "rule_engine"
+)
+
+// RiskContribution is the number of percentage points one factor added to the rule
+// engine score. Value describes the input, e.g. "27.4 (obese class I)" for BMI.
+type RiskContribution struct {
+	Factor string  `json:"factor"`
+	Value  string  `json:"value,omitempty"`
+	Points float64 `json:"points"`
+}
+
+// RiskAssessmentResult represents the result of a risk assessment. The rule engine
+// always scores the assessment, so its score and contributions are present even when
+// the reported percentage came from the language model.
 type RiskAssessmentResult struct {
-	ID              int       `json:"id"`
-	UserID          int       `json:"user_id"`
-	AssessmentID    int       `json:"assessment_id"`
-	RiskPercentage  int       `json:"risk_percentage"`
-	RiskFactors     []string  `json:"risk_factors"`
-	Recommendations []string  `json:"recommendations,omitempty"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID               int                `json:"id"`
+	UserID           int                `json:"user_id"`
+	AssessmentID     int                `json:"assessment_id"`
+	RiskPercentage   int                `json:"risk_percentage"`
+	RiskFactors      []string           `json:"risk_factors"`
+	Recommendations  []string           `json:"recommendations,omitempty"`
+	Source           string             `json:"source,omitempty"`
+	ModelVersion     string             `json:"model_version,omitempty"`
+	RuleScore        *int               `json:"rule_score,omitempty"`
+	RuleModelVersion string             `json:"rule_model_version,omitempty"`
+	Contributions    []RiskContribution `json:"contributions,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
 }
 
-// AssessmentRequest represents the request for a user assessment
+// AssessmentRequest represents the request for a user assessment. Answers are validated
+// against QuestionnaireVersion, or the current questionnaire when it is omitted. Requests
+// without answers use the four original fields and questionnaire version 1.
 type AssessmentRequest struct {
-	ScreenTimeHours    int `json:"screen_time_hours" binding:"required,min=1,max=4"`
-	ExerciseHours      int `json:"exercise_hours" binding:"required,min=1,max=4"`
-	LateNightFrequency int `json:"late_night_frequency" binding:"required,min=1,max=4"`
-	DietQuality        int `json:"diet_quality" binding:"required,min=1,max=4"`
+	QuestionnaireVersion int            `json:"questionnaire_version" binding:"omitempty,min=1"`
+	Answers              map[string]int `json:"answers"`
+	ScreenTimeHours      int            `json:"screen_time_hours" binding:"omitempty,min=1,max=4"`
+	ExerciseHours        int            `json:"exercise_hours" binding:"omitempty,min=1,max=4"`
+	LateNightFrequency   int            `json:"late_night_frequency" binding:"omitempty,min=1,max=4"`
+	DietQuality          int            `json:"diet_quality" binding:"omitempty,min=1,max=4"`
+	WeightKG             float64        `json:"-"` // set from the profile when the assessment is stored
 }
 
 // AssessmentResponse represents the response for an assessment
@@ -38,3 +68,51 @@ type AssessmentResponse struct {
 	Assessment UserAssessment       `json:"assessment"`
 	Result     RiskAssessmentResult `json:"result"`
 }
+
+// Reasons for a reassessment
+const (
+	ReassessNeverAssessed   = "never_assessed"
+	ReassessIntervalElapsed = "interval_elapsed"
+	ReassessWeightChanged   = "weight_changed"
+	ReassessInactivityGap   = "inactivity_gap"
+)
+
+// AssessmentStatus tells the client whether the user should take the assessment and when
+// the next one is due. Reason is the policy rule that sets NextDueAt.
+type AssessmentStatus struct {
+	NeedsAssessment bool       `json:"needs_assessment"`
+	Reason          string     `json:"reason"`
+	LastAssessedAt  *time.Time `json:"last_assessed_at,omitempty"`
+	NextDueAt       time.Time  `json:"next_due_at"`
+}
+
+// RiskFactorChange is how much one rule engine factor contributed to the previous and the
+// latest assessment
+type RiskFactorChange struct {
+	Factor         string  `json:"factor"`
+	PreviousValue  string  `json:"previous_value,omitempty"`
+	CurrentValue   string  `json:"current_value,omitempty"`
+	PreviousPoints float64 `json:"previous_points"`
+	CurrentPoints  float64 `json:"current_points"`
+	Delta          float64 `json:"delta"`
+}
+
+// Trend directions of the risk percentage
+const (
+	TrendImproved  = "improved"
+	TrendWorsened  = "worsened"
+	TrendUnchanged = "unchanged"
+	TrendFirst     = "first_assessment"
+)
+
+// AssessmentTrend compares the latest risk assessment result with the previous one.
+// Factor changes are only listed when both were scored by the same rule engine version.
+type AssessmentTrend struct {
+	Current           RiskAssessmentResult  `json:"current"`
+	Previous          *RiskAssessmentResult `json:"previous,omitempty"`
+	Delta             int                   `json:"delta"`
+	Direction         string                `json:"direction"`
+	FactorsComparable bool                  `json:"factors_comparable"`
+	ImprovedFactors   []RiskFactorChange    `json:"improved_factors"`
+	WorsenedFactors   []RiskFactorChange    `json:"worsened_factors"`
+}
@@ -45,5 +45,7 @@ type ConversationResponse struct {
 
 // MessagesResponse represents a response with messages
 type MessagesResponse struct {
-	Messages []ChatMessage `json:"messages"`
+	Messages   []ChatMessage `json:"messages"`
+	NextCursor string        `json:"next_cursor,omitempty"` // Set when paginated and more messages exist
+	HasMore    bool          `json:"has_more,omitempty"`
 }
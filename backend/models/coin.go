@@ -30,10 +30,26 @@ type SpendCoinsRequest struct {
 	ReferenceType   string `json:"reference_type,omitempty"`
 }
 
-// AddCoinsRequest represents a request to add coins
-type AddCoinsRequest struct {
-	Amount          int    `json:"amount" binding:"required,min=1"`
-	TransactionType string `json:"transaction_type" binding:"required"`
-	ReferenceID     int    `json:"reference_id,omitempty"`
-	ReferenceType   string `json:"reference_type,omitempty"`
+// GrantCoinsRequest represents an admin request to credit coins to a user
+type GrantCoinsRequest struct {
+	UserID int    `json:"user_id" binding:"required"`
+	Amount int    `json:"amount" binding:"required,min=1"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// CoinGrant represents a manual coin credit made by an admin
+type CoinGrant struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	GrantedBy int       `json:"granted_by"`
+	Amount    int       `json:"amount"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CoinBalanceDrift reports a user whose stored balance differs from their ledger
+type CoinBalanceDrift struct {
+	UserID        int `json:"user_id"`
+	StoredBalance int `json:"stored_balance"`
+	LedgerBalance int `json:"ledger_balance"`
 }
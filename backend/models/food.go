@@ -5,39 +5,117 @@ import "time"
 
 // FoodLog represents a food consumption record
 type FoodLog struct {
-	ID       int           `json:"id"`
-	UserID   int           `json:"user_id"`
-	FoodName string        `json:"food_name,omitempty"`
-	LogDate  time.Time     `json:"log_date"`
-	PhotoURL string        `json:"photo_url,omitempty"`
-	Notes    string        `json:"notes,omitempty"`
-	Analysis *FoodAnalysis `json:"analysis,omitempty"`
+	ID       int       `json:"id"`
+	UserID   int       `json:"user_id"`
+	FoodName string    `json:"food_name,omitempty"`
+	LogDate  time.Time `json:"log_date"`
+	PhotoURL string    `json:"photo_url,omitempty"`
+	PhotoKey string    `json:"-"` // storage key of an uploaded photo; PhotoURL is then a signed URL
+	Notes    string    `json:"notes,omitempty"`
+	// NutritionFoodID and ServingGrams are set for logs of a reference food, FoodProductID
+	// and ServingGrams for logs of a scanned product
+	NutritionFoodID int           `json:"nutrition_food_id,omitempty"`
+	FoodProductID   int           `json:"food_product_id,omitempty"`
+	ServingGrams    float64       `json:"serving_grams,omitempty"`
+	Analysis        *FoodAnalysis `json:"analysis,omitempty"`
+	// DailyWarnings lists the nutrients whose intake on the log's day is near or over
+	// the daily limit
+	DailyWarnings []NutrientWarning `json:"daily_warnings,omitempty"`
 }
 
-// FoodAnalysis represents the nutritional analysis of a food log
+// FoodAnalysis represents the nutritional analysis of a food log. When the analysis has
+// items, the totals are the sums over the items.
 type FoodAnalysis struct {
-	ID               int       `json:"id"`
-	FoodLogID        int       `json:"food_log_id"`
-	ProteinGrams     float64   `json:"protein_grams,omitempty"`
-	CarbsGrams       float64   `json:"carbs_grams,omitempty"`
-	FatGrams         float64   `json:"fat_grams,omitempty"`
-	FiberGrams       float64   `json:"fiber_grams,omitempty"`
-	Calories         int       `json:"calories,omitempty"`
-	DetectedItems    []byte    `json:"-"` // Stored as JSON in database
-	HealthinessScore int       `json:"healthiness_score,omitempty"`
-	AIConfidence     float64   `json:"ai_confidence,omitempty"`
-	AnalyzedAt       time.Time `json:"analyzed_at"`
-}
-
-// FoodLogRequest represents the request to create a food log
+	ID                int        `json:"id"`
+	FoodLogID         int        `json:"food_log_id"`
+	ProteinGrams      float64    `json:"protein_grams,omitempty"`
+	CarbsGrams        float64    `json:"carbs_grams,omitempty"`
+	FatGrams          float64    `json:"fat_grams,omitempty"`
+	FiberGrams        float64    `json:"fiber_grams,omitempty"`
+	SugarGrams        *float64   `json:"sugar_grams,omitempty"`         // nil when not estimated
+	SodiumMg          *float64   `json:"sodium_mg,omitempty"`           // nil when not estimated
+	SaturatedFatGrams *float64   `json:"saturated_fat_grams,omitempty"` // nil when not estimated
+	CholesterolMg     *float64   `json:"cholesterol_mg,omitempty"`      // nil when not estimated
+	Calories          int        `json:"calories,omitempty"`
+	DetectedItems     []byte     `json:"-"` // Stored as JSON in database
+	HealthinessScore  int        `json:"healthiness_score,omitempty"`
+	AIConfidence      float64    `json:"ai_confidence,omitempty"`
+	AnalyzedAt        time.Time  `json:"analyzed_at"`
+	Items             []FoodItem `json:"items"`
+}
+
+// FoodItem is one component of an analyzed meal, such as the rice, the side dish or the
+// vegetables of a mixed plate, with the nutrients of its estimated portion
+type FoodItem struct {
+	ID                int        `json:"id"`
+	FoodAnalysisID    int        `json:"food_analysis_id"`
+	Name              string     `json:"name"`
+	PortionGrams      float64    `json:"portion_grams"`
+	Calories          float64    `json:"calories"`
+	ProteinGrams      float64    `json:"protein_grams"`
+	CarbsGrams        float64    `json:"carbs_grams"`
+	FatGrams          float64    `json:"fat_grams"`
+	FiberGrams        float64    `json:"fiber_grams"`
+	SugarGrams        *float64   `json:"sugar_grams,omitempty"`         // nil when not estimated
+	SodiumMg          *float64   `json:"sodium_mg,omitempty"`           // nil when not estimated
+	SaturatedFatGrams *float64   `json:"saturated_fat_grams,omitempty"` // nil when not estimated
+	CholesterolMg     *float64   `json:"cholesterol_mg,omitempty"`      // nil when not estimated
+	Confidence        float64    `json:"confidence"`
+	CorrectedAt       *time.Time `json:"corrected_at,omitempty"` // set once the user corrected the item
+}
+
+// NutrientTotals are the sums of the nutrients with a daily limit over a user's food logs
+type NutrientTotals struct {
+	SodiumMg          float64
+	SugarGrams        float64
+	SaturatedFatGrams float64
+	CholesterolMg     float64
+}
+
+// NutrientWarning reports a day's intake of a nutrient that is near or over its daily limit
+type NutrientWarning struct {
+	Nutrient string  `json:"nutrient"` // sodium_mg, sugar_grams, saturated_fat_grams or cholesterol_mg
+	Intake   float64 `json:"intake"`
+	Limit    float64 `json:"limit"`
+	Unit     string  `json:"unit"`
+	Percent  int     `json:"percent"` // intake as a percentage of the limit
+	Level    string  `json:"level"`   // "near_limit" from 80% of the limit, "over_limit" from 100%
+	Message  string  `json:"message"`
+}
+
+// FoodItemCorrection is a user's correction of a detected item. A new portion scales the
+// item's estimated nutrients; a new name keeps them.
+type FoodItemCorrection struct {
+	Name         *string  `json:"name" binding:"omitempty,min=1,max=100"`
+	PortionGrams *float64 `json:"portion_grams" binding:"omitempty,gt=0,lte=5000"`
+}
+
+// FoodLogRequest represents the request to create a food log. With NutritionFoodID the
+// food is looked up in the nutrition reference database and analyzed from it for a
+// serving of ServingGrams, defaulting to the food's usual serving.
 type FoodLogRequest struct {
-	FoodName string `json:"food_name,omitempty"`
-	PhotoURL string `json:"photo_url,omitempty"`
-	Notes    string `json:"notes,omitempty"`
+	FoodName        string  `json:"food_name,omitempty"`
+	PhotoURL        string  `json:"photo_url,omitempty"`
+	PhotoKey        string  `json:"-"` // set when the photo was uploaded to our storage
+	Notes           string  `json:"notes,omitempty"`
+	NutritionFoodID int     `json:"nutrition_food_id,omitempty" binding:"omitempty,gt=0"`
+	ServingGrams    float64 `json:"serving_grams,omitempty" binding:"omitempty,gt=0,lte=5000"`
+	FoodProductID   int     `json:"-"` // set when the food was logged by barcode
+}
+
+// BarcodeLogRequest represents the request to log a packaged food by its EAN or UPC
+// barcode. The amount eaten is ServingGrams or else Servings of the package's serving
+// size, defaulting to one serving.
+type BarcodeLogRequest struct {
+	Barcode      string  `json:"barcode" binding:"required"`
+	Servings     float64 `json:"servings,omitempty" binding:"omitempty,gt=0,lte=50"`
+	ServingGrams float64 `json:"serving_grams,omitempty" binding:"omitempty,gt=0,lte=5000"`
+	Notes        string  `json:"notes,omitempty"`
 }
 
-// FoodAnalysisRequest represents the request for AI to analyze a food photo
+// FoodAnalysisRequest represents the request for AI to analyze a food photo. Without
+// ImageData the photo uploaded with the food log is analyzed.
 type FoodAnalysisRequest struct {
 	FoodLogID int    `json:"food_log_id" binding:"required"`
-	ImageData string `json:"image_data" binding:"required"` // Base64 encoded image
+	ImageData string `json:"image_data,omitempty"` // Base64 encoded image as a data URI
 }
@@ -0,0 +1,16 @@
+// models/idempotency.go
+package models
+
+import "time"
+
+// IdempotencyRecord stores the outcome of a request sent with an Idempotency-Key header
+type IdempotencyRecord struct {
+	ID           int
+	UserID       int
+	Key          string
+	RequestHash  string
+	StatusCode   *int // nil while the original request is still being processed
+	ResponseBody []byte
+	ContentType  string
+	CreatedAt    time.Time
+}
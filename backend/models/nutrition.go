@@ -0,0 +1,42 @@
+// models/nutrition.go
+package models
+
+// NutritionFood is a food of the nutrition reference database, such as a row of the
+// Indonesian food composition table (TKPI), with its nutrients per 100 g edible portion
+type NutritionFood struct {
+	ID                  int      `json:"id"`
+	Source              string   `json:"source"`      // table the food was imported from, e.g. "tkpi"
+	SourceCode          string   `json:"source_code"` // code of the food in that table
+	Name                string   `json:"name"`
+	NameEN              string   `json:"name_en,omitempty"`
+	Category            string   `json:"category,omitempty"`
+	EnergyKcal          float64  `json:"energy_kcal"`
+	ProteinGrams        float64  `json:"protein_grams"`
+	FatGrams            float64  `json:"fat_grams"`
+	CarbsGrams          float64  `json:"carbs_grams"`
+	FiberGrams          float64  `json:"fiber_grams"`
+	SugarGrams          *float64 `json:"sugar_grams,omitempty"`
+	SodiumMg            *float64 `json:"sodium_mg,omitempty"`
+	SaturatedFatGrams   *float64 `json:"saturated_fat_grams,omitempty"`
+	CholesterolMg       *float64 `json:"cholesterol_mg,omitempty"`
+	DefaultServingGrams float64  `json:"default_serving_grams"`
+}
+
+// FoodProduct is a packaged food identified by its barcode, such as a product of the
+// Open Food Facts database, with its nutrients per 100 g
+type FoodProduct struct {
+	ID                int      `json:"id"`
+	Barcode           string   `json:"barcode"` // GTIN-13
+	Name              string   `json:"name"`
+	Brand             string   `json:"brand,omitempty"`
+	ServingGrams      float64  `json:"serving_grams,omitempty"` // 0 when the package states no serving size
+	EnergyKcal        float64  `json:"energy_kcal"`
+	ProteinGrams      float64  `json:"protein_grams"`
+	FatGrams          float64  `json:"fat_grams"`
+	CarbsGrams        float64  `json:"carbs_grams"`
+	FiberGrams        float64  `json:"fiber_grams"`
+	SugarGrams        *float64 `json:"sugar_grams,omitempty"`
+	SodiumMg          *float64 `json:"sodium_mg,omitempty"`
+	SaturatedFatGrams *float64 `json:"saturated_fat_grams,omitempty"`
+	CholesterolMg     *float64 `json:"cholesterol_mg,omitempty"`
+}
@@ -0,0 +1,168 @@
+// models/pagination.go
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPageSize is used when a cursor is given without a limit
+	DefaultPageSize = 20
+	// MaxPageSize is the largest page a client can request
+	MaxPageSize = 100
+)
+
+// ListQuery represents the pagination, filter and sort parameters shared by list endpoints.
+// Without limit and cursor the endpoint returns the full (filtered) list as before.
+type ListQuery struct {
+	Limit  int    `form:"limit"`
+	Cursor string `form:"cursor"` // next_cursor of the previous page
+	From   string `form:"from"`   // RFC 3339 timestamp or YYYY-MM-DD (UTC), inclusive
+	To     string `form:"to"`     // RFC 3339 timestamp or YYYY-MM-DD (UTC, whole day), inclusive
+	Type   string `form:"type"`   // Endpoint specific type filter
+	Sort   string `form:"sort"`   // "asc" or "desc" by date
+}
+
+// Paginated reports whether the client asked for a page instead of the full list
+func (q ListQuery) Paginated() bool {
+	return q.Limit > 0 || q.Cursor != ""
+}
+
+// ListOptions are the parsed list parameters passed to repositories
+type ListOptions struct {
+	Limit     int // 0 returns every row
+	After     *Cursor
+	From      *time.Time
+	To        *time.Time // Exclusive
+	Type      string
+	Ascending bool
+}
+
+// Options validates the query and converts it into ListOptions.
+// defaultAscending is the sort order used when the query does not set one.
+func (q ListQuery) Options(defaultAscending bool) (ListOptions, error) {
+	opts := ListOptions{
+		Type:      strings.TrimSpace(q.Type),
+		Ascending: defaultAscending,
+	}
+
+	switch strings.ToLower(q.Sort) {
+	case "":
+	case "asc":
+		opts.Ascending = true
+	case "desc":
+		opts.Ascending = false
+	default:
+		return opts, errors.New("sort must be asc or desc")
+	}
+
+	if q.Limit < 0 {
+		return opts, errors.New("limit must be positive")
+	}
+	if q.Paginated() {
+		opts.Limit = q.Limit
+		if opts.Limit == 0 {
+			opts.Limit = DefaultPageSize
+		}
+		if opts.Limit > MaxPageSize {
+			opts.Limit = MaxPageSize
+		}
+	}
+
+	if q.Cursor != "" {
+		cursor, err := DecodeCursor(q.Cursor)
+		if err != nil {
+			return opts, err
+		}
+		opts.After = cursor
+	}
+
+	if q.From != "" {
+		from, _, err := parseListTime(q.From)
+		if err != nil {
+			return opts, fmt.Errorf("from: %v", err)
+		}
+		opts.From = &from
+	}
+	if q.To != "" {
+		to, dateOnly, err := parseListTime(q.To)
+		if err != nil {
+			return opts, fmt.Errorf("to: %v", err)
+		}
+		// A date includes the whole day; a timestamp is inclusive too
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		} else {
+			to = to.Add(time.Millisecond)
+		}
+		opts.To = &to
+	}
+
+	if opts.From != nil && opts.To != nil && !opts.From.Before(*opts.To) {
+		return opts, errors.New("from must be before to")
+	}
+
+	return opts, nil
+}
+
+// parseListTime accepts an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseListTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, errors.New("must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+	}
+	return t.UTC(), false, nil
+}
+
+// Cursor marks the last row of a page: its sort timestamp and ID
+type Cursor struct {
+	Time time.Time
+	ID   int
+}
+
+// Encode returns the opaque string form of the cursor
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.Time.UnixMicro(), 10) + ":" + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor created by Encode
+func DecodeCursor(value string) (*Cursor, error) {
+	invalid := errors.New("invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, invalid
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+
+	micros, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, invalid
+	}
+
+	return &Cursor{Time: time.UnixMicro(micros).UTC(), ID: id}, nil
+}
+
+// Page represents one page of a paginated list
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"` // Empty on the last page
+	HasMore    bool   `json:"has_more"`
+}
@@ -0,0 +1,40 @@
+// models/questionnaire.go
+package models
+
+import "time"
+
+// AnswerOption is one allowed answer to a questionnaire question. Description is how
+// the answer is put to the language model.
+type AnswerOption struct {
+	Value       int    `json:"value"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// Question is one question of the assessment questionnaire. Answers must be one of the
+// option values; required questions must be answered.
+type Question struct {
+	Key      string         `json:"key"`
+	Topic    string         `json:"topic"`
+	Text     string         `json:"text"`
+	Required bool           `json:"required"`
+	Options  []AnswerOption `json:"options"`
+}
+
+// Questionnaire is a released version of the assessment questionnaire. Released
+// versions are never edited, so stored answers keep their meaning.
+type Questionnaire struct {
+	ID        int        `json:"id"`
+	Version   int        `json:"version"`
+	Questions []Question `json:"questions"`
+	IsActive  bool       `json:"is_active"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Keys of the questions in the original four-question assessment
+const (
+	QuestionScreenTime = "screen_time_hours"
+	QuestionExercise   = "exercise_hours"
+	QuestionLateNight  = "late_night_frequency"
+	QuestionDiet       = "diet_quality"
+)
@@ -0,0 +1,29 @@
+// models/refresh_token.go
+package models
+
+import "time"
+
+// RefreshToken represents a stored refresh token. Tokens issued from the same login
+// share a FamilyID, which also identifies the device session.
+type RefreshToken struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	FamilyID   string     `json:"family_id"`
+	TokenHash  string     `json:"-"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy int        `json:"replaced_by,omitempty"`
+}
+
+// RefreshTokenRequest represents the request body for refresh and logout
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse represents a freshly issued access and refresh token pair
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+}
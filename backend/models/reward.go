@@ -0,0 +1,40 @@
+// models/reward.go
+package models
+
+import "time"
+
+// RewardItem represents an item in the rewards catalog
+type RewardItem struct {
+	ID                  int        `json:"id"`
+	Name                string     `json:"name"`
+	Description         string     `json:"description,omitempty"`
+	ImageURL            string     `json:"image_url,omitempty"`
+	PriceCoins          int        `json:"price_coins"`
+	Stock               int        `json:"stock"`
+	ValidFrom           *time.Time `json:"valid_from,omitempty"`
+	ValidUntil          *time.Time `json:"valid_until,omitempty"`
+	VoucherValidityDays int        `json:"voucher_validity_days,omitempty"`
+	IsActive            bool       `json:"is_active"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// RewardRedemption represents a reward redeemed by a user
+type RewardRedemption struct {
+	ID             int        `json:"id"`
+	UserID         int        `json:"user_id"`
+	RewardItemID   int        `json:"reward_item_id"`
+	RewardName     string     `json:"reward_name,omitempty"`
+	CoinsSpent     int        `json:"coins_spent"`
+	RedemptionCode string     `json:"redemption_code"`
+	Status         string     `json:"status"` // "issued", "used", "expired" or "cancelled"
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	UsedAt         *time.Time `json:"used_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// RedeemRewardResponse represents the response after redeeming a reward
+type RedeemRewardResponse struct {
+	Redemption RewardRedemption `json:"redemption"`
+	Coins      UserCoins        `json:"coins"`
+}
@@ -0,0 +1,49 @@
+// models/streak.go
+package models
+
+import "time"
+
+// StreakStatus represents a user's current daily and weekly activity streaks
+type StreakStatus struct {
+	Timezone           string `json:"timezone"`
+	CurrentStreak      int    `json:"current_streak"` // Consecutive active days
+	LongestStreak      int    `json:"longest_streak"`
+	StreakStartDate    string `json:"streak_start_date,omitempty"`
+	LastActiveDate     string `json:"last_active_date,omitempty"`
+	ActiveToday        bool   `json:"active_today"`
+	WeeklyStreak       int    `json:"weekly_streak"` // Consecutive weeks meeting the weekly goal
+	WeeklyGoalDays     int    `json:"weekly_goal_days"`
+	DaysActiveThisWeek int    `json:"days_active_this_week"`
+	FreezeAvailable    bool   `json:"freeze_available"`
+	FreezeCost         int    `json:"freeze_cost"`
+	NextMilestone      int    `json:"next_milestone,omitempty"`
+	NextMilestoneBonus int    `json:"next_milestone_bonus,omitempty"`
+}
+
+// StreakBonus represents a milestone bonus paid for a streak, or the reversal of one
+type StreakBonus struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Kind        string    `json:"kind"` // "daily" or "weekly"
+	StreakStart time.Time `json:"streak_start"`
+	ReachedOn   time.Time `json:"reached_on"` // day (or week start) the streak reached the milestone
+	Milestone   int       `json:"milestone"`
+	Coins       int       `json:"coins"`                 // negative for a reversal
+	ReversesID  *int      `json:"reverses_id,omitempty"` // bonus taken back by this reversal
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// StreakFreeze represents a freeze day bought with coins
+type StreakFreeze struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	CoinsSpent  int        `json:"coins_spent"`
+	PurchasedOn time.Time  `json:"purchased_on"`
+	UsedOn      *time.Time `json:"used_on,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TimezoneUpdateRequest represents the request to change a user's timezone
+type TimezoneUpdateRequest struct {
+	Timezone string `json:"timezone" binding:"required"` // IANA name, e.g. "Asia/Jakarta"
+}
@@ -60,17 +60,19 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// GoogleLoginRequest represents the request body for Google login
+// GoogleLoginRequest represents the request body for Google login.
+// The identity is taken from the verified ID token, never from the request.
 type GoogleLoginRequest struct {
-	GoogleID string `json:"google_id" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
-	Username string `json:"username" binding:"required"`
+	IDToken  string `json:"id_token" binding:"required"`
+	Username string `json:"username,omitempty"` // Preferred username for new accounts
 }
 
 // AuthResponse represents the response for authentication endpoints
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+	User         User   `json:"user"`
 }
 
 // ProfileUpdateRequest represents the request to update a user's profile
@@ -80,11 +82,9 @@ type ProfileUpdateRequest struct {
 	Weight float64 `json:"weight" binding:"required"`
 }
 
-// SupabaseAuthRequest untuk login/register dengan Supabase
+// SupabaseAuthRequest untuk login/register dengan Supabase.
+// Identitas diambil dari access token yang sudah diverifikasi, bukan dari request.
 type SupabaseAuthRequest struct {
-	SupabaseUUID string `json:"supabase_uuid" binding:"required"`
-	Email        string `json:"email" binding:"required,email"`
-	Username     string `json:"username" binding:"required"`
-	GoogleID     string `json:"google_id,omitempty"`
-	AvatarURL    string `json:"avatar_url,omitempty"`
+	AccessToken string `json:"access_token" binding:"required"`
+	Username    string `json:"username,omitempty"` // Username pilihan untuk akun baru
 }
@@ -0,0 +1,56 @@
+// nutrition/barcode.go
+package nutrition
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalidBarcode is returned for codes that are not a valid EAN-8, UPC-A, EAN-13 or
+// GTIN-14 barcode
+var ErrInvalidBarcode = errors.New("invalid EAN or UPC barcode")
+
+// NormalizeBarcode checks the length and check digit of an EAN or UPC barcode and returns
+// it in the form products are stored in: EAN-8 codes as they are, UPC-A codes as EAN-13
+// with a leading zero, and GTIN-14 codes with a leading zero as EAN-13. Spaces and dashes
+// are ignored.
+func NormalizeBarcode(code string) (string, error) {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return "", ErrInvalidBarcode
+		}
+	}
+
+	switch len(code) {
+	case 8, 13:
+	case 12:
+		code = "0" + code
+	case 14:
+		// Only GTIN-14 codes of single items have an EAN-13 equivalent
+		if code[0] == '0' {
+			code = code[1:]
+		}
+	default:
+		return "", ErrInvalidBarcode
+	}
+
+	if !validCheckDigit(code) {
+		return "", ErrInvalidBarcode
+	}
+	return code, nil
+}
+
+// validCheckDigit verifies the GS1 check digit, the last digit of the code
+func validCheckDigit(code string) bool {
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		digit := int(code[i] - '0')
+		// Digits are weighted 3 and 1 alternately, starting with 3 next to the check digit
+		if (len(code)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
@@ -0,0 +1,188 @@
+// nutrition/csv.go
+package nutrition
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// Columns of a nutrition CSV file
+const (
+	columnCode     = "code"
+	columnName     = "name"
+	columnNameEN   = "name_en"
+	columnCategory = "category"
+	columnEnergy   = "energy_kcal"
+	columnProtein  = "protein_grams"
+	columnFat      = "fat_grams"
+	columnCarbs    = "carbs_grams"
+	columnFiber    = "fiber_grams"
+	columnSugar    = "sugar_grams"
+	columnSodium   = "sodium_mg"
+	columnSatFat   = "saturated_fat_grams"
+	columnChol     = "cholesterol_mg"
+	columnServing  = "default_serving_grams"
+)
+
+// headerAliases maps normalized header names, in English or as written in the TKPI
+// spreadsheets, to columns. Headers are normalized to lower case letters and digits.
+var headerAliases = map[string]string{
+	"code": columnCode, "kode": columnCode, "kodebahan": columnCode,
+	"name": columnName, "nama": columnName, "namabahan": columnName, "namabahanmakanan": columnName,
+	"nameen": columnNameEN, "englishname": columnNameEN, "namainggris": columnNameEN,
+	"category": columnCategory, "kelompok": columnCategory, "kelompokbahan": columnCategory,
+	"energykcal": columnEnergy, "energy": columnEnergy, "energi": columnEnergy, "energikal": columnEnergy, "energikkal": columnEnergy,
+	"proteingrams": columnProtein, "protein": columnProtein, "proteing": columnProtein,
+	"fatgrams": columnFat, "fat": columnFat, "fatg": columnFat, "lemak": columnFat, "lemakg": columnFat,
+	"carbsgrams": columnCarbs, "carbs": columnCarbs, "carbsg": columnCarbs, "carbohydrate": columnCarbs,
+	"kh": columnCarbs, "khg": columnCarbs, "karbohidrat": columnCarbs, "karbohidratg": columnCarbs,
+	"fibergrams": columnFiber, "fiber": columnFiber, "fiberg": columnFiber, "serat": columnFiber, "seratg": columnFiber,
+	"sugargrams": columnSugar, "sugar": columnSugar, "sugarg": columnSugar, "sugars": columnSugar, "gula": columnSugar, "gulag": columnSugar,
+	"sodiummg": columnSodium, "sodium": columnSodium, "natrium": columnSodium, "natriummg": columnSodium, "natriumna": columnSodium, "natriumnamg": columnSodium,
+	"saturatedfatgrams": columnSatFat, "saturatedfat": columnSatFat, "saturatedfatg": columnSatFat, "lemakjenuh": columnSatFat, "lemakjenuhg": columnSatFat,
+	"cholesterolmg": columnChol, "cholesterol": columnChol, "kolesterol": columnChol, "kolesterolmg": columnChol,
+	"defaultservinggrams": columnServing, "servinggrams": columnServing, "serving": columnServing, "servingg": columnServing, "porsig": columnServing,
+}
+
+// ErrMissingColumns is returned for files without a code, name or energy column
+var ErrMissingColumns = errors.New("nutrition CSV needs code, name and energy columns")
+
+// ParseCSV reads reference foods from a CSV file with a header row. Comma and semicolon
+// separated files are accepted, and decimals may use a comma as spreadsheets in
+// Indonesian locales write them. Nutrients are per 100 g; rows without a code, name or
+// energy value are skipped and reported in skipped. Sugar and saturated fat are in grams,
+// sodium and cholesterol in milligrams; they are left unset where the table has no value.
+func ParseCSV(r io.Reader, source string) (foods []models.NutritionFood, skipped []string, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // byte order mark
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		if column, ok := headerAliases[normalizeHeader(name)]; ok {
+			if _, seen := columns[column]; !seen {
+				columns[column] = i
+			}
+		}
+	}
+	for _, required := range []string{columnCode, columnName, columnEnergy} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, ErrMissingColumns
+		}
+	}
+
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		field := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		food := models.NutritionFood{
+			Source:              source,
+			SourceCode:          field(columnCode),
+			Name:                field(columnName),
+			NameEN:              field(columnNameEN),
+			Category:            field(columnCategory),
+			DefaultServingGrams: 100,
+		}
+		energy, ok := parseAmount(field(columnEnergy))
+		if food.SourceCode == "" || food.Name == "" || !ok {
+			skipped = append(skipped, fmt.Sprintf("line %d: missing code, name or energy", line))
+			continue
+		}
+		food.EnergyKcal = energy
+		food.ProteinGrams, _ = parseAmount(field(columnProtein))
+		food.FatGrams, _ = parseAmount(field(columnFat))
+		food.CarbsGrams, _ = parseAmount(field(columnCarbs))
+		food.FiberGrams, _ = parseAmount(field(columnFiber))
+		food.SugarGrams = optionalAmount(field(columnSugar))
+		food.SodiumMg = optionalAmount(field(columnSodium))
+		food.SaturatedFatGrams = optionalAmount(field(columnSatFat))
+		food.CholesterolMg = optionalAmount(field(columnChol))
+		if serving, ok := parseAmount(field(columnServing)); ok && serving > 0 {
+			food.DefaultServingGrams = serving
+		}
+
+		foods = append(foods, food)
+	}
+
+	return foods, skipped, nil
+}
+
+// detectDelimiter picks semicolons when the header line has more of them than commas
+func detectDelimiter(data []byte) rune {
+	firstLine, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		return ';'
+	}
+	return ','
+}
+
+func normalizeHeader(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// optionalAmount parses an amount that is nil when not measured
+func optionalAmount(value string) *float64 {
+	amount, ok := parseAmount(value)
+	if !ok {
+		return nil
+	}
+	return &amount
+}
+
+// parseAmount parses a non-negative amount. Food composition tables mark unmeasured
+// values with "-" or leave them empty; those are reported as missing.
+func parseAmount(value string) (float64, bool) {
+	if value == "" || value == "-" {
+		return 0, false
+	}
+	if !strings.Contains(value, ".") {
+		value = strings.ReplaceAll(value, ",", ".")
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount < 0 {
+		return 0, false
+	}
+	return amount, true
+}
@@ -0,0 +1,185 @@
+// nutrition/openfoodfacts.go
+package nutrition
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// ErrNotOpenFoodFacts is returned for files without the columns of the Open Food Facts
+// CSV export
+var ErrNotOpenFoodFacts = errors.New("file is not an Open Food Facts CSV export")
+
+// maxEnergyKcal is the energy of pure fat; products above it have broken nutrition data
+const maxEnergyKcal = 900
+
+// ProductReader reads packaged foods from the tab-separated CSV export of Open Food Facts
+// (en.openfoodfacts.org.products.csv). The export is read line by line as it does not
+// quote its fields and runs to several gigabytes.
+type ProductReader struct {
+	reader  *bufio.Reader
+	columns map[string]int
+	country string
+	// Skipped counts products left out for an invalid barcode or missing name or energy
+	Skipped int
+}
+
+// NewProductReader reads the header of an Open Food Facts export. With a country tag such
+// as "en:indonesia" only products sold in that country are returned.
+func NewProductReader(r io.Reader, country string) (*ProductReader, error) {
+	p := &ProductReader{
+		reader:  bufio.NewReaderSize(r, 1<<20),
+		columns: map[string]int{},
+		country: strings.ToLower(country),
+	}
+
+	header, err := p.readLine()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	for i, name := range header {
+		p.columns[strings.TrimPrefix(name, "\ufeff")] = i
+	}
+	for _, required := range []string{"code", "product_name"} {
+		if _, ok := p.columns[required]; !ok {
+			return nil, ErrNotOpenFoodFacts
+		}
+	}
+
+	return p, nil
+}
+
+// Next returns the next usable product, or io.EOF after the last one
+func (p *ProductReader) Next() (*models.FoodProduct, error) {
+	for {
+		record, err := p.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(record) == 1 && record[0] == "" {
+			continue
+		}
+
+		field := func(column string) string {
+			i, ok := p.columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		if p.country != "" && !hasTag(field("countries_tags"), p.country) {
+			continue
+		}
+
+		product, ok := productFromRecord(field)
+		if !ok {
+			p.Skipped++
+			continue
+		}
+		return product, nil
+	}
+}
+
+func productFromRecord(field func(column string) string) (*models.FoodProduct, bool) {
+	barcode, err := NormalizeBarcode(field("code"))
+	if err != nil {
+		return nil, false
+	}
+
+	product := &models.FoodProduct{
+		Barcode: barcode,
+		Name:    field("product_name"),
+		Brand:   firstTag(field("brands")),
+	}
+	if product.Name == "" {
+		return nil, false
+	}
+
+	energy, ok := parseAmount(field("energy-kcal_100g"))
+	if !ok {
+		// Older products only state energy in kJ
+		if kj, ok := parseAmount(field("energy_100g")); ok {
+			energy, ok = kj/4.184, true
+		}
+	}
+	if energy <= 0 || energy > maxEnergyKcal {
+		return nil, false
+	}
+	product.EnergyKcal = roundTenth(energy)
+
+	product.ProteinGrams = gramsPer100(field("proteins_100g"))
+	product.FatGrams = gramsPer100(field("fat_100g"))
+	product.CarbsGrams = gramsPer100(field("carbohydrates_100g"))
+	product.FiberGrams = gramsPer100(field("fiber_100g"))
+	if sugar, ok := parseAmount(field("sugars_100g")); ok && sugar <= 100 {
+		product.SugarGrams = &sugar
+	}
+
+	// Sodium is given in grams; salt is 40% sodium by weight
+	if sodium, ok := parseAmount(field("sodium_100g")); ok && sodium <= 100 {
+		mg := roundTenth(sodium * 1000)
+		product.SodiumMg = &mg
+	} else if salt, ok := parseAmount(field("salt_100g")); ok && salt <= 100 {
+		mg := roundTenth(salt * 400)
+		product.SodiumMg = &mg
+	}
+
+	if saturatedFat, ok := parseAmount(field("saturated-fat_100g")); ok && saturatedFat <= 100 {
+		product.SaturatedFatGrams = &saturatedFat
+	}
+	// Cholesterol is given in grams too
+	if cholesterol, ok := parseAmount(field("cholesterol_100g")); ok && cholesterol <= 100 {
+		mg := roundTenth(cholesterol * 1000)
+		product.CholesterolMg = &mg
+	}
+
+	if serving, ok := parseAmount(field("serving_quantity")); ok && serving > 0 && serving <= 5000 {
+		product.ServingGrams = serving
+	}
+
+	return product, true
+}
+
+func (p *ProductReader) readLine() ([]string, error) {
+	line, err := p.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(line, "\r\n"), "\t"), nil
+}
+
+// gramsPer100 parses a nutrient amount per 100 g, treating impossible values as missing
+func gramsPer100(value string) float64 {
+	amount, ok := parseAmount(value)
+	if !ok || amount > 100 {
+		return 0
+	}
+	return amount
+}
+
+// hasTag reports whether a comma separated list of tags contains tag
+func hasTag(tags string, tag string) bool {
+	for _, t := range strings.Split(tags, ",") {
+		if strings.TrimSpace(t) == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// firstTag returns the first entry of a comma separated list such as the brands of a product
+func firstTag(tags string) string {
+	first, _, _ := strings.Cut(tags, ",")
+	return strings.TrimSpace(first)
+}
+
+func roundTenth(value float64) float64 {
+	return math.Round(value*10) / 10
+}
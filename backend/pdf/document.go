@@ -0,0 +1,210 @@
+// pdf/document.go
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// A4 page size in points
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font selects one of the two standard fonts every PDF reader provides, so no font
+// has to be embedded
+type Font int
+
+const (
+	Regular Font = iota // Helvetica
+	Bold                // Helvetica-Bold
+)
+
+// Color is an RGB color with components from 0 to 1
+type Color struct {
+	R, G, B float64
+}
+
+// Black is the default text color
+var Black = Color{}
+
+// Document is a PDF document built page by page in memory
+type Document struct {
+	Title   string
+	Author  string
+	Created time.Time
+	pages   []*Page
+}
+
+// New creates an empty document
+func New(title string) *Document {
+	return &Document{Title: title, Created: time.Now().UTC()}
+}
+
+// AddPage appends an A4 page and returns it
+func (d *Document) AddPage() *Page {
+	page := &Page{Width: A4Width, Height: A4Height}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the pages added so far
+func (d *Document) Pages() []*Page {
+	return d.pages
+}
+
+// Page is one page of a document. Coordinates are in points from the top-left corner.
+type Page struct {
+	Width   float64
+	Height  float64
+	content bytes.Buffer
+}
+
+// Text draws a single line of text with its baseline at y. Characters outside the
+// Windows-1252 character set are replaced by a question mark.
+func (p *Page) Text(x, y float64, font Font, size float64, color Color, text string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s rg %s %s Td (%s) Tj ET\n",
+		font+1, num(size), rgb(color), num(x), num(p.Height-y), escape(encode(text)))
+}
+
+// Line draws a straight line
+func (p *Page) Line(x1, y1, x2, y2, width float64, color Color) {
+	fmt.Fprintf(&p.content, "q %s w %s RG %s %s m %s %s l S Q\n",
+		num(width), rgb(color), num(x1), num(p.Height-y1), num(x2), num(p.Height-y2))
+}
+
+// Rect fills a rectangle whose top-left corner is at x, y
+func (p *Page) Rect(x, y, width, height float64, color Color) {
+	fmt.Fprintf(&p.content, "q %s rg %s %s %s %s re f Q\n",
+		rgb(color), num(x), num(p.Height-y-height), num(width), num(height))
+}
+
+// WriteTo writes the document as a PDF file
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &countingWriter{w: w}
+
+	// Fixed objects, followed by a page and a content stream per page
+	const (
+		catalogObj = 1
+		pagesObj   = 2
+		regularObj = 3
+		boldObj    = 4
+		infoObj    = 5
+		firstPage  = 6
+	)
+	objectCount := firstPage - 1 + 2*len(d.pages)
+	offsets := make([]int64, objectCount+1)
+
+	object := func(id int, body string) {
+		offsets[id] = out.n
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", id, body)
+	}
+
+	fmt.Fprint(out, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	object(catalogObj, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+
+	kids := bytes.Buffer{}
+	for i := range d.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", firstPage+2*i)
+	}
+	object(pagesObj, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(d.pages)))
+
+	object(regularObj, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object(boldObj, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	object(infoObj, fmt.Sprintf("<< /Title (%s) /Author (%s) /Producer (SIGAP) /CreationDate (D:%s) >>",
+		escape(encode(d.Title)), escape(encode(d.Author)), d.Created.UTC().Format("20060102150405Z")))
+
+	for i, page := range d.pages {
+		pageObj := firstPage + 2*i
+		contentObj := pageObj + 1
+
+		object(pageObj, fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>",
+			pagesObj, num(page.Width), num(page.Height), regularObj, boldObj, contentObj))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(page.content.Bytes()); err != nil {
+			return out.n, err
+		}
+		if err := zw.Close(); err != nil {
+			return out.n, err
+		}
+
+		offsets[contentObj] = out.n
+		fmt.Fprintf(out, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", contentObj, compressed.Len())
+		out.Write(compressed.Bytes())
+		fmt.Fprint(out, "\nendstream\nendobj\n")
+	}
+
+	xref := out.n
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", objectCount+1)
+	for id := 1; id <= objectCount; id++ {
+		fmt.Fprintf(out, "%010d 00000 n \n", offsets[id])
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		objectCount+1, catalogObj, infoObj, xref)
+
+	return out.n, out.err
+}
+
+// Bytes returns the document as a PDF file
+func (d *Document) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := d.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// countingWriter tracks the byte offsets needed for the cross-reference table and keeps
+// the first write error
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// num formats a number with at most two decimals
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+func rgb(c Color) string {
+	return num(c.R) + " " + num(c.G) + " " + num(c.B)
+}
+
+// escape escapes the characters that end or break a PDF literal string
+func escape(s []byte) string {
+	var buf bytes.Buffer
+	for _, b := range s {
+		switch b {
+		case '\\', '(', ')':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		case '\n', '\r':
+			buf.WriteByte(' ')
+		default:
+			buf.WriteByte(b)
+		}
+	}
+	return buf.String()
+}
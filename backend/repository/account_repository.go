@@ -0,0 +1,331 @@
+// repository/account_repository.go
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+var (
+	// ErrAccountNotFound is returned for users that do not exist or were already purged
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrDeletionNotScheduled is returned when cancelling or purging an account whose
+	// deletion is not scheduled
+	ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+)
+
+// AccountRepository handles database operations on a user's account as a whole: the
+// personal data export and account deletion
+type AccountRepository struct{}
+
+// NewAccountRepository creates a new AccountRepository
+func NewAccountRepository() *AccountRepository {
+	return &AccountRepository{}
+}
+
+// accountExportQueries select the user's rows of every table in the personal data export,
+// one JSON object per row. Credentials such as the password hash are left out.
+var accountExportQueries = []struct {
+	name  string
+	query string
+}{
+	{"user", `SELECT to_jsonb(u) - 'password_hash' FROM users u WHERE u.id = $1`},
+	{"health_profile", `
+	SELECT jsonb_build_object('age', age, 'height', height, 'weight', weight,
+		'date_of_birth', date_of_birth, 'gender', gender, 'updated_at', updated_at)
+	FROM users WHERE id = $1`},
+	{"assessments", `SELECT to_jsonb(a) FROM user_assessments a WHERE a.user_id = $1 ORDER BY a.id`},
+	{"assessment_results", `SELECT to_jsonb(r) FROM risk_assessment_results r WHERE r.user_id = $1 ORDER BY r.id`},
+	{"activities", `SELECT to_jsonb(a) FROM activity_logs a WHERE a.user_id = $1 ORDER BY a.id`},
+	{"activity_tracks", `SELECT to_jsonb(t) FROM activity_tracks t WHERE t.user_id = $1 ORDER BY t.id`},
+	{"food_logs", `
+	SELECT to_jsonb(f) || jsonb_build_object('analysis', to_jsonb(a) || jsonb_build_object('items', (
+		SELECT COALESCE(jsonb_agg(to_jsonb(i) ORDER BY i.position), '[]'::jsonb)
+		FROM food_analysis_items i
+		WHERE i.food_analysis_id = a.id)))
+	FROM food_logs f
+	LEFT JOIN food_analysis a ON a.food_log_id = f.id
+	WHERE f.user_id = $1
+	ORDER BY f.id`},
+	{"coin_balance", `SELECT to_jsonb(c) FROM user_coins c WHERE c.user_id = $1`},
+	{"coin_transactions", `SELECT to_jsonb(t) FROM coin_transactions t WHERE t.user_id = $1 ORDER BY t.id`},
+	{"reward_redemptions", `SELECT to_jsonb(r) FROM reward_redemptions r WHERE r.user_id = $1 ORDER BY r.id`},
+	{"chat_conversations", `SELECT to_jsonb(c) FROM chatbot_conversations c WHERE c.user_id = $1 ORDER BY c.id`},
+	{"chat_messages", `
+	SELECT to_jsonb(m)
+	FROM chat_messages m
+	JOIN chatbot_conversations c ON c.id = m.conversation_id
+	WHERE c.user_id = $1
+	ORDER BY m.id`},
+}
+
+// ExportTables returns every row of the user's personal data, table by table. Rows are
+// read in one repeatable read transaction so the tables are consistent with each other.
+func (r *AccountRepository) ExportTables(userID int) ([]models.ExportTable, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tables := make([]models.ExportTable, 0, len(accountExportQueries))
+	for _, export := range accountExportQueries {
+		rows, err := tx.Query(ctx, export.query, userID)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", export.name, err)
+		}
+
+		table := models.ExportTable{Name: export.name, Rows: []map[string]any{}}
+		for rows.Next() {
+			var raw []byte
+			if err := rows.Scan(&raw); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("export %s: %w", export.name, err)
+			}
+
+			// Keep numbers as written so IDs and amounts are not turned into floats
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.UseNumber()
+			row := map[string]any{}
+			if err := decoder.Decode(&row); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("export %s: %w", export.name, err)
+			}
+			table.Rows = append(table.Rows, row)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("export %s: %w", export.name, err)
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+// ScheduleDeletion schedules the account for deletion at scheduledAt. Asking again while
+// a deletion is scheduled keeps the original schedule.
+func (r *AccountRepository) ScheduleDeletion(userID int, scheduledAt time.Time) (*models.AccountDeletion, error) {
+	query := `
+	UPDATE users
+	SET deletion_requested_at = COALESCE(deletion_requested_at, NOW()),
+	    deletion_scheduled_at = COALESCE(deletion_scheduled_at, $2)
+	WHERE id = $1 AND deleted_at IS NULL
+	RETURNING deletion_requested_at, deletion_scheduled_at
+	`
+
+	deletion := &models.AccountDeletion{Scheduled: true}
+	err := config.DBPool.QueryRow(context.Background(), query, userID, scheduledAt).Scan(
+		&deletion.RequestedAt,
+		&deletion.ScheduledAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	return deletion, nil
+}
+
+// GetDeletion returns whether and when the account is scheduled for deletion
+func (r *AccountRepository) GetDeletion(userID int) (*models.AccountDeletion, error) {
+	query := `
+	SELECT deletion_requested_at, deletion_scheduled_at
+	FROM users
+	WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	deletion := &models.AccountDeletion{}
+	err := config.DBPool.QueryRow(context.Background(), query, userID).Scan(&deletion.RequestedAt, &deletion.ScheduledAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	deletion.Scheduled = deletion.ScheduledAt != nil
+	return deletion, nil
+}
+
+// CancelDeletion cancels the scheduled deletion of the account
+func (r *AccountRepository) CancelDeletion(userID int) error {
+	query := `
+	UPDATE users
+	SET deletion_requested_at = NULL, deletion_scheduled_at = NULL
+	WHERE id = $1 AND deleted_at IS NULL AND deletion_scheduled_at IS NOT NULL
+	`
+
+	tag, err := config.DBPool.Exec(context.Background(), query, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDeletionNotScheduled
+	}
+	return nil
+}
+
+// GetAccountsDueForDeletion returns the users whose grace period ended before now
+func (r *AccountRepository) GetAccountsDueForDeletion(now time.Time) ([]int, error) {
+	query := `
+	SELECT id
+	FROM users
+	WHERE deletion_scheduled_at <= $1 AND deleted_at IS NULL
+	ORDER BY deletion_scheduled_at
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, rows.Err()
+}
+
+// accountPurgeStatements hard-delete the user's personal data, children before parents.
+// The coin balance, ledger, grants and redemptions are kept for accounting; they point to
+// the anonymized users row and carry no personal data. Uploaded photos are queued for
+// deletion from storage before the food logs holding their keys go.
+var accountPurgeStatements = []string{
+	`DELETE FROM chat_messages WHERE conversation_id IN (SELECT id FROM chatbot_conversations WHERE user_id = $1)`,
+	`DELETE FROM chatbot_conversations WHERE user_id = $1`,
+	`INSERT INTO pending_photo_deletions (photo_key, user_id)
+	 SELECT photo_key, user_id FROM food_logs WHERE user_id = $1 AND photo_key IS NOT NULL
+	 ON CONFLICT (photo_key) DO NOTHING`,
+	`DELETE FROM food_analysis WHERE food_log_id IN (SELECT id FROM food_logs WHERE user_id = $1)`,
+	`DELETE FROM food_logs WHERE user_id = $1`,
+	`DELETE FROM activity_flags WHERE user_id = $1`,
+	`DELETE FROM activity_tracks WHERE user_id = $1`,
+	`DELETE FROM activity_recommendations WHERE user_id = $1`,
+	`DELETE FROM activity_logs WHERE user_id = $1`,
+	`DELETE FROM risk_assessment_results WHERE user_id = $1`,
+	`DELETE FROM user_assessments WHERE user_id = $1`,
+	`DELETE FROM streak_freezes WHERE user_id = $1`,
+	`DELETE FROM streak_bonuses WHERE user_id = $1`,
+	`DELETE FROM idempotency_keys WHERE user_id = $1`,
+	`DELETE FROM refresh_tokens WHERE user_id = $1`,
+}
+
+// PurgeAccount deletes the personal data of an account whose grace period has ended and
+// anonymizes its users row, all in one transaction. A deletion cancelled in the meantime
+// returns ErrDeletionNotScheduled and leaves the account untouched.
+func (r *AccountRepository) PurgeAccount(userID int) error {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the user so a concurrent cancel either wins or waits for the purge
+	var due bool
+	lockQuery := `
+	SELECT deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= NOW()
+	FROM users
+	WHERE id = $1 AND deleted_at IS NULL
+	FOR UPDATE
+	`
+	if err := tx.QueryRow(ctx, lockQuery, userID).Scan(&due); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrAccountNotFound
+		}
+		return err
+	}
+	if !due {
+		return ErrDeletionNotScheduled
+	}
+
+	for _, statement := range accountPurgeStatements {
+		if _, err := tx.Exec(ctx, statement, userID); err != nil {
+			return fmt.Errorf("purge account %d: %w", userID, err)
+		}
+	}
+
+	// Keep the row as a tombstone with unique placeholder credentials that cannot log in
+	anonymizeQuery := `
+	UPDATE users
+	SET username = 'deleted-' || id,
+	    email = 'deleted-' || id || '@deleted.invalid',
+	    password_hash = '',
+	    full_name = NULL,
+	    date_of_birth = NULL,
+	    gender = NULL,
+	    profile_picture_url = NULL,
+	    is_verified = FALSE,
+	    oauth_provider = NULL,
+	    oauth_id = NULL,
+	    google_id = NULL,
+	    supabase_uuid = NULL,
+	    age = NULL,
+	    height = NULL,
+	    weight = NULL,
+	    last_login = NULL,
+	    deletion_scheduled_at = NULL,
+	    deleted_at = NOW(),
+	    updated_at = NOW()
+	WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, anonymizeQuery, userID); err != nil {
+		return fmt.Errorf("anonymize account %d: %w", userID, err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetPendingPhotoDeletions returns the storage keys of purged accounts' photos that are
+// still to be deleted, oldest first
+func (r *AccountRepository) GetPendingPhotoDeletions() ([]string, error) {
+	query := `SELECT photo_key FROM pending_photo_deletions ORDER BY created_at, photo_key`
+
+	rows, err := config.DBPool.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// CompletePhotoDeletion removes a photo from the pending deletions once it is deleted
+// from storage
+func (r *AccountRepository) CompletePhotoDeletion(key string) error {
+	query := `DELETE FROM pending_photo_deletions WHERE photo_key = $1`
+
+	_, err := config.DBPool.Exec(context.Background(), query, key)
+	return err
+}
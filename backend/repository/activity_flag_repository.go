@@ -0,0 +1,199 @@
+// repository/activity_flag_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+var (
+	// ErrFlagNotFound is returned for unknown activity flags
+	ErrFlagNotFound = errors.New("activity flag not found")
+	// ErrFlagAlreadyReviewed is returned when a flag was already approved or rejected
+	ErrFlagAlreadyReviewed = errors.New("activity flag was already reviewed")
+)
+
+// ActivityFlagRepository handles database operations for flagged activity logs
+type ActivityFlagRepository struct{}
+
+// NewActivityFlagRepository creates a new ActivityFlagRepository
+func NewActivityFlagRepository() *ActivityFlagRepository {
+	return &ActivityFlagRepository{}
+}
+
+const activityFlagColumns = `
+	id, activity_id, user_id, reasons, action, coins_withheld, status, reviewed_by, reviewed_at, created_at, updated_at`
+
+func scanActivityFlag(row pgx.Row) (*models.ActivityFlag, error) {
+	var flag models.ActivityFlag
+
+	err := row.Scan(
+		&flag.ID,
+		&flag.ActivityID,
+		&flag.UserID,
+		&flag.Reasons,
+		&flag.Action,
+		&flag.CoinsWithheld,
+		&flag.Status,
+		&flag.ReviewedBy,
+		&flag.ReviewedAt,
+		&flag.CreatedAt,
+		&flag.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flag, nil
+}
+
+// SaveFlag records the flag of an activity log, replacing any earlier flag of the
+// same log. The new flag is pending review, as the checks found different problems.
+func (r *ActivityFlagRepository) SaveFlag(flag *models.ActivityFlag) (*models.ActivityFlag, error) {
+	query := `
+	INSERT INTO activity_flags (activity_id, user_id, reasons, action, coins_withheld)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (activity_id) DO UPDATE
+	SET reasons = EXCLUDED.reasons, action = EXCLUDED.action, coins_withheld = EXCLUDED.coins_withheld,
+		status = 'pending', reviewed_by = NULL, reviewed_at = NULL, updated_at = NOW()
+	RETURNING ` + activityFlagColumns
+
+	return scanActivityFlag(config.DBPool.QueryRow(
+		context.Background(),
+		query,
+		flag.ActivityID,
+		flag.UserID,
+		flag.Reasons,
+		flag.Action,
+		flag.CoinsWithheld,
+	))
+}
+
+// ClearFlag removes the flag of an activity log that passes the checks after an edit
+func (r *ActivityFlagRepository) ClearFlag(activityID int) error {
+	_, err := config.DBPool.Exec(context.Background(), `DELETE FROM activity_flags WHERE activity_id = $1`, activityID)
+	return err
+}
+
+// GetFlagByActivityID retrieves the flag of one of the user's activity logs, or nil if it has none
+func (r *ActivityFlagRepository) GetFlagByActivityID(userID int, activityID int) (*models.ActivityFlag, error) {
+	query := `SELECT ` + activityFlagColumns + ` FROM activity_flags WHERE activity_id = $1 AND user_id = $2`
+
+	flag, err := scanActivityFlag(config.DBPool.QueryRow(context.Background(), query, activityID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return flag, nil
+}
+
+// GetFlags retrieves flags for review, filtered by status through the type filter.
+// It returns the cursor of the next page when opts has a limit.
+func (r *ActivityFlagRepository) GetFlags(opts models.ListOptions) ([]models.ActivityFlag, string, error) {
+	filter := &listFilter{}
+	switch opts.Type {
+	case "":
+	case "pending", "approved", "rejected":
+		filter.where("status = ?", opts.Type)
+	default:
+		return nil, "", ErrInvalidListFilter
+	}
+
+	query := `SELECT ` + activityFlagColumns + `
+	FROM activity_flags
+	` + filter.page(opts, "created_at", "id")
+
+	rows, err := config.DBPool.Query(context.Background(), query, filter.args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var flags []models.ActivityFlag
+
+	for rows.Next() {
+		flag, err := scanActivityFlag(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		flags = append(flags, *flag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	flags, nextCursor := trimPage(flags, opts, func(f models.ActivityFlag) models.Cursor {
+		return models.Cursor{Time: f.CreatedAt, ID: f.ID}
+	})
+
+	return flags, nextCursor, nil
+}
+
+// ReviewFlag approves or rejects a pending flag. Approving credits the withheld coins
+// to the user and adds them to the activity log, in one transaction.
+func (r *ActivityFlagRepository) ReviewFlag(adminID int, flagID int, status string) (*models.ActivityFlag, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the flag so a concurrent review cannot release the coins twice
+	selectQuery := `SELECT ` + activityFlagColumns + ` FROM activity_flags WHERE id = $1 FOR UPDATE`
+
+	flag, err := scanActivityFlag(tx.QueryRow(ctx, selectQuery, flagID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFlagNotFound
+		}
+		return nil, err
+	}
+	if flag.Status != "pending" {
+		return nil, ErrFlagAlreadyReviewed
+	}
+
+	now := time.Now().UTC()
+	updateQuery := `
+	UPDATE activity_flags
+	SET status = $2, reviewed_by = $3, reviewed_at = $4, updated_at = $4
+	WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, updateQuery, flag.ID, status, adminID, now); err != nil {
+		return nil, err
+	}
+
+	if status == "approved" && flag.CoinsWithheld > 0 {
+		activityQuery := `UPDATE activity_logs SET coins_earned = coins_earned + $2 WHERE id = $1`
+		if _, err := tx.Exec(ctx, activityQuery, flag.ActivityID, flag.CoinsWithheld); err != nil {
+			return nil, err
+		}
+
+		err = creditCoins(ctx, tx, flag.UserID, flag.CoinsWithheld, "ActivityFlagRelease", flag.ActivityID, "activity_logs", "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	flag.Status = status
+	flag.ReviewedBy = &adminID
+	flag.ReviewedAt = &now
+	flag.UpdatedAt = now
+
+	return flag, nil
+}
@@ -5,14 +5,19 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/habdil/sigap-app/backend/config"
 	"github.com/habdil/sigap-app/backend/models"
 )
 
+// ErrActivityNotFound is returned for activity logs that do not exist or belong to another user
+var ErrActivityNotFound = errors.New("activity not found")
+
 // ActivityRepository handles database operations for activities
 type ActivityRepository struct{}
 
@@ -21,14 +26,15 @@ func NewActivityRepository() *ActivityRepository {
 	return &ActivityRepository{}
 }
 
-// CreateActivityLog creates a new activity log
+// CreateActivityLog creates a new activity log and credits the coins it earned, in one transaction
 func (r *ActivityRepository) CreateActivityLog(userID int, req *models.ActivityLogRequest, coinsEarned int) (*models.ActivityLog, error) {
 	query := `
     INSERT INTO activity_logs (
         user_id, activity_type, duration_minutes, distance_km, calories_burned,
-        heart_rate_avg, notes, weather_condition, location_data, avg_pace, coins_earned, music_played
+        heart_rate_avg, notes, weather_condition, location_data, avg_pace, coins_earned, music_played,
+        activity_date, calorie_method
     )
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, COALESCE($13::TIMESTAMP, NOW()), NULLIF($14, ''))
     RETURNING id, activity_date
     `
 
@@ -58,12 +64,20 @@ func (r *ActivityRepository) CreateActivityLog(userID int, req *models.ActivityL
 		AvgPace:          req.AvgPace,
 		CoinsEarned:      coinsEarned,
 		MusicPlayed:      req.MusicPlayed,
+		CalorieMethod:    req.CalorieMethod,
 	}
 
 	var activityDate time.Time
 
-	err := config.DBPool.QueryRow(
-		context.Background(),
+	ctx := context.Background()
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(
+		ctx,
 		query,
 		userID,
 		req.ActivityType,
@@ -77,101 +91,292 @@ func (r *ActivityRepository) CreateActivityLog(userID int, req *models.ActivityL
 		req.AvgPace,
 		coinsEarned,
 		req.MusicPlayed,
+		req.ActivityDate,
+		req.CalorieMethod,
 	).Scan(&activityLog.ID, &activityDate)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if coinsEarned > 0 {
+		err = creditCoins(ctx, tx, userID, coinsEarned, "Activity", activityLog.ID, "activity_logs", fmt.Sprintf("activity_logs:%d", activityLog.ID))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
 	activityLog.ActivityDate = activityDate
 	return activityLog, nil
 }
 
-// GetUserActivities retrieves all activities for a user
-func (r *ActivityRepository) GetUserActivities(userID int) ([]models.ActivityLog, error) {
-	query := `
-	SELECT 
+// activityColumns are the columns read by scanActivityLog
+const activityColumns = `
 		id, user_id, activity_type, duration_minutes, distance_km, calories_burned,
-		heart_rate_avg, activity_date, notes, weather_condition, location_data, avg_pace, coins_earned, music_played
-	FROM activity_logs
-	WHERE user_id = $1
-	ORDER BY activity_date DESC
-	`
+		heart_rate_avg, activity_date, notes, weather_condition, location_data, avg_pace, coins_earned, music_played,
+		COALESCE(calorie_method, '')`
 
-	rows, err := config.DBPool.Query(context.Background(), query, userID)
+// scanActivityLog scans a row selected with activityColumns
+func scanActivityLog(row pgx.Row) (*models.ActivityLog, error) {
+	var activity models.ActivityLog
+	var activityDate time.Time
+	var distanceKMNull, avgPaceNull pgtype.Float8
+	var caloriesBurnedNull, heartRateAvgNull pgtype.Int4
+	var notesNull, weatherConditionNull, musicPlayedNull pgtype.Text
+	var locationDataNull []byte
+
+	err := row.Scan(
+		&activity.ID,
+		&activity.UserID,
+		&activity.ActivityType,
+		&activity.DurationMinutes,
+		&distanceKMNull,
+		&caloriesBurnedNull,
+		&heartRateAvgNull,
+		&activityDate,
+		&notesNull,
+		&weatherConditionNull,
+		&locationDataNull,
+		&avgPaceNull,
+		&activity.CoinsEarned,
+		&musicPlayedNull,
+		&activity.CalorieMethod,
+	)
 	if err != nil {
 		return nil, err
 	}
+
+	activity.ActivityDate = activityDate
+
+	if distanceKMNull.Valid {
+		activity.DistanceKM = distanceKMNull.Float64
+	}
+	if caloriesBurnedNull.Valid {
+		activity.CaloriesBurned = int(caloriesBurnedNull.Int32)
+	}
+	if heartRateAvgNull.Valid {
+		activity.HeartRateAvg = int(heartRateAvgNull.Int32)
+	}
+	if notesNull.Valid {
+		activity.Notes = notesNull.String
+	}
+	if weatherConditionNull.Valid {
+		activity.WeatherCondition = weatherConditionNull.String
+	}
+	if locationDataNull != nil {
+		var locationData interface{}
+		if err := json.Unmarshal(locationDataNull, &locationData); err == nil {
+			activity.LocationData = locationData
+		}
+	}
+	if avgPaceNull.Valid {
+		activity.AvgPace = avgPaceNull.Float64
+	}
+	if musicPlayedNull.Valid {
+		activity.MusicPlayed = musicPlayedNull.String
+	}
+
+	return &activity, nil
+}
+
+// GetUserActivities retrieves a user's activities, filtered by date range and activity type.
+// It returns the cursor of the next page when opts has a limit.
+func (r *ActivityRepository) GetUserActivities(userID int, opts models.ListOptions) ([]models.ActivityLog, string, error) {
+	filter := &listFilter{}
+	filter.where("user_id = ?", userID)
+	if opts.Type != "" {
+		filter.where("activity_type = ?", opts.Type)
+	}
+
+	query := `
+	SELECT ` + activityColumns + `
+	FROM activity_logs
+	` + filter.page(opts, "activity_date", "id")
+
+	rows, err := config.DBPool.Query(context.Background(), query, filter.args...)
+	if err != nil {
+		return nil, "", err
+	}
 	defer rows.Close()
 
 	var activities []models.ActivityLog
 
 	for rows.Next() {
-		var activity models.ActivityLog
-		var activityDate time.Time
-		var distanceKMNull, avgPaceNull pgtype.Float8
-		var caloriesBurnedNull, heartRateAvgNull pgtype.Int4
-		var notesNull, weatherConditionNull, musicPlayedNull pgtype.Text
-		var locationDataNull []byte
-
-		err := rows.Scan(
-			&activity.ID,
-			&activity.UserID,
-			&activity.ActivityType,
-			&activity.DurationMinutes,
-			&distanceKMNull,
-			&caloriesBurnedNull,
-			&heartRateAvgNull,
-			&activityDate,
-			&notesNull,
-			&weatherConditionNull,
-			&locationDataNull,
-			&avgPaceNull,
-			&activity.CoinsEarned,
-			&musicPlayedNull,
-		)
+		activity, err := scanActivityLog(rows)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
-		activity.ActivityDate = activityDate
+		activities = append(activities, *activity)
+	}
 
-		if distanceKMNull.Valid {
-			activity.DistanceKM = distanceKMNull.Float64
-		}
-		if caloriesBurnedNull.Valid {
-			activity.CaloriesBurned = int(caloriesBurnedNull.Int32)
-		}
-		if heartRateAvgNull.Valid {
-			activity.HeartRateAvg = int(heartRateAvgNull.Int32)
-		}
-		if notesNull.Valid {
-			activity.Notes = notesNull.String
-		}
-		if weatherConditionNull.Valid {
-			activity.WeatherCondition = weatherConditionNull.String
-		}
-		if locationDataNull != nil {
-			var locationData interface{}
-			if err := json.Unmarshal(locationDataNull, &locationData); err == nil {
-				activity.LocationData = locationData
-			}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	activities, nextCursor := trimPage(activities, opts, func(a models.ActivityLog) models.Cursor {
+		return models.Cursor{Time: a.ActivityDate, ID: a.ID}
+	})
+
+	return activities, nextCursor, nil
+}
+
+// GetActivityByID retrieves one of the user's activity logs
+func (r *ActivityRepository) GetActivityByID(userID int, activityID int) (*models.ActivityLog, error) {
+	query := `SELECT ` + activityColumns + ` FROM activity_logs WHERE id = $1 AND user_id = $2`
+
+	activity, err := scanActivityLog(config.DBPool.QueryRow(context.Background(), query, activityID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrActivityNotFound
 		}
-		if avgPaceNull.Valid {
-			activity.AvgPace = avgPaceNull.Float64
+		return nil, err
+	}
+
+	return activity, nil
+}
+
+// GetLatestActivityDate returns when the user's most recent activity ended, or nil if
+// the user has not logged any
+func (r *ActivityRepository) GetLatestActivityDate(userID int) (*time.Time, error) {
+	query := `SELECT MAX(activity_date) FROM activity_logs WHERE user_id = $1`
+
+	var latest *time.Time
+	err := config.DBPool.QueryRow(context.Background(), query, userID).Scan(&latest)
+	return latest, err
+}
+
+// HasOverlappingActivity reports whether the user has another activity whose session,
+// ending at activity_date and lasting duration_minutes, overlaps start to end.
+// Rejected activities are ignored. excludeID skips the activity being edited.
+func (r *ActivityRepository) HasOverlappingActivity(userID int, excludeID int, start time.Time, end time.Time) (bool, error) {
+	query := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM activity_logs a
+		WHERE a.user_id = $1 AND a.id <> $2
+			AND a.activity_date > $3
+			AND a.activity_date - make_interval(mins => a.duration_minutes) < $4
+			AND NOT EXISTS (
+				SELECT 1 FROM activity_flags f WHERE f.activity_id = a.id AND f.status = 'rejected'
+			)
+	)
+	`
+
+	var overlapping bool
+	err := config.DBPool.QueryRow(context.Background(), query, userID, excludeID, start.UTC(), end.UTC()).Scan(&overlapping)
+	return overlapping, err
+}
+
+// UpdateActivityLog saves an edited activity log. With rescored, it posts a compensating
+// coin transaction for the difference between the new and the previously earned coins, in
+// one transaction; a negative adjustment fails with ErrInsufficientCoins if the coins were
+// already spent. Without it the stored coins, including any released on review, are kept.
+func (r *ActivityRepository) UpdateActivityLog(activity *models.ActivityLog, rescored bool) error {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the row so concurrent edits adjust coins against the latest value
+	var previousCoins int
+	lockQuery := `SELECT coins_earned FROM activity_logs WHERE id = $1 AND user_id = $2 FOR UPDATE`
+	if err := tx.QueryRow(ctx, lockQuery, activity.ID, activity.UserID).Scan(&previousCoins); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrActivityNotFound
 		}
-		if musicPlayedNull.Valid {
-			activity.MusicPlayed = musicPlayedNull.String
+		return err
+	}
+	if !rescored {
+		activity.CoinsEarned = previousCoins
+	}
+
+	var locationDataParam interface{}
+	if activity.LocationData != nil {
+		locationDataJSON, err := json.Marshal(activity.LocationData)
+		if err != nil {
+			return err
 		}
+		locationDataParam = string(locationDataJSON)
+	}
 
-		activities = append(activities, activity)
+	updateQuery := `
+	UPDATE activity_logs
+	SET activity_type = $1, duration_minutes = $2, distance_km = $3, calories_burned = $4,
+		heart_rate_avg = $5, notes = $6, weather_condition = $7, location_data = $8,
+		avg_pace = $9, coins_earned = $10, music_played = $11, calorie_method = NULLIF($12, '')
+	WHERE id = $13
+	`
+	_, err = tx.Exec(
+		ctx,
+		updateQuery,
+		activity.ActivityType,
+		activity.DurationMinutes,
+		activity.DistanceKM,
+		activity.CaloriesBurned,
+		activity.HeartRateAvg,
+		activity.Notes,
+		activity.WeatherCondition,
+		locationDataParam,
+		activity.AvgPace,
+		activity.CoinsEarned,
+		activity.MusicPlayed,
+		activity.CalorieMethod,
+		activity.ID,
+	)
+	if err != nil {
+		return err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
+	delta := activity.CoinsEarned - previousCoins
+	switch {
+	case delta > 0:
+		err = creditCoins(ctx, tx, activity.UserID, delta, "ActivityAdjustment", activity.ID, "activity_logs", "")
+	case delta < 0:
+		err = debitCoins(ctx, tx, activity.UserID, -delta, "ActivityAdjustment", activity.ID, "activity_logs", "")
+	}
+	if err != nil {
+		return err
 	}
 
-	return activities, nil
+	return tx.Commit(ctx)
+}
+
+// DeleteActivityLog deletes an activity log and takes back the coins it earned, in one transaction.
+// It fails with ErrInsufficientCoins if those coins were already spent.
+func (r *ActivityRepository) DeleteActivityLog(userID int, activityID int) error {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var coinsEarned int
+	deleteQuery := `DELETE FROM activity_logs WHERE id = $1 AND user_id = $2 RETURNING coins_earned`
+	if err := tx.QueryRow(ctx, deleteQuery, activityID, userID).Scan(&coinsEarned); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrActivityNotFound
+		}
+		return err
+	}
+
+	if coinsEarned > 0 {
+		err = debitCoins(ctx, tx, userID, coinsEarned, "ActivityReversal", activityID, "activity_logs", "")
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
 }
 
 // GetUserRecommendations retrieves activity recommendations for a user
@@ -258,3 +463,138 @@ func (r *ActivityRepository) SaveActivityRecommendation(rec *models.ActivityReco
 
 	return rec, nil
 }
+
+// GetActivityAggregates sums the user's activities between start (inclusive) and end
+// (exclusive), grouped by activity type and by the day, week or month they fall in
+// within the given timezone.
+func (r *ActivityRepository) GetActivityAggregates(userID int, period string, timezone string, start, end time.Time) ([]models.ActivityAggregate, error) {
+	// activity_date is stored in UTC
+	query := `
+	SELECT
+		date_trunc($2, activity_date AT TIME ZONE 'UTC' AT TIME ZONE $3)::DATE AS period_start,
+		activity_type,
+		COUNT(*)::INTEGER,
+		COALESCE(SUM(duration_minutes), 0)::INTEGER,
+		COALESCE(SUM(distance_km), 0)::FLOAT8,
+		COALESCE(SUM(calories_burned), 0)::INTEGER,
+		COALESCE(SUM(coins_earned), 0)::INTEGER
+	FROM activity_logs
+	WHERE user_id = $1 AND activity_date >= $4 AND activity_date < $5
+	GROUP BY period_start, activity_type
+	ORDER BY period_start, activity_type
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query, userID, period, timezone, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var aggregates []models.ActivityAggregate
+
+	for rows.Next() {
+		var aggregate models.ActivityAggregate
+
+		err := rows.Scan(
+			&aggregate.PeriodStart,
+			&aggregate.ActivityType,
+			&aggregate.Sessions,
+			&aggregate.Minutes,
+			&aggregate.DistanceKM,
+			&aggregate.Calories,
+			&aggregate.Coins,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		aggregates = append(aggregates, aggregate)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return aggregates, nil
+}
+
+// GetActivityTotals sums the user's activities between start (inclusive) and end (exclusive)
+func (r *ActivityRepository) GetActivityTotals(userID int, start, end time.Time) (*models.ActivityTotals, error) {
+	query := `
+	SELECT
+		COUNT(*)::INTEGER,
+		COALESCE(SUM(duration_minutes), 0)::INTEGER,
+		COALESCE(SUM(distance_km), 0)::FLOAT8,
+		COALESCE(SUM(calories_burned), 0)::INTEGER,
+		COALESCE(SUM(coins_earned), 0)::INTEGER
+	FROM activity_logs
+	WHERE user_id = $1 AND activity_date >= $2 AND activity_date < $3
+	`
+
+	var totals models.ActivityTotals
+	err := config.DBPool.QueryRow(context.Background(), query, userID, start.UTC(), end.UTC()).Scan(
+		&totals.Sessions,
+		&totals.Minutes,
+		&totals.DistanceKM,
+		&totals.Calories,
+		&totals.Coins,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &totals, nil
+}
+
+// GetPersonalBests retrieves the user's all-time records
+func (r *ActivityRepository) GetPersonalBests(userID int) (*models.PersonalBests, error) {
+	var bests models.PersonalBests
+	var err error
+
+	bests.LongestRun, err = r.getPersonalBest(userID, `activity_type = 'Running' AND distance_km > 0`, `distance_km DESC`)
+	if err != nil {
+		return nil, err
+	}
+
+	bests.LongestSession, err = r.getPersonalBest(userID, `TRUE`, `duration_minutes DESC`)
+	if err != nil {
+		return nil, err
+	}
+
+	bests.FastestPace, err = r.getPersonalBest(userID, `avg_pace > 0`, `avg_pace ASC`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bests, nil
+}
+
+// getPersonalBest returns the first activity matching condition in the given order, or nil.
+// condition and order are fixed SQL fragments, never user input.
+func (r *ActivityRepository) getPersonalBest(userID int, condition string, order string) (*models.PersonalBest, error) {
+	query := `
+	SELECT id, activity_type, duration_minutes, COALESCE(distance_km, 0), COALESCE(avg_pace, 0), activity_date
+	FROM activity_logs
+	WHERE user_id = $1 AND ` + condition + `
+	ORDER BY ` + order + `, activity_date ASC
+	LIMIT 1
+	`
+
+	var best models.PersonalBest
+	err := config.DBPool.QueryRow(context.Background(), query, userID).Scan(
+		&best.ActivityID,
+		&best.ActivityType,
+		&best.DurationMinutes,
+		&best.DistanceKM,
+		&best.AvgPace,
+		&best.ActivityDate,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &best, nil
+}
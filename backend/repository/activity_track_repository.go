@@ -0,0 +1,154 @@
+// repository/activity_track_repository.go
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+var (
+	// ErrTrackNotFound is returned when an activity has no imported track
+	ErrTrackNotFound = errors.New("activity track not found")
+	// ErrTrackAlreadyImported is returned when the same file is imported twice
+	ErrTrackAlreadyImported = errors.New("this file has already been imported")
+)
+
+// ActivityTrackRepository handles database operations for imported GPS tracks
+type ActivityTrackRepository struct{}
+
+// NewActivityTrackRepository creates a new ActivityTrackRepository
+func NewActivityTrackRepository() *ActivityTrackRepository {
+	return &ActivityTrackRepository{}
+}
+
+// ReserveTrack stores a parsed track before its activity is created. It fails with
+// ErrTrackAlreadyImported if the user already imported a file with the same hash.
+func (r *ActivityTrackRepository) ReserveTrack(track *models.ActivityTrack) error {
+	points, err := json.Marshal(track.Points)
+	if err != nil {
+		return err
+	}
+	splits, err := json.Marshal(track.Splits)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO activity_tracks (
+		user_id, source_format, sport, file_hash, started_at, ended_at, distance_km, duration_seconds,
+		avg_pace, elevation_gain_m, heart_rate_avg, heart_rate_max, points, splits
+	)
+	VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, NULLIF($11, 0), NULLIF($12, 0), $13, $14)
+	ON CONFLICT (user_id, file_hash) DO NOTHING
+	RETURNING id, created_at
+	`
+
+	err = config.DBPool.QueryRow(
+		context.Background(),
+		query,
+		track.UserID,
+		track.SourceFormat,
+		track.Sport,
+		track.FileHash,
+		track.StartedAt.UTC(),
+		track.EndedAt.UTC(),
+		track.DistanceKM,
+		track.DurationSeconds,
+		track.AvgPace,
+		track.ElevationGainM,
+		track.HeartRateAvg,
+		track.HeartRateMax,
+		string(points),
+		string(splits),
+	).Scan(&track.ID, &track.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTrackAlreadyImported
+		}
+		return err
+	}
+
+	return nil
+}
+
+// AttachTrack links a reserved track to the activity created from it
+func (r *ActivityTrackRepository) AttachTrack(trackID int, activityID int) error {
+	query := `UPDATE activity_tracks SET activity_id = $2 WHERE id = $1`
+
+	_, err := config.DBPool.Exec(context.Background(), query, trackID, activityID)
+	return err
+}
+
+// ReleaseTrack removes a reserved track whose activity could not be created
+func (r *ActivityTrackRepository) ReleaseTrack(trackID int) error {
+	query := `DELETE FROM activity_tracks WHERE id = $1 AND activity_id IS NULL`
+
+	_, err := config.DBPool.Exec(context.Background(), query, trackID)
+	return err
+}
+
+// GetTrackByActivityID retrieves the track imported for one of the user's activities
+func (r *ActivityTrackRepository) GetTrackByActivityID(userID int, activityID int) (*models.ActivityTrack, error) {
+	query := `
+	SELECT id, user_id, activity_id, source_format, COALESCE(sport, ''), started_at, ended_at,
+		distance_km, duration_seconds, avg_pace, elevation_gain_m, heart_rate_avg, heart_rate_max,
+		points, splits, created_at
+	FROM activity_tracks
+	WHERE activity_id = $1 AND user_id = $2
+	`
+
+	var track models.ActivityTrack
+	var avgPace pgtype.Float8
+	var heartRateAvg, heartRateMax pgtype.Int4
+	var points, splits []byte
+
+	err := config.DBPool.QueryRow(context.Background(), query, activityID, userID).Scan(
+		&track.ID,
+		&track.UserID,
+		&track.ActivityID,
+		&track.SourceFormat,
+		&track.Sport,
+		&track.StartedAt,
+		&track.EndedAt,
+		&track.DistanceKM,
+		&track.DurationSeconds,
+		&avgPace,
+		&track.ElevationGainM,
+		&heartRateAvg,
+		&heartRateMax,
+		&points,
+		&splits,
+		&track.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTrackNotFound
+		}
+		return nil, err
+	}
+
+	if avgPace.Valid {
+		track.AvgPace = avgPace.Float64
+	}
+	if heartRateAvg.Valid {
+		track.HeartRateAvg = int(heartRateAvg.Int32)
+	}
+	if heartRateMax.Valid {
+		track.HeartRateMax = int(heartRateMax.Int32)
+	}
+	if err := json.Unmarshal(points, &track.Points); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(splits, &track.Splits); err != nil {
+		return nil, err
+	}
+
+	return &track, nil
+}
@@ -0,0 +1,78 @@
+// repository/activity_type_repository.go
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// ActivityTypeRepository handles database operations for the activity type registry
+type ActivityTypeRepository struct{}
+
+// NewActivityTypeRepository creates a new ActivityTypeRepository
+func NewActivityTypeRepository() *ActivityTypeRepository {
+	return &ActivityTypeRepository{}
+}
+
+// GetActivityTypes retrieves all registered activity types, including inactive ones, in display order
+func (r *ActivityTypeRepository) GetActivityTypes() ([]models.ActivityType, error) {
+	query := `
+	SELECT id, name, labels, met, met_curve, coins_per_minute, bonus_tiers, uses_distance,
+		max_speed_kmh, sort_order, is_active, created_at, updated_at
+	FROM activity_types
+	ORDER BY sort_order ASC, id ASC
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []models.ActivityType
+
+	for rows.Next() {
+		var activityType models.ActivityType
+		var labels, metCurve, bonusTiers []byte
+
+		err := rows.Scan(
+			&activityType.ID,
+			&activityType.Name,
+			&labels,
+			&activityType.MET,
+			&metCurve,
+			&activityType.CoinsPerMinute,
+			&bonusTiers,
+			&activityType.UsesDistance,
+			&activityType.MaxSpeedKMH,
+			&activityType.SortOrder,
+			&activityType.IsActive,
+			&activityType.CreatedAt,
+			&activityType.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(labels, &activityType.Labels); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(metCurve, &activityType.METCurve); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(bonusTiers, &activityType.BonusTiers); err != nil {
+			return nil, err
+		}
+
+		types = append(types, activityType)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
@@ -14,6 +14,13 @@ import (
 	"github.com/habdil/sigap-app/backend/models"
 )
 
+var (
+	// ErrAssessmentNotFound is returned when the user has not taken an assessment
+	ErrAssessmentNotFound = errors.New("no assessment found for this user")
+	// ErrAssessmentResultNotFound is returned when the user has no assessment result
+	ErrAssessmentResultNotFound = errors.New("no assessment result found for this user")
+)
+
 // AssessmentRepository handles database operations for assessments
 type AssessmentRepository struct{}
 
@@ -22,25 +29,35 @@ func NewAssessmentRepository() *AssessmentRepository {
 	return &AssessmentRepository{}
 }
 
-// CreateAssessment creates a new assessment
+// CreateAssessment creates a new assessment from a request whose answers were validated
+// against its questionnaire version
 func (r *AssessmentRepository) CreateAssessment(userID int, req *models.AssessmentRequest) (*models.UserAssessment, error) {
+	answersJSON, err := json.Marshal(req.Answers)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-	INSERT INTO user_assessments (user_id, screen_time_hours, exercise_hours, late_night_frequency, diet_quality)
-	VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO user_assessments (user_id, screen_time_hours, exercise_hours, late_night_frequency, diet_quality,
+		questionnaire_version, answers, weight_kg)
+	VALUES ($1, NULLIF($2, 0), NULLIF($3, 0), NULLIF($4, 0), NULLIF($5, 0), $6, $7::jsonb, NULLIF($8, 0))
 	RETURNING id, created_at, updated_at
 	`
 
 	assessment := &models.UserAssessment{
-		UserID:             userID,
-		ScreenTimeHours:    req.ScreenTimeHours,
-		ExerciseHours:      req.ExerciseHours,
-		LateNightFrequency: req.LateNightFrequency,
-		DietQuality:        req.DietQuality,
+		UserID:               userID,
+		QuestionnaireVersion: req.QuestionnaireVersion,
+		Answers:              req.Answers,
+		ScreenTimeHours:      req.ScreenTimeHours,
+		ExerciseHours:        req.ExerciseHours,
+		LateNightFrequency:   req.LateNightFrequency,
+		DietQuality:          req.DietQuality,
+		WeightKG:             req.WeightKG,
 	}
 
 	var createdAt, updatedAt time.Time
 
-	err := config.DBPool.QueryRow(
+	err = config.DBPool.QueryRow(
 		context.Background(),
 		query,
 		userID,
@@ -48,6 +65,9 @@ func (r *AssessmentRepository) CreateAssessment(userID int, req *models.Assessme
 		req.ExerciseHours,
 		req.LateNightFrequency,
 		req.DietQuality,
+		req.QuestionnaireVersion,
+		string(answersJSON),
+		req.WeightKG,
 	).Scan(&assessment.ID, &createdAt, &updatedAt)
 
 	if err != nil {
@@ -60,59 +80,64 @@ func (r *AssessmentRepository) CreateAssessment(userID int, req *models.Assessme
 	return assessment, nil
 }
 
-// CreateRiskAssessmentResult creates a new risk assessment result
-func (r *AssessmentRepository) CreateRiskAssessmentResult(userID int, assessmentID int, riskPercentage int, riskFactors []string, recommendations []string) (*models.RiskAssessmentResult, error) {
+// CreateRiskAssessmentResult creates a new risk assessment result together with the
+// source of its percentage and the rule engine's per-factor contributions
+func (r *AssessmentRepository) CreateRiskAssessmentResult(result *models.RiskAssessmentResult) (*models.RiskAssessmentResult, error) {
 	// Set default values if null or empty
-	if riskFactors == nil || len(riskFactors) == 0 {
-		riskFactors = []string{"Unknown risk factor"}
+	if len(result.RiskFactors) == 0 {
+		result.RiskFactors = []string{"Unknown risk factor"}
 	}
 
-	if recommendations == nil || len(recommendations) == 0 {
-		recommendations = []string{"Consult with a healthcare professional"}
+	if len(result.Recommendations) == 0 {
+		result.Recommendations = []string{"Consult with a healthcare professional"}
 	}
 
 	// Log the values being inserted
-	log.Printf("Inserting risk assessment with factors: %v and recommendations: %v", riskFactors, recommendations)
+	log.Printf("Inserting risk assessment with factors: %v and recommendations: %v", result.RiskFactors, result.Recommendations)
 
 	// Convert slices to JSON objects
-	riskFactorsJSON, err := json.Marshal(riskFactors)
+	riskFactorsJSON, err := json.Marshal(result.RiskFactors)
 	if err != nil {
 		log.Printf("Error marshaling risk factors to JSON: %v", err)
 		return nil, err
 	}
 
-	recommendationsJSON, err := json.Marshal(recommendations)
+	recommendationsJSON, err := json.Marshal(result.Recommendations)
 	if err != nil {
 		log.Printf("Error marshaling recommendations to JSON: %v", err)
 		return nil, err
 	}
 
+	contributionsJSON, err := json.Marshal(result.Contributions)
+	if err != nil {
+		log.Printf("Error marshaling risk contributions to JSON: %v", err)
+		return nil, err
+	}
+
 	// Use query with explicit JSONB cast
 	query := `
-    INSERT INTO risk_assessment_results (user_id, assessment_id, risk_percentage, risk_factors, recommendations)
-    VALUES ($1, $2, $3, $4::jsonb, $5::jsonb)
+    INSERT INTO risk_assessment_results (user_id, assessment_id, risk_percentage, risk_factors, recommendations,
+        source, model_version, rule_score, rule_model_version, contributions)
+    VALUES ($1, $2, $3, $4::jsonb, $5::jsonb, $6, $7, $8, $9, $10::jsonb)
     RETURNING id, created_at
     `
 
-	result := &models.RiskAssessmentResult{
-		UserID:          userID,
-		AssessmentID:    assessmentID,
-		RiskPercentage:  riskPercentage,
-		RiskFactors:     riskFactors,
-		Recommendations: recommendations,
-	}
-
 	var createdAt time.Time
 
 	// Execute the query with JSON strings
 	err = config.DBPool.QueryRow(
 		context.Background(),
 		query,
-		userID,
-		assessmentID,
-		riskPercentage,
+		result.UserID,
+		result.AssessmentID,
+		result.RiskPercentage,
 		string(riskFactorsJSON),     // Convert []byte to string with explicit JSONB cast in query
 		string(recommendationsJSON), // Convert []byte to string with explicit JSONB cast in query
+		result.Source,
+		result.ModelVersion,
+		result.RuleScore,
+		result.RuleModelVersion,
+		string(contributionsJSON),
 	).Scan(&result.ID, &createdAt)
 
 	if err != nil {
@@ -130,10 +155,13 @@ func (r *AssessmentRepository) GetLatestAssessment(userID int) (*models.UserAsse
 	SELECT 
 		id, 
 		user_id, 
-		screen_time_hours, 
-		exercise_hours, 
-		late_night_frequency, 
-		diet_quality, 
+		COALESCE(screen_time_hours, 0), 
+		COALESCE(exercise_hours, 0), 
+		COALESCE(late_night_frequency, 0), 
+		COALESCE(diet_quality, 0), 
+		questionnaire_version,
+		answers,
+		COALESCE(weight_kg, 0),
 		created_at, 
 		updated_at
 	FROM 
@@ -147,6 +175,7 @@ func (r *AssessmentRepository) GetLatestAssessment(userID int) (*models.UserAsse
 
 	var assessment models.UserAssessment
 	var createdAt, updatedAt time.Time
+	var answersJSON []byte
 
 	err := config.DBPool.QueryRow(context.Background(), query, userID).Scan(
 		&assessment.ID,
@@ -155,66 +184,54 @@ func (r *AssessmentRepository) GetLatestAssessment(userID int) (*models.UserAsse
 		&assessment.ExerciseHours,
 		&assessment.LateNightFrequency,
 		&assessment.DietQuality,
+		&assessment.QuestionnaireVersion,
+		&answersJSON,
+		&assessment.WeightKG,
 		&createdAt,
 		&updatedAt,
 	)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("no assessment found for this user")
+			return nil, ErrAssessmentNotFound
 		}
 		return nil, err
 	}
 
 	assessment.CreatedAt = createdAt
 	assessment.UpdatedAt = updatedAt
+	assessment.Answers = parseAnswers(answersJSON)
 
 	return &assessment, nil
 }
 
-// GetLatestAssessmentResult retrieves the latest assessment result for a user
-func (r *AssessmentRepository) GetLatestAssessmentResult(userID int) (*models.RiskAssessmentResult, error) {
-	query := `
-	SELECT 
-		id, 
-		user_id, 
-		assessment_id, 
-		risk_percentage, 
-		risk_factors, 
-		recommendations, 
-		created_at
-	FROM 
-		risk_assessment_results
-	WHERE 
-		user_id = $1
-	ORDER BY 
-		created_at DESC
-	LIMIT 1
-	`
+const riskAssessmentResultColumns = `
+	id, user_id, assessment_id, risk_percentage, risk_factors, recommendations,
+	COALESCE(source, ''), COALESCE(model_version, ''), rule_score, COALESCE(rule_model_version, ''),
+	contributions, created_at`
 
+func scanRiskAssessmentResult(row pgx.Row) (*models.RiskAssessmentResult, error) {
 	var result models.RiskAssessmentResult
-	var createdAt time.Time
-	var riskFactorsJSON, recommendationsJSON []byte
+	var riskFactorsJSON, recommendationsJSON, contributionsJSON []byte
 
-	err := config.DBPool.QueryRow(context.Background(), query, userID).Scan(
+	err := row.Scan(
 		&result.ID,
 		&result.UserID,
 		&result.AssessmentID,
 		&result.RiskPercentage,
 		&riskFactorsJSON,
 		&recommendationsJSON,
-		&createdAt,
+		&result.Source,
+		&result.ModelVersion,
+		&result.RuleScore,
+		&result.RuleModelVersion,
+		&contributionsJSON,
+		&result.CreatedAt,
 	)
-
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("no assessment result found for this user")
-		}
 		return nil, err
 	}
 
-	result.CreatedAt = createdAt
-
 	// Parse JSON data untuk risk factors
 	if err := json.Unmarshal(riskFactorsJSON, &result.RiskFactors); err != nil {
 		log.Printf("Error unmarshaling risk factors: %v", err)
@@ -227,28 +244,95 @@ func (r *AssessmentRepository) GetLatestAssessmentResult(userID int) (*models.Ri
 		result.Recommendations = []string{"Error parsing recommendations"}
 	}
 
+	result.Contributions = parseRiskContributions(contributionsJSON)
+
 	return &result, nil
 }
 
-// GetAssessmentHistory retrieves the assessment history for a user
-func (r *AssessmentRepository) GetAssessmentHistory(userID int) ([]models.AssessmentResponse, error) {
+// GetLatestAssessmentResult retrieves the latest assessment result for a user
+func (r *AssessmentRepository) GetLatestAssessmentResult(userID int) (*models.RiskAssessmentResult, error) {
+	results, err := r.GetLatestAssessmentResults(userID, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrAssessmentResultNotFound
+	}
+
+	return &results[0], nil
+}
+
+// GetLatestAssessmentResults retrieves up to limit of the user's most recent assessment results, newest first
+func (r *AssessmentRepository) GetLatestAssessmentResults(userID int, limit int) ([]models.RiskAssessmentResult, error) {
+	query := `SELECT ` + riskAssessmentResultColumns + `
+	FROM risk_assessment_results
+	WHERE user_id = $1
+	ORDER BY created_at DESC, id DESC
+	LIMIT $2
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.RiskAssessmentResult
+
+	for rows.Next() {
+		result, err := scanRiskAssessmentResult(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// GetAssessmentHistory retrieves a user's assessment history, filtered by date range and by
+// risk level ("low", "moderate" or "high"). It returns the cursor of the next page when opts has a limit.
+func (r *AssessmentRepository) GetAssessmentHistory(userID int, opts models.ListOptions) ([]models.AssessmentResponse, string, error) {
+	filter := &listFilter{}
+	filter.where("a.user_id = ?", userID)
+	switch opts.Type {
+	case "":
+	case "low":
+		filter.where("EXISTS (SELECT 1 FROM risk_assessment_results x WHERE x.assessment_id = a.id AND x.risk_percentage < 30)")
+	case "moderate":
+		filter.where("EXISTS (SELECT 1 FROM risk_assessment_results x WHERE x.assessment_id = a.id AND x.risk_percentage >= 30 AND x.risk_percentage < 70)")
+	case "high":
+		filter.where("EXISTS (SELECT 1 FROM risk_assessment_results x WHERE x.assessment_id = a.id AND x.risk_percentage >= 70)")
+	default:
+		return nil, "", ErrInvalidListFilter
+	}
+
+	direction := "DESC"
+	if opts.Ascending {
+		direction = "ASC"
+	}
+
 	query := `
 	WITH assessments AS (
 		SELECT 
 			a.id, 
 			a.user_id, 
-			a.screen_time_hours, 
-			a.exercise_hours, 
-			a.late_night_frequency, 
-			a.diet_quality, 
+			COALESCE(a.screen_time_hours, 0) AS screen_time_hours, 
+			COALESCE(a.exercise_hours, 0) AS exercise_hours, 
+			COALESCE(a.late_night_frequency, 0) AS late_night_frequency, 
+			COALESCE(a.diet_quality, 0) AS diet_quality, 
+			a.questionnaire_version,
+			a.answers,
+			COALESCE(a.weight_kg, 0) AS weight_kg,
 			a.created_at, 
 			a.updated_at
 		FROM 
 			user_assessments a
-		WHERE 
-			a.user_id = $1
-		ORDER BY 
-			a.created_at DESC
+		` + filter.page(opts, "a.created_at", "a.id") + `
 	)
 	SELECT 
 		a.id, 
@@ -257,6 +341,9 @@ func (r *AssessmentRepository) GetAssessmentHistory(userID int) ([]models.Assess
 		a.exercise_hours, 
 		a.late_night_frequency, 
 		a.diet_quality, 
+		a.questionnaire_version,
+		a.answers,
+		a.weight_kg,
 		a.created_at, 
 		a.updated_at,
 		r.id,
@@ -264,18 +351,23 @@ func (r *AssessmentRepository) GetAssessmentHistory(userID int) ([]models.Assess
 		r.risk_percentage,
 		r.risk_factors,
 		r.recommendations,
+		COALESCE(r.source, ''),
+		COALESCE(r.model_version, ''),
+		r.rule_score,
+		COALESCE(r.rule_model_version, ''),
+		r.contributions,
 		r.created_at
 	FROM 
 		assessments a
 	LEFT JOIN 
 		risk_assessment_results r ON a.id = r.assessment_id
 	ORDER BY 
-		a.created_at DESC
+		a.created_at ` + direction + `, a.id ` + direction + `
 	`
 
-	rows, err := config.DBPool.Query(context.Background(), query, userID)
+	rows, err := config.DBPool.Query(context.Background(), query, filter.args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -288,7 +380,7 @@ func (r *AssessmentRepository) GetAssessmentHistory(userID int) ([]models.Assess
 		var assessmentCreatedAt, assessmentUpdatedAt, resultCreatedAt pgtype.Timestamp
 		var resultID, assessmentID pgtype.Int4
 		var riskPercentage pgtype.Int4
-		var riskFactorsJSON, recommendationsJSON []byte
+		var answersJSON, riskFactorsJSON, recommendationsJSON, contributionsJSON []byte
 
 		err := rows.Scan(
 			&assessment.ID,
@@ -297,6 +389,9 @@ func (r *AssessmentRepository) GetAssessmentHistory(userID int) ([]models.Assess
 			&assessment.ExerciseHours,
 			&assessment.LateNightFrequency,
 			&assessment.DietQuality,
+			&assessment.QuestionnaireVersion,
+			&answersJSON,
+			&assessment.WeightKG,
 			&assessmentCreatedAt,
 			&assessmentUpdatedAt,
 			&resultID,
@@ -304,10 +399,15 @@ func (r *AssessmentRepository) GetAssessmentHistory(userID int) ([]models.Assess
 			&riskPercentage,
 			&riskFactorsJSON,
 			&recommendationsJSON,
+			&result.Source,
+			&result.ModelVersion,
+			&result.RuleScore,
+			&result.RuleModelVersion,
+			&contributionsJSON,
 			&resultCreatedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		if assessmentCreatedAt.Valid {
@@ -316,6 +416,7 @@ func (r *AssessmentRepository) GetAssessmentHistory(userID int) ([]models.Assess
 		if assessmentUpdatedAt.Valid {
 			assessment.UpdatedAt = assessmentUpdatedAt.Time
 		}
+		assessment.Answers = parseAnswers(answersJSON)
 
 		resp.Assessment = assessment
 
@@ -341,6 +442,7 @@ func (r *AssessmentRepository) GetAssessmentHistory(userID int) ([]models.Assess
 				recommendations = []string{"Error parsing recommendations"}
 			}
 			result.Recommendations = recommendations
+			result.Contributions = parseRiskContributions(contributionsJSON)
 
 			if resultCreatedAt.Valid {
 				result.CreatedAt = resultCreatedAt.Time
@@ -353,8 +455,40 @@ func (r *AssessmentRepository) GetAssessmentHistory(userID int) ([]models.Assess
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return results, nil
+	results, nextCursor := trimPage(results, opts, func(a models.AssessmentResponse) models.Cursor {
+		return models.Cursor{Time: a.Assessment.CreatedAt, ID: a.Assessment.ID}
+	})
+
+	return results, nextCursor, nil
+}
+
+// parseRiskContributions decodes the stored rule engine contributions, which are NULL
+// for results stored before they were recorded
+func parseRiskContributions(data []byte) []models.RiskContribution {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var contributions []models.RiskContribution
+	if err := json.Unmarshal(data, &contributions); err != nil {
+		log.Printf("Error unmarshaling risk contributions: %v", err)
+		return nil
+	}
+	return contributions
+}
+
+// parseAnswers decodes the stored answers of an assessment by question key
+func parseAnswers(data []byte) map[string]int {
+	answers := map[string]int{}
+	if len(data) == 0 {
+		return answers
+	}
+
+	if err := json.Unmarshal(data, &answers); err != nil {
+		log.Printf("Error unmarshaling assessment answers: %v", err)
+	}
+	return answers
 }
@@ -88,7 +88,7 @@ func (r *ChatbotRepository) GetConversation(conversationID int, userID int) (*mo
 	conversation.UpdatedAt = updatedAt
 
 	// Get messages for the conversation
-	messages, err := r.GetMessagesByConversation(conversationID)
+	messages, _, err := r.GetMessagesByConversation(conversationID, models.ListOptions{Ascending: true})
 	if err == nil {
 		conversation.Messages = messages
 	}
@@ -96,18 +96,29 @@ func (r *ChatbotRepository) GetConversation(conversationID int, userID int) (*mo
 	return &conversation, nil
 }
 
-// GetUserConversations retrieves all conversations for a user
-func (r *ChatbotRepository) GetUserConversations(userID int) ([]models.ChatbotConversation, error) {
+// GetUserConversations retrieves a user's conversations by last update, filtered by date range
+// and by type ("active" or "inactive"). It returns the cursor of the next page when opts has a limit.
+func (r *ChatbotRepository) GetUserConversations(userID int, opts models.ListOptions) ([]models.ChatbotConversation, string, error) {
+	filter := &listFilter{}
+	filter.where("user_id = ?", userID)
+	switch opts.Type {
+	case "":
+	case "active":
+		filter.where("is_active = TRUE")
+	case "inactive":
+		filter.where("is_active = FALSE")
+	default:
+		return nil, "", ErrInvalidListFilter
+	}
+
 	query := `
     SELECT id, user_id, title, created_at, updated_at, is_active
     FROM chatbot_conversations
-    WHERE user_id = $1
-    ORDER BY updated_at DESC
-    `
+    ` + filter.page(opts, "updated_at", "id")
 
-	rows, err := config.DBPool.Query(context.Background(), query, userID)
+	rows, err := config.DBPool.Query(context.Background(), query, filter.args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -126,7 +137,7 @@ func (r *ChatbotRepository) GetUserConversations(userID int) ([]models.ChatbotCo
 			&conversation.IsActive,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		conversation.CreatedAt = createdAt
@@ -136,10 +147,14 @@ func (r *ChatbotRepository) GetUserConversations(userID int) ([]models.ChatbotCo
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return conversations, nil
+	conversations, nextCursor := trimPage(conversations, opts, func(c models.ChatbotConversation) models.Cursor {
+		return models.Cursor{Time: c.UpdatedAt, ID: c.ID}
+	})
+
+	return conversations, nextCursor, nil
 }
 
 // AddMessage adds a message to a conversation
@@ -228,18 +243,27 @@ func (r *ChatbotRepository) AddMessage(userID int, conversationID int, content s
 	return message, nil
 }
 
-// GetMessagesByConversation gets all messages for a conversation
-func (r *ChatbotRepository) GetMessagesByConversation(conversationID int) ([]models.ChatMessage, error) {
+// GetMessagesByConversation gets the messages of a conversation, filtered by date range and
+// sender type ("user" or "bot"). It returns the cursor of the next page when opts has a limit.
+func (r *ChatbotRepository) GetMessagesByConversation(conversationID int, opts models.ListOptions) ([]models.ChatMessage, string, error) {
+	filter := &listFilter{}
+	filter.where("conversation_id = ?", conversationID)
+	switch opts.Type {
+	case "":
+	case "user", "bot":
+		filter.where("sender_type = ?", opts.Type)
+	default:
+		return nil, "", ErrInvalidListFilter
+	}
+
 	query := `
     SELECT id, conversation_id, user_id, content, sender_type, created_at, metadata
     FROM chat_messages
-    WHERE conversation_id = $1
-    ORDER BY created_at ASC
-    `
+    ` + filter.page(opts, "created_at", "id")
 
-	rows, err := config.DBPool.Query(context.Background(), query, conversationID)
+	rows, err := config.DBPool.Query(context.Background(), query, filter.args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -261,7 +285,7 @@ func (r *ChatbotRepository) GetMessagesByConversation(conversationID int) ([]mod
 			&metadataNull,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		message.CreatedAt = createdAt
@@ -280,10 +304,14 @@ func (r *ChatbotRepository) GetMessagesByConversation(conversationID int) ([]mod
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return messages, nil
+	messages, nextCursor := trimPage(messages, opts, func(m models.ChatMessage) models.Cursor {
+		return models.Cursor{Time: m.CreatedAt, ID: m.ID}
+	})
+
+	return messages, nextCursor, nil
 }
 
 // DeleteConversation removes a conversation
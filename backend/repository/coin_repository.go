@@ -4,14 +4,23 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/habdil/sigap-app/backend/config"
 	"github.com/habdil/sigap-app/backend/models"
 )
 
+var (
+	// ErrInsufficientCoins is returned when a user's balance cannot cover a debit
+	ErrInsufficientCoins = errors.New("insufficient coins")
+	// ErrInvalidCoinSource is returned for credits without a recognised source reference
+	ErrInvalidCoinSource = errors.New("coin credit has no valid source reference")
+)
+
 // CoinRepository handles database operations for user coins
 type CoinRepository struct{}
 
@@ -66,8 +75,18 @@ func (r *CoinRepository) GetUserCoins(userID int) (*models.UserCoins, error) {
 	return &coins, nil
 }
 
-// AddCoins adds coins to a user's balance and records the transaction
-func (r *CoinRepository) AddCoins(userID int, amount int, transactionType string, referenceID int, referenceType string) error {
+// creditSources are the reference types a coin credit may point at. Credits only come
+// from server-side rules or admin grants, never from a client supplied amount.
+var creditSources = map[string]bool{
+	"activity_logs":  true,
+	"coin_grants":    true,
+	"streak_bonuses": true,
+}
+
+// AddCoins adds coins to a user's balance and records the transaction.
+// The credit must reference the record that earned it, e.g. an activity log.
+// A credit repeated with the same idempotency key is applied only once.
+func (r *CoinRepository) AddCoins(userID int, amount int, transactionType string, referenceID int, referenceType string, idempotencyKey string) error {
 	// Start a transaction
 	tx, err := config.DBPool.Begin(context.Background())
 	if err != nil {
@@ -75,114 +94,157 @@ func (r *CoinRepository) AddCoins(userID int, amount int, transactionType string
 	}
 	defer tx.Rollback(context.Background())
 
-	// Try to ensure user has a coin record
-	ensureQuery := `
-	INSERT INTO user_coins (user_id, total_coins)
-	VALUES ($1, 0)
-	ON CONFLICT (user_id) DO NOTHING
-	`
-	_, err = tx.Exec(context.Background(), ensureQuery, userID)
-	if err != nil {
+	if err := creditCoins(context.Background(), tx, userID, amount, transactionType, referenceID, referenceType, idempotencyKey); err != nil {
 		return err
 	}
 
-	// Update user's coin balance
-	updateQuery := `
-	UPDATE user_coins
-	SET total_coins = total_coins + $1, updated_at = NOW()
-	WHERE user_id = $2
+	// Commit the transaction
+	return tx.Commit(context.Background())
+}
+
+// GrantCoins records an admin grant and credits the user in one transaction
+func (r *CoinRepository) GrantCoins(adminID int, userID int, amount int, reason string) (*models.CoinGrant, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	grant := &models.CoinGrant{
+		UserID:    userID,
+		GrantedBy: adminID,
+		Amount:    amount,
+		Reason:    reason,
+	}
+
+	grantQuery := `
+	INSERT INTO coin_grants (user_id, granted_by, amount, reason)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at
 	`
-	_, err = tx.Exec(context.Background(), updateQuery, amount, userID)
+	err = tx.QueryRow(ctx, grantQuery, userID, adminID, amount, reason).Scan(&grant.ID, &grant.CreatedAt)
 	if err != nil {
+		return nil, err
+	}
+
+	if err := creditCoins(ctx, tx, userID, amount, "AdminGrant", grant.ID, "coin_grants", fmt.Sprintf("coin_grants:%d", grant.ID)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+// creditCoins records the transaction and updates the balance inside tx
+func creditCoins(ctx context.Context, tx pgx.Tx, userID int, amount int, transactionType string, referenceID int, referenceType string, idempotencyKey string) error {
+	if amount <= 0 {
+		return errors.New("credit amount must be positive")
+	}
+	if referenceID <= 0 || !creditSources[referenceType] {
+		return ErrInvalidCoinSource
+	}
+
+	applied, err := insertCoinTransaction(ctx, tx, userID, amount, transactionType, referenceID, referenceType, idempotencyKey)
+	if err != nil || !applied {
 		return err
 	}
 
-	// Record the transaction
-	transactionQuery := `
-	INSERT INTO coin_transactions (user_id, amount, transaction_type, reference_id, reference_type, created_at)
-	VALUES ($1, $2, $3, $4, $5, NOW())
+	// Update user's coin balance, creating the record on first credit
+	updateQuery := `
+	INSERT INTO user_coins (user_id, total_coins, updated_at)
+	VALUES ($1, $2, NOW())
+	ON CONFLICT (user_id) DO UPDATE
+	SET total_coins = user_coins.total_coins + EXCLUDED.total_coins, updated_at = NOW()
 	`
-	_, err = tx.Exec(
-		context.Background(),
-		transactionQuery,
-		userID,
-		amount,
-		transactionType,
-		referenceID,
-		referenceType,
-	)
+	_, err = tx.Exec(ctx, updateQuery, userID, amount)
+	return err
+}
+
+// insertCoinTransaction records a ledger entry. It reports false without error when an
+// entry with the same idempotency key already exists, meaning the change was already applied.
+func insertCoinTransaction(ctx context.Context, tx pgx.Tx, userID int, amount int, transactionType string, referenceID int, referenceType string, idempotencyKey string) (bool, error) {
+	query := `
+	INSERT INTO coin_transactions (user_id, amount, transaction_type, reference_id, reference_type, idempotency_key, created_at)
+	VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, ''), NULLIF($6, ''), NOW())
+	ON CONFLICT (user_id, idempotency_key) DO NOTHING
+	`
+
+	tag, err := tx.Exec(ctx, query, userID, amount, transactionType, referenceID, referenceType, idempotencyKey)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	// Commit the transaction
-	return tx.Commit(context.Background())
+	return tag.RowsAffected() > 0, nil
 }
 
-// SpendCoins deducts coins from a user's balance and records the transaction
-func (r *CoinRepository) SpendCoins(userID int, amount int, transactionType string, referenceID int, referenceType string) error {
-	// First check if user has enough coins
-	coins, err := r.GetUserCoins(userID)
+// SpendCoins deducts coins from a user's balance and records the transaction.
+// A spend repeated with the same idempotency key is applied only once.
+func (r *CoinRepository) SpendCoins(userID int, amount int, transactionType string, referenceID int, referenceType string, idempotencyKey string) error {
+	ctx := context.Background()
+
+	// Start a transaction
+	tx, err := config.DBPool.Begin(ctx)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback(ctx)
 
-	if coins.TotalCoins < amount {
-		return errors.New("insufficient coins")
+	if err := debitCoins(ctx, tx, userID, amount, transactionType, referenceID, referenceType, idempotencyKey); err != nil {
+		return err
 	}
 
-	// Start a transaction
-	tx, err := config.DBPool.Begin(context.Background())
-	if err != nil {
+	// Commit the transaction
+	return tx.Commit(ctx)
+}
+
+// debitCoins records the transaction and deducts the balance inside tx
+func debitCoins(ctx context.Context, tx pgx.Tx, userID int, amount int, transactionType string, referenceID int, referenceType string, idempotencyKey string) error {
+	// Record the transaction (negative amount for spending)
+	applied, err := insertCoinTransaction(ctx, tx, userID, -amount, transactionType, referenceID, referenceType, idempotencyKey)
+	if err != nil || !applied {
 		return err
 	}
-	defer tx.Rollback(context.Background())
 
-	// Update user's coin balance
+	// Deduct only if the balance covers the amount. The check and the update are one
+	// statement under the row lock, so concurrent spends cannot overdraw the balance.
 	updateQuery := `
 	UPDATE user_coins
 	SET total_coins = total_coins - $1, updated_at = NOW()
-	WHERE user_id = $2
+	WHERE user_id = $2 AND total_coins >= $1
 	`
-	_, err = tx.Exec(context.Background(), updateQuery, amount, userID)
+	tag, err := tx.Exec(ctx, updateQuery, amount, userID)
 	if err != nil {
 		return err
 	}
-
-	// Record the transaction (negative amount for spending)
-	transactionQuery := `
-	INSERT INTO coin_transactions (user_id, amount, transaction_type, reference_id, reference_type, created_at)
-	VALUES ($1, $2, $3, $4, $5, NOW())
-	`
-	_, err = tx.Exec(
-		context.Background(),
-		transactionQuery,
-		userID,
-		-amount, // Negative amount for spending
-		transactionType,
-		referenceID,
-		referenceType,
-	)
-	if err != nil {
-		return err
+	if tag.RowsAffected() == 0 {
+		return ErrInsufficientCoins
 	}
 
-	// Commit the transaction
-	return tx.Commit(context.Background())
+	return nil
 }
 
-// GetTransactionHistory retrieves a user's coin transaction history
-func (r *CoinRepository) GetTransactionHistory(userID int) ([]models.CoinTransaction, error) {
+// GetTransactionHistory retrieves a user's coin transactions, filtered by date range and
+// transaction type. It returns the cursor of the next page when opts has a limit.
+func (r *CoinRepository) GetTransactionHistory(userID int, opts models.ListOptions) ([]models.CoinTransaction, string, error) {
+	filter := &listFilter{}
+	filter.where("user_id = ?", userID)
+	if opts.Type != "" {
+		filter.where("transaction_type = ?", opts.Type)
+	}
+
 	query := `
 	SELECT id, user_id, amount, transaction_type, reference_id, reference_type, created_at
 	FROM coin_transactions
-	WHERE user_id = $1
-	ORDER BY created_at DESC
-	`
+	` + filter.page(opts, "created_at", "id")
 
-	rows, err := config.DBPool.Query(context.Background(), query, userID)
+	rows, err := config.DBPool.Query(context.Background(), query, filter.args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -191,7 +253,8 @@ func (r *CoinRepository) GetTransactionHistory(userID int) ([]models.CoinTransac
 	for rows.Next() {
 		var tx models.CoinTransaction
 		var createdAt time.Time
-		var referenceIDNull, referenceTypeNull interface{}
+		var referenceIDNull pgtype.Int4
+		var referenceTypeNull pgtype.Text
 
 		err := rows.Scan(
 			&tx.ID,
@@ -203,16 +266,16 @@ func (r *CoinRepository) GetTransactionHistory(userID int) ([]models.CoinTransac
 			&createdAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		// Handle nullable fields
-		if refID, ok := referenceIDNull.(int); ok {
-			tx.ReferenceID = refID
+		if referenceIDNull.Valid {
+			tx.ReferenceID = int(referenceIDNull.Int32)
 		}
 
-		if refType, ok := referenceTypeNull.(string); ok {
-			tx.ReferenceType = refType
+		if referenceTypeNull.Valid {
+			tx.ReferenceType = referenceTypeNull.String
 		}
 
 		tx.CreatedAt = createdAt
@@ -220,8 +283,87 @@ func (r *CoinRepository) GetTransactionHistory(userID int) ([]models.CoinTransac
 	}
 
 	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	transactions, nextCursor := trimPage(transactions, opts, func(t models.CoinTransaction) models.Cursor {
+		return models.Cursor{Time: t.CreatedAt, ID: t.ID}
+	})
+
+	return transactions, nextCursor, nil
+}
+
+// FindBalanceDrift lists users whose user_coins balance differs from the sum of their transactions
+func (r *CoinRepository) FindBalanceDrift() ([]models.CoinBalanceDrift, error) {
+	query := `
+	WITH ledger AS (
+		SELECT user_id, SUM(amount) AS total
+		FROM coin_transactions
+		GROUP BY user_id
+	)
+	SELECT COALESCE(uc.user_id, l.user_id), COALESCE(uc.total_coins, 0), COALESCE(l.total, 0)::INTEGER
+	FROM user_coins uc
+	FULL OUTER JOIN ledger l ON l.user_id = uc.user_id
+	WHERE COALESCE(uc.total_coins, 0) <> COALESCE(l.total, 0)
+	ORDER BY 1
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	var drifts []models.CoinBalanceDrift
+
+	for rows.Next() {
+		var drift models.CoinBalanceDrift
+		if err := rows.Scan(&drift.UserID, &drift.StoredBalance, &drift.LedgerBalance); err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, drift)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return drifts, nil
+}
+
+// ReconcileBalance resets a user's balance to the sum of their transactions and returns it
+func (r *CoinRepository) ReconcileBalance(userID int) (int, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO user_coins (user_id, total_coins) VALUES ($1, 0) ON CONFLICT (user_id) DO NOTHING`, userID); err != nil {
+		return 0, err
+	}
+
+	// Lock the balance first so in-flight credits and spends finish before the ledger is summed
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM user_coins WHERE user_id = $1 FOR UPDATE`, userID); err != nil {
+		return 0, err
+	}
+
+	var balance int
+	sumQuery := `SELECT COALESCE(SUM(amount), 0)::INTEGER FROM coin_transactions WHERE user_id = $1`
+	if err := tx.QueryRow(ctx, sumQuery, userID).Scan(&balance); err != nil {
+		return 0, err
+	}
+
+	updateQuery := `UPDATE user_coins SET total_coins = $1, updated_at = NOW() WHERE user_id = $2`
+	if _, err := tx.Exec(ctx, updateQuery, balance, userID); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
 
-	return transactions, nil
+	return balance, nil
 }
@@ -13,6 +13,13 @@ import (
 	"github.com/habdil/sigap-app/backend/models"
 )
 
+var (
+	// ErrFoodLogNotFound is returned for food logs that do not exist
+	ErrFoodLogNotFound = errors.New("food log not found")
+	// ErrFoodItemNotFound is returned for analysis items that do not exist
+	ErrFoodItemNotFound = errors.New("food item not found")
+)
+
 // FoodRepository handles database operations for food logs
 type FoodRepository struct{}
 
@@ -23,34 +30,68 @@ func NewFoodRepository() *FoodRepository {
 
 // CreateFoodLog creates a new food log entry
 func (r *FoodRepository) CreateFoodLog(userID int, req *models.FoodLogRequest) (*models.FoodLog, error) {
+	return r.CreateFoodLogWithAnalysis(userID, req, nil)
+}
+
+// CreateFoodLogWithAnalysis creates a food log together with its analysis, such as one
+// computed from a reference food, so a log is never left without the analysis it was
+// created with. A nil analysis creates the log alone.
+func (r *FoodRepository) CreateFoodLogWithAnalysis(userID int, req *models.FoodLogRequest, analysis *models.FoodAnalysis) (*models.FoodLog, error) {
 	query := `
-	INSERT INTO food_logs (user_id, food_name, notes, photo_url)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO food_logs (user_id, food_name, notes, photo_url, photo_key, nutrition_food_id, food_product_id, serving_grams)
+	VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6::integer, 0), NULLIF($7::integer, 0), NULLIF($8::double precision, 0))
 	RETURNING id, log_date
 	`
 
 	foodLog := &models.FoodLog{
-		UserID:   userID,
-		FoodName: req.FoodName,
-		Notes:    req.Notes,
-		PhotoURL: req.PhotoURL,
+		UserID:          userID,
+		FoodName:        req.FoodName,
+		Notes:           req.Notes,
+		PhotoURL:        req.PhotoURL,
+		PhotoKey:        req.PhotoKey,
+		NutritionFoodID: req.NutritionFoodID,
+		FoodProductID:   req.FoodProductID,
+		ServingGrams:    req.ServingGrams,
 	}
 
 	var logDate time.Time
 
-	err := config.DBPool.QueryRow(
-		context.Background(),
+	ctx := context.Background()
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(
+		ctx,
 		query,
 		userID,
 		req.FoodName,
 		req.Notes,
 		req.PhotoURL,
+		req.PhotoKey,
+		req.NutritionFoodID,
+		req.FoodProductID,
+		req.ServingGrams,
 	).Scan(&foodLog.ID, &logDate)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if analysis != nil {
+		analysis.FoodLogID = foodLog.ID
+		if err := r.saveFoodAnalysis(ctx, tx, analysis); err != nil {
+			return nil, err
+		}
+		foodLog.Analysis = analysis
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
 	foodLog.LogDate = logDate
 	return foodLog, nil
 }
@@ -58,13 +99,15 @@ func (r *FoodRepository) CreateFoodLog(userID int, req *models.FoodLogRequest) (
 // GetFoodLogByID retrieves a specific food log
 func (r *FoodRepository) GetFoodLogByID(logID int) (*models.FoodLog, error) {
 	query := `
-	SELECT id, user_id, food_name, log_date, photo_url, notes
+	SELECT id, user_id, food_name, log_date, photo_url, photo_key, notes, nutrition_food_id, food_product_id, serving_grams
 	FROM food_logs
 	WHERE id = $1
 	`
 
 	var foodLog models.FoodLog
-	var foodNameNull, photoURLNull, notesNull pgtype.Text
+	var foodNameNull, photoURLNull, photoKeyNull, notesNull pgtype.Text
+	var nutritionFoodIDNull, foodProductIDNull pgtype.Int4
+	var servingGramsNull pgtype.Float8
 	var logDate time.Time
 
 	err := config.DBPool.QueryRow(context.Background(), query, logID).Scan(
@@ -73,12 +116,16 @@ func (r *FoodRepository) GetFoodLogByID(logID int) (*models.FoodLog, error) {
 		&foodNameNull,
 		&logDate,
 		&photoURLNull,
+		&photoKeyNull,
 		&notesNull,
+		&nutritionFoodIDNull,
+		&foodProductIDNull,
+		&servingGramsNull,
 	)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("food log not found")
+			return nil, ErrFoodLogNotFound
 		}
 		return nil, err
 	}
@@ -91,9 +138,21 @@ func (r *FoodRepository) GetFoodLogByID(logID int) (*models.FoodLog, error) {
 	if photoURLNull.Valid {
 		foodLog.PhotoURL = photoURLNull.String
 	}
+	if photoKeyNull.Valid {
+		foodLog.PhotoKey = photoKeyNull.String
+	}
 	if notesNull.Valid {
 		foodLog.Notes = notesNull.String
 	}
+	if nutritionFoodIDNull.Valid {
+		foodLog.NutritionFoodID = int(nutritionFoodIDNull.Int32)
+	}
+	if foodProductIDNull.Valid {
+		foodLog.FoodProductID = int(foodProductIDNull.Int32)
+	}
+	if servingGramsNull.Valid {
+		foodLog.ServingGrams = servingGramsNull.Float64
+	}
 
 	// Try to get food analysis if it exists
 	analysis, err := r.GetFoodAnalysisByLogID(logID)
@@ -104,21 +163,55 @@ func (r *FoodRepository) GetFoodLogByID(logID int) (*models.FoodLog, error) {
 	return &foodLog, nil
 }
 
-// GetUserFoodLogs retrieves all food logs for a user
-func (r *FoodRepository) GetUserFoodLogs(userID int) ([]models.FoodLog, error) {
+// GetUserPhotoKeys returns the storage keys of every photo the user uploaded
+func (r *FoodRepository) GetUserPhotoKeys(userID int) ([]string, error) {
+	query := `SELECT photo_key FROM food_logs WHERE user_id = $1 AND photo_key IS NOT NULL ORDER BY id`
+
+	rows, err := config.DBPool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// GetUserFoodLogs retrieves a user's food logs, filtered by date range and by type
+// ("analyzed" or "unanalyzed"). It returns the cursor of the next page when opts has a limit.
+func (r *FoodRepository) GetUserFoodLogs(userID int, opts models.ListOptions) ([]models.FoodLog, string, error) {
+	filter := &listFilter{}
+	filter.where("f.user_id = ?", userID)
+	switch opts.Type {
+	case "":
+	case "analyzed":
+		filter.where("a.id IS NOT NULL")
+	case "unanalyzed":
+		filter.where("a.id IS NULL")
+	default:
+		return nil, "", ErrInvalidListFilter
+	}
+
 	query := `
-	SELECT f.id, f.user_id, f.food_name, f.log_date, f.photo_url, f.notes,
-	       a.id, a.protein_grams, a.carbs_grams, a.fat_grams, a.fiber_grams, 
-	       a.calories, a.detected_items, a.healthiness_score, a.ai_confidence, a.analyzed_at
+	SELECT f.id, f.user_id, f.food_name, f.log_date, f.photo_url, f.photo_key, f.notes,
+	       f.nutrition_food_id, f.food_product_id, f.serving_grams,
+	       a.id, a.protein_grams, a.carbs_grams, a.fat_grams, a.fiber_grams, a.sugar_grams, a.sodium_mg,
+	       a.saturated_fat_grams, a.cholesterol_mg, a.calories, a.detected_items, a.healthiness_score, a.ai_confidence, a.analyzed_at
 	FROM food_logs f
 	LEFT JOIN food_analysis a ON f.id = a.food_log_id
-	WHERE f.user_id = $1
-	ORDER BY f.log_date DESC
-	`
+	` + filter.page(opts, "f.log_date", "f.id")
 
-	rows, err := config.DBPool.Query(context.Background(), query, userID)
+	rows, err := config.DBPool.Query(context.Background(), query, filter.args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -128,8 +221,9 @@ func (r *FoodRepository) GetUserFoodLogs(userID int) ([]models.FoodLog, error) {
 		var log models.FoodLog
 		var analysis models.FoodAnalysis
 		var logDate, analyzedAt pgtype.Timestamp
-		var foodNameNull, photoURLNull, notesNull pgtype.Text
-		var analysisIDNull pgtype.Int4
+		var foodNameNull, photoURLNull, photoKeyNull, notesNull pgtype.Text
+		var nutritionFoodIDNull, foodProductIDNull, analysisIDNull pgtype.Int4
+		var servingGramsNull pgtype.Float8
 		var proteinGramsNull, carbsGramsNull, fatGramsNull, fiberGramsNull, aiConfidenceNull pgtype.Float8
 		var caloriesNull, healthinessScoreNull pgtype.Int4
 		var detectedItemsNull []byte
@@ -140,12 +234,20 @@ func (r *FoodRepository) GetUserFoodLogs(userID int) ([]models.FoodLog, error) {
 			&foodNameNull,
 			&logDate,
 			&photoURLNull,
+			&photoKeyNull,
 			&notesNull,
+			&nutritionFoodIDNull,
+			&foodProductIDNull,
+			&servingGramsNull,
 			&analysisIDNull,
 			&proteinGramsNull,
 			&carbsGramsNull,
 			&fatGramsNull,
 			&fiberGramsNull,
+			&analysis.SugarGrams,
+			&analysis.SodiumMg,
+			&analysis.SaturatedFatGrams,
+			&analysis.CholesterolMg,
 			&caloriesNull,
 			&detectedItemsNull,
 			&healthinessScoreNull,
@@ -153,7 +255,7 @@ func (r *FoodRepository) GetUserFoodLogs(userID int) ([]models.FoodLog, error) {
 			&analyzedAt,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
 		if logDate.Valid {
@@ -165,9 +267,21 @@ func (r *FoodRepository) GetUserFoodLogs(userID int) ([]models.FoodLog, error) {
 		if photoURLNull.Valid {
 			log.PhotoURL = photoURLNull.String
 		}
+		if photoKeyNull.Valid {
+			log.PhotoKey = photoKeyNull.String
+		}
 		if notesNull.Valid {
 			log.Notes = notesNull.String
 		}
+		if nutritionFoodIDNull.Valid {
+			log.NutritionFoodID = int(nutritionFoodIDNull.Int32)
+		}
+		if foodProductIDNull.Valid {
+			log.FoodProductID = int(foodProductIDNull.Int32)
+		}
+		if servingGramsNull.Valid {
+			log.ServingGrams = servingGramsNull.Float64
+		}
 
 		// repository/food_repository.go (lanjutan)
 		// If we have analysis data, include it
@@ -210,26 +324,72 @@ func (r *FoodRepository) GetUserFoodLogs(userID int) ([]models.FoodLog, error) {
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return foodLogs, nil
+	foodLogs, nextCursor := trimPage(foodLogs, opts, func(l models.FoodLog) models.Cursor {
+		return models.Cursor{Time: l.LogDate, ID: l.ID}
+	})
+
+	var analysisIDs []int
+	for _, log := range foodLogs {
+		if log.Analysis != nil {
+			analysisIDs = append(analysisIDs, log.Analysis.ID)
+		}
+	}
+	items, err := r.getFoodItems(analysisIDs)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, log := range foodLogs {
+		if log.Analysis != nil {
+			log.Analysis.Items = itemsOrEmpty(items[log.Analysis.ID])
+		}
+	}
+
+	return foodLogs, nextCursor, nil
 }
 
-// SaveFoodAnalysis saves a food analysis to the database
+// SaveFoodAnalysis saves a food analysis to the database, replacing a previous analysis
+// of the same food log together with its items
 func (r *FoodRepository) SaveFoodAnalysis(analysis *models.FoodAnalysis) (*models.FoodAnalysis, error) {
+	ctx := context.Background()
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := r.saveFoodAnalysis(ctx, tx, analysis); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return analysis, nil
+}
+
+// saveFoodAnalysis upserts the analysis and replaces its items within tx
+func (r *FoodRepository) saveFoodAnalysis(ctx context.Context, tx pgx.Tx, analysis *models.FoodAnalysis) error {
 	query := `
     INSERT INTO food_analysis (
-        food_log_id, protein_grams, carbs_grams, fat_grams, fiber_grams,
-        calories, detected_items, healthiness_score, ai_confidence, analyzed_at
+        food_log_id, protein_grams, carbs_grams, fat_grams, fiber_grams, sugar_grams,
+        sodium_mg, saturated_fat_grams, cholesterol_mg, calories, detected_items,
+        healthiness_score, ai_confidence, analyzed_at
     )
-    VALUES ($1, $2, $3, $4, $5, $6, $7::jsonb, $8, $9, $10)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11::jsonb, $12, $13, $14)
     ON CONFLICT (food_log_id) 
     DO UPDATE SET
         protein_grams = EXCLUDED.protein_grams,
         carbs_grams = EXCLUDED.carbs_grams,
         fat_grams = EXCLUDED.fat_grams,
         fiber_grams = EXCLUDED.fiber_grams,
+        sugar_grams = EXCLUDED.sugar_grams,
+        sodium_mg = EXCLUDED.sodium_mg,
+        saturated_fat_grams = EXCLUDED.saturated_fat_grams,
+        cholesterol_mg = EXCLUDED.cholesterol_mg,
         calories = EXCLUDED.calories,
         detected_items = EXCLUDED.detected_items,
         healthiness_score = EXCLUDED.healthiness_score,
@@ -247,14 +407,18 @@ func (r *FoodRepository) SaveFoodAnalysis(analysis *models.FoodAnalysis) (*model
 		detectedItemsJSON = "[]"
 	}
 
-	err := config.DBPool.QueryRow(
-		context.Background(),
+	err := tx.QueryRow(
+		ctx,
 		query,
 		analysis.FoodLogID,
 		analysis.ProteinGrams,
 		analysis.CarbsGrams,
 		analysis.FatGrams,
 		analysis.FiberGrams,
+		analysis.SugarGrams,
+		analysis.SodiumMg,
+		analysis.SaturatedFatGrams,
+		analysis.CholesterolMg,
 		analysis.Calories,
 		detectedItemsJSON, // Gunakan string JSON
 		analysis.HealthinessScore,
@@ -262,18 +426,210 @@ func (r *FoodRepository) SaveFoodAnalysis(analysis *models.FoodAnalysis) (*model
 		analysis.AnalyzedAt,
 	).Scan(&analysis.ID)
 
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM food_analysis_items WHERE food_analysis_id = $1`, analysis.ID); err != nil {
+		return err
+	}
+
+	// The values are also kept as the estimate that corrections scale from
+	itemQuery := `
+	INSERT INTO food_analysis_items (
+		food_analysis_id, position, name, portion_grams, calories, protein_grams, carbs_grams,
+		fat_grams, fiber_grams, sugar_grams, sodium_mg, saturated_fat_grams, cholesterol_mg, confidence,
+		estimated_portion_grams, estimated_calories, estimated_protein_grams, estimated_carbs_grams,
+		estimated_fat_grams, estimated_fiber_grams, estimated_sugar_grams, estimated_sodium_mg,
+		estimated_saturated_fat_grams, estimated_cholesterol_mg
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	RETURNING id
+	`
+	for i := range analysis.Items {
+		item := &analysis.Items[i]
+		item.FoodAnalysisID = analysis.ID
+		err := tx.QueryRow(ctx, itemQuery,
+			analysis.ID, i, item.Name, item.PortionGrams, item.Calories,
+			item.ProteinGrams, item.CarbsGrams, item.FatGrams, item.FiberGrams, item.SugarGrams,
+			item.SodiumMg, item.SaturatedFatGrams, item.CholesterolMg, item.Confidence,
+		).Scan(&item.ID)
+		if err != nil {
+			return err
+		}
+	}
+	analysis.Items = itemsOrEmpty(analysis.Items)
+
+	return nil
+}
+
+// CorrectFoodItem applies the user's correction to an item of an analysis and recomputes
+// the analysis totals from its items. A new portion scales the nutrients from the first
+// estimate, so repeated corrections do not compound the rounding; items estimated without
+// a portion only take the new one.
+func (r *FoodRepository) CorrectFoodItem(analysisID int, itemID int, correction *models.FoodItemCorrection) (*models.FoodItem, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback(ctx)
 
-	return analysis, nil
+	// Lock the analysis so concurrent corrections of its items sum up each other's changes
+	lockQuery := `SELECT id FROM food_analysis WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRow(ctx, lockQuery, analysisID).Scan(new(int)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFoodItemNotFound
+		}
+		return nil, err
+	}
+
+	updateQuery := `
+	UPDATE food_analysis_items
+	SET name = COALESCE($3, name),
+	    portion_grams = COALESCE($4, portion_grams),
+	    calories = ` + scaledFromEstimate("calories") + `,
+	    protein_grams = ` + scaledFromEstimate("protein_grams") + `,
+	    carbs_grams = ` + scaledFromEstimate("carbs_grams") + `,
+	    fat_grams = ` + scaledFromEstimate("fat_grams") + `,
+	    fiber_grams = ` + scaledFromEstimate("fiber_grams") + `,
+	    sugar_grams = ` + scaledFromEstimate("sugar_grams") + `,
+	    sodium_mg = ` + scaledFromEstimate("sodium_mg") + `,
+	    saturated_fat_grams = ` + scaledFromEstimate("saturated_fat_grams") + `,
+	    cholesterol_mg = ` + scaledFromEstimate("cholesterol_mg") + `,
+	    corrected_at = NOW()
+	WHERE id = $1 AND food_analysis_id = $2
+	RETURNING ` + foodItemColumns
+	item, err := scanFoodItem(tx.QueryRow(ctx, updateQuery, itemID, analysisID, correction.Name, correction.PortionGrams))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFoodItemNotFound
+		}
+		return nil, err
+	}
+
+	totalsQuery := `
+	UPDATE food_analysis a
+	SET calories = ROUND(t.calories),
+	    protein_grams = ROUND(t.protein_grams::numeric, 1),
+	    carbs_grams = ROUND(t.carbs_grams::numeric, 1),
+	    fat_grams = ROUND(t.fat_grams::numeric, 1),
+	    fiber_grams = ROUND(t.fiber_grams::numeric, 1),
+	    sugar_grams = ROUND(t.sugar_grams::numeric, 1),
+	    sodium_mg = ROUND(t.sodium_mg::numeric, 1),
+	    saturated_fat_grams = ROUND(t.saturated_fat_grams::numeric, 1),
+	    cholesterol_mg = ROUND(t.cholesterol_mg::numeric, 1),
+	    detected_items = t.names
+	FROM (
+		SELECT COALESCE(SUM(calories), 0) AS calories,
+		       COALESCE(SUM(protein_grams), 0) AS protein_grams,
+		       COALESCE(SUM(carbs_grams), 0) AS carbs_grams,
+		       COALESCE(SUM(fat_grams), 0) AS fat_grams,
+		       COALESCE(SUM(fiber_grams), 0) AS fiber_grams,
+		       SUM(sugar_grams) AS sugar_grams,
+		       SUM(sodium_mg) AS sodium_mg,
+		       SUM(saturated_fat_grams) AS saturated_fat_grams,
+		       SUM(cholesterol_mg) AS cholesterol_mg,
+		       COALESCE(jsonb_agg(name ORDER BY position), '[]'::jsonb) AS names
+		FROM food_analysis_items
+		WHERE food_analysis_id = $1
+	) t
+	WHERE a.id = $1
+	`
+	if _, err := tx.Exec(ctx, totalsQuery, item.FoodAnalysisID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// scaledFromEstimate is the value of a nutrient column after a correction: its first
+// estimate scaled to the new portion $4, rounded to a tenth, or the current value when
+// the portion is unchanged or was never estimated
+func scaledFromEstimate(column string) string {
+	return `CASE WHEN $4::double precision IS NOT NULL AND estimated_portion_grams > 0
+		THEN ROUND((estimated_` + column + ` * $4 / estimated_portion_grams)::numeric, 1)
+		ELSE ` + column + ` END`
+}
+
+const foodItemColumns = `id, food_analysis_id, name, portion_grams, calories, protein_grams,
+	carbs_grams, fat_grams, fiber_grams, sugar_grams, sodium_mg, saturated_fat_grams, cholesterol_mg,
+	confidence, corrected_at`
+
+// getFoodItems returns the items of the given analyses, by analysis ID in list order
+func (r *FoodRepository) getFoodItems(analysisIDs []int) (map[int][]models.FoodItem, error) {
+	items := map[int][]models.FoodItem{}
+	if len(analysisIDs) == 0 {
+		return items, nil
+	}
+
+	query := `SELECT ` + foodItemColumns + `
+	FROM food_analysis_items
+	WHERE food_analysis_id = ANY($1)
+	ORDER BY food_analysis_id, position`
+
+	rows, err := config.DBPool.Query(context.Background(), query, analysisIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanFoodItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items[item.FoodAnalysisID] = append(items[item.FoodAnalysisID], *item)
+	}
+
+	return items, rows.Err()
+}
+
+// scanFoodItem scans a row selected with foodItemColumns
+func scanFoodItem(row pgx.Row) (*models.FoodItem, error) {
+	var item models.FoodItem
+	err := row.Scan(
+		&item.ID,
+		&item.FoodAnalysisID,
+		&item.Name,
+		&item.PortionGrams,
+		&item.Calories,
+		&item.ProteinGrams,
+		&item.CarbsGrams,
+		&item.FatGrams,
+		&item.FiberGrams,
+		&item.SugarGrams,
+		&item.SodiumMg,
+		&item.SaturatedFatGrams,
+		&item.CholesterolMg,
+		&item.Confidence,
+		&item.CorrectedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// itemsOrEmpty keeps analyses from before per-item recognition rendering items as []
+func itemsOrEmpty(items []models.FoodItem) []models.FoodItem {
+	if items == nil {
+		return []models.FoodItem{}
+	}
+	return items
 }
 
 // GetFoodAnalysisByLogID retrieves the analysis for a specific food log
 func (r *FoodRepository) GetFoodAnalysisByLogID(logID int) (*models.FoodAnalysis, error) {
 	query := `
 	SELECT id, food_log_id, protein_grams, carbs_grams, fat_grams, fiber_grams,
-	       calories, detected_items, healthiness_score, ai_confidence, analyzed_at
+	       sugar_grams, sodium_mg, saturated_fat_grams, cholesterol_mg, calories, detected_items,
+	       healthiness_score, ai_confidence, analyzed_at
 	FROM food_analysis
 	WHERE food_log_id = $1
 	`
@@ -291,6 +647,10 @@ func (r *FoodRepository) GetFoodAnalysisByLogID(logID int) (*models.FoodAnalysis
 		&carbsGramsNull,
 		&fatGramsNull,
 		&fiberGramsNull,
+		&analysis.SugarGrams,
+		&analysis.SodiumMg,
+		&analysis.SaturatedFatGrams,
+		&analysis.CholesterolMg,
 		&caloriesNull,
 		&detectedItemsNull,
 		&healthinessScoreNull,
@@ -333,5 +693,55 @@ func (r *FoodRepository) GetFoodAnalysisByLogID(logID int) (*models.FoodAnalysis
 		analysis.AnalyzedAt = analyzedAt.Time
 	}
 
+	items, err := r.getFoodItems([]int{analysis.ID})
+	if err != nil {
+		return nil, err
+	}
+	analysis.Items = itemsOrEmpty(items[analysis.ID])
+
 	return &analysis, nil
 }
+
+// GetDailyNutrientTotals sums the nutrients with a daily limit over the user's analyzed
+// food logs between start (inclusive) and end (exclusive), by the day they fall in within
+// the given timezone. Days are keyed as YYYY-MM-DD.
+func (r *FoodRepository) GetDailyNutrientTotals(userID int, timezone string, start, end time.Time) (map[string]models.NutrientTotals, error) {
+	// log_date is stored in UTC
+	query := `
+	SELECT
+		to_char(f.log_date AT TIME ZONE 'UTC' AT TIME ZONE $2, 'YYYY-MM-DD') AS day,
+		COALESCE(SUM(a.sodium_mg), 0)::FLOAT8,
+		COALESCE(SUM(a.sugar_grams), 0)::FLOAT8,
+		COALESCE(SUM(a.saturated_fat_grams), 0)::FLOAT8,
+		COALESCE(SUM(a.cholesterol_mg), 0)::FLOAT8
+	FROM food_logs f
+	JOIN food_analysis a ON a.food_log_id = f.id
+	WHERE f.user_id = $1 AND f.log_date >= $3 AND f.log_date < $4
+	GROUP BY day
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query, userID, timezone, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := map[string]models.NutrientTotals{}
+	for rows.Next() {
+		var day string
+		var dayTotals models.NutrientTotals
+		err := rows.Scan(
+			&day,
+			&dayTotals.SodiumMg,
+			&dayTotals.SugarGrams,
+			&dayTotals.SaturatedFatGrams,
+			&dayTotals.CholesterolMg,
+		)
+		if err != nil {
+			return nil, err
+		}
+		totals[day] = dayTotals
+	}
+
+	return totals, rows.Err()
+}
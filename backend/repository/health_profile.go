@@ -40,7 +40,7 @@ func (r *HealthProfileRepository) UpdateUserProfile(userID int, profile *models.
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return errors.New("user not found")
+			return ErrUserNotFound
 		}
 		return err
 	}
@@ -75,7 +75,7 @@ func (r *HealthProfileRepository) GetUserProfile(userID int) (*models.HealthProf
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
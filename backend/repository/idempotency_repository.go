@@ -0,0 +1,100 @@
+// repository/idempotency_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// ErrIdempotencyKeyReleased is returned when the request holding a key failed and released
+// it while another request with the same key was reserving it
+var ErrIdempotencyKeyReleased = errors.New("idempotency key was released, please retry")
+
+// IdempotencyRepository handles database operations for idempotency keys
+type IdempotencyRepository struct{}
+
+// NewIdempotencyRepository creates a new IdempotencyRepository
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{}
+}
+
+// Reserve claims a key for a new request. It returns reserved=true when the caller should
+// process the request, otherwise the existing record for that key. Keys older than ttl
+// are treated as unused and claimed again.
+func (r *IdempotencyRepository) Reserve(userID int, key string, requestHash string, ttl time.Duration) (*models.IdempotencyRecord, bool, error) {
+	ctx := context.Background()
+	staleBefore := time.Now().UTC().Add(-ttl)
+
+	reserveQuery := `
+	INSERT INTO idempotency_keys (user_id, key, request_hash, created_at)
+	VALUES ($1, $2, $3, NOW())
+	ON CONFLICT (user_id, key) DO UPDATE
+	SET request_hash = EXCLUDED.request_hash, status_code = NULL, response_body = NULL,
+		content_type = NULL, created_at = NOW()
+	WHERE idempotency_keys.created_at < $4
+	RETURNING id, created_at
+	`
+
+	record := &models.IdempotencyRecord{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+	}
+
+	err := config.DBPool.QueryRow(ctx, reserveQuery, userID, key, requestHash, staleBefore).Scan(&record.ID, &record.CreatedAt)
+	if err == nil {
+		return record, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, err
+	}
+
+	// The key is taken by a recent request: return what it stored
+	selectQuery := `
+	SELECT id, request_hash, status_code, response_body, COALESCE(content_type, ''), created_at
+	FROM idempotency_keys
+	WHERE user_id = $1 AND key = $2
+	`
+
+	err = config.DBPool.QueryRow(ctx, selectQuery, userID, key).Scan(
+		&record.ID,
+		&record.RequestHash,
+		&record.StatusCode,
+		&record.ResponseBody,
+		&record.ContentType,
+		&record.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The original request failed and released the key in the meantime
+			return nil, false, ErrIdempotencyKeyReleased
+		}
+		return nil, false, err
+	}
+
+	return record, false, nil
+}
+
+// Complete stores the response of a processed request
+func (r *IdempotencyRepository) Complete(id int, statusCode int, body []byte, contentType string) error {
+	query := `
+	UPDATE idempotency_keys
+	SET status_code = $1, response_body = $2, content_type = $3
+	WHERE id = $4
+	`
+
+	_, err := config.DBPool.Exec(context.Background(), query, statusCode, body, contentType, id)
+	return err
+}
+
+// Release deletes a reservation so the request can be retried with the same key
+func (r *IdempotencyRepository) Release(id int) error {
+	_, err := config.DBPool.Exec(context.Background(), `DELETE FROM idempotency_keys WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,288 @@
+// repository/nutrition_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+var (
+	// ErrNutritionFoodNotFound is returned for reference foods that do not exist
+	ErrNutritionFoodNotFound = errors.New("nutrition food not found")
+	// ErrFoodProductNotFound is returned for barcodes of products that are not in the database
+	ErrFoodProductNotFound = errors.New("product not found")
+)
+
+// NutritionRepository handles database operations for the nutrition reference database
+// and the packaged food products
+type NutritionRepository struct{}
+
+// NewNutritionRepository creates a new NutritionRepository
+func NewNutritionRepository() *NutritionRepository {
+	return &NutritionRepository{}
+}
+
+const nutritionFoodColumns = `id, source, source_code, name, name_en, category, energy_kcal,
+	protein_grams, fat_grams, carbs_grams, fiber_grams, sugar_grams, sodium_mg, saturated_fat_grams,
+	cholesterol_mg, default_serving_grams`
+
+// UpsertNutritionFoods inserts the foods, updating foods already imported from the same
+// source with the same code. It returns the number of foods inserted and updated.
+func (r *NutritionRepository) UpsertNutritionFoods(foods []models.NutritionFood) (inserted int, updated int, err error) {
+	query := `
+	INSERT INTO nutrition_foods (
+		source, source_code, name, name_en, category, energy_kcal,
+		protein_grams, fat_grams, carbs_grams, fiber_grams, sugar_grams, sodium_mg,
+		saturated_fat_grams, cholesterol_mg, default_serving_grams
+	)
+	VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	ON CONFLICT (source, source_code)
+	DO UPDATE SET
+		name = EXCLUDED.name,
+		name_en = EXCLUDED.name_en,
+		category = EXCLUDED.category,
+		energy_kcal = EXCLUDED.energy_kcal,
+		protein_grams = EXCLUDED.protein_grams,
+		fat_grams = EXCLUDED.fat_grams,
+		carbs_grams = EXCLUDED.carbs_grams,
+		fiber_grams = EXCLUDED.fiber_grams,
+		sugar_grams = EXCLUDED.sugar_grams,
+		sodium_mg = EXCLUDED.sodium_mg,
+		saturated_fat_grams = EXCLUDED.saturated_fat_grams,
+		cholesterol_mg = EXCLUDED.cholesterol_mg,
+		default_serving_grams = EXCLUDED.default_serving_grams,
+		updated_at = NOW()
+	RETURNING id, (xmax = 0)
+	`
+
+	ctx := context.Background()
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range foods {
+		food := &foods[i]
+		var isInsert bool
+		err := tx.QueryRow(ctx, query,
+			food.Source, food.SourceCode, food.Name, food.NameEN, food.Category, food.EnergyKcal,
+			food.ProteinGrams, food.FatGrams, food.CarbsGrams, food.FiberGrams, food.SugarGrams, food.SodiumMg,
+			food.SaturatedFatGrams, food.CholesterolMg, food.DefaultServingGrams,
+		).Scan(&food.ID, &isInsert)
+		if err != nil {
+			return 0, 0, err
+		}
+		if isInsert {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	return inserted, updated, nil
+}
+
+// SearchNutritionFoods finds foods whose Indonesian or English name starts with the query,
+// followed by foods with a similar name so typos like "nasi gorng" still match
+func (r *NutritionRepository) SearchNutritionFoods(search string, limit int) ([]models.NutritionFood, error) {
+	query := `
+	SELECT ` + nutritionFoodColumns + `
+	FROM nutrition_foods
+	WHERE lower(name) LIKE $2 ESCAPE '\'
+	   OR lower(name_en) LIKE $2 ESCAPE '\'
+	   OR lower(name) % $1
+	ORDER BY (lower(name) LIKE $2 ESCAPE '\' OR lower(name_en) LIKE $2 ESCAPE '\') DESC,
+	         similarity(lower(name), $1) DESC,
+	         name, id
+	LIMIT $3
+	`
+
+	search = strings.ToLower(strings.TrimSpace(search))
+	prefix := likeEscaper.Replace(search) + "%"
+
+	rows, err := config.DBPool.Query(context.Background(), query, search, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	foods := []models.NutritionFood{}
+	for rows.Next() {
+		food, err := scanNutritionFood(rows)
+		if err != nil {
+			return nil, err
+		}
+		foods = append(foods, *food)
+	}
+
+	return foods, rows.Err()
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetNutritionFood retrieves a reference food
+func (r *NutritionRepository) GetNutritionFood(id int) (*models.NutritionFood, error) {
+	query := `SELECT ` + nutritionFoodColumns + ` FROM nutrition_foods WHERE id = $1`
+
+	food, err := scanNutritionFood(config.DBPool.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNutritionFoodNotFound
+		}
+		return nil, err
+	}
+
+	return food, nil
+}
+
+func scanNutritionFood(row pgx.Row) (*models.NutritionFood, error) {
+	var food models.NutritionFood
+	var nameENNull, categoryNull pgtype.Text
+
+	err := row.Scan(
+		&food.ID,
+		&food.Source,
+		&food.SourceCode,
+		&food.Name,
+		&nameENNull,
+		&categoryNull,
+		&food.EnergyKcal,
+		&food.ProteinGrams,
+		&food.FatGrams,
+		&food.CarbsGrams,
+		&food.FiberGrams,
+		&food.SugarGrams,
+		&food.SodiumMg,
+		&food.SaturatedFatGrams,
+		&food.CholesterolMg,
+		&food.DefaultServingGrams,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if nameENNull.Valid {
+		food.NameEN = nameENNull.String
+	}
+	if categoryNull.Valid {
+		food.Category = categoryNull.String
+	}
+
+	return &food, nil
+}
+
+const foodProductColumns = `id, barcode, name, brand, serving_grams, energy_kcal, protein_grams,
+	fat_grams, carbs_grams, fiber_grams, sugar_grams, sodium_mg, saturated_fat_grams, cholesterol_mg`
+
+// UpsertFoodProducts inserts the products, updating products with the same barcode. It
+// returns the number of products inserted and updated.
+func (r *NutritionRepository) UpsertFoodProducts(products []models.FoodProduct) (inserted int, updated int, err error) {
+	query := `
+	INSERT INTO food_products (
+		barcode, name, brand, serving_grams, energy_kcal, protein_grams,
+		fat_grams, carbs_grams, fiber_grams, sugar_grams, sodium_mg, saturated_fat_grams, cholesterol_mg
+	)
+	VALUES ($1, $2, NULLIF($3, ''), NULLIF($4::double precision, 0), $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	ON CONFLICT (barcode)
+	DO UPDATE SET
+		name = EXCLUDED.name,
+		brand = EXCLUDED.brand,
+		serving_grams = EXCLUDED.serving_grams,
+		energy_kcal = EXCLUDED.energy_kcal,
+		protein_grams = EXCLUDED.protein_grams,
+		fat_grams = EXCLUDED.fat_grams,
+		carbs_grams = EXCLUDED.carbs_grams,
+		fiber_grams = EXCLUDED.fiber_grams,
+		sugar_grams = EXCLUDED.sugar_grams,
+		sodium_mg = EXCLUDED.sodium_mg,
+		saturated_fat_grams = EXCLUDED.saturated_fat_grams,
+		cholesterol_mg = EXCLUDED.cholesterol_mg,
+		updated_at = NOW()
+	RETURNING id, (xmax = 0)
+	`
+
+	ctx := context.Background()
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	for i := range products {
+		product := &products[i]
+		var isInsert bool
+		err := tx.QueryRow(ctx, query,
+			product.Barcode, product.Name, product.Brand, product.ServingGrams, product.EnergyKcal,
+			product.ProteinGrams, product.FatGrams, product.CarbsGrams, product.FiberGrams,
+			product.SugarGrams, product.SodiumMg, product.SaturatedFatGrams, product.CholesterolMg,
+		).Scan(&product.ID, &isInsert)
+		if err != nil {
+			return 0, 0, err
+		}
+		if isInsert {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	return inserted, updated, nil
+}
+
+// GetFoodProductByBarcode retrieves a product by its normalized barcode
+func (r *NutritionRepository) GetFoodProductByBarcode(barcode string) (*models.FoodProduct, error) {
+	query := `SELECT ` + foodProductColumns + ` FROM food_products WHERE barcode = $1`
+
+	var product models.FoodProduct
+	var brandNull pgtype.Text
+	var servingGramsNull pgtype.Float8
+
+	err := config.DBPool.QueryRow(context.Background(), query, barcode).Scan(
+		&product.ID,
+		&product.Barcode,
+		&product.Name,
+		&brandNull,
+		&servingGramsNull,
+		&product.EnergyKcal,
+		&product.ProteinGrams,
+		&product.FatGrams,
+		&product.CarbsGrams,
+		&product.FiberGrams,
+		&product.SugarGrams,
+		&product.SodiumMg,
+		&product.SaturatedFatGrams,
+		&product.CholesterolMg,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFoodProductNotFound
+		}
+		return nil, err
+	}
+
+	if brandNull.Valid {
+		product.Brand = brandNull.String
+	}
+	if servingGramsNull.Valid {
+		product.ServingGrams = servingGramsNull.Float64
+	}
+
+	return &product, nil
+}
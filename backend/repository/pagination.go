@@ -0,0 +1,73 @@
+// repository/pagination.go
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// ErrInvalidListFilter is returned for a type filter value an endpoint does not support
+var ErrInvalidListFilter = errors.New("invalid type filter")
+
+// listFilter builds the WHERE, ORDER BY and LIMIT clauses shared by the list queries.
+// Conditions use ? placeholders, which are numbered in the order they are added.
+type listFilter struct {
+	conditions []string
+	args       []interface{}
+}
+
+// where adds a condition with its arguments
+func (f *listFilter) where(condition string, args ...interface{}) {
+	for _, arg := range args {
+		f.args = append(f.args, arg)
+		condition = strings.Replace(condition, "?", "$"+strconv.Itoa(len(f.args)), 1)
+	}
+	f.conditions = append(f.conditions, condition)
+}
+
+// page adds the date range and cursor conditions for keyset pagination on
+// (timeColumn, idColumn) and returns the WHERE ... ORDER BY ... LIMIT clauses.
+// One extra row is fetched so trimPage can tell whether another page exists.
+func (f *listFilter) page(opts models.ListOptions, timeColumn string, idColumn string) string {
+	if opts.From != nil {
+		f.where(timeColumn+" >= ?", opts.From.UTC())
+	}
+	if opts.To != nil {
+		f.where(timeColumn+" < ?", opts.To.UTC())
+	}
+
+	direction, comparison := "DESC", "<"
+	if opts.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	if opts.After != nil {
+		f.where(fmt.Sprintf("(%s, %s) %s (?, ?)", timeColumn, idColumn, comparison), opts.After.Time, opts.After.ID)
+	}
+
+	clause := ""
+	if len(f.conditions) > 0 {
+		clause = "WHERE " + strings.Join(f.conditions, " AND ")
+	}
+	clause += fmt.Sprintf("\n\tORDER BY %s %s, %s %s", timeColumn, direction, idColumn, direction)
+	if opts.Limit > 0 {
+		clause += fmt.Sprintf("\n\tLIMIT %d", opts.Limit+1)
+	}
+
+	return clause
+}
+
+// trimPage drops the extra row fetched by listFilter.page and returns the cursor of
+// the next page, or an empty string if this is the last one
+func trimPage[T any](items []T, opts models.ListOptions, cursor func(T) models.Cursor) ([]T, string) {
+	if opts.Limit <= 0 || len(items) <= opts.Limit {
+		return items, ""
+	}
+
+	items = items[:opts.Limit]
+	return items, cursor(items[len(items)-1]).Encode()
+}
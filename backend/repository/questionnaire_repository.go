@@ -0,0 +1,71 @@
+// repository/questionnaire_repository.go
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// ErrQuestionnaireNotFound is returned for unknown questionnaire versions
+var ErrQuestionnaireNotFound = errors.New("questionnaire not found")
+
+// QuestionnaireRepository handles database operations for assessment questionnaires
+type QuestionnaireRepository struct{}
+
+// NewQuestionnaireRepository creates a new QuestionnaireRepository
+func NewQuestionnaireRepository() *QuestionnaireRepository {
+	return &QuestionnaireRepository{}
+}
+
+const questionnaireColumns = `id, version, questions, is_active, created_at`
+
+func scanQuestionnaire(row pgx.Row) (*models.Questionnaire, error) {
+	var questionnaire models.Questionnaire
+	var questions []byte
+
+	err := row.Scan(
+		&questionnaire.ID,
+		&questionnaire.Version,
+		&questions,
+		&questionnaire.IsActive,
+		&questionnaire.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrQuestionnaireNotFound
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(questions, &questionnaire.Questions); err != nil {
+		return nil, err
+	}
+
+	return &questionnaire, nil
+}
+
+// GetCurrentQuestionnaire retrieves the highest active questionnaire version
+func (r *QuestionnaireRepository) GetCurrentQuestionnaire() (*models.Questionnaire, error) {
+	query := `SELECT ` + questionnaireColumns + `
+	FROM assessment_questionnaires
+	WHERE is_active
+	ORDER BY version DESC
+	LIMIT 1
+	`
+
+	return scanQuestionnaire(config.DBPool.QueryRow(context.Background(), query))
+}
+
+// GetQuestionnaire retrieves a questionnaire version, including inactive ones so that
+// older submissions can still be read
+func (r *QuestionnaireRepository) GetQuestionnaire(version int) (*models.Questionnaire, error) {
+	query := `SELECT ` + questionnaireColumns + ` FROM assessment_questionnaires WHERE version = $1`
+
+	return scanQuestionnaire(config.DBPool.QueryRow(context.Background(), query, version))
+}
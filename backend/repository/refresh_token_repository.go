@@ -0,0 +1,201 @@
+// repository/refresh_token_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+var (
+	// ErrRefreshTokenNotFound is returned for unknown refresh tokens
+	ErrRefreshTokenNotFound = errors.New("invalid refresh token")
+	// ErrRefreshTokenExpired is returned for refresh tokens past their expiry
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	// ErrRefreshTokenReused is returned when an already rotated or revoked token is presented
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected, session revoked")
+)
+
+// RefreshTokenRepository handles database operations for refresh tokens
+type RefreshTokenRepository struct{}
+
+// NewRefreshTokenRepository creates a new RefreshTokenRepository
+func NewRefreshTokenRepository() *RefreshTokenRepository {
+	return &RefreshTokenRepository{}
+}
+
+// CreateRefreshToken stores a new refresh token in the given family
+func (r *RefreshTokenRepository) CreateRefreshToken(userID int, familyID string, tokenHash string, expiresAt time.Time) (*models.RefreshToken, error) {
+	query := `
+	INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at
+	`
+
+	token := &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
+		ExpiresAt: expiresAt,
+	}
+
+	err := config.DBPool.QueryRow(
+		context.Background(),
+		query,
+		userID,
+		familyID,
+		tokenHash,
+		expiresAt,
+	).Scan(&token.ID, &token.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// RotateRefreshToken revokes the presented token and stores its replacement in the same
+// family. Presenting a token that was already revoked revokes the whole family.
+func (r *RefreshTokenRepository) RotateRefreshToken(tokenHash string, newTokenHash string, expiresAt time.Time) (*models.RefreshToken, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the presented token so concurrent refreshes cannot both rotate it
+	selectQuery := `
+	SELECT id, user_id, family_id, expires_at, revoked_at
+	FROM refresh_tokens
+	WHERE token_hash = $1
+	FOR UPDATE
+	`
+
+	var current models.RefreshToken
+	var revokedAt pgtype.Timestamp
+
+	err = tx.QueryRow(ctx, selectQuery, tokenHash).Scan(
+		&current.ID,
+		&current.UserID,
+		&current.FamilyID,
+		&current.ExpiresAt,
+		&revokedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	if revokedAt.Valid {
+		// The token was already used: assume it was stolen and end the session
+		if _, err := tx.Exec(ctx, revokeFamilyQuery, current.FamilyID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrRefreshTokenExpired
+	}
+
+	insertQuery := `
+	INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at
+	`
+
+	next := &models.RefreshToken{
+		UserID:    current.UserID,
+		FamilyID:  current.FamilyID,
+		TokenHash: newTokenHash,
+		ExpiresAt: expiresAt,
+	}
+
+	err = tx.QueryRow(ctx, insertQuery, current.UserID, current.FamilyID, newTokenHash, expiresAt).Scan(&next.ID, &next.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	updateQuery := `
+	UPDATE refresh_tokens
+	SET revoked_at = NOW(), replaced_by = $1
+	WHERE id = $2
+	`
+	if _, err := tx.Exec(ctx, updateQuery, next.ID, current.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// revokeFamilyQuery revokes every still active token of a family
+const revokeFamilyQuery = `
+	UPDATE refresh_tokens
+	SET revoked_at = NOW()
+	WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+// RevokeFamilyByToken ends the session the given refresh token belongs to
+func (r *RefreshTokenRepository) RevokeFamilyByToken(tokenHash string) error {
+	var familyID string
+
+	query := `SELECT family_id FROM refresh_tokens WHERE token_hash = $1`
+	err := config.DBPool.QueryRow(context.Background(), query, tokenHash).Scan(&familyID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrRefreshTokenNotFound
+		}
+		return err
+	}
+
+	_, err = config.DBPool.Exec(context.Background(), revokeFamilyQuery, familyID)
+	return err
+}
+
+// RevokeAllForUser ends every session of a user
+func (r *RefreshTokenRepository) RevokeAllForUser(userID int) error {
+	query := `
+	UPDATE refresh_tokens
+	SET revoked_at = NOW()
+	WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := config.DBPool.Exec(context.Background(), query, userID)
+	return err
+}
+
+// IsSessionActive reports whether a token family still has an unrevoked, unexpired token
+func (r *RefreshTokenRepository) IsSessionActive(userID int, familyID string) (bool, error) {
+	var active bool
+	query := `
+	SELECT EXISTS(
+		SELECT 1 FROM refresh_tokens
+		WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	)
+	`
+
+	err := config.DBPool.QueryRow(context.Background(), query, userID, familyID).Scan(&active)
+	if err != nil {
+		return false, err
+	}
+
+	return active, nil
+}
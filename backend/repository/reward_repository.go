@@ -0,0 +1,259 @@
+// repository/reward_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+var (
+	// ErrRewardNotFound is returned for unknown reward items
+	ErrRewardNotFound = errors.New("reward not found")
+	// ErrRewardUnavailable is returned for inactive rewards or rewards outside their validity window
+	ErrRewardUnavailable = errors.New("reward is not available")
+	// ErrRewardOutOfStock is returned when a reward has no stock left
+	ErrRewardOutOfStock = errors.New("reward is out of stock")
+)
+
+// RewardRepository handles database operations for the rewards catalog and redemptions
+type RewardRepository struct{}
+
+// NewRewardRepository creates a new RewardRepository
+func NewRewardRepository() *RewardRepository {
+	return &RewardRepository{}
+}
+
+const rewardItemColumns = `
+	id, name, COALESCE(description, ''), COALESCE(image_url, ''), price_coins, stock,
+	valid_from, valid_until, COALESCE(voucher_validity_days, 0), is_active, created_at, updated_at
+	`
+
+func scanRewardItem(row pgx.Row) (*models.RewardItem, error) {
+	var item models.RewardItem
+
+	err := row.Scan(
+		&item.ID,
+		&item.Name,
+		&item.Description,
+		&item.ImageURL,
+		&item.PriceCoins,
+		&item.Stock,
+		&item.ValidFrom,
+		&item.ValidUntil,
+		&item.VoucherValidityDays,
+		&item.IsActive,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// GetAvailableRewards retrieves active rewards whose validity window includes now
+func (r *RewardRepository) GetAvailableRewards() ([]models.RewardItem, error) {
+	query := `
+	SELECT ` + rewardItemColumns + `
+	FROM reward_items
+	WHERE is_active = TRUE
+		AND (valid_from IS NULL OR valid_from <= $1)
+		AND (valid_until IS NULL OR valid_until >= $1)
+	ORDER BY price_coins ASC, id ASC
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.RewardItem
+
+	for rows.Next() {
+		item, err := scanRewardItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// GetRewardByID retrieves a reward item by ID
+func (r *RewardRepository) GetRewardByID(id int) (*models.RewardItem, error) {
+	query := `SELECT ` + rewardItemColumns + ` FROM reward_items WHERE id = $1`
+
+	item, err := scanRewardItem(config.DBPool.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRewardNotFound
+		}
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// RedeemReward checks stock, debits the user's coins and issues a redemption in one transaction
+func (r *RewardRepository) RedeemReward(userID int, rewardID int, code string) (*models.RewardRedemption, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the item so concurrent redemptions cannot oversell the last unit
+	selectQuery := `SELECT ` + rewardItemColumns + ` FROM reward_items WHERE id = $1 FOR UPDATE`
+
+	item, err := scanRewardItem(tx.QueryRow(ctx, selectQuery, rewardID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRewardNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !item.IsActive ||
+		(item.ValidFrom != nil && now.Before(*item.ValidFrom)) ||
+		(item.ValidUntil != nil && now.After(*item.ValidUntil)) {
+		return nil, ErrRewardUnavailable
+	}
+	if item.Stock <= 0 {
+		return nil, ErrRewardOutOfStock
+	}
+
+	// Debit only if the balance covers the price; the row lock makes the check atomic
+	debitQuery := `
+	UPDATE user_coins
+	SET total_coins = total_coins - $1, updated_at = NOW()
+	WHERE user_id = $2 AND total_coins >= $1
+	`
+	tag, err := tx.Exec(ctx, debitQuery, item.PriceCoins, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrInsufficientCoins
+	}
+
+	stockQuery := `UPDATE reward_items SET stock = stock - 1, updated_at = NOW() WHERE id = $1`
+	if _, err := tx.Exec(ctx, stockQuery, item.ID); err != nil {
+		return nil, err
+	}
+
+	redemption := &models.RewardRedemption{
+		UserID:         userID,
+		RewardItemID:   item.ID,
+		RewardName:     item.Name,
+		CoinsSpent:     item.PriceCoins,
+		RedemptionCode: code,
+		Status:         "issued",
+	}
+	if item.VoucherValidityDays > 0 {
+		expiresAt := now.AddDate(0, 0, item.VoucherValidityDays)
+		redemption.ExpiresAt = &expiresAt
+	}
+
+	insertQuery := `
+	INSERT INTO reward_redemptions (user_id, reward_item_id, coins_spent, redemption_code, status, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at
+	`
+	err = tx.QueryRow(
+		ctx,
+		insertQuery,
+		userID,
+		item.ID,
+		item.PriceCoins,
+		code,
+		redemption.Status,
+		redemption.ExpiresAt,
+		now,
+	).Scan(&redemption.ID, &redemption.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the transaction (negative amount for spending)
+	transactionQuery := `
+	INSERT INTO coin_transactions (user_id, amount, transaction_type, reference_id, reference_type, created_at)
+	VALUES ($1, $2, $3, $4, $5, NOW())
+	`
+	_, err = tx.Exec(ctx, transactionQuery, userID, -item.PriceCoins, "Reward", redemption.ID, "reward_redemptions")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return redemption, nil
+}
+
+// GetUserRedemptions retrieves the rewards a user has redeemed, newest first
+func (r *RewardRepository) GetUserRedemptions(userID int) ([]models.RewardRedemption, error) {
+	// Vouchers past their expiry are reported as expired without a background job
+	query := `
+	SELECT rr.id, rr.user_id, rr.reward_item_id, ri.name, rr.coins_spent, rr.redemption_code,
+		CASE WHEN rr.status = 'issued' AND rr.expires_at IS NOT NULL AND rr.expires_at < $2
+			THEN 'expired' ELSE rr.status END,
+		rr.expires_at, rr.used_at, rr.created_at
+	FROM reward_redemptions rr
+	JOIN reward_items ri ON ri.id = rr.reward_item_id
+	WHERE rr.user_id = $1
+	ORDER BY rr.created_at DESC
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var redemptions []models.RewardRedemption
+
+	for rows.Next() {
+		var redemption models.RewardRedemption
+
+		err := rows.Scan(
+			&redemption.ID,
+			&redemption.UserID,
+			&redemption.RewardItemID,
+			&redemption.RewardName,
+			&redemption.CoinsSpent,
+			&redemption.RedemptionCode,
+			&redemption.Status,
+			&redemption.ExpiresAt,
+			&redemption.UsedAt,
+			&redemption.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		redemptions = append(redemptions, redemption)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return redemptions, nil
+}
@@ -0,0 +1,324 @@
+// repository/streak_repository.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/habdil/sigap-app/backend/config"
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// ErrFreezeAlreadyOwned is returned when a user tries to buy a second unused freeze
+var ErrFreezeAlreadyOwned = errors.New("you already have an unused streak freeze")
+
+// StreakRepository handles database operations for activity streaks
+type StreakRepository struct{}
+
+// NewStreakRepository creates a new StreakRepository
+func NewStreakRepository() *StreakRepository {
+	return &StreakRepository{}
+}
+
+// GetActiveDays returns the distinct calendar days, in the given timezone, on which the
+// user logged an activity of at least minMinutes. Activities whose coins are withheld by
+// the plausibility checks do not count until approved. Days are returned as UTC midnights, oldest first.
+func (r *StreakRepository) GetActiveDays(userID int, timezone string, minMinutes int) ([]time.Time, error) {
+	// activity_date is stored in UTC
+	query := `
+	SELECT DISTINCT (a.activity_date AT TIME ZONE 'UTC' AT TIME ZONE $2)::DATE AS day
+	FROM activity_logs a
+	WHERE a.user_id = $1 AND a.duration_minutes >= $3
+		AND NOT EXISTS (
+			SELECT 1 FROM activity_flags f
+			WHERE f.activity_id = a.id AND f.action = 'withheld' AND f.status <> 'approved'
+		)
+	ORDER BY day
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query, userID, timezone, minMinutes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDays(rows)
+}
+
+// GetFrozenDays returns the days covered by used freezes, oldest first
+func (r *StreakRepository) GetFrozenDays(userID int) ([]time.Time, error) {
+	query := `
+	SELECT used_on
+	FROM streak_freezes
+	WHERE user_id = $1 AND used_on IS NOT NULL
+	ORDER BY used_on
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDays(rows)
+}
+
+func scanDays(rows pgx.Rows) ([]time.Time, error) {
+	var days []time.Time
+
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return days, nil
+}
+
+// GetUnusedFreeze returns the user's unused freeze, or nil if they have none
+func (r *StreakRepository) GetUnusedFreeze(userID int) (*models.StreakFreeze, error) {
+	query := `
+	SELECT id, user_id, coins_spent, purchased_on, created_at
+	FROM streak_freezes
+	WHERE user_id = $1 AND used_on IS NULL
+	`
+
+	var freeze models.StreakFreeze
+	err := config.DBPool.QueryRow(context.Background(), query, userID).Scan(
+		&freeze.ID,
+		&freeze.UserID,
+		&freeze.CoinsSpent,
+		&freeze.PurchasedOn,
+		&freeze.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &freeze, nil
+}
+
+// UseFreeze spends the user's unused freeze on a missed day. It reports false if the
+// user had no unused freeze left.
+func (r *StreakRepository) UseFreeze(userID int, day time.Time) (bool, error) {
+	query := `
+	UPDATE streak_freezes
+	SET used_on = $2
+	WHERE user_id = $1 AND used_on IS NULL
+	`
+
+	tag, err := config.DBPool.Exec(context.Background(), query, userID, day)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// PurchaseFreeze buys a freeze with coins in one transaction
+func (r *StreakRepository) PurchaseFreeze(userID int, cost int, purchasedOn time.Time) (*models.StreakFreeze, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	freeze := &models.StreakFreeze{
+		UserID:      userID,
+		CoinsSpent:  cost,
+		PurchasedOn: purchasedOn,
+	}
+
+	// The partial unique index allows only one unused freeze per user
+	insertQuery := `
+	INSERT INTO streak_freezes (user_id, coins_spent, purchased_on)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id) WHERE used_on IS NULL DO NOTHING
+	RETURNING id, created_at
+	`
+	err = tx.QueryRow(ctx, insertQuery, userID, cost, purchasedOn).Scan(&freeze.ID, &freeze.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFreezeAlreadyOwned
+		}
+		return nil, err
+	}
+
+	err = debitCoins(ctx, tx, userID, cost, "StreakFreeze", freeze.ID, "streak_freezes", fmt.Sprintf("streak_freezes:%d", freeze.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return freeze, nil
+}
+
+const streakBonusColumns = `id, user_id, kind, streak_start, reached_on, milestone, coins, reverses_id, created_at`
+
+// GetActiveBonuses returns the user's bonuses that were paid and not reversed, in the
+// order they were reached
+func (r *StreakRepository) GetActiveBonuses(userID int) ([]models.StreakBonus, error) {
+	query := `
+	SELECT ` + streakBonusColumns + `
+	FROM streak_bonuses b
+	WHERE b.user_id = $1 AND b.reverses_id IS NULL
+		AND NOT EXISTS (SELECT 1 FROM streak_bonuses r WHERE r.reverses_id = b.id)
+	ORDER BY b.reached_on, b.id
+	`
+
+	rows, err := config.DBPool.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bonuses []models.StreakBonus
+	for rows.Next() {
+		var bonus models.StreakBonus
+		err := rows.Scan(
+			&bonus.ID,
+			&bonus.UserID,
+			&bonus.Kind,
+			&bonus.StreakStart,
+			&bonus.ReachedOn,
+			&bonus.Milestone,
+			&bonus.Coins,
+			&bonus.ReversesID,
+			&bonus.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		bonuses = append(bonuses, bonus)
+	}
+
+	return bonuses, rows.Err()
+}
+
+// CreateBonus records a milestone bonus and credits its coins in one transaction. The
+// bonus covers the run from bonus.StreakStart to runEnd; it reports false without error
+// if a bonus for the milestone that was not reversed was already reached in that run.
+func (r *StreakRepository) CreateBonus(bonus *models.StreakBonus, runEnd time.Time) (bool, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockStreakBonuses(ctx, tx, bonus.UserID); err != nil {
+		return false, err
+	}
+
+	insertQuery := `
+	INSERT INTO streak_bonuses (user_id, kind, streak_start, reached_on, milestone, coins)
+	SELECT $1, $2, $3, $4::DATE, $5, $6::INTEGER
+	WHERE NOT EXISTS (
+		SELECT 1 FROM streak_bonuses b
+		WHERE b.user_id = $1 AND b.kind = $2 AND b.milestone = $5 AND b.reverses_id IS NULL
+			AND b.reached_on BETWEEN $3 AND $7
+			AND NOT EXISTS (SELECT 1 FROM streak_bonuses r WHERE r.reverses_id = b.id)
+	)
+	RETURNING id, created_at
+	`
+	err = tx.QueryRow(ctx, insertQuery,
+		bonus.UserID, bonus.Kind, bonus.StreakStart, bonus.ReachedOn, bonus.Milestone, bonus.Coins, runEnd,
+	).Scan(&bonus.ID, &bonus.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	err = creditCoins(ctx, tx, bonus.UserID, bonus.Coins, "StreakBonus", bonus.ID, "streak_bonuses", fmt.Sprintf("streak_bonuses:%d", bonus.ID))
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ReverseBonus takes back a bonus with a compensating row and debits its coins in one
+// transaction. It returns nil without error if the bonus was already reversed, and fails
+// with ErrInsufficientCoins if the coins were already spent.
+func (r *StreakRepository) ReverseBonus(bonus models.StreakBonus) (*models.StreakBonus, error) {
+	ctx := context.Background()
+
+	tx, err := config.DBPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockStreakBonuses(ctx, tx, bonus.UserID); err != nil {
+		return nil, err
+	}
+
+	reversal := &models.StreakBonus{
+		UserID:      bonus.UserID,
+		Kind:        bonus.Kind,
+		StreakStart: bonus.StreakStart,
+		ReachedOn:   bonus.ReachedOn,
+		Milestone:   bonus.Milestone,
+		Coins:       -bonus.Coins,
+		ReversesID:  &bonus.ID,
+	}
+
+	insertQuery := `
+	INSERT INTO streak_bonuses (user_id, kind, streak_start, reached_on, milestone, coins, reverses_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	ON CONFLICT (reverses_id) WHERE reverses_id IS NOT NULL DO NOTHING
+	RETURNING id, created_at
+	`
+	err = tx.QueryRow(ctx, insertQuery,
+		reversal.UserID, reversal.Kind, reversal.StreakStart, reversal.ReachedOn, reversal.Milestone, reversal.Coins, bonus.ID,
+	).Scan(&reversal.ID, &reversal.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	err = debitCoins(ctx, tx, bonus.UserID, bonus.Coins, "StreakBonusReversal", reversal.ID, "streak_bonuses", fmt.Sprintf("streak_bonuses:%d", reversal.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return reversal, nil
+}
+
+// lockStreakBonuses serializes bonus payments and reversals of a user, so concurrent
+// activities cannot pay the same milestone twice
+func lockStreakBonuses(ctx context.Context, tx pgx.Tx, userID int) error {
+	_, err := tx.Exec(ctx, `SELECT 1 FROM users WHERE id = $1 FOR NO KEY UPDATE`, userID)
+	return err
+}
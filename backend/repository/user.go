@@ -11,6 +11,9 @@ import (
 	"github.com/habdil/sigap-app/backend/models"
 )
 
+// ErrUserNotFound is returned for users that do not exist
+var ErrUserNotFound = errors.New("user not found")
+
 // UserRepository menangani semua operasi database untuk pengguna
 type UserRepository struct{}
 
@@ -93,7 +96,7 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -184,7 +187,7 @@ func (r *UserRepository) GetUserByID(id int) (*models.User, error) {
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -260,7 +263,7 @@ func (r *UserRepository) GetUserByGoogleID(googleID string) (*models.User, error
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -335,7 +338,7 @@ func (r *UserRepository) GetUserBySupabaseUUID(supabaseUUID string) (*models.Use
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -407,3 +410,63 @@ func (r *UserRepository) UpdateUserSupabaseInfo(userID int, supabaseUUID, google
 
 	return err
 }
+
+// UpdateUserGoogleID menautkan Google ID ke user yang sudah ada
+func (r *UserRepository) UpdateUserGoogleID(userID int, googleID string) error {
+	query := `
+    UPDATE users 
+    SET google_id = $1, updated_at = NOW()
+    WHERE id = $2
+    `
+
+	_, err := config.DBPool.Exec(context.Background(), query, googleID, userID)
+	return err
+}
+
+// GetUserRole mengambil role user, default "regular" jika kosong
+func (r *UserRepository) GetUserRole(userID int) (string, error) {
+	query := `SELECT COALESCE(role, '') FROM users WHERE id = $1`
+
+	var role string
+	err := config.DBPool.QueryRow(context.Background(), query, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+
+	if role == "" {
+		role = "regular"
+	}
+
+	return role, nil
+}
+
+// GetUserTimezone mengambil timezone user (nama IANA)
+func (r *UserRepository) GetUserTimezone(userID int) (string, error) {
+	query := `SELECT timezone FROM users WHERE id = $1`
+
+	var timezone string
+	err := config.DBPool.QueryRow(context.Background(), query, userID).Scan(&timezone)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+
+	return timezone, nil
+}
+
+// UpdateUserTimezone menyimpan timezone user (nama IANA)
+func (r *UserRepository) UpdateUserTimezone(userID int, timezone string) error {
+	query := `
+    UPDATE users 
+    SET timezone = $1, updated_at = NOW()
+    WHERE id = $2
+    `
+
+	_, err := config.DBPool.Exec(context.Background(), query, timezone, userID)
+	return err
+}
@@ -0,0 +1,218 @@
+// risk/model.go
+package risk
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// CurrentVersion is the model version used for new assessments
+const CurrentVersion = "rules-v2"
+
+// Input is everything the model scores
+type Input struct {
+	Age      int            // years, 0 if unknown
+	HeightCM float64        // 0 if unknown
+	WeightKG float64        // 0 if unknown
+	Answers  map[string]int // questionnaire answers by question key
+}
+
+// Band awards Points to values in [Min, Max). A zero Max means no upper bound.
+type Band struct {
+	Min    float64
+	Max    float64
+	Points float64
+	Label  string
+}
+
+// AnswerFactor awards Points to the answers of one questionnaire question.
+// Unanswered questions score nothing.
+type AnswerFactor struct {
+	Factor   string
+	Question string
+	Points   map[int]float64
+}
+
+// Model is one version of the rule-based scoring engine. A version is never changed
+// once released, so stored results can always be reproduced and explained.
+type Model struct {
+	Version string
+	// Baseline is the score before any factor is applied
+	Baseline float64
+	// AgeBands and BMIBands score the profile; unknown values score nothing
+	AgeBands []Band
+	BMIBands []Band
+	// Factors score the questionnaire answers, in the order they are reported
+	Factors []AnswerFactor
+}
+
+var ageBands = []Band{
+	{Min: 0, Max: 30, Points: 0, Label: "under 30"},
+	{Min: 30, Max: 45, Points: 5, Label: "30-44"},
+	{Min: 45, Max: 55, Points: 12, Label: "45-54"},
+	{Min: 55, Max: 65, Points: 20, Label: "55-64"},
+	{Min: 65, Max: 0, Points: 30, Label: "65 and over"},
+}
+
+// bmiBands are the WHO Asia-Pacific BMI cut-offs, which fit our Indonesian users better
+var bmiBands = []Band{
+	{Min: 0, Max: 18.5, Points: 2, Label: "underweight"},
+	{Min: 18.5, Max: 23, Points: 0, Label: "normal"},
+	{Min: 23, Max: 25, Points: 4, Label: "overweight"},
+	{Min: 25, Max: 30, Points: 8, Label: "obese class I"},
+	{Min: 30, Max: 0, Points: 14, Label: "obese class II"},
+}
+
+// lifestyleFactors score the four questions of questionnaire version 1
+var lifestyleFactors = []AnswerFactor{
+	{Factor: "screen_time", Question: "screen_time_hours", Points: map[int]float64{1: 0, 2: 3, 3: 6, 4: 10}},
+	{Factor: "exercise", Question: "exercise_hours", Points: map[int]float64{1: 12, 2: 6, 3: 0, 4: -3}},
+	{Factor: "late_nights", Question: "late_night_frequency", Points: map[int]float64{1: 0, 2: 2, 3: 6, 4: 10}},
+	{Factor: "diet", Question: "diet_quality", Points: map[int]float64{1: 0, 2: 3, 3: 7, 4: 12}},
+}
+
+// versions holds every released model by version
+var versions = map[string]*Model{
+	"rules-v1": {
+		Version:  "rules-v1",
+		Baseline: 15,
+		AgeBands: ageBands,
+		BMIBands: bmiBands,
+		Factors:  lifestyleFactors,
+	},
+	// rules-v2 scores the medical history questions of questionnaire version 2. Version 1
+	// submissions stay with rules-v1, whose higher baseline stands in for the unasked
+	// questions, so retaking an old questionnaire does not show a false improvement.
+	"rules-v2": {
+		Version:  "rules-v2",
+		Baseline: 10,
+		AgeBands: ageBands,
+		BMIBands: bmiBands,
+		Factors: append(append([]AnswerFactor{}, lifestyleFactors...),
+			AnswerFactor{Factor: "smoking", Question: "smoking", Points: map[int]float64{1: 0, 2: 3, 3: 6, 4: 10}},
+			AnswerFactor{Factor: "blood_pressure", Question: "blood_pressure", Points: map[int]float64{1: 0, 2: 4, 3: 12, 4: 3}},
+			AnswerFactor{Factor: "family_history", Question: "family_history", Points: map[int]float64{1: 0, 2: 6, 3: 2}},
+			AnswerFactor{Factor: "diabetes", Question: "diabetes", Points: map[int]float64{1: 0, 2: 4, 3: 10}},
+		),
+	},
+}
+
+// Current returns the model used for new assessments
+func Current() *Model {
+	return versions[CurrentVersion]
+}
+
+// questionnaireModels maps questionnaire versions to the model that scores them. Newer
+// questionnaires are scored by the current model until they get a model of their own.
+var questionnaireModels = map[int]string{
+	1: "rules-v1",
+	2: "rules-v2",
+}
+
+// ForQuestionnaire returns the model that scores answers to a questionnaire version
+func ForQuestionnaire(version int) (*Model, error) {
+	modelVersion, ok := questionnaireModels[version]
+	if !ok {
+		modelVersion = CurrentVersion
+	}
+	return Get(modelVersion)
+}
+
+// Get returns a released model by version
+func Get(version string) (*Model, error) {
+	model, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown risk model version %q", version)
+	}
+	return model, nil
+}
+
+// Result is a score together with the contribution of every factor to it
+type Result struct {
+	Version       string
+	Percentage    int
+	Contributions []models.RiskContribution
+}
+
+// Score computes the stroke risk percentage. The contributions add up to the
+// score before it is clamped to 0-100.
+func (m *Model) Score(input Input) Result {
+	contributions := []models.RiskContribution{
+		{Factor: "baseline", Value: "", Points: m.Baseline},
+	}
+
+	if input.Age > 0 {
+		band := findBand(m.AgeBands, float64(input.Age))
+		contributions = append(contributions, models.RiskContribution{
+			Factor: "age",
+			Value:  fmt.Sprintf("%d (%s)", input.Age, band.Label),
+			Points: band.Points,
+		})
+	} else {
+		contributions = append(contributions, models.RiskContribution{Factor: "age", Value: "unknown"})
+	}
+
+	if bmi := BMI(input.HeightCM, input.WeightKG); bmi > 0 {
+		band := findBand(m.BMIBands, bmi)
+		contributions = append(contributions, models.RiskContribution{
+			Factor: "bmi",
+			Value:  fmt.Sprintf("%.1f (%s)", bmi, band.Label),
+			Points: band.Points,
+		})
+	} else {
+		contributions = append(contributions, models.RiskContribution{Factor: "bmi", Value: "unknown"})
+	}
+
+	for _, factor := range m.Factors {
+		contributions = append(contributions, answerContribution(factor, input.Answers))
+	}
+
+	total := 0.0
+	for _, contribution := range contributions {
+		total += contribution.Points
+	}
+
+	return Result{
+		Version:       m.Version,
+		Percentage:    int(math.Round(math.Max(0, math.Min(100, total)))),
+		Contributions: contributions,
+	}
+}
+
+// BMI returns the body mass index, or 0 if height or weight is unknown
+func BMI(heightCM float64, weightKG float64) float64 {
+	if heightCM <= 0 || weightKG <= 0 {
+		return 0
+	}
+	heightM := heightCM / 100
+	return math.Round(weightKG/(heightM*heightM)*10) / 10
+}
+
+// BMILabel returns the category of a BMI under the model's cut-offs
+func (m *Model) BMILabel(bmi float64) string {
+	return findBand(m.BMIBands, bmi).Label
+}
+
+func findBand(bands []Band, value float64) Band {
+	for _, band := range bands {
+		if value >= band.Min && (band.Max == 0 || value < band.Max) {
+			return band
+		}
+	}
+	return Band{Label: "out of range"}
+}
+
+func answerContribution(factor AnswerFactor, answers map[string]int) models.RiskContribution {
+	answer, answered := answers[factor.Question]
+	points, known := factor.Points[answer]
+	if !answered || !known {
+		return models.RiskContribution{Factor: factor.Factor, Value: "unknown"}
+	}
+	return models.RiskContribution{
+		Factor: factor.Factor,
+		Value:  fmt.Sprintf("%d", answer),
+		Points: points,
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/controllers"
+	"github.com/habdil/sigap-app/backend/middlewares"
+)
+
+// SetupAccountRoutes sets up the personal data export and account deletion routes
+func SetupAccountRoutes(router *gin.Engine) {
+	accountController := controllers.NewAccountController()
+
+	// All account routes are protected
+	account := router.Group("/api/account")
+	account.Use(middlewares.AuthMiddleware())
+	{
+		account.POST("/export", accountController.ExportData)
+		account.DELETE("", accountController.DeleteAccount)
+		account.GET("/deletion", accountController.GetDeletion)
+		account.POST("/deletion/cancel", accountController.CancelDeletion)
+	}
+}
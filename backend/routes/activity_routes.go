@@ -16,8 +16,17 @@ func SetupActivityRoutes(router *gin.Engine) {
 	activity := router.Group("/api/activities")
 	activity.Use(middlewares.AuthMiddleware())
 	{
-		activity.POST("", activityController.LogActivity)
+		activity.POST("", middlewares.Idempotency(), activityController.LogActivity)
 		activity.GET("", activityController.GetUserActivities)
+		activity.POST("/import", activityController.ImportActivity)
+		activity.GET("/types", activityController.GetActivityTypes)
 		activity.GET("/recommendations", activityController.GetRecommendedActivities)
+		activity.GET("/stats", activityController.GetActivityStats)
+		activity.GET("/streak", activityController.GetStreak)
+		activity.POST("/streak/freeze", middlewares.Idempotency(), activityController.PurchaseStreakFreeze)
+		activity.GET("/:id", activityController.GetActivity)
+		activity.GET("/:id/track", activityController.GetActivityTrack)
+		activity.PATCH("/:id", middlewares.Idempotency(), activityController.UpdateActivity)
+		activity.DELETE("/:id", activityController.DeleteActivity)
 	}
 }
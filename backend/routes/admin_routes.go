@@ -0,0 +1,24 @@
+// routes/admin_routes.go
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/controllers"
+	"github.com/habdil/sigap-app/backend/middlewares"
+)
+
+// SetupAdminRoutes sets up the admin-only routes
+func SetupAdminRoutes(router *gin.Engine) {
+	coinController := controllers.NewCoinController()
+	activityController := controllers.NewActivityController()
+
+	// Admin routes require an authenticated user with the admin role
+	admin := router.Group("/api/admin")
+	admin.Use(middlewares.AuthMiddleware(), middlewares.RequireRole("admin"))
+	{
+		admin.POST("/coins/grant", middlewares.Idempotency(), coinController.GrantCoins)
+		admin.GET("/activity-flags", activityController.GetActivityFlags)
+		admin.POST("/activity-flags/:id/review", middlewares.Idempotency(), activityController.ReviewActivityFlag)
+	}
+}
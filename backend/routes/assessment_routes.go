@@ -16,8 +16,10 @@ func SetupAssessmentRoutes(router *gin.Engine) {
 	assessment.Use(middlewares.AuthMiddleware())
 	{
 		assessment.POST("", assessmentController.SubmitAssessment)
+		assessment.GET("/questionnaire", assessmentController.GetQuestionnaire)
 		assessment.GET("/latest", assessmentController.GetLatestAssessment)
 		assessment.GET("/history", assessmentController.GetAssessmentHistory)
 		assessment.GET("/status", assessmentController.CheckAssessmentStatus)
+		assessment.GET("/trend", assessmentController.GetAssessmentTrend)
 	}
 }
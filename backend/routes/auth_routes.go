@@ -18,6 +18,8 @@ func SetupAuthRoutes(router *gin.Engine) {
 		auth.POST("/login", authController.Login)
 		auth.POST("/google-login", authController.GoogleLogin)
 		auth.POST("/supabase-auth", authController.SupabaseAuth)
+		auth.POST("/refresh", authController.RefreshToken)
+		auth.POST("/logout", authController.Logout)
 	}
 
 	// Protected routes
@@ -25,5 +27,6 @@ func SetupAuthRoutes(router *gin.Engine) {
 	protected.Use(middlewares.AuthMiddleware())
 	{
 		protected.GET("/user", authController.GetCurrentUser)
+		protected.POST("/auth/logout-all", authController.LogoutAll)
 	}
 }
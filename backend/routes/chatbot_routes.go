@@ -25,5 +25,7 @@ func SetupChatbotRoutes(router *gin.Engine) {
 		// Message management
 		chatbot.GET("/conversations/:id/messages", chatbotController.GetMessages)
 		chatbot.POST("/conversations/:id/messages", chatbotController.SendMessage)
+		chatbot.GET("/conversations/:id/messages/stream", chatbotController.StreamMessage)
+		chatbot.POST("/conversations/:id/messages/stream", chatbotController.StreamMessage)
 	}
 }
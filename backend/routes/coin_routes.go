@@ -17,8 +17,7 @@ func SetupCoinRoutes(router *gin.Engine) {
 	coins.Use(middlewares.AuthMiddleware())
 	{
 		coins.GET("", coinController.GetUserCoins)
-		coins.POST("/add", coinController.AddCoins)
-		coins.POST("/spend", coinController.SpendCoins)
+		coins.POST("/spend", middlewares.Idempotency(), coinController.SpendCoins)
 		coins.GET("/transactions", coinController.GetTransactionHistory)
 	}
 }
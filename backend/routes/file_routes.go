@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/controllers"
+	"github.com/habdil/sigap-app/backend/storage"
+)
+
+// SetupFileRoutes sets up the download route of the local storage backend. S3-compatible
+// backends hand out presigned URLs of their own and need no route.
+func SetupFileRoutes(router *gin.Engine) {
+	local, ok := storage.New().(*storage.Local)
+	if !ok {
+		return
+	}
+	fileController := controllers.NewFileController(local)
+
+	// Files are authorized by the signature in the URL instead of a login
+	router.GET(storage.LocalURLPath+"*key", fileController.GetFile)
+}
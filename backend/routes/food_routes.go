@@ -17,7 +17,11 @@ func SetupFoodRoutes(router *gin.Engine) {
 	food.Use(middlewares.AuthMiddleware())
 	{
 		food.POST("", foodController.LogFood)
+		food.POST("/photo", foodController.UploadFoodPhoto)
+		food.POST("/barcode", foodController.LogBarcode)
 		food.POST("/analyze", foodController.AnalyzeFood)
 		food.GET("", foodController.GetUserFoodLogs)
+		food.GET("/search", foodController.SearchFoods)
+		food.PATCH("/:id/items/:itemId", foodController.CorrectFoodItem)
 	}
 }
@@ -17,5 +17,6 @@ func SetupProfileRoutes(router *gin.Engine) {
 	{
 		profile.GET("", profileController.GetProfile)
 		profile.PUT("", profileController.UpdateProfile)
+		profile.PUT("/timezone", profileController.UpdateTimezone)
 	}
 }
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/controllers"
+	"github.com/habdil/sigap-app/backend/middlewares"
+)
+
+// SetupReportRoutes sets up the report routes
+func SetupReportRoutes(router *gin.Engine) {
+	reportController := controllers.NewReportController()
+
+	// All report routes are protected
+	reports := router.Group("/api/reports")
+	reports.Use(middlewares.AuthMiddleware())
+	{
+		reports.GET("/health.pdf", reportController.GetHealthReport)
+	}
+}
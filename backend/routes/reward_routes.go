@@ -0,0 +1,24 @@
+// routes/reward_routes.go
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/habdil/sigap-app/backend/controllers"
+	"github.com/habdil/sigap-app/backend/middlewares"
+)
+
+// SetupRewardRoutes sets up the rewards catalog and redemption routes
+func SetupRewardRoutes(router *gin.Engine) {
+	rewardController := controllers.NewRewardController()
+
+	// All reward routes are protected
+	rewards := router.Group("/api/rewards")
+	rewards.Use(middlewares.AuthMiddleware())
+	{
+		rewards.GET("", rewardController.GetRewards)
+		rewards.GET("/mine", rewardController.GetMyRewards)
+		rewards.GET("/:id", rewardController.GetReward)
+		rewards.POST("/:id/redeem", middlewares.Idempotency(), rewardController.RedeemReward)
+	}
+}
@@ -0,0 +1,214 @@
+// services/account_service.go
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
+	"github.com/habdil/sigap-app/backend/storage"
+)
+
+// defaultDeletionGracePeriod is how long a deleted account can still be restored
+const defaultDeletionGracePeriod = 30 * 24 * time.Hour
+
+// AccountService handles the personal data export and account deletion
+type AccountService struct {
+	accountRepo *repository.AccountRepository
+	foodRepo    *repository.FoodRepository
+	storage     storage.Storage
+	gracePeriod time.Duration
+}
+
+// NewAccountService creates a new AccountService. The deletion grace period is read from
+// ACCOUNT_DELETION_GRACE_PERIOD.
+func NewAccountService() *AccountService {
+	gracePeriod := defaultDeletionGracePeriod
+	if value := os.Getenv("ACCOUNT_DELETION_GRACE_PERIOD"); value != "" {
+		if period, err := time.ParseDuration(value); err == nil && period >= 0 {
+			gracePeriod = period
+		} else {
+			log.Printf("Warning: invalid ACCOUNT_DELETION_GRACE_PERIOD %q, using %s", value, gracePeriod)
+		}
+	}
+
+	return &AccountService{
+		accountRepo: repository.NewAccountRepository(),
+		foodRepo:    repository.NewFoodRepository(),
+		storage:     storage.New(),
+		gracePeriod: gracePeriod,
+	}
+}
+
+// exportManifest describes the contents of a personal data export
+type exportManifest struct {
+	UserID     int            `json:"user_id"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Files      map[string]int `json:"files"` // rows per table
+}
+
+// ExportData returns a ZIP archive with all of the user's personal data. Every table is
+// written as JSON and as CSV; nested values such as a food log's analysis are JSON encoded
+// in their CSV cell. Uploaded food photos are included under photos/.
+func (s *AccountService) ExportData(userID int) ([]byte, error) {
+	tables, err := s.accountRepo.ExportTables(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	now := time.Now().UTC()
+
+	manifest := exportManifest{UserID: userID, ExportedAt: now, Files: map[string]int{}}
+	for _, table := range tables {
+		manifest.Files[table.Name] = len(table.Rows)
+
+		jsonData, err := json.MarshalIndent(table.Rows, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeZipFile(archive, table.Name+".json", jsonData, now); err != nil {
+			return nil, err
+		}
+
+		csvData, err := exportCSV(table.Rows)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeZipFile(archive, table.Name+".csv", csvData, now); err != nil {
+			return nil, err
+		}
+	}
+
+	photoKeys, err := s.foodRepo.GetUserPhotoKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range photoKeys {
+		photo, err := s.storage.Get(context.Background(), key)
+		if err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if err := writeZipFile(archive, "photos/"+path.Base(key), photo.Data, now); err != nil {
+			return nil, err
+		}
+		manifest.Files["photos"]++
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeZipFile(archive, "manifest.json", manifestData, now); err != nil {
+		return nil, err
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeZipFile(archive *zip.Writer, name string, data []byte, modified time.Time) error {
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	return err
+}
+
+// exportCSV writes rows as CSV with a header of every column found in them, id first
+// and the rest in alphabetical order
+func exportCSV(rows []map[string]any) ([]byte, error) {
+	seen := map[string]bool{}
+	var columns []string
+	for _, row := range rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		if (columns[i] == "id") != (columns[j] == "id") {
+			return columns[i] == "id"
+		}
+		return columns[i] < columns[j]
+	})
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(columns); err != nil {
+		return nil, err
+	}
+
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			cell, err := csvCell(row[column])
+			if err != nil {
+				return nil, err
+			}
+			record[i] = cell
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+func csvCell(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("encode csv cell: %w", err)
+		}
+		return string(data), nil
+	}
+}
+
+// RequestDeletion schedules the account for deletion once the grace period ends. Until
+// then the user keeps full access and can cancel; asking again keeps the original date.
+func (s *AccountService) RequestDeletion(userID int) (*models.AccountDeletion, error) {
+	return s.accountRepo.ScheduleDeletion(userID, time.Now().UTC().Add(s.gracePeriod))
+}
+
+// GetDeletion returns whether and when the account is scheduled for deletion
+func (s *AccountService) GetDeletion(userID int) (*models.AccountDeletion, error) {
+	return s.accountRepo.GetDeletion(userID)
+}
+
+// CancelDeletion cancels the scheduled deletion of the account
+func (s *AccountService) CancelDeletion(userID int) error {
+	return s.accountRepo.CancelDeletion(userID)
+}
@@ -0,0 +1,156 @@
+// services/activity_checks.go
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+const (
+	// maxSessionMinutes is the longest session that earns coins
+	maxSessionMinutes = 8 * 60
+	// paceTolerance is how far the reported pace may be from distance / duration
+	paceTolerance = 0.25
+	// overlapSlack lets back-to-back sessions touch without counting as overlapping
+	overlapSlack = time.Minute
+	minHeartRate = 30
+	maxHeartRate = 230
+	// maxClockSkew is how far in the future a session may end, for devices whose clock is ahead
+	maxClockSkew = 5 * time.Minute
+)
+
+// ErrFutureActivity is returned for activity logs that end in the future
+var ErrFutureActivity = errors.New("activity date cannot be in the future")
+
+// withheldReasons are the flags whose coins are held back until an admin reviews the log.
+// Other flags only cap the coins.
+var withheldReasons = map[string]bool{
+	models.FlagPaceTooFast:          true,
+	models.FlagPaceInconsistent:     true,
+	models.FlagOverlappingSession:   true,
+	models.FlagImplausibleHeartRate: true,
+}
+
+// activityCheck is the result of the plausibility checks for one activity log
+type activityCheck struct {
+	Reasons  []string
+	Withhold bool
+}
+
+// checkActivityDate rejects sessions that end after now, allowing for maxClockSkew.
+// Coins and streaks are earned on the activity date, so they cannot be collected early.
+func checkActivityDate(end time.Time) error {
+	if end.After(time.Now().Add(maxClockSkew)) {
+		return ErrFutureActivity
+	}
+	return nil
+}
+
+// checkActivity flags physically implausible activity logs. The session is taken to
+// end at the activity date. excludeID is the activity being edited, 0 for a new one.
+func (s *ActivityService) checkActivity(userID int, excludeID int, activityType *models.ActivityType, activity *models.ActivityLog) (*activityCheck, error) {
+	check := &activityCheck{}
+	flag := func(reason string) {
+		check.Reasons = append(check.Reasons, reason)
+		if withheldReasons[reason] {
+			check.Withhold = true
+		}
+	}
+
+	duration := float64(activity.DurationMinutes)
+
+	if activity.DurationMinutes > maxSessionMinutes {
+		flag(models.FlagDurationTooLong)
+	}
+
+	// Average speed from distance and duration, or from the reported pace
+	var speedKMH float64
+	switch {
+	case activity.DistanceKM > 0 && duration > 0:
+		speedKMH = activity.DistanceKM / (duration / 60)
+	case activity.AvgPace > 0:
+		speedKMH = 60 / activity.AvgPace
+	}
+	if activityType.MaxSpeedKMH != nil && speedKMH > *activityType.MaxSpeedKMH {
+		flag(models.FlagPaceTooFast)
+	}
+
+	if activity.AvgPace > 0 && activity.DistanceKM > 0 && duration > 0 {
+		expectedPace := duration / activity.DistanceKM
+		if math.Abs(activity.AvgPace-expectedPace) > paceTolerance*expectedPace {
+			flag(models.FlagPaceInconsistent)
+		}
+	}
+
+	if activity.HeartRateAvg != 0 && (activity.HeartRateAvg < minHeartRate || activity.HeartRateAvg > maxHeartRate) {
+		flag(models.FlagImplausibleHeartRate)
+	}
+
+	end := activity.ActivityDate
+	start := end.Add(-time.Duration(activity.DurationMinutes) * time.Minute)
+	overlapping, err := s.activityRepo.HasOverlappingActivity(userID, excludeID, start.Add(overlapSlack), end.Add(-overlapSlack))
+	if err != nil {
+		return nil, err
+	}
+	if overlapping {
+		flag(models.FlagOverlappingSession)
+	}
+
+	return check, nil
+}
+
+// awardCoins returns the coins to credit for an activity and the coins held back
+// for review. Sessions longer than maxSessionMinutes earn coins for that long only.
+func (s *ActivityService) awardCoins(activityType *models.ActivityType, activity *models.ActivityLog, check *activityCheck) (coins int, withheld int) {
+	minutes := activity.DurationMinutes
+	if minutes > maxSessionMinutes {
+		minutes = maxSessionMinutes
+	}
+
+	coins = s.calculateCoinsForActivity(activityType, minutes)
+	if check.Withhold {
+		return 0, coins
+	}
+	return coins, 0
+}
+
+// saveFlag records the result of the checks for an activity log, clearing an earlier
+// flag if the log now passes
+func (s *ActivityService) saveFlag(activity *models.ActivityLog, check *activityCheck, withheld int) (*models.ActivityFlag, error) {
+	if len(check.Reasons) == 0 {
+		return nil, s.flagRepo.ClearFlag(activity.ID)
+	}
+
+	action := "capped"
+	if check.Withhold {
+		action = "withheld"
+	}
+
+	return s.flagRepo.SaveFlag(&models.ActivityFlag{
+		ActivityID:    activity.ID,
+		UserID:        activity.UserID,
+		Reasons:       check.Reasons,
+		Action:        action,
+		CoinsWithheld: withheld,
+	})
+}
+
+// GetActivityFlags gets flagged activity logs for review and the cursor of the next page
+func (s *ActivityService) GetActivityFlags(opts models.ListOptions) ([]models.ActivityFlag, string, error) {
+	return s.flagRepo.GetFlags(opts)
+}
+
+// ReviewActivityFlag approves or rejects a flagged activity log. Approving releases the
+// withheld coins and lets the activity count towards the streak.
+func (s *ActivityService) ReviewActivityFlag(adminID int, flagID int, req *models.ActivityFlagReviewRequest) (*models.ActivityFlag, error) {
+	flag, err := s.flagRepo.ReviewFlag(adminID, flagID, req.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	s.settleStreakBonuses(flag.UserID)
+	return flag, nil
+}
@@ -0,0 +1,115 @@
+// services/activity_import_service.go
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/tracks"
+)
+
+// MaxActivityFileSize is the largest GPX, TCX or FIT file accepted for import
+const MaxActivityFileSize = 20 << 20
+
+// ErrInvalidActivityFile is returned for activity files that cannot be imported
+var ErrInvalidActivityFile = errors.New("invalid activity file")
+
+// ImportActivity creates an activity from a GPX, TCX or FIT file. The activity goes
+// through LogActivity so calories, coins and streaks are applied as for a logged one,
+// and the parsed route is stored with it. activityType overrides the sport in the file;
+// it is required when the file names no sport or one that is not registered.
+func (s *ActivityService) ImportActivity(userID int, filename string, data []byte, activityType string) (*models.ActivityImportResponse, error) {
+	track, err := tracks.Parse(filename, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidActivityFile, err)
+	}
+
+	// Sports in files are named like the registered types, e.g. "running" for Running
+	if activityType == "" {
+		if track.Sport == "" {
+			return nil, fmt.Errorf("%w: the file does not name a sport, set activity_type", ErrUnknownActivityType)
+		}
+		registered, err := s.typeService.Lookup(track.Sport)
+		if err != nil {
+			if errors.Is(err, ErrUnknownActivityType) {
+				return nil, fmt.Errorf("%w: the file's sport %q is not registered, set activity_type", ErrUnknownActivityType, track.Sport)
+			}
+			return nil, err
+		}
+		activityType = registered.Name
+	}
+
+	hash := sha256.Sum256(data)
+	track.UserID = userID
+	track.FileHash = hex.EncodeToString(hash[:])
+
+	// Reserve the track first so the same file cannot create two activities
+	if err := s.trackRepo.ReserveTrack(track); err != nil {
+		return nil, err
+	}
+
+	durationMinutes := int(math.Round(float64(track.DurationSeconds) / 60))
+	if durationMinutes < 1 {
+		durationMinutes = 1
+	}
+
+	req := &models.ActivityLogRequest{
+		ActivityType:    activityType,
+		DurationMinutes: durationMinutes,
+		DistanceKM:      track.DistanceKM,
+		HeartRateAvg:    track.HeartRateAvg,
+		AvgPace:         track.AvgPace,
+		LocationData:    trackLocationData(track),
+		// Like logged activities, the activity date is when the session ended
+		ActivityDate: &track.EndedAt,
+	}
+
+	activity, err := s.LogActivity(userID, req)
+	if err != nil {
+		if releaseErr := s.trackRepo.ReleaseTrack(track.ID); releaseErr != nil {
+			log.Printf("Error releasing activity track %d: %v", track.ID, releaseErr)
+		}
+		return nil, err
+	}
+
+	if err := s.trackRepo.AttachTrack(track.ID, activity.ID); err != nil {
+		// Take the activity and its coins back and free the file for another import
+		if deleteErr := s.DeleteActivity(userID, activity.ID); deleteErr != nil {
+			log.Printf("Error deleting activity %d without its track: %v", activity.ID, deleteErr)
+		} else if releaseErr := s.trackRepo.ReleaseTrack(track.ID); releaseErr != nil {
+			log.Printf("Error releasing activity track %d: %v", track.ID, releaseErr)
+		}
+		return nil, err
+	}
+	track.ActivityID = &activity.ID
+
+	return &models.ActivityImportResponse{
+		Activity: activity,
+		Track:    track,
+	}, nil
+}
+
+// GetActivityTrack gets the imported route and splits of one of the user's activities
+func (s *ActivityService) GetActivityTrack(userID int, activityID int) (*models.ActivityTrack, error) {
+	return s.trackRepo.GetTrackByActivityID(userID, activityID)
+}
+
+// trackLocationData is the summary kept in the activity's location_data
+func trackLocationData(track *models.ActivityTrack) map[string]interface{} {
+	locationData := map[string]interface{}{
+		"track_id":         track.ID,
+		"source_format":    track.SourceFormat,
+		"elevation_gain_m": track.ElevationGainM,
+	}
+	if len(track.Points) > 0 {
+		start, end := track.Points[0], track.Points[len(track.Points)-1]
+		locationData["start"] = map[string]float64{"lat": start.Lat, "lon": start.Lon}
+		locationData["end"] = map[string]float64{"lat": end.Lat, "lon": end.Lon}
+	}
+	return locationData
+}
@@ -4,6 +4,7 @@ package services
 import (
 	"log"
 	"math"
+	"slices"
 	"time"
 
 	"github.com/habdil/sigap-app/backend/models"
@@ -15,7 +16,10 @@ type ActivityService struct {
 	activityRepo   *repository.ActivityRepository
 	userRepo       *repository.UserRepository
 	assessmentRepo *repository.AssessmentRepository
-	coinRepo       *repository.CoinRepository
+	trackRepo      *repository.ActivityTrackRepository
+	flagRepo       *repository.ActivityFlagRepository
+	typeService    *ActivityTypeService
+	streakService  *StreakService
 }
 
 // NewActivityService creates a new ActivityService
@@ -24,55 +28,234 @@ func NewActivityService() *ActivityService {
 		activityRepo:   repository.NewActivityRepository(),
 		userRepo:       repository.NewUserRepository(),
 		assessmentRepo: repository.NewAssessmentRepository(),
-		coinRepo:       repository.NewCoinRepository(),
+		trackRepo:      repository.NewActivityTrackRepository(),
+		flagRepo:       repository.NewActivityFlagRepository(),
+		typeService:    NewActivityTypeService(),
+		streakService:  NewStreakService(),
 	}
 }
 
 // LogActivity logs a new activity and awards coins
 func (s *ActivityService) LogActivity(userID int, req *models.ActivityLogRequest) (*models.ActivityLog, error) {
+	activityType, err := s.typeService.Lookup(req.ActivityType)
+	if err != nil {
+		return nil, err
+	}
+	req.ActivityType = activityType.Name
+
 	// Calculate calories if not provided
-	if req.CaloriesBurned == 0 && req.ActivityType != "Yoga" && req.ActivityType != "Badminton" {
-		// Get user info for weight-based calculation
-		user, err := s.userRepo.GetUserByID(userID)
+	if req.CaloriesBurned == 0 {
+		req.CaloriesBurned, req.CalorieMethod = s.calculateCalories(activityType, req.DurationMinutes, req.DistanceKM, req.HeartRateAvg, s.calorieProfile(userID))
+	} else {
+		req.CalorieMethod = models.CalorieMethodManual
+	}
+
+	// Check the entry is physically plausible before awarding coins
+	activityDate := time.Now().UTC()
+	if req.ActivityDate != nil {
+		activityDate = *req.ActivityDate
+	}
+	if err := checkActivityDate(activityDate); err != nil {
+		return nil, err
+	}
+	candidate := &models.ActivityLog{
+		UserID:          userID,
+		ActivityType:    req.ActivityType,
+		DurationMinutes: req.DurationMinutes,
+		DistanceKM:      req.DistanceKM,
+		HeartRateAvg:    req.HeartRateAvg,
+		ActivityDate:    activityDate,
+		AvgPace:         req.AvgPace,
+	}
+	check, err := s.checkActivity(userID, 0, activityType, candidate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate coins to award
+	coinsEarned, coinsWithheld := s.awardCoins(activityType, candidate, check)
+
+	// Create log in database and award coins to user
+	activityLog, err := s.activityRepo.CreateActivityLog(userID, req, coinsEarned)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the flag for review
+	if len(check.Reasons) > 0 {
+		activityLog.Flag, err = s.saveFlag(activityLog, check, coinsWithheld)
 		if err != nil {
-			log.Printf("Error getting user for calorie calculation: %v", err)
-			// Use default weight if user not found
-			user = &models.User{Weight: 70.0}
+			log.Printf("Error flagging activity %d: %v", activityLog.ID, err)
 		}
+	}
 
-		// Calculate calories based on activity type and duration
-		weight := user.Weight
-		if weight <= 0 {
-			weight = 70.0 // Default weight if not set
+	// Update streaks and pay any milestone bonus reached. Withheld logs do not count.
+	if !check.Withhold {
+		if _, err := s.streakService.RecordActivity(userID); err != nil {
+			log.Printf("Error updating streak: %v", err)
+			// Continue even if the streak update fails
 		}
+	}
 
-		req.CaloriesBurned = s.calculateCalories(req.ActivityType, req.DurationMinutes, weight, req.DistanceKM)
+	return activityLog, nil
+}
+
+// GetUserActivities gets a user's activities and the cursor of the next page
+func (s *ActivityService) GetUserActivities(userID int, opts models.ListOptions) ([]models.ActivityLog, string, error) {
+	return s.activityRepo.GetUserActivities(userID, opts)
+}
+
+// GetActivity gets one of the user's activities
+func (s *ActivityService) GetActivity(userID int, activityID int) (*models.ActivityLog, error) {
+	activity, err := s.activityRepo.GetActivityByID(userID, activityID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Calculate coins to award
-	coinsEarned := s.calculateCoinsForActivity(req.ActivityType, req.DurationMinutes)
+	activity.Flag, err = s.flagRepo.GetFlagByActivityID(userID, activityID)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create log in database
-	activityLog, err := s.activityRepo.CreateActivityLog(userID, req, coinsEarned)
+	return activity, nil
+}
+
+// UpdateActivity applies a partial update to an activity. When the workout changes, its
+// calories and coins are recalculated and the plausibility checks run again; the coin
+// difference is posted as an adjustment in the same transaction. Edits of notes, weather,
+// music or location keep the coins and the flag as they are.
+func (s *ActivityService) UpdateActivity(userID int, activityID int, req *models.ActivityLogUpdateRequest) (*models.ActivityLog, error) {
+	activity, err := s.activityRepo.GetActivityByID(userID, activityID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A changed type must be registered; a legacy type that was since removed keeps the default rates
+	var activityType *models.ActivityType
+	if req.ActivityType != nil {
+		activityType, err = s.typeService.Lookup(*req.ActivityType)
+	} else {
+		activityType, err = s.typeService.LookupOrDefault(activity.ActivityType)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if req.ActivityType != nil {
+		req.ActivityType = &activityType.Name
+	}
+
+	workoutChanged := (req.ActivityType != nil && *req.ActivityType != activity.ActivityType) ||
+		(req.DurationMinutes != nil && *req.DurationMinutes != activity.DurationMinutes) ||
+		(req.DistanceKM != nil && *req.DistanceKM != activity.DistanceKM) ||
+		(req.HeartRateAvg != nil && *req.HeartRateAvg != activity.HeartRateAvg)
+	// The pace is not part of the calorie formula but is checked for plausibility
+	rescore := workoutChanged || (req.AvgPace != nil && *req.AvgPace != activity.AvgPace)
 
-	// Award coins to user
-	if coinsEarned > 0 {
-		err = s.coinRepo.AddCoins(userID, coinsEarned, "Activity", activityLog.ID, "activity_logs")
+	if req.ActivityType != nil {
+		activity.ActivityType = *req.ActivityType
+	}
+	if req.DurationMinutes != nil {
+		activity.DurationMinutes = *req.DurationMinutes
+	}
+	if req.DistanceKM != nil {
+		activity.DistanceKM = *req.DistanceKM
+	}
+	if req.HeartRateAvg != nil {
+		activity.HeartRateAvg = *req.HeartRateAvg
+	}
+	if req.Notes != nil {
+		activity.Notes = *req.Notes
+	}
+	if req.WeatherCondition != nil {
+		activity.WeatherCondition = *req.WeatherCondition
+	}
+	if req.LocationData != nil {
+		activity.LocationData = req.LocationData
+	}
+	if req.AvgPace != nil {
+		activity.AvgPace = *req.AvgPace
+	}
+	if req.MusicPlayed != nil {
+		activity.MusicPlayed = *req.MusicPlayed
+	}
+
+	// Recalculate calories the same way as LogActivity unless the client sends them
+	if req.CaloriesBurned != nil {
+		activity.CaloriesBurned = *req.CaloriesBurned
+		activity.CalorieMethod = models.CalorieMethodManual
+	} else if workoutChanged {
+		activity.CaloriesBurned, activity.CalorieMethod = s.calculateCalories(activityType, activity.DurationMinutes, activity.DistanceKM, activity.HeartRateAvg, s.calorieProfile(userID))
+	}
+
+	flag, err := s.flagRepo.GetFlagByActivityID(userID, activity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rescore {
+		if err := s.activityRepo.UpdateActivityLog(activity, false); err != nil {
+			return nil, err
+		}
+		activity.Flag = flag
+		return activity, nil
+	}
+
+	check, err := s.checkActivity(userID, activity.ID, activityType, activity)
+	if err != nil {
+		return nil, err
+	}
+
+	// A flag an admin already reviewed stands while the checks find the same problems, so
+	// approved coins stay released and the log does not go back into the review queue
+	reviewed := flag != nil && flag.Status != "pending" && slices.Equal(flag.Reasons, check.Reasons)
+	if reviewed && flag.Status == "approved" {
+		check.Withhold = false
+	}
+
+	coinsEarned, coinsWithheld := s.awardCoins(activityType, activity, check)
+	activity.CoinsEarned = coinsEarned
+
+	if err := s.activityRepo.UpdateActivityLog(activity, true); err != nil {
+		return nil, err
+	}
+
+	if reviewed {
+		activity.Flag = flag
+	} else {
+		activity.Flag, err = s.saveFlag(activity, check, coinsWithheld)
 		if err != nil {
-			log.Printf("Error awarding coins: %v", err)
-			// Continue even if coin award fails
+			log.Printf("Error flagging activity %d: %v", activity.ID, err)
 		}
 	}
 
-	return activityLog, nil
+	// A shorter or withheld activity can break the streak a bonus was paid for
+	s.settleStreakBonuses(userID)
+
+	return activity, nil
+}
+
+// DeleteActivity deletes an activity and takes back the coins it earned, together with
+// any streak bonus the streak no longer reaches without it
+func (s *ActivityService) DeleteActivity(userID int, activityID int) error {
+	if err := s.activityRepo.DeleteActivityLog(userID, activityID); err != nil {
+		return err
+	}
+
+	s.settleStreakBonuses(userID)
+	return nil
+}
+
+// settleStreakBonuses pays and reverses streak bonuses after a change to past activity.
+// The change is already saved, so a failure is only logged and settled on the next activity.
+func (s *ActivityService) settleStreakBonuses(userID int) {
+	if _, err := s.streakService.SettleBonuses(userID); err != nil {
+		log.Printf("Error settling streak bonuses of user %d: %v", userID, err)
+	}
 }
 
-// GetUserActivities gets all activities for a user
-func (s *ActivityService) GetUserActivities(userID int) ([]models.ActivityLog, error) {
-	return s.activityRepo.GetUserActivities(userID)
+// GetActivityTypes gets the registered activity types
+func (s *ActivityService) GetActivityTypes() ([]models.ActivityType, error) {
+	return s.typeService.GetActivityTypes()
 }
 
 // GetRecommendedActivities gets activity recommendations for a user
@@ -105,60 +288,55 @@ func (s *ActivityService) GetRecommendedActivities(userID int) ([]models.Activit
 	return recommendations, nil
 }
 
-// calculateCalories estimates calories burned during an activity
-func (s *ActivityService) calculateCalories(activityType string, durationMinutes int, weightKg float64, distanceKm float64) int {
-	// MET values (Metabolic Equivalent of Task)
-	// These are approximate values
-	var met float64
-	switch activityType {
-	case "Jogging":
-		met = 7.0
-	case "Running":
-		met = 9.8
-	case "Yoga":
-		met = 3.0
-	case "Badminton":
-		met = 5.5
-	default:
-		met = 5.0 // Default for unknown activities
+// calorieProfile returns the user's details used for calorie estimation. A missing
+// weight defaults to 70 kg; a missing age or sex disables the heart rate formula.
+func (s *ActivityService) calorieProfile(userID int) calorieProfile {
+	profile := calorieProfile{WeightKG: 70.0}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		log.Printf("Error getting user for calorie calculation: %v", err)
+		return profile
+	}
+
+	if user.Weight > 0 {
+		profile.WeightKG = user.Weight
+	}
+	profile.Age = user.Age
+	if !user.DateOfBirth.IsZero() {
+		profile.Age = ageOn(user.DateOfBirth, time.Now())
+	}
+	profile.Sex = normalizeSex(user.Gender)
+
+	return profile
+}
+
+// calculateCalories estimates calories burned during an activity and reports the method used.
+// With an average heart rate, age and sex it uses the Keytel et al. (2005) prediction
+// equations, which track real energy expenditure far better than MET for chest strap
+// users. Otherwise it falls back to MET * weight * hours.
+func (s *ActivityService) calculateCalories(activityType *models.ActivityType, durationMinutes int, distanceKm float64, heartRateAvg int, profile calorieProfile) (int, string) {
+	if calories, ok := keytelCalories(heartRateAvg, durationMinutes, profile); ok {
+		return calories, models.CalorieMethodHeartRate
 	}
 
+	// MET (Metabolic Equivalent of Task) from the registry, speed-dependent where known
+	met := activityMET(activityType, durationMinutes, distanceKm)
+
 	// Calories = MET * weight (kg) * duration (hours)
 	durationHours := float64(durationMinutes) / 60.0
-	calories := met * weightKg * durationHours
+	calories := met * profile.WeightKG * durationHours
 
-	return int(math.Round(calories))
+	return int(math.Round(calories)), models.CalorieMethodMET
 }
 
 // calculateCoinsForActivity determines coins earned from activity
-func (s *ActivityService) calculateCoinsForActivity(activityType string, durationMinutes int) int {
+func (s *ActivityService) calculateCoinsForActivity(activityType *models.ActivityType, durationMinutes int) int {
 	// Base coins per minute of activity
-	var coinsPerMinute float64
-	switch activityType {
-	case "Running":
-		coinsPerMinute = 0.5 // Higher intensity, more coins
-	case "Jogging":
-		coinsPerMinute = 0.4
-	case "Yoga":
-		coinsPerMinute = 0.3
-	case "Badminton":
-		coinsPerMinute = 0.4
-	default:
-		coinsPerMinute = 0.3
-	}
-
-	// Calculate coins with bonus for longer activities
-	baseCoins := coinsPerMinute * float64(durationMinutes)
-
-	// Add bonus for activities over 30 minutes
-	var bonus float64 = 0
-	if durationMinutes >= 60 {
-		bonus = 10 // Big bonus for 1 hour+
-	} else if durationMinutes >= 45 {
-		bonus = 5
-	} else if durationMinutes >= 30 {
-		bonus = 3
-	}
+	baseCoins := activityType.CoinsPerMinute * float64(durationMinutes)
+
+	// Add the bonus for longer activities
+	bonus := float64(activityBonusCoins(activityType, durationMinutes))
 
 	totalCoins := baseCoins + bonus
 	return int(math.Round(totalCoins))
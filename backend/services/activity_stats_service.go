@@ -0,0 +1,168 @@
+// services/activity_stats_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 3 * 366
+)
+
+// ErrInvalidStatsQuery is returned for malformed activity statistics parameters
+var ErrInvalidStatsQuery = errors.New("invalid stats query")
+
+// GetActivityStats aggregates the user's activities per day, week or month over a date range
+func (s *ActivityService) GetActivityStats(userID int, query *models.ActivityStatsQuery) (*models.ActivityStats, error) {
+	timezone, location := loadUserLocation(s.userRepo, userID)
+	if query.Timezone != "" {
+		loc, err := time.LoadLocation(query.Timezone)
+		if err != nil || query.Timezone == "Local" {
+			return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidStatsQuery, query.Timezone)
+		}
+		timezone, location = query.Timezone, loc
+	}
+
+	period := query.Period
+	if period == "" {
+		period = "day"
+	}
+	if period != "day" && period != "week" && period != "month" {
+		return nil, fmt.Errorf("%w: period must be day, week or month", ErrInvalidStatsQuery)
+	}
+
+	// Dates are calendar days in the chosen timezone
+	now := time.Now().In(location)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	if query.To != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", query.To, location)
+		if err != nil {
+			return nil, fmt.Errorf("%w: to must be a YYYY-MM-DD date", ErrInvalidStatsQuery)
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -(defaultStatsDays - 1))
+	if query.From != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", query.From, location)
+		if err != nil {
+			return nil, fmt.Errorf("%w: from must be a YYYY-MM-DD date", ErrInvalidStatsQuery)
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidStatsQuery)
+	}
+	if to.Sub(from) > maxStatsDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: date range is limited to %d days", ErrInvalidStatsQuery, maxStatsDays)
+	}
+
+	// The range ends at the start of the day after to
+	end := to.AddDate(0, 0, 1)
+
+	aggregates, err := s.activityRepo.GetActivityAggregates(userID, period, timezone, from, end)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.ActivityStats{
+		Timezone: timezone,
+		Period:   period,
+		From:     from.Format("2006-01-02"),
+		To:       to.Format("2006-01-02"),
+		ByType:   []models.ActivityTypeTotals{},
+		Buckets:  []models.ActivityStatsBucket{},
+	}
+
+	typeIndex := make(map[string]int)
+	for _, aggregate := range aggregates {
+		addTotals(&stats.Totals, aggregate.ActivityTotals)
+
+		i, ok := typeIndex[aggregate.ActivityType]
+		if !ok {
+			i = len(stats.ByType)
+			typeIndex[aggregate.ActivityType] = i
+			stats.ByType = append(stats.ByType, models.ActivityTypeTotals{ActivityType: aggregate.ActivityType})
+		}
+		addTotals(&stats.ByType[i].ActivityTotals, aggregate.ActivityTotals)
+
+		// Aggregates are ordered by period, so a new period starts a new bucket
+		periodStart := aggregate.PeriodStart.Format("2006-01-02")
+		if len(stats.Buckets) == 0 || stats.Buckets[len(stats.Buckets)-1].PeriodStart != periodStart {
+			stats.Buckets = append(stats.Buckets, models.ActivityStatsBucket{PeriodStart: periodStart})
+		}
+		bucket := &stats.Buckets[len(stats.Buckets)-1]
+		addTotals(&bucket.ActivityTotals, aggregate.ActivityTotals)
+		bucket.ByType = append(bucket.ByType, models.ActivityTypeTotals{
+			ActivityType:   aggregate.ActivityType,
+			ActivityTotals: aggregate.ActivityTotals,
+		})
+	}
+
+	bests, err := s.activityRepo.GetPersonalBests(userID)
+	if err != nil {
+		return nil, err
+	}
+	stats.PersonalBests = *bests
+
+	weekOverWeek, err := s.weekOverWeek(userID, to)
+	if err != nil {
+		return nil, err
+	}
+	stats.WeekOverWeek = *weekOverWeek
+
+	return stats, nil
+}
+
+// weekOverWeek compares Monday up to day with the same days of the previous week
+func (s *ActivityService) weekOverWeek(userID int, day time.Time) (*models.WeekOverWeek, error) {
+	weekday := (int(day.Weekday()) + 6) % 7 // Monday = 0
+	thisStart := day.AddDate(0, 0, -weekday)
+	thisEnd := day.AddDate(0, 0, 1)
+
+	thisWeek, err := s.activityRepo.GetActivityTotals(userID, thisStart, thisEnd)
+	if err != nil {
+		return nil, err
+	}
+	lastWeek, err := s.activityRepo.GetActivityTotals(userID, thisStart.AddDate(0, 0, -7), thisEnd.AddDate(0, 0, -7))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WeekOverWeek{
+		WeekStart:         thisStart.Format("2006-01-02"),
+		ThisWeek:          *thisWeek,
+		LastWeek:          *lastWeek,
+		SessionsDelta:     thisWeek.Sessions - lastWeek.Sessions,
+		MinutesDelta:      thisWeek.Minutes - lastWeek.Minutes,
+		DistanceKMDelta:   thisWeek.DistanceKM - lastWeek.DistanceKM,
+		CaloriesDelta:     thisWeek.Calories - lastWeek.Calories,
+		MinutesChangePct:  percentChange(float64(thisWeek.Minutes), float64(lastWeek.Minutes)),
+		DistanceChangePct: percentChange(thisWeek.DistanceKM, lastWeek.DistanceKM),
+		CaloriesChangePct: percentChange(float64(thisWeek.Calories), float64(lastWeek.Calories)),
+	}, nil
+}
+
+func addTotals(total *models.ActivityTotals, add models.ActivityTotals) {
+	total.Sessions += add.Sessions
+	total.Minutes += add.Minutes
+	total.DistanceKM += add.DistanceKM
+	total.Calories += add.Calories
+	total.Coins += add.Coins
+}
+
+// percentChange returns the change from previous to current in percent, or nil if previous is zero
+func percentChange(current, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round((current-previous)/previous*1000) / 10
+	return &change
+}
@@ -0,0 +1,153 @@
+// services/activity_type_service.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+// activityTypesCacheTTL is how long the registry is cached; edits to the
+// activity_types table show up after at most this long
+const activityTypesCacheTTL = 5 * time.Minute
+
+// ErrUnknownActivityType is returned for activity types that are not in the registry
+var ErrUnknownActivityType = errors.New("unknown activity type")
+
+// defaultActivityType is used for activity logs whose type is no longer registered.
+// It matches the rates that applied to unknown types before the registry existed.
+var defaultActivityType = models.ActivityType{
+	MET:            5.0,
+	CoinsPerMinute: 0.3,
+	BonusTiers: []models.CoinBonusTier{
+		{MinMinutes: 30, Coins: 3},
+		{MinMinutes: 45, Coins: 5},
+		{MinMinutes: 60, Coins: 10},
+	},
+}
+
+// ActivityTypeService serves the activity type registry from a short-lived cache
+type ActivityTypeService struct {
+	activityTypeRepo *repository.ActivityTypeRepository
+
+	mu       sync.Mutex
+	types    []models.ActivityType
+	loadedAt time.Time
+}
+
+// NewActivityTypeService creates a new ActivityTypeService
+func NewActivityTypeService() *ActivityTypeService {
+	return &ActivityTypeService{
+		activityTypeRepo: repository.NewActivityTypeRepository(),
+	}
+}
+
+// GetActivityTypes returns the active activity types in display order
+func (s *ActivityTypeService) GetActivityTypes() ([]models.ActivityType, error) {
+	types, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	active := []models.ActivityType{}
+	for _, activityType := range types {
+		if activityType.IsActive {
+			active = append(active, activityType)
+		}
+	}
+	return active, nil
+}
+
+// Lookup returns the active activity type with the given name, ignoring case
+func (s *ActivityTypeService) Lookup(name string) (*models.ActivityType, error) {
+	types, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range types {
+		if types[i].IsActive && strings.EqualFold(types[i].Name, strings.TrimSpace(name)) {
+			activityType := types[i]
+			return &activityType, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnknownActivityType, name)
+}
+
+// LookupOrDefault returns the activity type with the given name, falling back to the
+// default rates for types that were removed from the registry
+func (s *ActivityTypeService) LookupOrDefault(name string) (*models.ActivityType, error) {
+	activityType, err := s.Lookup(name)
+	if errors.Is(err, ErrUnknownActivityType) {
+		fallback := defaultActivityType
+		fallback.Name = name
+		return &fallback, nil
+	}
+	return activityType, err
+}
+
+// load returns the cached registry, reloading it when it has expired
+func (s *ActivityTypeService) load() ([]models.ActivityType, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.types != nil && time.Since(s.loadedAt) < activityTypesCacheTTL {
+		return s.types, nil
+	}
+
+	types, err := s.activityTypeRepo.GetActivityTypes()
+	if err != nil {
+		// Keep serving the cached registry if the database is temporarily unavailable
+		if s.types != nil {
+			return s.types, nil
+		}
+		return nil, err
+	}
+	if types == nil {
+		types = []models.ActivityType{}
+	}
+
+	s.types = types
+	s.loadedAt = time.Now()
+
+	return types, nil
+}
+
+// activityMET returns the MET of an activity. Types with a MET curve use the average
+// speed when the distance is known, interpolating between the points of the curve.
+func activityMET(activityType *models.ActivityType, durationMinutes int, distanceKM float64) float64 {
+	curve := activityType.METCurve
+	if len(curve) == 0 || !activityType.UsesDistance || distanceKM <= 0 || durationMinutes <= 0 {
+		return activityType.MET
+	}
+
+	speed := distanceKM / (float64(durationMinutes) / 60)
+	if speed <= curve[0].SpeedKMH {
+		return curve[0].MET
+	}
+	for i := 1; i < len(curve); i++ {
+		if speed <= curve[i].SpeedKMH {
+			low, high := curve[i-1], curve[i]
+			fraction := (speed - low.SpeedKMH) / (high.SpeedKMH - low.SpeedKMH)
+			return low.MET + fraction*(high.MET-low.MET)
+		}
+	}
+	return curve[len(curve)-1].MET
+}
+
+// activityBonusCoins returns the bonus of the highest tier the session reaches
+func activityBonusCoins(activityType *models.ActivityType, durationMinutes int) int {
+	bonus := 0
+	for _, tier := range activityType.BonusTiers {
+		if durationMinutes >= tier.MinMinutes && tier.Coins > bonus {
+			bonus = tier.Coins
+		}
+	}
+	return bonus
+}
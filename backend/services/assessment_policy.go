@@ -0,0 +1,187 @@
+// services/assessment_policy.go
+package services
+
+import (
+	"errors"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+// reassessmentPolicy decides when a user should retake the assessment: after Interval,
+// or sooner when their weight changed by WeightChangePercent since the last assessment
+// or they have not logged an activity for InactivityGap. A zero value disables a rule.
+type reassessmentPolicy struct {
+	Interval            time.Duration
+	WeightChangePercent float64
+	InactivityGap       time.Duration
+}
+
+// reassessmentPolicyFromEnv reads the policy from ASSESSMENT_RETAKE_INTERVAL,
+// ASSESSMENT_RETAKE_WEIGHT_CHANGE_PERCENT and ASSESSMENT_RETAKE_INACTIVITY_GAP
+func reassessmentPolicyFromEnv() reassessmentPolicy {
+	policy := reassessmentPolicy{
+		Interval:            30 * 24 * time.Hour,
+		WeightChangePercent: 5,
+		InactivityGap:       14 * 24 * time.Hour,
+	}
+
+	if value := os.Getenv("ASSESSMENT_RETAKE_INTERVAL"); value != "" {
+		if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+			policy.Interval = interval
+		} else {
+			log.Printf("Warning: invalid ASSESSMENT_RETAKE_INTERVAL %q, using %s", value, policy.Interval)
+		}
+	}
+	if value := os.Getenv("ASSESSMENT_RETAKE_WEIGHT_CHANGE_PERCENT"); value != "" {
+		if percent, err := strconv.ParseFloat(value, 64); err == nil && percent >= 0 {
+			policy.WeightChangePercent = percent
+		} else {
+			log.Printf("Warning: invalid ASSESSMENT_RETAKE_WEIGHT_CHANGE_PERCENT %q, using %.1f", value, policy.WeightChangePercent)
+		}
+	}
+	if value := os.Getenv("ASSESSMENT_RETAKE_INACTIVITY_GAP"); value != "" {
+		if gap, err := time.ParseDuration(value); err == nil && gap >= 0 {
+			policy.InactivityGap = gap
+		} else {
+			log.Printf("Warning: invalid ASSESSMENT_RETAKE_INACTIVITY_GAP %q, using %s", value, policy.InactivityGap)
+		}
+	}
+
+	return policy
+}
+
+// GetAssessmentStatus tells whether the user needs to take the assessment and when the
+// next one is due under the reassessment policy
+func (s *AssessmentService) GetAssessmentStatus(userID int) (*models.AssessmentStatus, error) {
+	now := time.Now().UTC()
+
+	assessment, err := s.assessmentRepo.GetLatestAssessment(userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrAssessmentNotFound) {
+			return &models.AssessmentStatus{
+				NeedsAssessment: true,
+				Reason:          models.ReassessNeverAssessed,
+				NextDueAt:       now,
+			}, nil
+		}
+		return nil, err
+	}
+
+	status := &models.AssessmentStatus{
+		Reason:         models.ReassessIntervalElapsed,
+		LastAssessedAt: &assessment.CreatedAt,
+		NextDueAt:      assessment.CreatedAt.Add(s.policy.Interval),
+	}
+	dueSooner := func(at time.Time, reason string) {
+		if at.Before(status.NextDueAt) {
+			status.NextDueAt = at
+			status.Reason = reason
+		}
+	}
+
+	// A large weight change since the assessment changes the BMI factor
+	if s.policy.WeightChangePercent > 0 && assessment.WeightKG > 0 {
+		user, err := s.userRepo.GetUserByID(userID)
+		if err != nil {
+			return nil, err
+		}
+		if user.Weight > 0 {
+			change := math.Abs(user.Weight-assessment.WeightKG) / assessment.WeightKG * 100
+			if change >= s.policy.WeightChangePercent {
+				dueSooner(now, models.ReassessWeightChanged)
+			}
+		}
+	}
+
+	// A long break from exercise that started after the assessment changes the exercise answer
+	if s.policy.InactivityGap > 0 {
+		latest, err := s.activityRepo.GetLatestActivityDate(userID)
+		if err != nil {
+			return nil, err
+		}
+		if latest != nil {
+			gapReachedAt := latest.Add(s.policy.InactivityGap)
+			if !gapReachedAt.After(now) && assessment.CreatedAt.Before(gapReachedAt) {
+				dueSooner(gapReachedAt, models.ReassessInactivityGap)
+			}
+		}
+	}
+
+	status.NeedsAssessment = !now.Before(status.NextDueAt)
+
+	return status, nil
+}
+
+// GetAssessmentTrend compares the user's latest risk assessment result with the previous one
+func (s *AssessmentService) GetAssessmentTrend(userID int) (*models.AssessmentTrend, error) {
+	results, err := s.assessmentRepo.GetLatestAssessmentResults(userID, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, repository.ErrAssessmentResultNotFound
+	}
+
+	trend := &models.AssessmentTrend{
+		Current:         results[0],
+		Direction:       models.TrendFirst,
+		ImprovedFactors: []models.RiskFactorChange{},
+		WorsenedFactors: []models.RiskFactorChange{},
+	}
+	if len(results) == 1 {
+		return trend, nil
+	}
+
+	current, previous := results[0], results[1]
+	trend.Previous = &previous
+	trend.Delta = current.RiskPercentage - previous.RiskPercentage
+
+	switch {
+	case trend.Delta < 0:
+		trend.Direction = models.TrendImproved
+	case trend.Delta > 0:
+		trend.Direction = models.TrendWorsened
+	default:
+		trend.Direction = models.TrendUnchanged
+	}
+
+	// Points are only comparable within one rule engine version
+	if current.RuleModelVersion == "" || current.RuleModelVersion != previous.RuleModelVersion {
+		return trend, nil
+	}
+	trend.FactorsComparable = true
+
+	previousFactors := map[string]models.RiskContribution{}
+	for _, contribution := range previous.Contributions {
+		previousFactors[contribution.Factor] = contribution
+	}
+
+	for _, contribution := range current.Contributions {
+		before, ok := previousFactors[contribution.Factor]
+		if !ok || before.Points == contribution.Points {
+			continue
+		}
+
+		change := models.RiskFactorChange{
+			Factor:         contribution.Factor,
+			PreviousValue:  before.Value,
+			CurrentValue:   contribution.Value,
+			PreviousPoints: before.Points,
+			CurrentPoints:  contribution.Points,
+			Delta:          contribution.Points - before.Points,
+		}
+		if change.Delta < 0 {
+			trend.ImprovedFactors = append(trend.ImprovedFactors, change)
+		} else {
+			trend.WorsenedFactors = append(trend.WorsenedFactors, change)
+		}
+	}
+
+	return trend, nil
+}
@@ -0,0 +1,122 @@
+// services/assessment_questionnaire.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// ErrInvalidAnswers is returned when assessment answers do not match the questionnaire
+var ErrInvalidAnswers = errors.New("invalid assessment answers")
+
+// GetQuestionnaire returns a questionnaire version, or the current questionnaire for version 0
+func (s *AssessmentService) GetQuestionnaire(version int) (*models.Questionnaire, error) {
+	if version == 0 {
+		return s.questionnaireRepo.GetCurrentQuestionnaire()
+	}
+	return s.questionnaireRepo.GetQuestionnaire(version)
+}
+
+// prepareAnswers validates the answers of a submission against its questionnaire and
+// returns the questionnaire. Requests from clients that predate the questionnaire only
+// carry the four original fields; they are answers to version 1.
+func (s *AssessmentService) prepareAnswers(req *models.AssessmentRequest) (*models.Questionnaire, error) {
+	if len(req.Answers) == 0 {
+		if req.QuestionnaireVersion == 0 {
+			req.QuestionnaireVersion = 1
+		}
+		req.Answers = map[string]int{}
+		for key, value := range map[string]int{
+			models.QuestionScreenTime: req.ScreenTimeHours,
+			models.QuestionExercise:   req.ExerciseHours,
+			models.QuestionLateNight:  req.LateNightFrequency,
+			models.QuestionDiet:       req.DietQuality,
+		} {
+			if value != 0 {
+				req.Answers[key] = value
+			}
+		}
+	}
+
+	questionnaire, err := s.GetQuestionnaire(req.QuestionnaireVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateAnswers(questionnaire, req.Answers); err != nil {
+		return nil, err
+	}
+
+	req.QuestionnaireVersion = questionnaire.Version
+	req.ScreenTimeHours = req.Answers[models.QuestionScreenTime]
+	req.ExerciseHours = req.Answers[models.QuestionExercise]
+	req.LateNightFrequency = req.Answers[models.QuestionLateNight]
+	req.DietQuality = req.Answers[models.QuestionDiet]
+
+	return questionnaire, nil
+}
+
+// validateAnswers checks that every required question is answered, that answers are
+// allowed options and that no answer is given to an unknown question
+func validateAnswers(questionnaire *models.Questionnaire, answers map[string]int) error {
+	known := map[string]bool{}
+
+	for _, question := range questionnaire.Questions {
+		known[question.Key] = true
+
+		answer, ok := answers[question.Key]
+		if !ok {
+			if question.Required {
+				return fmt.Errorf("%w: %s is required", ErrInvalidAnswers, question.Key)
+			}
+			continue
+		}
+
+		if answerOption(question, answer) == nil {
+			return fmt.Errorf("%w: %d is not an option for %s", ErrInvalidAnswers, answer, question.Key)
+		}
+	}
+
+	var unknown []string
+	for key := range answers {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: questionnaire version %d has no question %s",
+			ErrInvalidAnswers, questionnaire.Version, strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// answerOption returns the option of a question with the given value, or nil
+func answerOption(question models.Question, value int) *models.AnswerOption {
+	for i := range question.Options {
+		if question.Options[i].Value == value {
+			return &question.Options[i]
+		}
+	}
+	return nil
+}
+
+// describeAnswers renders the answers as "Topic: description" lines for the prompt
+func describeAnswers(questionnaire *models.Questionnaire, answers map[string]int) string {
+	var lines []string
+	for _, question := range questionnaire.Questions {
+		answer, ok := answers[question.Key]
+		if !ok {
+			continue
+		}
+		if option := answerOption(question, answer); option != nil {
+			lines = append(lines, fmt.Sprintf("%s: %s", question.Topic, option.Description))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -1,65 +1,84 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
-	"strings"
 	"time"
 
+	"github.com/habdil/sigap-app/backend/llm"
 	"github.com/habdil/sigap-app/backend/models"
 	"github.com/habdil/sigap-app/backend/repository"
+	"github.com/habdil/sigap-app/backend/risk"
 )
 
 // AssessmentService handles assessment business logic
 type AssessmentService struct {
-	assessmentRepo *repository.AssessmentRepository
-	userRepo       *repository.UserRepository
+	assessmentRepo    *repository.AssessmentRepository
+	questionnaireRepo *repository.QuestionnaireRepository
+	userRepo          *repository.UserRepository
+	activityRepo      *repository.ActivityRepository
+	llm               llm.Provider
+	policy            reassessmentPolicy
 }
 
 // NewAssessmentService creates a new AssessmentService
 func NewAssessmentService() *AssessmentService {
 	return &AssessmentService{
-		assessmentRepo: repository.NewAssessmentRepository(),
-		userRepo:       repository.NewUserRepository(),
+		assessmentRepo:    repository.NewAssessmentRepository(),
+		questionnaireRepo: repository.NewQuestionnaireRepository(),
+		userRepo:          repository.NewUserRepository(),
+		activityRepo:      repository.NewActivityRepository(),
+		llm:               llm.NewForFeature(llm.FeatureAssessment),
+		policy:            reassessmentPolicyFromEnv(),
 	}
 }
 
 // SubmitAssessment submits a new assessment and gets the risk analysis
 func (s *AssessmentService) SubmitAssessment(userID int, req *models.AssessmentRequest) (*models.AssessmentResponse, error) {
+	// Validate the answers against the questionnaire version
+	questionnaire, err := s.prepareAnswers(req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get user details
 	user, err := s.userRepo.GetUserByID(userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create assessment record
+	// Score the assessment with the rule engine, which explains every percentage point
+	score, err := s.scoreRisk(user, questionnaire, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create assessment record, remembering the weight for the reassessment policy
+	req.WeightKG = user.Weight
 	assessment, err := s.assessmentRepo.CreateAssessment(userID, req)
 	if err != nil {
 		return nil, err
 	}
 
 	// Call the AI Model for risk analysis
-	riskPercentage, riskFactors, recommendations, err := s.analyzeRisk(user, req)
+	result, err := s.analyzeRisk(user, questionnaire, req, score)
 	if err != nil {
 		log.Printf("Error analyzing risk: %v", err)
-		// Default values in case of error
-		riskPercentage = 50
-		riskFactors = []string{"Error analyzing risk", "Data not sufficient", "Try again later"}
-		recommendations = []string{"Complete your profile", "Try assessment again later", "Contact support if problem persists"}
+		// Fall back to the rule engine
+		result = ruleEngineResult(req, score)
 	}
 
+	result.UserID = userID
+	result.AssessmentID = assessment.ID
+	result.RuleScore = &score.Percentage
+	result.RuleModelVersion = score.Version
+	result.Contributions = score.Contributions
+
 	// Save the risk assessment result
-	result, err := s.assessmentRepo.CreateRiskAssessmentResult(
-		userID,
-		assessment.ID,
-		riskPercentage,
-		riskFactors,
-		recommendations,
-	)
+	result, err = s.assessmentRepo.CreateRiskAssessmentResult(result)
 	if err != nil {
 		return nil, err
 	}
@@ -97,23 +116,46 @@ func (s *AssessmentService) GetLatestAssessment(userID int) (*models.AssessmentR
 }
 
 // GetAssessmentHistory retrieves the assessment history for a user
-func (s *AssessmentService) GetAssessmentHistory(userID int) ([]models.AssessmentResponse, error) {
-	return s.assessmentRepo.GetAssessmentHistory(userID)
+func (s *AssessmentService) GetAssessmentHistory(userID int, opts models.ListOptions) ([]models.AssessmentResponse, string, error) {
+	return s.assessmentRepo.GetAssessmentHistory(userID, opts)
 }
 
-// analyzeRisk calls the Gemini AI model to analyze the risk
-func (s *AssessmentService) analyzeRisk(user *models.User, req *models.AssessmentRequest) (int, []string, []string, error) {
-	// Get the Gemini API key from environment variables
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return 0, nil, nil, fmt.Errorf("GEMINI_API_KEY environment variable is not set")
+// scoreRisk scores the assessment with the rule engine model of its questionnaire version
+func (s *AssessmentService) scoreRisk(user *models.User, questionnaire *models.Questionnaire, req *models.AssessmentRequest) (risk.Result, error) {
+	model, err := risk.ForQuestionnaire(questionnaire.Version)
+	if err != nil {
+		return risk.Result{}, err
 	}
 
-	// Prepare the prompt for Gemini
-	screenTimeDesc := getScreenTimeDescription(req.ScreenTimeHours)
-	exerciseDesc := getExerciseDescription(req.ExerciseHours)
-	lateNightDesc := getLateNightDescription(req.LateNightFrequency)
-	dietDesc := getDietDescription(req.DietQuality)
+	age := user.Age
+	if !user.DateOfBirth.IsZero() {
+		age = ageOn(user.DateOfBirth, time.Now())
+	}
+
+	return model.Score(risk.Input{
+		Age:      age,
+		HeightCM: user.Height,
+		WeightKG: user.Weight,
+		Answers:  req.Answers,
+	}), nil
+}
+
+// ruleEngineResult reports the rule engine score, for when the AI model gives no usable answer
+func ruleEngineResult(req *models.AssessmentRequest, score risk.Result) *models.RiskAssessmentResult {
+	return &models.RiskAssessmentResult{
+		RiskPercentage:  score.Percentage,
+		RiskFactors:     generateRiskFactors(req),
+		Recommendations: generateRecommendations(req),
+		Source:          models.RiskSourceRuleEngine,
+		ModelVersion:    score.Version,
+	}
+}
+
+// analyzeRisk calls the configured AI model to analyze the risk, falling back to the
+// rule engine score when the model answer is empty or unusable
+func (s *AssessmentService) analyzeRisk(user *models.User, questionnaire *models.Questionnaire, req *models.AssessmentRequest, score risk.Result) (*models.RiskAssessmentResult, error) {
+	// Prepare the prompt for the model from the questionnaire's answer descriptions
+	answers := describeAnswers(questionnaire, req.Answers)
 
 	// Use default values if user profile is incomplete
 	age := 30
@@ -133,10 +175,7 @@ func (s *AssessmentService) analyzeRisk(user *models.User, req *models.Assessmen
 
 	prompt := fmt.Sprintf(`You are a stroke risk assessment AI. Analyze this user profile:
 Age: %d, Height: %.2f cm, Weight: %.2f kg
-Screen time: %s
-Exercise: %s
-Late night habits: %s
-Diet: %s
+%s
 
 Return ONLY a JSON object with these fields:
 - risk_percentage: an integer from 0-100
@@ -145,138 +184,24 @@ Return ONLY a JSON object with these fields:
 
 Example response format:
 {"risk_percentage": 65, "risk_factors": ["factor1", "factor2", "factor3"], "recommendations": ["recommendation1", "recommendation2", "recommendation3"]}
-`, age, height, weight, screenTimeDesc, exerciseDesc, lateNightDesc, dietDesc)
-
-	// Create the request to Gemini API - using gemini-2.0-flash as shown in the documentation
-	url := "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent?key=" + apiKey
-
-	requestBody := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]interface{}{
-					{
-						"text": prompt,
-					},
-				},
-			},
-		},
-	}
-
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return 0, nil, nil, err
-	}
-
-	// Log the request body for debugging
-	log.Printf("Gemini API request body: %s", string(jsonBody))
-
-	// Make the request to Gemini API
-	req2, err := http.NewRequest("POST", url, strings.NewReader(string(jsonBody)))
-	if err != nil {
-		return 0, nil, nil, err
-	}
-	req2.Header.Set("Content-Type", "application/json")
+`, age, height, weight, answers)
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req2)
+	// Ask the configured language model for the risk analysis
+	text, err := s.llm.GenerateText(context.Background(), prompt, llm.Options{})
 	if err != nil {
-		return 0, nil, nil, err
-	}
-	defer resp.Body.Close()
-
-	// Read the response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 0, nil, nil, err
-	}
-
-	// Log the raw response for debugging
-	log.Printf("Gemini API raw response: %s", string(body))
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return 0, nil, nil, fmt.Errorf("gemini API returned status code %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse the Gemini response
-	var geminiResponse map[string]interface{}
-	if err := json.Unmarshal(body, &geminiResponse); err != nil {
-		return 0, nil, nil, fmt.Errorf("failed to parse Gemini response: %v", err)
-	}
-
-	// Extract the text content from Gemini's response
-	var text string
-	candidates, ok := geminiResponse["candidates"].([]interface{})
-	if !ok || len(candidates) == 0 {
-		// Fallback to heuristic when no candidates
-		riskPercentage := calculateRiskScore(user, req)
-		riskFactors := generateRiskFactors(req)
-		recommendations := generateRecommendations(req)
-		return riskPercentage, riskFactors, recommendations, nil
-	}
-
-	// Navigate the response structure to find the text
-	firstCandidate, ok := candidates[0].(map[string]interface{})
-	if !ok {
-		// Fallback to heuristic when invalid format
-		riskPercentage := calculateRiskScore(user, req)
-		riskFactors := generateRiskFactors(req)
-		recommendations := generateRecommendations(req)
-		return riskPercentage, riskFactors, recommendations, nil
-	}
-
-	content, ok := firstCandidate["content"].(map[string]interface{})
-	if !ok {
-		// Fallback to heuristic when content not found
-		riskPercentage := calculateRiskScore(user, req)
-		riskFactors := generateRiskFactors(req)
-		recommendations := generateRecommendations(req)
-		return riskPercentage, riskFactors, recommendations, nil
-	}
-
-	parts, ok := content["parts"].([]interface{})
-	if !ok || len(parts) == 0 {
-		// Fallback to heuristic when parts not found
-		riskPercentage := calculateRiskScore(user, req)
-		riskFactors := generateRiskFactors(req)
-		recommendations := generateRecommendations(req)
-		return riskPercentage, riskFactors, recommendations, nil
-	}
-
-	for _, part := range parts {
-		partMap, ok := part.(map[string]interface{})
-		if !ok {
-			continue
+		if !errors.Is(err, llm.ErrEmptyResponse) {
+			return nil, err
 		}
 
-		if t, ok := partMap["text"].(string); ok {
-			text = t
-			break
-		}
+		// Fallback to the rule engine when the model returned no text
+		return ruleEngineResult(req, score), nil
 	}
 
-	if text == "" {
-		// Fallback to heuristic when no text found
-		riskPercentage := calculateRiskScore(user, req)
-		riskFactors := generateRiskFactors(req)
-		recommendations := generateRecommendations(req)
-		return riskPercentage, riskFactors, recommendations, nil
-	}
+	// Log the raw response for debugging
+	log.Printf("%s raw response: %s", s.llm.Name(), text)
 
 	// Clean the text and extract JSON
-	text = strings.TrimSpace(text)
-
-	// Remove markdown code blocks if present (```json and ```)
-	text = strings.ReplaceAll(text, "```json", "")
-	text = strings.ReplaceAll(text, "```", "")
-
-	// Find JSON content (handles cases where there might be text before or after the JSON)
-	jsonStart := strings.Index(text, "{")
-	jsonEnd := strings.LastIndex(text, "}")
-
-	if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
-		text = text[jsonStart : jsonEnd+1]
-	}
+	text = llm.ExtractJSON(text)
 
 	log.Printf("Extracted JSON text: %s", text)
 
@@ -288,14 +213,16 @@ Example response format:
 	}
 
 	if err := json.Unmarshal([]byte(text), &result); err != nil {
-		log.Printf("Error parsing JSON from Gemini response: %v, raw text: %s", err, text)
+		log.Printf("Error parsing JSON from %s response: %v, raw text: %s", s.llm.Name(), err, text)
 
-		// Fallback: Try to create a simple heuristic assessment
-		riskPercentage := calculateRiskScore(user, req)
-		riskFactors := generateRiskFactors(req)
-		recommendations := generateRecommendations(req)
+		// Fallback to the rule engine
+		return ruleEngineResult(req, score), nil
+	}
 
-		return riskPercentage, riskFactors, recommendations, nil
+	// A percentage outside 0-100 means the answer cannot be trusted
+	if result.RiskPercentage < 0 || result.RiskPercentage > 100 {
+		log.Printf("%s returned an invalid risk percentage %d", s.llm.Name(), result.RiskPercentage)
+		return ruleEngineResult(req, score), nil
 	}
 
 	// Ensure risk factors is never nil or empty
@@ -303,11 +230,6 @@ Example response format:
 		result.RiskFactors = generateRiskFactors(req)
 	}
 
-	// Validate the results
-	if result.RiskPercentage < 0 || result.RiskPercentage > 100 {
-		result.RiskPercentage = calculateRiskScore(user, req) // Use heuristic if outside valid range
-	}
-
 	// Ensure we have at least one risk factor and recommendation
 	if result.RiskFactors == nil || len(result.RiskFactors) == 0 {
 		result.RiskFactors = generateRiskFactors(req)
@@ -365,51 +287,41 @@ Example response format:
 		}
 	}
 
-	return result.RiskPercentage, result.RiskFactors, result.Recommendations, nil
+	return &models.RiskAssessmentResult{
+		RiskPercentage:  result.RiskPercentage,
+		RiskFactors:     result.RiskFactors,
+		Recommendations: result.Recommendations,
+		Source:          models.RiskSourceLLM,
+		ModelVersion:    llm.ModelID(s.llm),
+	}, nil
 }
 
-// calculateRiskScore provides a simple heuristic for stroke risk when Gemini fails
-func calculateRiskScore(user *models.User, req *models.AssessmentRequest) int {
-	baseScore := 30 // Start with a base score
+// generateRiskFactors creates risk factors based on assessment
+func generateRiskFactors(req *models.AssessmentRequest) []string {
+	factors := []string{}
 
-	// Age factor (older = higher risk)
-	if user.Age > 60 {
-		baseScore += 20
-	} else if user.Age > 45 {
-		baseScore += 10
+	// Medical history from questionnaire version 2 comes first
+	if req.Answers["blood_pressure"] == 3 {
+		factors = append(factors, "High blood pressure")
 	}
 
-	// Screen time factor
-	baseScore += (req.ScreenTimeHours - 1) * 5
-
-	// Exercise factor (more = lower risk)
-	baseScore -= (req.ExerciseHours - 1) * 5
-
-	// Late night habits factor
-	baseScore += (req.LateNightFrequency - 1) * 5
-
-	// Diet factor
-	baseScore += (req.DietQuality - 1) * 5
-
-	// Clamp the score between 0 and 100
-	if baseScore < 0 {
-		baseScore = 0
-	} else if baseScore > 100 {
-		baseScore = 100
+	if req.Answers["diabetes"] == 3 {
+		factors = append(factors, "Diabetes")
 	}
 
-	return baseScore
-}
+	if req.Answers["smoking"] >= 3 {
+		factors = append(factors, "Smoking")
+	}
 
-// generateRiskFactors creates risk factors based on assessment
-func generateRiskFactors(req *models.AssessmentRequest) []string {
-	factors := []string{}
+	if req.Answers["family_history"] == 2 {
+		factors = append(factors, "Family history of stroke")
+	}
 
 	if req.ScreenTimeHours >= 3 {
 		factors = append(factors, "Excessive screen time")
 	}
 
-	if req.ExerciseHours <= 2 {
+	if req.ExerciseHours > 0 && req.ExerciseHours <= 2 {
 		factors = append(factors, "Insufficient physical activity")
 	}
 
@@ -438,11 +350,23 @@ func generateRiskFactors(req *models.AssessmentRequest) []string {
 func generateRecommendations(req *models.AssessmentRequest) []string {
 	recommendations := []string{}
 
+	if req.Answers["blood_pressure"] == 3 || req.Answers["blood_pressure"] == 4 {
+		recommendations = append(recommendations, "Have your blood pressure checked and follow your doctor's advice")
+	}
+
+	if req.Answers["diabetes"] >= 2 {
+		recommendations = append(recommendations, "Keep your blood sugar under control with regular check-ups")
+	}
+
+	if req.Answers["smoking"] >= 3 {
+		recommendations = append(recommendations, "Stop smoking, with help from a cessation program if needed")
+	}
+
 	if req.ScreenTimeHours >= 3 {
 		recommendations = append(recommendations, "Reduce daily screen time to less than 4 hours")
 	}
 
-	if req.ExerciseHours <= 2 {
+	if req.ExerciseHours > 0 && req.ExerciseHours <= 2 {
 		recommendations = append(recommendations, "Increase physical activity to at least 30 minutes daily")
 	}
 
@@ -470,82 +394,3 @@ func generateRecommendations(req *models.AssessmentRequest) []string {
 
 	return recommendations[:3]
 }
-
-// Helper functions to convert numeric values to descriptions
-func getScreenTimeDescription(hours int) string {
-	switch hours {
-	case 1:
-		return "just an hour"
-	case 2:
-		return "around 2-4 hours"
-	case 3:
-		return "around 5-8 hours"
-	case 4:
-		return "more than 9 hours per day"
-	default:
-		return "unknown"
-	}
-}
-
-func getExerciseDescription(hours int) string {
-	switch hours {
-	case 1:
-		return "just an hour"
-	case 2:
-		return "around 2-4 hours"
-	case 3:
-		return "around 5-8 hours"
-	case 4:
-		return "more than 9 hours per day"
-	default:
-		return "unknown"
-	}
-}
-
-func getLateNightDescription(frequency int) string {
-	switch frequency {
-	case 1:
-		return "never"
-	case 2:
-		return "once a week when tomorrow is a vacation day"
-	case 3:
-		return "about 2-4 times a week"
-	case 4:
-		return "every day without pause and continuously"
-	default:
-		return "unknown"
-	}
-}
-
-func getDietDescription(quality int) string {
-	switch quality {
-	case 1:
-		return "a regular diet with plenty of vegetables and fruit"
-	case 2:
-		return "a little messy but still consuming vegetables"
-	case 3:
-		return "messy diet and sometimes eat fast and high-fat foods"
-	case 4:
-		return "no vegetables, no fruits, only eat something like junk food"
-	default:
-		return "unknown"
-	}
-}
-
-// CheckAssessmentStatus mengecek apakah user perlu mengisi assessment baru
-func (s *AssessmentService) CheckAssessmentStatus(userID int) (bool, error) {
-	// Coba dapatkan assessment terakhir user
-	_, err := s.assessmentRepo.GetLatestAssessment(userID)
-
-	// Jika error karena tidak ada assessment, user perlu mengisi assessment baru
-	if err != nil {
-		if strings.Contains(err.Error(), "no assessment found for this user") {
-			return true, nil // true = perlu assessment baru
-		}
-		// Jika error lain, kembalikan error tersebut
-		return false, err
-	}
-
-	// User sudah punya assessment yang masih valid
-	return false, nil // false = tidak perlu assessment baru
-}
@@ -1,22 +1,35 @@
 package services
 
 import (
+	"crypto/rand"
 	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
 
 	"github.com/habdil/sigap-app/backend/models"
 	"github.com/habdil/sigap-app/backend/repository"
 	"github.com/habdil/sigap-app/backend/utils"
 )
 
+// ErrUnverifiedEmailLink is returned when a provider identity has the email of an
+// existing account but the provider does not vouch that the email is verified
+var ErrUnverifiedEmailLink = errors.New("an account with this email already exists and the email is not verified by the provider")
+
 // AuthService handles authentication business logic
 type AuthService struct {
-	userRepo *repository.UserRepository
+	userRepo        *repository.UserRepository
+	refreshRepo     *repository.RefreshTokenRepository
+	identityService *IdentityService
 }
 
 // NewAuthService creates a new instance of AuthService
 func NewAuthService() *AuthService {
 	return &AuthService{
-		userRepo: repository.NewUserRepository(),
+		userRepo:        repository.NewUserRepository(),
+		refreshRepo:     repository.NewRefreshTokenRepository(),
+		identityService: NewIdentityService(),
 	}
 }
 
@@ -57,19 +70,8 @@ func (s *AuthService) SignUp(req models.SignupRequest) (*models.AuthResponse, er
 		return nil, err
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create response
-	response := &models.AuthResponse{
-		Token: token,
-		User:  *user,
-	}
-
-	return response, nil
+	// Start a new session and issue its tokens
+	return s.newSession(user)
 }
 
 // Login authenticates a user
@@ -85,62 +87,56 @@ func (s *AuthService) Login(req models.LoginRequest) (*models.AuthResponse, erro
 		return nil, errors.New("invalid email or password")
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Create response
-	response := &models.AuthResponse{
-		Token: token,
-		User:  *user,
-	}
-
-	return response, nil
+	// Start a new session and issue its tokens
+	return s.newSession(user)
 }
 
-// GoogleLogin authenticates or creates a user using Google credentials
+// GoogleLogin authenticates or creates a user from a verified Google ID token
 func (s *AuthService) GoogleLogin(req models.GoogleLoginRequest) (*models.AuthResponse, error) {
-	// Check if user with Google ID exists
-	user, err := s.userRepo.GetUserByGoogleID(req.GoogleID)
+	// Verify the ID token and take the identity from its claims
+	identity, err := s.identityService.VerifyGoogleIDToken(req.IDToken)
 	if err != nil {
-		// User does not exist, create new user
-		emailTaken, err := s.userRepo.IsEmailTaken(req.Email)
-		if err != nil {
-			return nil, err
-		}
-		if emailTaken {
-			return nil, errors.New("email already in use by another account")
-		}
+		return nil, err
+	}
 
-		// Create user
-		user = &models.User{
-			Username: req.Username,
-			Email:    req.Email,
-			GoogleID: req.GoogleID,
-			// No password for Google login
-		}
+	// Check if user with Google ID exists
+	user, err := s.userRepo.GetUserByGoogleID(identity.GoogleID)
+	if err == nil {
+		return s.newSession(user)
+	}
 
-		// Save user to database
-		if err := s.userRepo.CreateUser(user); err != nil {
+	// Link an existing account with the same (Google verified) email
+	user, err = s.userRepo.GetUserByEmail(identity.Email)
+	if err == nil {
+		if err := s.userRepo.UpdateUserGoogleID(user.ID, identity.GoogleID); err != nil {
 			return nil, err
 		}
+		user.GoogleID = identity.GoogleID
+		return s.newSession(user)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user.ID)
+	// User does not exist, create new user
+	username, err := s.availableUsername(req.Username, identity)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create response
-	response := &models.AuthResponse{
-		Token: token,
-		User:  *user,
+	user = &models.User{
+		Username:   username,
+		Email:      identity.Email,
+		GoogleID:   identity.GoogleID,
+		FullName:   identity.Name,
+		IsVerified: true,
+		// No password for Google login
+	}
+
+	// Save user to database
+	if err := s.userRepo.CreateUser(user); err != nil {
+		return nil, err
 	}
 
-	return response, nil
+	// Start a new session and issue its tokens
+	return s.newSession(user)
 }
 
 // GetUserByID retrieves a user by ID
@@ -148,28 +144,41 @@ func (s *AuthService) GetUserByID(id int) (*models.User, error) {
 	return s.userRepo.GetUserByID(id)
 }
 
-// SupabaseAuth handles authentication with Supabase
+// SupabaseAuth handles authentication with a verified Supabase access token
 func (s *AuthService) SupabaseAuth(req models.SupabaseAuthRequest) (*models.AuthResponse, error) {
+	// Verifikasi access token dan ambil identitas dari claims
+	identity, err := s.identityService.VerifySupabaseToken(req.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
 	// Coba cari user berdasarkan supabase_uuid
-	user, err := s.userRepo.GetUserBySupabaseUUID(req.SupabaseUUID)
+	user, err := s.userRepo.GetUserBySupabaseUUID(identity.Subject)
 	if err == nil {
-		// User ditemukan, generate token
-		token, err := utils.GenerateToken(user.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		return &models.AuthResponse{
-			Token: token,
-			User:  *user,
-		}, nil
+		// User ditemukan, buat sesi baru
+		return s.newSession(user)
 	}
 
 	// Coba cari user berdasarkan email
-	user, err = s.userRepo.GetUserByEmail(req.Email)
+	user, err = s.userRepo.GetUserByEmail(identity.Email)
 	if err == nil {
+		// Only a verified email proves the token holder owns the existing account
+		if !identity.EmailVerified {
+			confirmed, err := s.identityService.SupabaseEmailConfirmed(req.AccessToken)
+			if err != nil {
+				return nil, err
+			}
+			if !confirmed {
+				return nil, ErrUnverifiedEmailLink
+			}
+		}
+
 		// User sudah ada, perbarui dengan supabase_uuid
-		err = s.userRepo.UpdateUserSupabaseInfo(user.ID, req.SupabaseUUID, req.GoogleID)
+		googleID := identity.GoogleID
+		if googleID == "" {
+			googleID = user.GoogleID
+		}
+		err = s.userRepo.UpdateUserSupabaseInfo(user.ID, identity.Subject, googleID)
 		if err != nil {
 			return nil, err
 		}
@@ -180,24 +189,21 @@ func (s *AuthService) SupabaseAuth(req models.SupabaseAuthRequest) (*models.Auth
 			return nil, err
 		}
 
-		// Generate token
-		token, err := utils.GenerateToken(user.ID)
-		if err != nil {
-			return nil, err
-		}
-
-		return &models.AuthResponse{
-			Token: token,
-			User:  *user,
-		}, nil
+		// Buat sesi baru
+		return s.newSession(user)
 	}
 
 	// User baru, buat user
+	username, err := s.availableUsername(req.Username, identity)
+	if err != nil {
+		return nil, err
+	}
+
 	user = &models.User{
-		Username:     req.Username,
-		Email:        req.Email,
-		SupabaseUUID: req.SupabaseUUID,
-		GoogleID:     req.GoogleID,
+		Username:     username,
+		Email:        identity.Email,
+		SupabaseUUID: identity.Subject,
+		GoogleID:     identity.GoogleID,
 	}
 
 	// Simpan user baru
@@ -205,14 +211,117 @@ func (s *AuthService) SupabaseAuth(req models.SupabaseAuthRequest) (*models.Auth
 		return nil, err
 	}
 
-	// Generate token
-	token, err := utils.GenerateToken(user.ID)
+	// Buat sesi baru
+	return s.newSession(user)
+}
+
+// availableUsername picks the requested username, or one derived from the identity,
+// adding a numeric suffix when it is already taken
+func (s *AuthService) availableUsername(requested string, identity *VerifiedIdentity) (string, error) {
+	base := strings.TrimSpace(requested)
+	if base == "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		taken, err := s.userRepo.IsUsernameTaken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+
+		suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s%04d", base, suffix.Int64())
+	}
+
+	return "", errors.New("could not find an available username")
+}
+
+// RefreshToken rotates a refresh token and issues a new access token for the same session
+func (s *AuthService) RefreshToken(refreshToken string) (*models.TokenResponse, error) {
+	newRefreshToken, newHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshExpiry, err := utils.RefreshTokenExpiry()
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.refreshRepo.RotateRefreshToken(utils.HashToken(refreshToken), newHash, time.Now().Add(refreshExpiry).UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.accessToken(stored.UserID, stored.FamilyID, newRefreshToken)
+}
+
+// Logout revokes the session the refresh token belongs to
+func (s *AuthService) Logout(refreshToken string) error {
+	return s.refreshRepo.RevokeFamilyByToken(utils.HashToken(refreshToken))
+}
+
+// LogoutAll revokes every session of the user, on all devices
+func (s *AuthService) LogoutAll(userID int) error {
+	return s.refreshRepo.RevokeAllForUser(userID)
+}
+
+// newSession starts a new refresh token family for the user and returns the auth response
+func (s *AuthService) newSession(user *models.User) (*models.AuthResponse, error) {
+	familyID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshExpiry, err := utils.RefreshTokenExpiry()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.refreshRepo.CreateRefreshToken(user.ID, familyID, refreshHash, time.Now().Add(refreshExpiry).UTC()); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.accessToken(user.ID, familyID, refreshToken)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.AuthResponse{
-		Token: token,
-		User:  *user,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
+	}, nil
+}
+
+// accessToken signs an access token for the session and pairs it with the refresh token
+func (s *AuthService) accessToken(userID int, familyID string, refreshToken string) (*models.TokenResponse, error) {
+	token, err := utils.GenerateToken(userID, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiry, err := utils.AccessTokenExpiry()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(expiry.Seconds()),
 	}, nil
 }
@@ -0,0 +1,82 @@
+// services/calorie_estimation.go
+package services
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// keytelMinHeartRate is the lowest average heart rate the Keytel equations were
+	// fitted on; below it they underestimate badly, so MET is used instead
+	keytelMinHeartRate = 90
+	// keytelMaxHeartRate rejects readings no heart produces
+	keytelMaxHeartRate = maxHeartRate
+	keytelMinAge       = 18
+	keytelMaxAge       = 100
+
+	sexMale   = "male"
+	sexFemale = "female"
+)
+
+// calorieProfile holds the user details the calorie formulas need
+type calorieProfile struct {
+	WeightKG float64
+	Age      int
+	Sex      string // sexMale, sexFemale or "" if unknown
+}
+
+// keytelCalories estimates calories from the average heart rate with the sex-specific
+// Keytel et al. (2005) equations. It reports false when the inputs are outside the
+// range the equations are valid for.
+func keytelCalories(heartRateAvg int, durationMinutes int, profile calorieProfile) (int, bool) {
+	if heartRateAvg < keytelMinHeartRate || heartRateAvg > keytelMaxHeartRate {
+		return 0, false
+	}
+	if profile.Age < keytelMinAge || profile.Age > keytelMaxAge || profile.WeightKG <= 0 {
+		return 0, false
+	}
+
+	hr, weight, age := float64(heartRateAvg), profile.WeightKG, float64(profile.Age)
+
+	// Energy expenditure in kJ per minute, converted to kcal
+	var kjPerMinute float64
+	switch profile.Sex {
+	case sexMale:
+		kjPerMinute = -55.0969 + 0.6309*hr + 0.1988*weight + 0.2017*age
+	case sexFemale:
+		kjPerMinute = -20.4022 + 0.4472*hr - 0.1263*weight + 0.074*age
+	default:
+		return 0, false
+	}
+
+	calories := kjPerMinute / 4.184 * float64(durationMinutes)
+	if calories <= 0 {
+		return 0, false
+	}
+
+	return int(math.Round(calories)), true
+}
+
+// normalizeSex maps the free-text gender stored on the profile to a sex for the
+// calorie equations, accepting English and Indonesian values
+func normalizeSex(gender string) string {
+	switch strings.ToLower(strings.TrimSpace(gender)) {
+	case "male", "m", "man", "laki-laki", "laki laki", "pria", "l":
+		return sexMale
+	case "female", "f", "woman", "perempuan", "wanita", "p":
+		return sexFemale
+	default:
+		return ""
+	}
+}
+
+// ageOn returns the age in whole years on the given date
+func ageOn(dateOfBirth time.Time, on time.Time) int {
+	age := on.Year() - dateOfBirth.Year()
+	if on.Month() < dateOfBirth.Month() || (on.Month() == dateOfBirth.Month() && on.Day() < dateOfBirth.Day()) {
+		age--
+	}
+	return age
+}
@@ -2,15 +2,13 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
-	"strings"
 	"time"
 
+	"github.com/habdil/sigap-app/backend/llm"
 	"github.com/habdil/sigap-app/backend/models"
 	"github.com/habdil/sigap-app/backend/repository"
 )
@@ -19,6 +17,7 @@ import (
 type ChatbotService struct {
 	chatbotRepo *repository.ChatbotRepository
 	userRepo    *repository.UserRepository
+	llm         llm.Provider
 }
 
 // NewChatbotService creates a new ChatbotService
@@ -26,6 +25,7 @@ func NewChatbotService() *ChatbotService {
 	return &ChatbotService{
 		chatbotRepo: repository.NewChatbotRepository(),
 		userRepo:    repository.NewUserRepository(),
+		llm:         llm.NewForFeature(llm.FeatureChatbot),
 	}
 }
 
@@ -60,8 +60,8 @@ func (s *ChatbotService) GetConversation(conversationID int, userID int) (*model
 }
 
 // GetUserConversations retrieves all conversations for a user
-func (s *ChatbotService) GetUserConversations(userID int) ([]models.ChatbotConversation, error) {
-	return s.chatbotRepo.GetUserConversations(userID)
+func (s *ChatbotService) GetUserConversations(userID int, opts models.ListOptions) ([]models.ChatbotConversation, string, error) {
+	return s.chatbotRepo.GetUserConversations(userID, opts)
 }
 
 // SendMessage sends a user message and gets a bot response
@@ -72,19 +72,8 @@ func (s *ChatbotService) SendMessage(userID int, conversationID int, content str
 		return nil, nil, fmt.Errorf("failed to add user message: %v", err)
 	}
 
-	// Get user information for context
-	user, err := s.userRepo.GetUserByID(userID)
-	if err != nil {
-		log.Printf("Warning: Could not get user info: %v", err)
-		// Continue with minimal context
-	}
-
-	// Get conversation history for context
-	messages, err := s.chatbotRepo.GetMessagesByConversation(conversationID)
-	if err != nil {
-		log.Printf("Warning: Could not get conversation history: %v", err)
-		// Continue with minimal context
-	}
+	// Get user information and conversation history for context
+	user, messages := s.conversationContext(userID, conversationID)
 
 	// Generate bot response
 	botResponse, err := s.generateBotResponse(content, user, messages)
@@ -110,216 +99,151 @@ func (s *ChatbotService) SendMessage(userID int, conversationID int, content str
 	return userMessage, botMessage, nil
 }
 
-// GetMessages gets all messages for a conversation
-func (s *ChatbotService) GetMessages(conversationID int, userID int) ([]models.ChatMessage, error) {
-	// First check if the user has access to this conversation
-	conversation, err := s.chatbotRepo.GetConversation(conversationID, userID)
+// AddUserMessage stores a user message before its reply is streamed
+func (s *ChatbotService) AddUserMessage(userID int, conversationID int, content string) (*models.ChatMessage, error) {
+	userMessage, err := s.chatbotRepo.AddMessage(userID, conversationID, content, "user", nil)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to add user message: %v", err)
 	}
 
-	if conversation.UserID != userID {
-		return nil, fmt.Errorf("access denied to conversation")
-	}
-
-	return s.chatbotRepo.GetMessagesByConversation(conversationID)
-}
-
-// DeleteConversation deletes a conversation
-func (s *ChatbotService) DeleteConversation(conversationID int, userID int) error {
-	return s.chatbotRepo.DeleteConversation(conversationID, userID)
+	return userMessage, nil
 }
 
-// generateBotResponse calls the AI API to generate a response
-func (s *ChatbotService) generateBotResponse(userMessage string, user *models.User, conversationHistory []models.ChatMessage) (string, error) {
-	// Get the Gemini API key from environment variables
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return "", fmt.Errorf("GEMINI_API_KEY environment variable is not set")
-	}
+// StreamBotResponse generates the reply to a stored user message, passing each chunk
+// to onChunk as it arrives. The bot message is saved once the stream completes, fails
+// or is cancelled through ctx, so an interrupted reply is kept with what was received.
+func (s *ChatbotService) StreamBotResponse(ctx context.Context, userID int, conversationID int, userMessage *models.ChatMessage, onChunk func(chunk string) error) (*models.ChatMessage, error) {
+	user, messages := s.conversationContext(userID, conversationID)
 
-	// Buat format payload yang benar untuk Gemini 1.5
-	// Format ini berbeda dari versi sebelumnya yang menggunakan role
-	systemPrompt := "Kamu adalah AI Health Assistant untuk aplikasi SIGAP, fokus pada gaya hidup sehat dan pencegahan stroke. " +
-		"Berikan saran tentang olahraga, nutrisi, dan manajemen stres. " +
-		"Jawab dengan sopan, informatif, jangan di bold responnya apapun pertanyaan saya dan singkat dalam bahasa Inggris. " +
-		"Jangan lupa untuk sesekali mengingatkan pentingnya aktivitas fisik, pola makan sehat, dan istirahat yang cukup."
+	botResponse, err := s.llm.ChatStream(ctx, buildSystemPrompt(user), buildChatHistory(userMessage.Content, messages), chatOptions, onChunk)
 
-	// Format untuk Gemini 1.5 menggunakan parts dalam contents
-	contents := []map[string]interface{}{
-		{
-			"parts": []map[string]interface{}{
-				{
-					"text": systemPrompt,
-				},
-			},
-			"role": "user", // Untuk Gemini 1.5, peran sistem diganti dengan user
-		},
+	metadata := map[string]interface{}{
+		"time_ms":  time.Since(userMessage.CreatedAt).Milliseconds(),
+		"streamed": true,
 	}
 
-	// Tambahkan informasi pengguna jika tersedia
-	if user != nil {
-		userContext := fmt.Sprintf("Informasi pengguna: Usia: %d, Tinggi: %.1f cm, Berat: %.1f kg.",
-			user.Age, user.Height, user.Weight)
-
-		contents = append(contents, map[string]interface{}{
-			"parts": []map[string]interface{}{
-				{
-					"text": userContext,
-				},
-			},
-			"role": "user",
-		})
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		log.Printf("Bot response stream cancelled after %d characters", len(botResponse))
+		metadata["cancelled"] = true
+	case botResponse == "":
+		log.Printf("Error streaming bot response: %v", err)
+		// Use fallback response
+		botResponse = "Maaf, saya mengalami kendala dalam memproses permintaan Anda. Mohon coba lagi."
+		metadata["error"] = true
+	default:
+		log.Printf("Bot response stream interrupted: %v", err)
+		metadata["interrupted"] = true
 	}
 
-	// Tambahkan histori percakapan (maksimal 5 pesan terakhir)
-	historyLimit := 5
-	if len(conversationHistory) > 0 {
-		startIdx := 0
-		if len(conversationHistory) > historyLimit {
-			startIdx = len(conversationHistory) - historyLimit
-		}
-
-		for _, msg := range conversationHistory[startIdx:] {
-			role := "user"
-			if msg.SenderType == "bot" {
-				role = "model" // Untuk Gemini 1.5, peran assistant diganti dengan model
-			}
-
-			contents = append(contents, map[string]interface{}{
-				"parts": []map[string]interface{}{
-					{
-						"text": msg.Content,
-					},
-				},
-				"role": role,
-			})
-		}
+	if botResponse == "" {
+		// Nothing was generated before the client went away
+		return nil, err
 	}
 
-	// Tambahkan pesan pengguna saat ini
-	contents = append(contents, map[string]interface{}{
-		"parts": []map[string]interface{}{
-			{
-				"text": userMessage,
-			},
-		},
-		"role": "user",
-	})
-
-	// Buat request untuk Gemini API
-	url := "https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:generateContent?key=" + apiKey
-
-	requestBody := map[string]interface{}{
-		"contents": contents,
-		"safetySettings": []map[string]interface{}{
-			{
-				"category":  "HARM_CATEGORY_HARASSMENT",
-				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
-			},
-			{
-				"category":  "HARM_CATEGORY_HATE_SPEECH",
-				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
-			},
-			{
-				"category":  "HARM_CATEGORY_SEXUALLY_EXPLICIT",
-				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
-			},
-			{
-				"category":  "HARM_CATEGORY_DANGEROUS_CONTENT",
-				"threshold": "BLOCK_MEDIUM_AND_ABOVE",
-			},
-		},
-		"generationConfig": map[string]interface{}{
-			"temperature":     0.7,
-			"topP":            0.8,
-			"topK":            40,
-			"maxOutputTokens": 1024,
-		},
+	botMessage, addErr := s.chatbotRepo.AddMessage(userID, conversationID, botResponse, "bot", metadata)
+	if addErr != nil {
+		return nil, fmt.Errorf("failed to add bot message: %v", addErr)
 	}
 
-	// Tambahkan logging untuk debug
-	jsonBodyDebug, _ := json.MarshalIndent(requestBody, "", "  ")
-	log.Printf("Request to Gemini API: %s", string(jsonBodyDebug))
+	return botMessage, nil
+}
 
-	jsonBody, err := json.Marshal(requestBody)
+// conversationContext loads the user profile and message history used to prompt the model
+func (s *ChatbotService) conversationContext(userID int, conversationID int) (*models.User, []models.ChatMessage) {
+	// Get user information for context
+	user, err := s.userRepo.GetUserByID(userID)
 	if err != nil {
-		return "", err
+		log.Printf("Warning: Could not get user info: %v", err)
+		// Continue with minimal context
 	}
 
-	req, err := http.NewRequest("POST", url, strings.NewReader(string(jsonBody)))
+	// Get conversation history for context
+	messages, _, err := s.chatbotRepo.GetMessagesByConversation(conversationID, models.ListOptions{Ascending: true})
 	if err != nil {
-		return "", err
+		log.Printf("Warning: Could not get conversation history: %v", err)
+		// Continue with minimal context
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{Timeout: 20 * time.Second}
-	resp, err := client.Do(req)
+	return user, messages
+}
+
+// GetMessages gets the messages of a conversation and the cursor of the next page
+func (s *ChatbotService) GetMessages(conversationID int, userID int, opts models.ListOptions) ([]models.ChatMessage, string, error) {
+	// First check if the user has access to this conversation
+	conversation, err := s.chatbotRepo.GetConversation(conversationID, userID)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
-	defer resp.Body.Close()
 
-	// Baca respons
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
+	if conversation.UserID != userID {
+		return nil, "", fmt.Errorf("access denied to conversation")
 	}
 
-	// Tambahkan logging respons untuk debug
-	log.Printf("Response from Gemini API: %s", string(body))
+	return s.chatbotRepo.GetMessagesByConversation(conversationID, opts)
+}
 
-	// Periksa status respons
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Gemini API returned status code %d: %s", resp.StatusCode, string(body))
-	}
+// DeleteConversation deletes a conversation
+func (s *ChatbotService) DeleteConversation(conversationID int, userID int) error {
+	return s.chatbotRepo.DeleteConversation(conversationID, userID)
+}
 
-	// Parse respons
-	var geminiResponse map[string]interface{}
-	if err := json.Unmarshal(body, &geminiResponse); err != nil {
-		return "", fmt.Errorf("failed to parse API response: %v", err)
+// generateBotResponse calls the AI API to generate a response
+func (s *ChatbotService) generateBotResponse(userMessage string, user *models.User, conversationHistory []models.ChatMessage) (string, error) {
+	text, err := s.llm.Chat(context.Background(), buildSystemPrompt(user), buildChatHistory(userMessage, conversationHistory), chatOptions)
+	if errors.Is(err, llm.ErrEmptyResponse) {
+		// Jika tidak ada teks yang ditemukan, kembalikan respons default
+		return "Maaf, saya tidak dapat memberikan respons saat ini. Silakan coba lagi nanti.", nil
 	}
 
-	// Ekstrak teks dari respons
-	candidates, ok := geminiResponse["candidates"].([]interface{})
-	if !ok || len(candidates) == 0 {
-		return "", fmt.Errorf("no candidates in response")
-	}
+	return text, err
+}
 
-	candidate, ok := candidates[0].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("invalid candidate format")
-	}
+// chatOptions are the generation settings used for chatbot replies
+var chatOptions = llm.Options{
+	Temperature:     0.7,
+	TopP:            0.8,
+	TopK:            40,
+	MaxOutputTokens: 1024,
+}
 
-	content, ok := candidate["content"].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("no content in response")
-	}
+// buildSystemPrompt creates the assistant instructions, including user context when available
+func buildSystemPrompt(user *models.User) string {
+	systemPrompt := "Kamu adalah AI Health Assistant untuk aplikasi SIGAP, fokus pada gaya hidup sehat dan pencegahan stroke. " +
+		"Berikan saran tentang olahraga, nutrisi, dan manajemen stres. " +
+		"Jawab dengan sopan, informatif, jangan di bold responnya apapun pertanyaan saya dan singkat dalam bahasa Inggris. " +
+		"Jangan lupa untuk sesekali mengingatkan pentingnya aktivitas fisik, pola makan sehat, dan istirahat yang cukup."
 
-	parts, ok := content["parts"].([]interface{})
-	if !ok || len(parts) == 0 {
-		return "", fmt.Errorf("no parts in content")
+	// Tambahkan informasi pengguna jika tersedia
+	if user != nil {
+		systemPrompt += fmt.Sprintf("\nInformasi pengguna: Usia: %d, Tinggi: %.1f cm, Berat: %.1f kg.",
+			user.Age, user.Height, user.Weight)
 	}
 
-	// Log untuk debug
-	log.Printf("Parts from response: %+v", parts)
+	return systemPrompt
+}
 
-	for _, part := range parts {
-		partMap, ok := part.(map[string]interface{})
-		if !ok {
-			log.Printf("Part is not a map: %+v", part)
-			continue
-		}
+// buildChatHistory converts the last stored messages plus the new user message into model turns
+func buildChatHistory(userMessage string, conversationHistory []models.ChatMessage) []llm.Message {
+	// Tambahkan histori percakapan (maksimal 5 pesan terakhir)
+	historyLimit := 5
+	startIdx := 0
+	if len(conversationHistory) > historyLimit {
+		startIdx = len(conversationHistory) - historyLimit
+	}
 
-		text, ok := partMap["text"].(string)
-		if !ok {
-			log.Printf("Part does not contain text: %+v", partMap)
-			continue
+	history := make([]llm.Message, 0, historyLimit+1)
+	for _, msg := range conversationHistory[startIdx:] {
+		role := llm.RoleUser
+		if msg.SenderType == "bot" {
+			role = llm.RoleAssistant
 		}
-
-		return text, nil
+		history = append(history, llm.Message{Role: role, Content: msg.Content})
 	}
 
-	// Jika tidak ada teks yang ditemukan, kembalikan respons default
-	return "Maaf, saya tidak dapat memberikan respons saat ini. Silakan coba lagi nanti.", nil
+	// Tambahkan pesan pengguna saat ini
+	history = append(history, llm.Message{Role: llm.RoleUser, Content: userMessage})
+
+	return history
 }
@@ -9,12 +9,14 @@ import (
 // CoinService handles coin-related business logic
 type CoinService struct {
 	coinRepo *repository.CoinRepository
+	userRepo *repository.UserRepository
 }
 
 // NewCoinService creates a new CoinService instance
 func NewCoinService() *CoinService {
 	return &CoinService{
 		coinRepo: repository.NewCoinRepository(),
+		userRepo: repository.NewUserRepository(),
 	}
 }
 
@@ -23,17 +25,26 @@ func (s *CoinService) GetUserCoins(userID int) (*models.UserCoins, error) {
 	return s.coinRepo.GetUserCoins(userID)
 }
 
-// AddCoins adds coins to a user's balance
-func (s *CoinService) AddCoins(userID int, req *models.AddCoinsRequest) error {
-	return s.coinRepo.AddCoins(userID, req.Amount, req.TransactionType, req.ReferenceID, req.ReferenceType)
+// GrantCoins credits coins to a user on behalf of an admin
+func (s *CoinService) GrantCoins(adminID int, req *models.GrantCoinsRequest) (*models.CoinGrant, error) {
+	if _, err := s.userRepo.GetUserByID(req.UserID); err != nil {
+		return nil, err
+	}
+
+	return s.coinRepo.GrantCoins(adminID, req.UserID, req.Amount, req.Reason)
 }
 
-// SpendCoins deducts coins from a user's balance
-func (s *CoinService) SpendCoins(userID int, req *models.SpendCoinsRequest) error {
-	return s.coinRepo.SpendCoins(userID, req.Amount, req.TransactionType, req.ReferenceID, req.ReferenceType)
+// SpendCoins deducts coins from a user's balance.
+// A non-empty idempotency key makes retries of the same spend a no-op.
+func (s *CoinService) SpendCoins(userID int, req *models.SpendCoinsRequest, idempotencyKey string) error {
+	if idempotencyKey != "" {
+		idempotencyKey = "spend:" + idempotencyKey
+	}
+
+	return s.coinRepo.SpendCoins(userID, req.Amount, req.TransactionType, req.ReferenceID, req.ReferenceType, idempotencyKey)
 }
 
 // GetTransactionHistory retrieves a user's coin transaction history
-func (s *CoinService) GetTransactionHistory(userID int) ([]models.CoinTransaction, error) {
-	return s.coinRepo.GetTransactionHistory(userID)
+func (s *CoinService) GetTransactionHistory(userID int, opts models.ListOptions) ([]models.CoinTransaction, string, error) {
+	return s.coinRepo.GetTransactionHistory(userID, opts)
 }
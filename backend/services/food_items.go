@@ -0,0 +1,137 @@
+// services/food_items.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+// ErrInvalidFoodItemCorrection is returned for corrections without a new name or portion
+var ErrInvalidFoodItemCorrection = errors.New("a correction needs a non-empty name or portion_grams")
+
+// defaultItemConfidence is used for items the model gave no usable confidence for
+const defaultItemConfidence = 0.5
+
+func itemConfidence(confidence float64) float64 {
+	if confidence <= 0 || confidence > 1 {
+		return defaultItemConfidence
+	}
+	return confidence
+}
+
+// applyItemTotals sets the analysis totals, detected item names and confidence from its
+// items. The confidence is the mean of the items' confidences weighted by calories, so a
+// doubtful sambal weighs less than a doubtful portion of rice.
+func applyItemTotals(analysis *models.FoodAnalysis) {
+	var calories, protein, carbs, fat, fiber, weightedConfidence, plainConfidence float64
+	var sugar, sodium, saturatedFat, cholesterol *float64
+	names := make([]string, 0, len(analysis.Items))
+
+	for _, item := range analysis.Items {
+		calories += item.Calories
+		protein += item.ProteinGrams
+		carbs += item.CarbsGrams
+		fat += item.FatGrams
+		fiber += item.FiberGrams
+		sugar = addOptional(sugar, item.SugarGrams)
+		sodium = addOptional(sodium, item.SodiumMg)
+		saturatedFat = addOptional(saturatedFat, item.SaturatedFatGrams)
+		cholesterol = addOptional(cholesterol, item.CholesterolMg)
+		weightedConfidence += item.Confidence * item.Calories
+		plainConfidence += item.Confidence
+		names = append(names, item.Name)
+	}
+
+	analysis.Calories = int(math.Round(calories))
+	analysis.ProteinGrams = roundTenth(protein)
+	analysis.CarbsGrams = roundTenth(carbs)
+	analysis.FatGrams = roundTenth(fat)
+	analysis.FiberGrams = roundTenth(fiber)
+	analysis.SugarGrams = roundOptional(sugar)
+	analysis.SodiumMg = roundOptional(sodium)
+	analysis.SaturatedFatGrams = roundOptional(saturatedFat)
+	analysis.CholesterolMg = roundOptional(cholesterol)
+	analysis.DetectedItems, _ = json.Marshal(names)
+
+	switch {
+	case calories > 0:
+		analysis.AIConfidence = math.Round(weightedConfidence/calories*100) / 100
+	case len(analysis.Items) > 0:
+		analysis.AIConfidence = math.Round(plainConfidence/float64(len(analysis.Items))*100) / 100
+	}
+}
+
+func roundTenth(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+// addOptional adds an amount that may not have been estimated. The sum stays nil only
+// when no amount was estimated.
+func addOptional(sum *float64, value *float64) *float64 {
+	if value == nil {
+		return sum
+	}
+	if sum == nil {
+		return optional(*value)
+	}
+	return optional(*sum + *value)
+}
+
+// scaleOptional scales an amount that may not have been estimated
+func scaleOptional(value *float64, scale float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	return optional(roundTenth(*value * scale))
+}
+
+func roundOptional(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	return optional(roundTenth(*value))
+}
+
+func optional(value float64) *float64 {
+	return &value
+}
+
+// CorrectFoodItem applies the user's correction of a detected item and returns the
+// analysis with its totals recomputed. A new portion scales the item's nutrients in
+// proportion to the first estimate, however often it is corrected; a new name keeps them. The healthiness score is the model's judgement of
+// the whole meal and is kept as is.
+func (s *FoodService) CorrectFoodItem(userID int, foodLogID int, itemID int, correction *models.FoodItemCorrection) (*models.FoodAnalysis, error) {
+	if correction.Name != nil {
+		name := strings.TrimSpace(*correction.Name)
+		if name == "" {
+			return nil, ErrInvalidFoodItemCorrection
+		}
+		correction.Name = &name
+	}
+	if correction.Name == nil && correction.PortionGrams == nil {
+		return nil, ErrInvalidFoodItemCorrection
+	}
+
+	foodLog, err := s.foodRepo.GetFoodLogByID(foodLogID)
+	if err != nil {
+		return nil, err
+	}
+	// Other users' logs are reported as missing rather than forbidden
+	if foodLog.UserID != userID {
+		return nil, repository.ErrFoodLogNotFound
+	}
+	if foodLog.Analysis == nil {
+		return nil, repository.ErrFoodItemNotFound
+	}
+
+	if _, err := s.foodRepo.CorrectFoodItem(foodLog.Analysis.ID, itemID, correction); err != nil {
+		return nil, err
+	}
+
+	return s.foodRepo.GetFoodAnalysisByLogID(foodLogID)
+}
@@ -0,0 +1,132 @@
+// services/food_limits.go
+package services
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// Warning levels of a day's nutrient intake
+const (
+	nutrientNearLimit = "near_limit"
+	nutrientOverLimit = "over_limit"
+)
+
+// nearLimitShare is the share of a daily limit from which the intake is reported
+const nearLimitShare = 0.8
+
+// nutrientLimit is a daily intake limit for adults based on WHO guidance. Limits given as
+// a share of energy intake are converted for a 2000 kcal diet.
+type nutrientLimit struct {
+	nutrient string
+	unit     string
+	limit    float64
+	intake   func(models.NutrientTotals) float64
+	guidance string
+}
+
+var dailyNutrientLimits = []nutrientLimit{
+	{
+		nutrient: "sodium_mg",
+		unit:     "mg",
+		limit:    2000,
+		intake:   func(t models.NutrientTotals) float64 { return t.SodiumMg },
+		guidance: "WHO recommends less than 2000 mg of sodium (5 g of salt) a day; high sodium intake raises blood pressure, the main risk factor for stroke",
+	},
+	{
+		nutrient: "sugar_grams",
+		unit:     "g",
+		limit:    50,
+		intake:   func(t models.NutrientTotals) float64 { return t.SugarGrams },
+		guidance: "WHO recommends keeping sugars below 10% of energy intake, about 50 g a day",
+	},
+	{
+		nutrient: "saturated_fat_grams",
+		unit:     "g",
+		limit:    22,
+		intake:   func(t models.NutrientTotals) float64 { return t.SaturatedFatGrams },
+		guidance: "WHO recommends keeping saturated fat below 10% of energy intake, about 22 g a day",
+	},
+	{
+		nutrient: "cholesterol_mg",
+		unit:     "mg",
+		limit:    300,
+		intake:   func(t models.NutrientTotals) float64 { return t.CholesterolMg },
+		guidance: "WHO and FAO advise less than 300 mg of cholesterol a day",
+	},
+}
+
+// nutrientWarnings returns a warning for every nutrient whose intake reached
+// nearLimitShare of its daily limit
+func nutrientWarnings(totals models.NutrientTotals) []models.NutrientWarning {
+	var warnings []models.NutrientWarning
+	for _, limit := range dailyNutrientLimits {
+		intake := limit.intake(totals)
+		if intake < limit.limit*nearLimitShare {
+			continue
+		}
+
+		level := nutrientNearLimit
+		message := fmt.Sprintf("Intake on this day is close to the daily limit. %s.", limit.guidance)
+		if intake >= limit.limit {
+			level = nutrientOverLimit
+			message = fmt.Sprintf("Intake on this day is over the daily limit. %s.", limit.guidance)
+		}
+
+		warnings = append(warnings, models.NutrientWarning{
+			Nutrient: limit.nutrient,
+			Intake:   roundTenth(intake),
+			Limit:    limit.limit,
+			Unit:     limit.unit,
+			Percent:  int(math.Round(intake / limit.limit * 100)),
+			Level:    level,
+			Message:  message,
+		})
+	}
+	return warnings
+}
+
+// attachDailyWarning sets the warnings for the day of a single food log
+func (s *FoodService) attachDailyWarning(userID int, foodLog *models.FoodLog) {
+	logs := []models.FoodLog{*foodLog}
+	s.attachDailyWarnings(userID, logs)
+	foodLog.DailyWarnings = logs[0].DailyWarnings
+}
+
+// attachDailyWarnings sets the warnings for the day of each food log, in the user's
+// timezone. Failures are logged and leave the logs without warnings, as they should not
+// fail the request.
+func (s *FoodService) attachDailyWarnings(userID int, logs []models.FoodLog) {
+	if len(logs) == 0 {
+		return
+	}
+
+	timezone, location := loadUserLocation(s.userRepo, userID)
+	days := make([]string, len(logs))
+	var start, end time.Time
+	for i, foodLog := range logs {
+		local := foodLog.LogDate.In(location)
+		dayStart := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+		days[i] = dayStart.Format("2006-01-02")
+		if start.IsZero() || dayStart.Before(start) {
+			start = dayStart
+		}
+		if dayEnd := dayStart.AddDate(0, 0, 1); dayEnd.After(end) {
+			end = dayEnd
+		}
+	}
+
+	totals, err := s.foodRepo.GetDailyNutrientTotals(userID, timezone, start, end)
+	if err != nil {
+		log.Printf("Failed to compute daily nutrient totals of user %d: %v", userID, err)
+		return
+	}
+
+	for i := range logs {
+		logs[i].DailyWarnings = nutrientWarnings(totals[days[i]])
+	}
+}
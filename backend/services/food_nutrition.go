@@ -0,0 +1,152 @@
+// services/food_nutrition.go
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/nutrition"
+)
+
+// Limits on the number of reference foods a search returns
+const (
+	defaultFoodSearchLimit = 20
+	maxFoodSearchLimit     = 50
+)
+
+// SearchFoods searches the nutrition reference database by name. An empty query finds
+// nothing rather than the whole table.
+func (s *FoodService) SearchFoods(query string, limit int) ([]models.NutritionFood, error) {
+	if strings.TrimSpace(query) == "" {
+		return []models.NutritionFood{}, nil
+	}
+	if limit <= 0 {
+		limit = defaultFoodSearchLimit
+	}
+	if limit > maxFoodSearchLimit {
+		limit = maxFoodSearchLimit
+	}
+	return s.nutritionRepo.SearchNutritionFoods(query, limit)
+}
+
+// logReferenceFood logs a serving of a reference food together with its analysis. The
+// nutrients come from the reference table, so the analysis is exact for the food and
+// needs no AI call.
+func (s *FoodService) logReferenceFood(userID int, req *models.FoodLogRequest) (*models.FoodLog, error) {
+	food, err := s.nutritionRepo.GetNutritionFood(req.NutritionFoodID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ServingGrams == 0 {
+		req.ServingGrams = food.DefaultServingGrams
+	}
+	if strings.TrimSpace(req.FoodName) == "" {
+		req.FoodName = food.Name
+	}
+
+	analysis := referenceFoodAnalysis(food, req.ServingGrams)
+	return s.foodRepo.CreateFoodLogWithAnalysis(userID, req, analysis)
+}
+
+// referenceFoodAnalysis scales a reference food's nutrients per 100 g to a serving
+func referenceFoodAnalysis(food *models.NutritionFood, servingGrams float64) *models.FoodAnalysis {
+	return servingAnalysis(models.FoodItem{
+		Name:              food.Name,
+		Calories:          food.EnergyKcal,
+		ProteinGrams:      food.ProteinGrams,
+		CarbsGrams:        food.CarbsGrams,
+		FatGrams:          food.FatGrams,
+		FiberGrams:        food.FiberGrams,
+		SugarGrams:        food.SugarGrams,
+		SodiumMg:          food.SodiumMg,
+		SaturatedFatGrams: food.SaturatedFatGrams,
+		CholesterolMg:     food.CholesterolMg,
+	}, servingGrams)
+}
+
+// LogBarcode logs a packaged food scanned by its EAN or UPC barcode together with its
+// analysis, computed from the product's nutrition facts for the amount eaten
+func (s *FoodService) LogBarcode(userID int, req *models.BarcodeLogRequest) (*models.FoodLog, error) {
+	barcode, err := nutrition.NormalizeBarcode(req.Barcode)
+	if err != nil {
+		return nil, err
+	}
+	product, err := s.nutritionRepo.GetFoodProductByBarcode(barcode)
+	if err != nil {
+		return nil, err
+	}
+
+	servingGrams := req.ServingGrams
+	if servingGrams == 0 {
+		// Packages without a serving size are counted in 100 g servings
+		serving := product.ServingGrams
+		if serving == 0 {
+			serving = 100
+		}
+		servings := req.Servings
+		if servings == 0 {
+			servings = 1
+		}
+		servingGrams = roundTenth(serving * servings)
+	}
+
+	name := product.Name
+	if product.Brand != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(product.Brand)) {
+		name = product.Brand + " " + name
+	}
+
+	analysis := servingAnalysis(models.FoodItem{
+		Name:              name,
+		Calories:          product.EnergyKcal,
+		ProteinGrams:      product.ProteinGrams,
+		CarbsGrams:        product.CarbsGrams,
+		FatGrams:          product.FatGrams,
+		FiberGrams:        product.FiberGrams,
+		SugarGrams:        product.SugarGrams,
+		SodiumMg:          product.SodiumMg,
+		SaturatedFatGrams: product.SaturatedFatGrams,
+		CholesterolMg:     product.CholesterolMg,
+	}, servingGrams)
+
+	logReq := &models.FoodLogRequest{
+		FoodName:      name,
+		Notes:         req.Notes,
+		FoodProductID: product.ID,
+		ServingGrams:  servingGrams,
+	}
+	foodLog, err := s.foodRepo.CreateFoodLogWithAnalysis(userID, logReq, analysis)
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachDailyWarning(userID, foodLog)
+	return foodLog, nil
+}
+
+// servingAnalysis builds the analysis of a serving of a food with known nutrients per
+// 100 g. The values come from a reference table or a package label, so the item has full
+// confidence.
+func servingAnalysis(per100g models.FoodItem, servingGrams float64) *models.FoodAnalysis {
+	scale := servingGrams / 100
+	analysis := &models.FoodAnalysis{
+		AnalyzedAt: time.Now(),
+		Items: []models.FoodItem{{
+			Name:              per100g.Name,
+			PortionGrams:      servingGrams,
+			Calories:          roundTenth(per100g.Calories * scale),
+			ProteinGrams:      roundTenth(per100g.ProteinGrams * scale),
+			CarbsGrams:        roundTenth(per100g.CarbsGrams * scale),
+			FatGrams:          roundTenth(per100g.FatGrams * scale),
+			FiberGrams:        roundTenth(per100g.FiberGrams * scale),
+			SugarGrams:        scaleOptional(per100g.SugarGrams, scale),
+			SodiumMg:          scaleOptional(per100g.SodiumMg, scale),
+			SaturatedFatGrams: scaleOptional(per100g.SaturatedFatGrams, scale),
+			CholesterolMg:     scaleOptional(per100g.CholesterolMg, scale),
+			Confidence:        1,
+		}},
+	}
+	applyItemTotals(analysis)
+	return analysis
+}
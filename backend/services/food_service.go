@@ -2,38 +2,129 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"os"
+	"math"
 	"strings"
 	"time"
 
+	"github.com/habdil/sigap-app/backend/images"
+	"github.com/habdil/sigap-app/backend/llm"
 	"github.com/habdil/sigap-app/backend/models"
 	"github.com/habdil/sigap-app/backend/repository"
+	"github.com/habdil/sigap-app/backend/storage"
+)
+
+// MaxFoodPhotoSize is the largest food photo accepted for upload
+const MaxFoodPhotoSize = 8 << 20
+
+var (
+	// ErrInvalidFoodPhoto is returned for uploads that are not a readable JPEG, PNG or WebP image
+	ErrInvalidFoodPhoto = errors.New("invalid food photo")
+	// ErrNoFoodPhoto is returned when analyzing a food log without an uploaded photo or image data
+	ErrNoFoodPhoto = errors.New("food log has no uploaded photo; send image_data")
 )
 
 // FoodService handles food logging and analysis business logic
 type FoodService struct {
-	foodRepo *repository.FoodRepository
-	userRepo *repository.UserRepository
+	foodRepo      *repository.FoodRepository
+	userRepo      *repository.UserRepository
+	nutritionRepo *repository.NutritionRepository
+	llm           llm.Provider
+	storage       storage.Storage
+	urlExpiry     time.Duration
 }
 
 // NewFoodService creates a new FoodService
 func NewFoodService() *FoodService {
 	return &FoodService{
-		foodRepo: repository.NewFoodRepository(),
-		userRepo: repository.NewUserRepository(),
+		foodRepo:      repository.NewFoodRepository(),
+		userRepo:      repository.NewUserRepository(),
+		nutritionRepo: repository.NewNutritionRepository(),
+		llm:           llm.NewForFeature(llm.FeatureFood),
+		storage:       storage.New(),
+		urlExpiry:     storage.URLExpiry(),
 	}
 }
 
-// LogFood logs a new food entry
+// LogFood logs a new food entry. Entries of a reference food are analyzed from the
+// nutrition reference database right away.
 func (s *FoodService) LogFood(userID int, req *models.FoodLogRequest) (*models.FoodLog, error) {
-	return s.foodRepo.CreateFoodLog(userID, req)
+	req.PhotoKey = ""
+	req.FoodProductID = 0
+
+	var foodLog *models.FoodLog
+	var err error
+	if req.NutritionFoodID != 0 {
+		foodLog, err = s.logReferenceFood(userID, req)
+	} else {
+		req.ServingGrams = 0
+		foodLog, err = s.foodRepo.CreateFoodLog(userID, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.attachDailyWarning(userID, foodLog)
+	return foodLog, nil
+}
+
+// LogFoodWithPhoto logs a new food entry with an uploaded photo. The photo must be a JPEG,
+// PNG or WebP image; its location metadata is removed before it is stored.
+func (s *FoodService) LogFoodWithPhoto(userID int, req *models.FoodLogRequest, photo []byte) (*models.FoodLog, error) {
+	mimeType, err := images.Detect(photo)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFoodPhoto, err)
+	}
+	photo, err = images.StripLocation(photo, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFoodPhoto, err)
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("food/%d/%s%s", userID, hex.EncodeToString(name), images.Extension(mimeType))
+
+	ctx := context.Background()
+	if err := s.storage.Put(ctx, key, photo, mimeType); err != nil {
+		return nil, fmt.Errorf("failed to store photo: %w", err)
+	}
+
+	req.PhotoURL = ""
+	req.PhotoKey = key
+	foodLog, err := s.foodRepo.CreateFoodLog(userID, req)
+	if err != nil {
+		if deleteErr := s.storage.Delete(ctx, key); deleteErr != nil {
+			log.Printf("Failed to delete orphaned photo %s: %v", key, deleteErr)
+		}
+		return nil, err
+	}
+
+	s.signPhotoURL(foodLog)
+	s.attachDailyWarning(userID, foodLog)
+	return foodLog, nil
+}
+
+// signPhotoURL points PhotoURL at a signed download link when the photo was uploaded to
+// our storage. Links to photos hosted elsewhere are left as they are.
+func (s *FoodService) signPhotoURL(foodLog *models.FoodLog) {
+	if foodLog.PhotoKey == "" {
+		return
+	}
+	url, err := s.storage.SignedURL(context.Background(), foodLog.PhotoKey, s.urlExpiry)
+	if err != nil {
+		log.Printf("Failed to sign photo URL of food log %d: %v", foodLog.ID, err)
+		return
+	}
+	foodLog.PhotoURL = url
 }
 
 // AnalyzeFood analyzes a food image using AI
@@ -54,8 +145,27 @@ func (s *FoodService) AnalyzeFood(userID int, req *models.FoodAnalysisRequest) (
 		return existingAnalysis, nil
 	}
 
+	// Analyze the image sent with the request, or else the photo uploaded with the log
+	var imgBytes []byte
+	var mimeType string
+	switch {
+	case req.ImageData != "":
+		imgBytes, mimeType, err = decodeImageData(req.ImageData)
+		if err != nil {
+			return nil, err
+		}
+	case foodLog.PhotoKey != "":
+		photo, err := s.storage.Get(context.Background(), foodLog.PhotoKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load photo: %w", err)
+		}
+		imgBytes, mimeType = photo.Data, photo.ContentType
+	default:
+		return nil, ErrNoFoodPhoto
+	}
+
 	// Perform AI analysis on the image
-	analysis, err := s.analyzeImage(req.ImageData)
+	analysis, err := s.analyzeImage(imgBytes, mimeType)
 	if err != nil {
 		return nil, err
 	}
@@ -67,38 +177,45 @@ func (s *FoodService) AnalyzeFood(userID int, req *models.FoodAnalysisRequest) (
 	return s.foodRepo.SaveFoodAnalysis(analysis)
 }
 
-// GetUserFoodLogs gets all food logs for a user
-func (s *FoodService) GetUserFoodLogs(userID int) ([]models.FoodLog, error) {
-	return s.foodRepo.GetUserFoodLogs(userID)
+// GetUserFoodLogs gets a user's food logs and the cursor of the next page
+func (s *FoodService) GetUserFoodLogs(userID int, opts models.ListOptions) ([]models.FoodLog, string, error) {
+	logs, nextCursor, err := s.foodRepo.GetUserFoodLogs(userID, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range logs {
+		s.signPhotoURL(&logs[i])
+	}
+	s.attachDailyWarnings(userID, logs)
+	return logs, nextCursor, nil
 }
 
-// analyzeImage uses AI to analyze food nutritional content
-func (s *FoodService) analyzeImage(imageData string) (*models.FoodAnalysis, error) {
+// decodeImageData decodes a base64 data URI such as "data:image/png;base64,..."
+func decodeImageData(imageData string) ([]byte, string, error) {
 	// Check if the image data is base64 encoded
 	if !strings.HasPrefix(imageData, "data:image") {
-		return nil, errors.New("invalid image format: must be base64 encoded")
+		return nil, "", errors.New("invalid image format: must be base64 encoded")
 	}
 
 	// Extract the base64 data
 	parts := strings.Split(imageData, ",")
 	if len(parts) != 2 {
-		return nil, errors.New("invalid image data format")
+		return nil, "", errors.New("invalid image data format")
 	}
 
 	// Decode base64 to binary
 	imgBytes, err := base64.StdEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode image: %v", err)
+		return nil, "", fmt.Errorf("failed to decode image: %v", err)
 	}
 
-	// Get API key from environment
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		return nil, errors.New("GEMINI_API_KEY environment variable is not set")
-	}
+	return imgBytes, imageMimeType(parts[0]), nil
+}
 
-	// Call Gemini Vision API to analyze the food
-	response, err := s.callGeminiVisionAPI(apiKey, imgBytes)
+// analyzeImage uses AI to analyze food nutritional content
+func (s *FoodService) analyzeImage(imgBytes []byte, mimeType string) (*models.FoodAnalysis, error) {
+	// Call the vision model to analyze the food
+	response, err := s.callVisionAPI(imgBytes, mimeType)
 	if err != nil {
 		// Fallback to a mock analysis if API fails
 		log.Printf("AI Analysis failed, using mock data: %v", err)
@@ -115,121 +232,62 @@ func (s *FoodService) analyzeImage(imageData string) (*models.FoodAnalysis, erro
 	return analysis, nil
 }
 
-// callGeminiVisionAPI calls Google's Gemini Vision API to analyze food image
-func (s *FoodService) callGeminiVisionAPI(apiKey string, imageData []byte) (string, error) {
-	// Encode image to base64 for API
-	base64Image := base64.StdEncoding.EncodeToString(imageData)
-
-	// Create the request payload for Gemini Vision API
-	// Update model ke gemini-1.5-flash
-	requestBody := map[string]interface{}{
-		"contents": []map[string]interface{}{
-			{
-				"parts": []map[string]interface{}{
-					{
-						"text": "Analyze this food image and provide nutritional information. Return JSON with protein_grams, carbs_grams, fat_grams, fiber_grams, and calories. Identify the food items in the image and provide a healthiness score from 1-10.",
-					},
-					{
-						"inlineData": map[string]interface{}{
-							"mimeType": "image/jpeg",
-							"data":     base64Image,
-						},
-					},
-				},
-			},
-		},
-	}
+// foodVisionPrompt asks for every component of the meal separately. Mixed Indonesian
+// plates are the common case, so the prompt spells out how to split them.
+const foodVisionPrompt = `Analyze this food photo. List every separate component of the meal as its own item: on mixed Indonesian plates list the rice (nasi), each side dish (lauk), the vegetables (sayur), sambal and crackers (kerupuk) separately. Estimate the portion of each item in grams and its nutrients for that portion.
 
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", err
-	}
+Return only JSON in this format:
+{"items": [{"name": "nasi putih", "portion_grams": 150, "calories": 195, "protein_grams": 4, "carbs_grams": 43, "fat_grams": 0.4, "fiber_grams": 0.6, "sugar_grams": 0.1, "sodium_mg": 2, "saturated_fat_grams": 0.1, "cholesterol_mg": 0, "confidence": 0.9}], "healthiness_score": 7}
 
-	// Update URL endpoint untuk menggunakan model gemini-1.5-flash
-	url := "https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:generateContent?key=" + apiKey
-	req, err := http.NewRequest("POST", url, strings.NewReader(string(jsonBody)))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
+Include the sodium from salt, soy sauce (kecap), sambal, broth and seasoning, the sugar from sweet sauces and drinks, and the saturated fat and cholesterol from coconut milk (santan), frying oil, organ meats and eggs. confidence is from 0 to 1 and says how sure you are of the item and its portion. healthiness_score is from 1 to 10 for the whole meal.`
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Read the response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	// Check response status
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("AI API returned status code %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Extract the text from Gemini response
-	var geminiResponse map[string]interface{}
-	if err := json.Unmarshal(body, &geminiResponse); err != nil {
-		return "", err
-	}
-
-	candidates, ok := geminiResponse["candidates"].([]interface{})
-	if !ok || len(candidates) == 0 {
-		return "", errors.New("no candidates in response")
-	}
-
-	firstCandidate, ok := candidates[0].(map[string]interface{})
-	if !ok {
-		return "", errors.New("invalid candidate format")
-	}
+// callVisionAPI asks the configured vision model to analyze a food image
+func (s *FoodService) callVisionAPI(imageData []byte, mimeType string) (string, error) {
+	return s.llm.GenerateVision(context.Background(), foodVisionPrompt, llm.Image{
+		MimeType: mimeType,
+		Data:     imageData,
+	}, llm.Options{})
+}
 
-	content, ok := firstCandidate["content"].(map[string]interface{})
-	if !ok {
-		return "", errors.New("no content in response")
+// imageMimeType extracts the MIME type from a data URI header such as "data:image/png;base64"
+func imageMimeType(header string) string {
+	mimeType := strings.TrimPrefix(header, "data:")
+	if idx := strings.Index(mimeType, ";"); idx != -1 {
+		mimeType = mimeType[:idx]
 	}
-
-	parts, ok := content["parts"].([]interface{})
-	if !ok || len(parts) == 0 {
-		return "", errors.New("no parts in content")
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "image/jpeg"
 	}
-
-	for _, part := range parts {
-		partMap, ok := part.(map[string]interface{})
-		if !ok {
-			continue
-		}
-
-		if text, ok := partMap["text"].(string); ok {
-			return text, nil
-		}
-	}
-
-	return "", errors.New("no text found in response")
+	return mimeType
 }
 
-// parseAIResponse extracts nutritional information from AI text response
+// parseAIResponse extracts the detected items and their nutrients from the AI text
+// response. Answers with only meal totals, as older prompts produced, become one item.
 func (s *FoodService) parseAIResponse(aiResponse string) (*models.FoodAnalysis, error) {
 	// Clean the text and extract JSON
-	text := strings.TrimSpace(aiResponse)
-
-	// Find JSON content
-	jsonStart := strings.Index(text, "{")
-	jsonEnd := strings.LastIndex(text, "}")
+	jsonText := llm.ExtractJSON(aiResponse)
 
-	if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
-		jsonText := text[jsonStart : jsonEnd+1]
+	if strings.HasPrefix(jsonText, "{") {
 
 		// Try to parse the JSON
 		var result struct {
-			ProteinGrams     float64  `json:"protein_grams"`
-			CarbsGrams       float64  `json:"carbs_grams"`
-			FatGrams         float64  `json:"fat_grams"`
-			FiberGrams       float64  `json:"fiber_grams"`
-			Calories         int      `json:"calories"`
+			Items []struct {
+				Name         string  `json:"name"`
+				PortionGrams float64 `json:"portion_grams"`
+				Calories     float64 `json:"calories"`
+				ProteinGrams float64 `json:"protein_grams"`
+				CarbsGrams   float64 `json:"carbs_grams"`
+				FatGrams     float64 `json:"fat_grams"`
+				FiberGrams   float64 `json:"fiber_grams"`
+				aiLimitedNutrients
+				Confidence float64 `json:"confidence"`
+			} `json:"items"`
+			ProteinGrams float64 `json:"protein_grams"`
+			CarbsGrams   float64 `json:"carbs_grams"`
+			FatGrams     float64 `json:"fat_grams"`
+			FiberGrams   float64 `json:"fiber_grams"`
+			aiLimitedNutrients
+			Calories         float64  `json:"calories"`
 			DetectedItems    []string `json:"detected_items"`
 			HealthinessScore int      `json:"healthiness_score"`
 		}
@@ -239,39 +297,102 @@ func (s *FoodService) parseAIResponse(aiResponse string) (*models.FoodAnalysis,
 			return s.createMockAnalysis(), nil
 		}
 
-		// Create detected items JSON
-		detectedItemsJSON, _ := json.Marshal(result.DetectedItems)
-
-		// Create and return the analysis
-		return &models.FoodAnalysis{
-			ProteinGrams:     result.ProteinGrams,
-			CarbsGrams:       result.CarbsGrams,
-			FatGrams:         result.FatGrams,
-			FiberGrams:       result.FiberGrams,
-			Calories:         result.Calories,
-			DetectedItems:    detectedItemsJSON,
+		var items []models.FoodItem
+		for _, item := range result.Items {
+			name := strings.TrimSpace(item.Name)
+			if name == "" {
+				continue
+			}
+			foodItem := models.FoodItem{
+				Name:         name,
+				PortionGrams: math.Max(item.PortionGrams, 0),
+				Calories:     math.Max(item.Calories, 0),
+				ProteinGrams: math.Max(item.ProteinGrams, 0),
+				CarbsGrams:   math.Max(item.CarbsGrams, 0),
+				FatGrams:     math.Max(item.FatGrams, 0),
+				FiberGrams:   math.Max(item.FiberGrams, 0),
+				Confidence:   itemConfidence(item.Confidence),
+			}
+			item.aiLimitedNutrients.apply(&foodItem)
+			items = append(items, foodItem)
+		}
+
+		// Totals without items: keep them as one item for the whole meal. Its portion is
+		// unknown, so a portion correction cannot rescale it.
+		if len(items) == 0 && result.Calories > 0 {
+			name := strings.Join(result.DetectedItems, ", ")
+			if name == "" {
+				name = "Meal"
+			}
+			foodItem := models.FoodItem{
+				Name:         name,
+				Calories:     result.Calories,
+				ProteinGrams: result.ProteinGrams,
+				CarbsGrams:   result.CarbsGrams,
+				FatGrams:     result.FatGrams,
+				FiberGrams:   result.FiberGrams,
+				Confidence:   defaultItemConfidence,
+			}
+			result.aiLimitedNutrients.apply(&foodItem)
+			items = append(items, foodItem)
+		}
+
+		if len(items) == 0 {
+			log.Printf("AI response has no food items")
+			return s.createMockAnalysis(), nil
+		}
+
+		analysis := &models.FoodAnalysis{
+			Items:            items,
 			HealthinessScore: result.HealthinessScore,
-			AIConfidence:     0.9, // Default confidence
-		}, nil
+		}
+		applyItemTotals(analysis)
+		return analysis, nil
 	}
 
 	// If JSON parsing fails, create mock data
 	return s.createMockAnalysis(), nil
 }
 
+// aiLimitedNutrients are the nutrients with a daily limit in an AI response. They are
+// pointers so nutrients the model left out stay unknown instead of becoming zero.
+type aiLimitedNutrients struct {
+	SugarGrams        *float64 `json:"sugar_grams"`
+	SodiumMg          *float64 `json:"sodium_mg"`
+	SaturatedFatGrams *float64 `json:"saturated_fat_grams"`
+	CholesterolMg     *float64 `json:"cholesterol_mg"`
+}
+
+// apply copies the nutrients to the item, dropping negative estimates
+func (n aiLimitedNutrients) apply(item *models.FoodItem) {
+	item.SugarGrams = nonNegative(n.SugarGrams)
+	item.SodiumMg = nonNegative(n.SodiumMg)
+	item.SaturatedFatGrams = nonNegative(n.SaturatedFatGrams)
+	item.CholesterolMg = nonNegative(n.CholesterolMg)
+}
+
+func nonNegative(value *float64) *float64 {
+	if value == nil || *value < 0 {
+		return nil
+	}
+	return value
+}
+
 // createMockAnalysis creates mock nutritional data when analysis fails
 func (s *FoodService) createMockAnalysis() *models.FoodAnalysis {
 	// Default nutritional values
-	detectedItems, _ := json.Marshal([]string{"Unknown food item"})
-
-	return &models.FoodAnalysis{
-		ProteinGrams:     15.0,
-		CarbsGrams:       30.0,
-		FatGrams:         10.0,
-		FiberGrams:       5.0,
-		Calories:         275,
-		DetectedItems:    detectedItems,
+	analysis := &models.FoodAnalysis{
+		Items: []models.FoodItem{{
+			Name:         "Unknown food item",
+			Calories:     275,
+			ProteinGrams: 15.0,
+			CarbsGrams:   30.0,
+			FatGrams:     10.0,
+			FiberGrams:   5.0,
+			Confidence:   0.5, // Lower confidence for mock data
+		}},
 		HealthinessScore: 6,
-		AIConfidence:     0.5, // Lower confidence for mock data
 	}
+	applyItemTotals(analysis)
+	return analysis
 }
@@ -1,6 +1,9 @@
 package services
 
 import (
+	"errors"
+	"time"
+
 	"github.com/habdil/sigap-app/backend/models"
 	"github.com/habdil/sigap-app/backend/repository"
 )
@@ -8,12 +11,14 @@ import (
 // HealthProfileService handles health profile business logic
 type HealthProfileService struct {
 	profileRepo *repository.HealthProfileRepository
+	userRepo    *repository.UserRepository
 }
 
 // NewHealthProfileService creates a new HealthProfileService
 func NewHealthProfileService() *HealthProfileService {
 	return &HealthProfileService{
 		profileRepo: repository.NewHealthProfileRepository(),
+		userRepo:    repository.NewUserRepository(),
 	}
 }
 
@@ -26,3 +31,15 @@ func (s *HealthProfileService) UpdateUserProfile(userID int, req *models.Profile
 func (s *HealthProfileService) GetUserProfile(userID int) (*models.HealthProfile, error) {
 	return s.profileRepo.GetUserProfile(userID)
 }
+
+// UpdateTimezone sets the timezone used for the user's daily streaks
+func (s *HealthProfileService) UpdateTimezone(userID int, timezone string) error {
+	if timezone == "Local" {
+		return errors.New("invalid timezone")
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return errors.New("invalid timezone")
+	}
+
+	return s.userRepo.UpdateUserTimezone(userID, timezone)
+}
@@ -0,0 +1,661 @@
+// services/health_report_pdf.go
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/pdf"
+	"github.com/habdil/sigap-app/backend/risk"
+)
+
+const (
+	reportMargin = 50.0
+	reportTop    = 60.0
+	reportBottom = pdf.A4Height - 60
+	reportWidth  = pdf.A4Width - 2*reportMargin
+
+	// weeklyActivityTarget is the WHO recommendation of 150 minutes of moderate activity a week
+	weeklyActivityTarget = 150
+)
+
+var (
+	reportAccent = pdf.Color{R: 0.11, G: 0.38, B: 0.62}
+	reportMuted  = pdf.Color{R: 0.4, G: 0.4, B: 0.4}
+	reportRule   = pdf.Color{R: 0.85, G: 0.85, B: 0.85}
+	reportShade  = pdf.Color{R: 0.94, G: 0.95, B: 0.97}
+	reportGood   = pdf.Color{R: 0.2, G: 0.6, B: 0.35}
+	reportWarn   = pdf.Color{R: 0.9, G: 0.6, B: 0.1}
+	reportBad    = pdf.Color{R: 0.8, G: 0.2, B: 0.2}
+)
+
+// renderHealthReport lays out the health report on A4 pages
+func renderHealthReport(report *healthReport) ([]byte, error) {
+	name := report.User.FullName
+	if name == "" {
+		name = report.User.Username
+	}
+
+	doc := pdf.New("SIGAP Health Report")
+	doc.Author = name
+	doc.Created = report.GeneratedAt
+
+	w := &reportWriter{doc: doc}
+	w.newPage()
+
+	w.text("SIGAP Health Report", pdf.Bold, 20, reportAccent)
+	w.y += 4
+	w.text(fmt.Sprintf("Period: %s to %s", reportDate(report.From), reportDate(report.To.Add(-time.Millisecond))), pdf.Regular, 10, reportMuted)
+	w.text("Generated on "+report.GeneratedAt.Format("2 Jan 2006 15:04 MST"), pdf.Regular, 10, reportMuted)
+	w.y += 8
+
+	writeProfileSection(w, report)
+	writeAssessmentSection(w, report)
+	writeActivitySection(w, report)
+	writeNutritionSection(w, report)
+
+	w.heading("About this report")
+	w.paragraph("This report summarises data the user entered or recorded in the SIGAP app. Stroke risk "+
+		"percentages are screening estimates from a questionnaire, not a diagnosis. Food nutrition values "+
+		"are estimated from meal photos, nutrition reference tables or package labels and may be inaccurate. Please interpret them alongside a clinical examination.",
+		pdf.Regular, 9, reportMuted)
+
+	w.footers("SIGAP Health Report - " + name)
+
+	return doc.Bytes()
+}
+
+func writeProfileSection(w *reportWriter, report *healthReport) {
+	user := report.User
+	w.heading("Profile")
+
+	name := user.FullName
+	if name == "" {
+		name = user.Username
+	}
+	rows := [][2]string{{"Name", name}}
+
+	age := user.Age
+	if !user.DateOfBirth.IsZero() {
+		age = ageOn(user.DateOfBirth, report.GeneratedAt)
+		rows = append(rows, [2]string{"Date of birth", reportDate(user.DateOfBirth)})
+	}
+	if age > 0 {
+		rows = append(rows, [2]string{"Age", fmt.Sprintf("%d years", age)})
+	}
+	if sex := normalizeSex(user.Gender); sex != "" {
+		rows = append(rows, [2]string{"Sex", strings.ToUpper(sex[:1]) + sex[1:]})
+	}
+	if user.Height > 0 {
+		rows = append(rows, [2]string{"Height", fmt.Sprintf("%.0f cm", user.Height)})
+	}
+	if user.Weight > 0 {
+		rows = append(rows, [2]string{"Weight", fmt.Sprintf("%.1f kg", user.Weight)})
+	}
+	if bmi := risk.BMI(user.Height, user.Weight); bmi > 0 {
+		rows = append(rows, [2]string{"BMI", fmt.Sprintf("%.1f (%s, Asia-Pacific cut-offs)", bmi, risk.Current().BMILabel(bmi))})
+	}
+
+	w.keyValues(rows)
+}
+
+func writeAssessmentSection(w *reportWriter, report *healthReport) {
+	w.heading("Stroke risk assessments")
+
+	var results []models.AssessmentResponse
+	for _, assessment := range report.Assessments {
+		if assessment.Result.ID != 0 {
+			results = append(results, assessment)
+		}
+	}
+	if len(results) == 0 {
+		w.paragraph("No assessments were taken in this period.", pdf.Regular, 10, reportMuted)
+		return
+	}
+
+	latest := results[len(results)-1].Result
+	summary := fmt.Sprintf("Latest stroke risk estimate: %d%% on %s (%s).",
+		latest.RiskPercentage, reportDate(latest.CreatedAt), riskLevel(latest.RiskPercentage))
+	if len(results) > 1 {
+		first := results[0].Result
+		delta := latest.RiskPercentage - first.RiskPercentage
+		switch {
+		case delta < 0:
+			summary += fmt.Sprintf(" Down %d points since %s.", -delta, reportDate(first.CreatedAt))
+		case delta > 0:
+			summary += fmt.Sprintf(" Up %d points since %s.", delta, reportDate(first.CreatedAt))
+		default:
+			summary += fmt.Sprintf(" Unchanged since %s.", reportDate(first.CreatedAt))
+		}
+	}
+	w.paragraph(summary, pdf.Regular, 10, pdf.Black)
+
+	// Risk over time, the latest twelve assessments
+	chart := results
+	if len(chart) > 12 {
+		chart = chart[len(chart)-12:]
+	}
+	var bars []reportBar
+	for _, assessment := range chart {
+		bars = append(bars, reportBar{
+			Label: assessment.Result.CreatedAt.Format("2 Jan"),
+			Value: float64(assessment.Result.RiskPercentage),
+			Color: riskColor(assessment.Result.RiskPercentage),
+		})
+	}
+	w.barChart("Risk estimate (%)", bars, 100, 0)
+
+	var rows [][]string
+	previous := -1
+	for _, assessment := range results {
+		result := assessment.Result
+		change := "-"
+		if previous >= 0 {
+			change = fmt.Sprintf("%+d", result.RiskPercentage-previous)
+		}
+		previous = result.RiskPercentage
+
+		rows = append(rows, []string{
+			reportDate(result.CreatedAt),
+			fmt.Sprintf("%d%%", result.RiskPercentage),
+			change,
+			riskSourceLabel(result.Source),
+			result.ModelVersion,
+			fmt.Sprintf("%d", assessment.Assessment.QuestionnaireVersion),
+		})
+	}
+	w.table([]reportColumn{
+		{"Date", 0.18}, {"Risk", 0.1}, {"Change", 0.1}, {"Source", 0.17}, {"Model", 0.3}, {"Questionnaire", 0.15},
+	}, rows)
+
+	if len(latest.Contributions) > 0 {
+		w.subheading(fmt.Sprintf("How the latest score is made up (rule engine %s, score %d%%)", latest.RuleModelVersion, derefInt(latest.RuleScore)))
+		var rows [][]string
+		for _, contribution := range latest.Contributions {
+			value := contribution.Value
+			if value == "" {
+				value = "-"
+			}
+			rows = append(rows, []string{riskFactorLabel(contribution.Factor), value, fmt.Sprintf("%+.0f", contribution.Points)})
+		}
+		w.table([]reportColumn{{"Factor", 0.4}, {"Answer or value", 0.4}, {"Points", 0.2}}, rows)
+	}
+
+	if len(latest.RiskFactors) > 0 {
+		w.subheading("Risk factors identified")
+		w.bullets(latest.RiskFactors)
+	}
+	if len(latest.Recommendations) > 0 {
+		w.subheading("Recommendations given")
+		w.bullets(latest.Recommendations)
+	}
+}
+
+func writeActivitySection(w *reportWriter, report *healthReport) {
+	w.heading("Physical activity")
+
+	if len(report.Activities) == 0 {
+		w.paragraph("No activities were logged in this period.", pdf.Regular, 10, reportMuted)
+		return
+	}
+
+	var totals models.ActivityTotals
+	byType := map[string]*models.ActivityTypeTotals{}
+	weekly := map[time.Time]int{}
+
+	for _, activity := range report.Activities {
+		totals.Sessions++
+		totals.Minutes += activity.DurationMinutes
+		totals.DistanceKM += activity.DistanceKM
+		totals.Calories += activity.CaloriesBurned
+
+		typeTotals, ok := byType[activity.ActivityType]
+		if !ok {
+			typeTotals = &models.ActivityTypeTotals{ActivityType: activity.ActivityType}
+			byType[activity.ActivityType] = typeTotals
+		}
+		typeTotals.Sessions++
+		typeTotals.Minutes += activity.DurationMinutes
+		typeTotals.DistanceKM += activity.DistanceKM
+		typeTotals.Calories += activity.CaloriesBurned
+
+		weekly[reportWeekStart(activity.ActivityDate)] += activity.DurationMinutes
+	}
+
+	// Every week of the period, including weeks without activity
+	var weeks []time.Time
+	for week := reportWeekStart(report.From); week.Before(report.To); week = week.AddDate(0, 0, 7) {
+		weeks = append(weeks, week)
+	}
+	weeksOnTarget := 0
+	for _, week := range weeks {
+		if weekly[week] >= weeklyActivityTarget {
+			weeksOnTarget++
+		}
+	}
+
+	w.keyValues([][2]string{
+		{"Sessions", fmt.Sprintf("%d", totals.Sessions)},
+		{"Active time", fmt.Sprintf("%d h %02d min", totals.Minutes/60, totals.Minutes%60)},
+		{"Distance", fmt.Sprintf("%.1f km", totals.DistanceKM)},
+		{"Calories burned", fmt.Sprintf("%d kcal", totals.Calories)},
+		{"Average per week", fmt.Sprintf("%.0f min", float64(totals.Minutes)/math.Max(1, float64(len(weeks))))},
+		{"Weeks with 150+ min", fmt.Sprintf("%d of %d", weeksOnTarget, len(weeks))},
+	})
+
+	// Weekly minutes, the latest sixteen weeks
+	chartWeeks := weeks
+	if len(chartWeeks) > 16 {
+		chartWeeks = chartWeeks[len(chartWeeks)-16:]
+	}
+	var bars []reportBar
+	maxMinutes := float64(weeklyActivityTarget)
+	for _, week := range chartWeeks {
+		minutes := float64(weekly[week])
+		maxMinutes = math.Max(maxMinutes, minutes)
+		color := reportWarn
+		if minutes >= weeklyActivityTarget {
+			color = reportGood
+		}
+		bars = append(bars, reportBar{Label: week.Format("2 Jan"), Value: minutes, Color: color})
+	}
+	w.barChart("Active minutes per week (line: 150 min WHO target)", bars, maxMinutes*1.1, weeklyActivityTarget)
+
+	types := make([]*models.ActivityTypeTotals, 0, len(byType))
+	for _, typeTotals := range byType {
+		types = append(types, typeTotals)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Minutes != types[j].Minutes {
+			return types[i].Minutes > types[j].Minutes
+		}
+		return types[i].ActivityType < types[j].ActivityType
+	})
+
+	var rows [][]string
+	for _, typeTotals := range types {
+		distance := "-"
+		if typeTotals.DistanceKM > 0 {
+			distance = fmt.Sprintf("%.1f km", typeTotals.DistanceKM)
+		}
+		rows = append(rows, []string{
+			typeTotals.ActivityType,
+			fmt.Sprintf("%d", typeTotals.Sessions),
+			fmt.Sprintf("%d min", typeTotals.Minutes),
+			distance,
+			fmt.Sprintf("%d kcal", typeTotals.Calories),
+		})
+	}
+	w.table([]reportColumn{{"Activity", 0.3}, {"Sessions", 0.14}, {"Time", 0.18}, {"Distance", 0.18}, {"Calories", 0.2}}, rows)
+}
+
+func writeNutritionSection(w *reportWriter, report *healthReport) {
+	w.heading("Nutrition")
+
+	if len(report.FoodLogs) == 0 {
+		w.paragraph("No meals were logged in this period.", pdf.Regular, 10, reportMuted)
+		return
+	}
+
+	type dayTotals struct {
+		calories                   int
+		protein, carbs, fat, fiber float64
+	}
+	days := map[string]*dayTotals{}
+	foods := map[string]int{}
+	analyzed, healthinessSum := 0, 0
+
+	for _, log := range report.FoodLogs {
+		if name := strings.TrimSpace(log.FoodName); name != "" {
+			foods[strings.ToLower(name)]++
+		}
+		if log.Analysis == nil {
+			continue
+		}
+
+		analyzed++
+		healthinessSum += log.Analysis.HealthinessScore
+
+		day := log.LogDate.Format("2006-01-02")
+		totals, ok := days[day]
+		if !ok {
+			totals = &dayTotals{}
+			days[day] = totals
+		}
+		totals.calories += log.Analysis.Calories
+		totals.protein += log.Analysis.ProteinGrams
+		totals.carbs += log.Analysis.CarbsGrams
+		totals.fat += log.Analysis.FatGrams
+		totals.fiber += log.Analysis.FiberGrams
+	}
+
+	rows := [][2]string{
+		{"Meals logged", fmt.Sprintf("%d", len(report.FoodLogs))},
+		{"Meals with nutrition analysis", fmt.Sprintf("%d", analyzed)},
+	}
+	if analyzed > 0 {
+		rows = append(rows, [2]string{"Average healthiness score", fmt.Sprintf("%.0f / 100", float64(healthinessSum)/float64(analyzed))})
+	}
+	w.keyValues(rows)
+
+	if len(days) > 0 {
+		var sum dayTotals
+		for _, totals := range days {
+			sum.calories += totals.calories
+			sum.protein += totals.protein
+			sum.carbs += totals.carbs
+			sum.fat += totals.fat
+			sum.fiber += totals.fiber
+		}
+		n := float64(len(days))
+
+		w.subheading(fmt.Sprintf("Daily average over %d days with analysed meals", len(days)))
+		w.table([]reportColumn{{"Energy", 0.2}, {"Protein", 0.2}, {"Carbohydrates", 0.2}, {"Fat", 0.2}, {"Fibre", 0.2}}, [][]string{{
+			fmt.Sprintf("%.0f kcal", float64(sum.calories)/n),
+			fmt.Sprintf("%.0f g", sum.protein/n),
+			fmt.Sprintf("%.0f g", sum.carbs/n),
+			fmt.Sprintf("%.0f g", sum.fat/n),
+			fmt.Sprintf("%.0f g", sum.fiber/n),
+		}})
+	}
+
+	if len(foods) > 0 {
+		names := make([]string, 0, len(foods))
+		for name := range foods {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if foods[names[i]] != foods[names[j]] {
+				return foods[names[i]] > foods[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		if len(names) > 10 {
+			names = names[:10]
+		}
+
+		w.subheading("Most logged foods")
+		var rows [][]string
+		for _, name := range names {
+			rows = append(rows, []string{name, fmt.Sprintf("%d", foods[name])})
+		}
+		w.table([]reportColumn{{"Food", 0.8}, {"Times", 0.2}}, rows)
+	}
+}
+
+func reportDate(t time.Time) string {
+	return t.Format("2 Jan 2006")
+}
+
+// reportWeekStart returns the Monday starting the week of t, in UTC
+func reportWeekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+func riskLevel(percentage int) string {
+	switch {
+	case percentage >= 70:
+		return "high"
+	case percentage >= 30:
+		return "moderate"
+	default:
+		return "low"
+	}
+}
+
+func riskColor(percentage int) pdf.Color {
+	switch riskLevel(percentage) {
+	case "high":
+		return reportBad
+	case "moderate":
+		return reportWarn
+	default:
+		return reportGood
+	}
+}
+
+func riskSourceLabel(source string) string {
+	switch source {
+	case models.RiskSourceLLM:
+		return "AI model"
+	case models.RiskSourceRuleEngine:
+		return "Rule engine"
+	default:
+		return "-"
+	}
+}
+
+func riskFactorLabel(factor string) string {
+	labels := map[string]string{
+		"baseline":       "Baseline",
+		"age":            "Age",
+		"bmi":            "BMI",
+		"screen_time":    "Screen time",
+		"exercise":       "Exercise",
+		"late_nights":    "Late nights",
+		"diet":           "Diet",
+		"smoking":        "Smoking",
+		"blood_pressure": "Blood pressure",
+		"family_history": "Family history of stroke",
+		"diabetes":       "Diabetes",
+	}
+	if label, ok := labels[factor]; ok {
+		return label
+	}
+	return factor
+}
+
+func derefInt(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// reportWriter places report content top to bottom, starting a new page when it runs out of room
+type reportWriter struct {
+	doc  *pdf.Document
+	page *pdf.Page
+	y    float64
+}
+
+// reportColumn is a table column; Width is a fraction of the content width
+type reportColumn struct {
+	Title string
+	Width float64
+}
+
+// reportBar is one bar of a bar chart
+type reportBar struct {
+	Label string
+	Value float64
+	Color pdf.Color
+}
+
+func (w *reportWriter) newPage() {
+	w.page = w.doc.AddPage()
+	w.y = reportTop
+}
+
+// space starts a new page unless height points still fit on the current one
+func (w *reportWriter) space(height float64) {
+	if w.y+height > reportBottom {
+		w.newPage()
+	}
+}
+
+func (w *reportWriter) text(text string, font pdf.Font, size float64, color pdf.Color) {
+	w.space(size * 1.4)
+	w.y += size
+	w.page.Text(reportMargin, w.y, font, size, color, text)
+	w.y += size * 0.4
+}
+
+func (w *reportWriter) heading(text string) {
+	// Keep a heading together with the start of its section
+	w.space(80)
+	w.y += 14
+	w.text(text, pdf.Bold, 14, reportAccent)
+	w.page.Line(reportMargin, w.y, reportMargin+reportWidth, w.y, 0.8, reportAccent)
+	w.y += 8
+}
+
+func (w *reportWriter) subheading(text string) {
+	w.space(50)
+	w.y += 6
+	w.text(text, pdf.Bold, 10.5, pdf.Black)
+	w.y += 2
+}
+
+func (w *reportWriter) paragraph(text string, font pdf.Font, size float64, color pdf.Color) {
+	for _, line := range pdf.Wrap(text, font, size, reportWidth) {
+		w.text(line, font, size, color)
+	}
+	w.y += 4
+}
+
+func (w *reportWriter) bullets(items []string) {
+	const indent = 12.0
+	for _, item := range items {
+		for i, line := range pdf.Wrap(item, pdf.Regular, 10, reportWidth-indent) {
+			w.space(14)
+			w.y += 10
+			if i == 0 {
+				w.page.Text(reportMargin, w.y, pdf.Regular, 10, pdf.Black, "•")
+			}
+			w.page.Text(reportMargin+indent, w.y, pdf.Regular, 10, pdf.Black, line)
+			w.y += 4
+		}
+	}
+	w.y += 4
+}
+
+func (w *reportWriter) keyValues(rows [][2]string) {
+	const keyWidth = 160.0
+	for _, row := range rows {
+		w.space(15)
+		w.y += 10
+		w.page.Text(reportMargin, w.y, pdf.Regular, 10, reportMuted, row[0])
+		w.page.Text(reportMargin+keyWidth, w.y, pdf.Bold, 10, pdf.Black, fit(row[1], pdf.Bold, 10, reportWidth-keyWidth))
+		w.y += 5
+	}
+	w.y += 6
+}
+
+// table draws rows under a shaded header, repeating the header on every page
+func (w *reportWriter) table(columns []reportColumn, rows [][]string) {
+	const (
+		rowHeight = 16.0
+		padding   = 4.0
+		size      = 9.0
+	)
+
+	header := func() {
+		w.page.Rect(reportMargin, w.y, reportWidth, rowHeight, reportShade)
+		x := reportMargin
+		for _, column := range columns {
+			width := column.Width * reportWidth
+			w.page.Text(x+padding, w.y+11.5, pdf.Bold, size, pdf.Black, fit(column.Title, pdf.Bold, size, width-2*padding))
+			x += width
+		}
+		w.y += rowHeight
+	}
+
+	w.space(2 * rowHeight)
+	header()
+
+	for _, row := range rows {
+		if w.y+rowHeight > reportBottom {
+			w.newPage()
+			header()
+		}
+
+		x := reportMargin
+		for i, column := range columns {
+			width := column.Width * reportWidth
+			if i < len(row) {
+				w.page.Text(x+padding, w.y+11.5, pdf.Regular, size, pdf.Black, fit(row[i], pdf.Regular, size, width-2*padding))
+			}
+			x += width
+		}
+		w.y += rowHeight
+		w.page.Line(reportMargin, w.y, reportMargin+reportWidth, w.y, 0.5, reportRule)
+	}
+	w.y += 10
+}
+
+// barChart draws vertical bars scaled to maxValue, with a dashed-looking target line when target > 0
+func (w *reportWriter) barChart(title string, bars []reportBar, maxValue float64, target float64) {
+	const (
+		chartHeight = 110.0
+		labelHeight = 14.0
+	)
+	if len(bars) == 0 || maxValue <= 0 {
+		return
+	}
+
+	w.space(chartHeight + labelHeight + 30)
+	w.y += 4
+	w.text(title, pdf.Bold, 9, reportMuted)
+	w.y += 4
+
+	top := w.y
+	bottom := top + chartHeight
+	slot := reportWidth / float64(len(bars))
+	barWidth := math.Min(28, slot*0.6)
+
+	w.page.Line(reportMargin, bottom, reportMargin+reportWidth, bottom, 0.8, reportMuted)
+
+	for i, bar := range bars {
+		height := math.Min(bar.Value, maxValue) / maxValue * chartHeight
+		x := reportMargin + float64(i)*slot + (slot-barWidth)/2
+		if height > 0 {
+			w.page.Rect(x, bottom-height, barWidth, height, bar.Color)
+		}
+
+		value := fmt.Sprintf("%.0f", bar.Value)
+		w.page.Text(x+(barWidth-pdf.TextWidth(value, pdf.Regular, 7))/2, bottom-height-3, pdf.Regular, 7, pdf.Black, value)
+
+		label := fit(bar.Label, pdf.Regular, 7, slot-2)
+		w.page.Text(x+(barWidth-pdf.TextWidth(label, pdf.Regular, 7))/2, bottom+10, pdf.Regular, 7, reportMuted, label)
+	}
+
+	if target > 0 && target <= maxValue {
+		y := bottom - target/maxValue*chartHeight
+		for x := reportMargin; x < reportMargin+reportWidth; x += 8 {
+			w.page.Line(x, y, math.Min(x+4, reportMargin+reportWidth), y, 0.8, reportBad)
+		}
+	}
+
+	w.y = bottom + labelHeight + 8
+}
+
+// footers writes the title and page numbers at the bottom of every page
+func (w *reportWriter) footers(title string) {
+	pages := w.doc.Pages()
+	for i, page := range pages {
+		y := pdf.A4Height - 30
+		page.Line(reportMargin, y-12, reportMargin+reportWidth, y-12, 0.5, reportRule)
+		page.Text(reportMargin, y, pdf.Regular, 8, reportMuted, fit(title, pdf.Regular, 8, reportWidth-80))
+		number := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		page.Text(reportMargin+reportWidth-pdf.TextWidth(number, pdf.Regular, 8), y, pdf.Regular, 8, reportMuted, number)
+	}
+}
+
+// fit shortens text with an ellipsis until it is no wider than width
+func fit(text string, font pdf.Font, size float64, width float64) string {
+	if pdf.TextWidth(text, font, size) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.TextWidth(string(runes)+"…", font, size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,285 @@
+// services/identity_service.go
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/habdil/sigap-app/backend/utils"
+)
+
+const defaultGoogleJWKSURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+// VerifiedIdentity is the identity taken from a verified provider token
+type VerifiedIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	GoogleID      string
+}
+
+// IdentityService verifies identity tokens issued by Google and Supabase
+type IdentityService struct {
+	googleJWKS      *utils.JWKS
+	googleAudiences []string
+
+	supabaseJWKS     *utils.JWKS
+	supabaseIssuer   string
+	supabaseAudience string
+
+	// The Supabase user endpoint reports whether the email was confirmed, which access
+	// tokens do not carry
+	supabaseUserURL string
+	supabaseAPIKey  string
+	httpClient      *http.Client
+}
+
+// NewIdentityService creates a new IdentityService from the environment
+func NewIdentityService() *IdentityService {
+	googleJWKSURL := os.Getenv("GOOGLE_JWKS_URL")
+	if googleJWKSURL == "" {
+		googleJWKSURL = defaultGoogleJWKSURL
+	}
+
+	supabaseURL := strings.TrimRight(os.Getenv("SUPABASE_URL"), "/")
+
+	supabaseJWKSURL := os.Getenv("SUPABASE_JWKS_URL")
+	if supabaseJWKSURL == "" && supabaseURL != "" {
+		supabaseJWKSURL = supabaseURL + "/auth/v1/.well-known/jwks.json"
+	}
+
+	supabaseIssuer := os.Getenv("SUPABASE_JWT_ISSUER")
+	if supabaseIssuer == "" && supabaseURL != "" {
+		supabaseIssuer = supabaseURL + "/auth/v1"
+	}
+
+	supabaseAudience := os.Getenv("SUPABASE_JWT_AUDIENCE")
+	if supabaseAudience == "" {
+		supabaseAudience = "authenticated"
+	}
+
+	supabaseUserURL := ""
+	if supabaseURL != "" {
+		supabaseUserURL = supabaseURL + "/auth/v1/user"
+	}
+
+	return &IdentityService{
+		googleJWKS:       utils.NewJWKS(googleJWKSURL),
+		googleAudiences:  splitList(os.Getenv("GOOGLE_CLIENT_IDS")),
+		supabaseJWKS:     utils.NewJWKS(supabaseJWKSURL),
+		supabaseIssuer:   supabaseIssuer,
+		supabaseAudience: supabaseAudience,
+		supabaseUserURL:  supabaseUserURL,
+		supabaseAPIKey:   os.Getenv("SUPABASE_ANON_KEY"),
+		httpClient:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// googleClaims are the claims of a Google ID token
+type googleClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	jwt.RegisteredClaims
+}
+
+// supabaseClaims are the claims of a Supabase access token. user_metadata can be edited by
+// the user and is only used for display; app_metadata and amr are set by Supabase itself.
+type supabaseClaims struct {
+	Email        string `json:"email"`
+	UserMetadata struct {
+		FullName   string `json:"full_name"`
+		Name       string `json:"name"`
+		AvatarURL  string `json:"avatar_url"`
+		Picture    string `json:"picture"`
+		ProviderID string `json:"provider_id"`
+	} `json:"user_metadata"`
+	AppMetadata struct {
+		Provider string `json:"provider"`
+	} `json:"app_metadata"`
+	// AuthMethods are the ways the session was authenticated
+	AuthMethods []struct {
+		Method string `json:"method"`
+	} `json:"amr"`
+	jwt.RegisteredClaims
+}
+
+// emailVerifyingProviders are sign-in providers that only hand out verified emails
+var emailVerifyingProviders = map[string]bool{"google": true}
+
+// emailProvingMethods are authentication methods that follow a link sent to the email,
+// so the session itself proves control of it. Password and OTP sessions do not: OTPs can
+// also be sent by SMS.
+var emailProvingMethods = map[string]bool{
+	"magiclink":    true,
+	"email/signup": true,
+	"email_change": true,
+	"invite":       true,
+	"recovery":     true,
+}
+
+// emailVerified reports whether the token alone shows that Supabase vouches for the
+// email: the user signed in with a provider that verifies emails, or through a link sent
+// to the email. Otherwise SupabaseEmailConfirmed asks Supabase.
+func (c *supabaseClaims) emailVerified() bool {
+	if emailVerifyingProviders[c.AppMetadata.Provider] {
+		return true
+	}
+	for _, amr := range c.AuthMethods {
+		if emailProvingMethods[amr.Method] {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyGoogleIDToken verifies a Google ID token and returns the identity it carries
+func (s *IdentityService) VerifyGoogleIDToken(idToken string) (*VerifiedIdentity, error) {
+	if len(s.googleAudiences) == 0 {
+		return nil, errors.New("GOOGLE_CLIENT_IDS environment variable is not set")
+	}
+
+	claims := &googleClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, s.googleJWKS.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Google ID token: %v", err)
+	}
+
+	if claims.Issuer != "accounts.google.com" && claims.Issuer != "https://accounts.google.com" {
+		return nil, errors.New("invalid Google ID token: unexpected issuer")
+	}
+	if !audienceMatches(claims.Audience, s.googleAudiences) {
+		return nil, errors.New("invalid Google ID token: unexpected audience")
+	}
+	if claims.Subject == "" || claims.Email == "" {
+		return nil, errors.New("invalid Google ID token: missing subject or email")
+	}
+	if !claims.EmailVerified {
+		return nil, errors.New("google account email is not verified")
+	}
+
+	return &VerifiedIdentity{
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+		GoogleID:      claims.Subject,
+	}, nil
+}
+
+// VerifySupabaseToken verifies a Supabase access token and returns the identity it carries.
+// EmailVerified is only set when the token itself shows the email is verified; see
+// SupabaseEmailConfirmed.
+func (s *IdentityService) VerifySupabaseToken(accessToken string) (*VerifiedIdentity, error) {
+	if s.supabaseIssuer == "" {
+		return nil, errors.New("SUPABASE_URL environment variable is not set")
+	}
+
+	claims := &supabaseClaims{}
+	_, err := jwt.ParseWithClaims(accessToken, claims, s.supabaseJWKS.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(s.supabaseIssuer),
+		jwt.WithAudience(s.supabaseAudience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Supabase access token: %v", err)
+	}
+
+	if claims.Subject == "" || claims.Email == "" {
+		return nil, errors.New("invalid Supabase access token: missing subject or email")
+	}
+
+	identity := &VerifiedIdentity{
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(claims.Email),
+		EmailVerified: claims.emailVerified(),
+		Name:          claims.UserMetadata.FullName,
+		Picture:       claims.UserMetadata.AvatarURL,
+	}
+	if identity.Name == "" {
+		identity.Name = claims.UserMetadata.Name
+	}
+	if identity.Picture == "" {
+		identity.Picture = claims.UserMetadata.Picture
+	}
+	if claims.AppMetadata.Provider == "google" {
+		identity.GoogleID = claims.UserMetadata.ProviderID
+	}
+
+	return identity, nil
+}
+
+// audienceMatches reports whether any token audience is in the allowed list
+func audienceMatches(audience jwt.ClaimStrings, allowed []string) bool {
+	for _, aud := range audience {
+		for _, want := range allowed {
+			if aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitList splits a comma separated environment value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// SupabaseEmailConfirmed asks the Supabase user endpoint, on behalf of the holder of a
+// verified access token, whether the user's email has been confirmed. Access tokens of
+// email and password users do not say so.
+func (s *IdentityService) SupabaseEmailConfirmed(accessToken string) (bool, error) {
+	if s.supabaseUserURL == "" {
+		return false, errors.New("SUPABASE_URL environment variable is not set")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, s.supabaseUserURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if s.supabaseAPIKey != "" {
+		req.Header.Set("apikey", s.supabaseAPIKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch Supabase user: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("supabase user endpoint returned status code %d", resp.StatusCode)
+	}
+
+	var user struct {
+		EmailConfirmedAt *string `json:"email_confirmed_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return false, fmt.Errorf("failed to decode Supabase user: %v", err)
+	}
+
+	return user.EmailConfirmedAt != nil && *user.EmailConfirmedAt != "", nil
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/habdil/sigap-app/backend/utils"
+)
+
+const (
+	testKeyID            = "test-key"
+	testSupabaseIssuer   = "https://project.supabase.co/auth/v1"
+	testSupabaseAudience = "authenticated"
+	testGoogleClientID   = "client.apps.googleusercontent.com"
+)
+
+// newTestIdentityService returns an IdentityService that trusts a freshly generated key,
+// published from a test JWKS server, together with that key
+func newTestIdentityService(t *testing.T) (*IdentityService, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	jwk := map[string]string{
+		"kid": testKeyID,
+		"kty": "RSA",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []interface{}{jwk}})
+	}))
+	t.Cleanup(server.Close)
+
+	return &IdentityService{
+		googleJWKS:       utils.NewJWKS(server.URL),
+		googleAudiences:  []string{testGoogleClientID},
+		supabaseJWKS:     utils.NewJWKS(server.URL),
+		supabaseIssuer:   testSupabaseIssuer,
+		supabaseAudience: testSupabaseAudience,
+	}, key
+}
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = testKeyID
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+// supabaseTestClaims are the claims of an access token Supabase issues after an email and
+// password sign-in. Whether the email is confirmed is not among them; user_metadata
+// carries an email_verified flag, but users can edit it.
+func supabaseTestClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub":   "supabase-user",
+		"iss":   testSupabaseIssuer,
+		"aud":   testSupabaseAudience,
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"email": "User@Example.com",
+		"phone": "",
+		"app_metadata": map[string]interface{}{
+			"provider":  "email",
+			"providers": []interface{}{"email"},
+		},
+		"user_metadata": map[string]interface{}{
+			"email":          "User@Example.com",
+			"email_verified": true,
+			"full_name":      "Test User",
+			"phone_verified": false,
+			"sub":            "supabase-user",
+		},
+		"role":         "authenticated",
+		"aal":          "aal1",
+		"amr":          []interface{}{map[string]interface{}{"method": "password", "timestamp": now.Unix()}},
+		"session_id":   "00000000-0000-0000-0000-000000000001",
+		"is_anonymous": false,
+	}
+}
+
+// newTestSupabaseUserEndpoint points the service's Supabase user endpoint at a test server
+// that reports the given email_confirmed_at, and returns the access tokens it was called with
+func newTestSupabaseUserEndpoint(t *testing.T, service *IdentityService, emailConfirmedAt interface{}) *[]string {
+	t.Helper()
+
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":                 "supabase-user",
+			"email":              "user@example.com",
+			"email_confirmed_at": emailConfirmedAt,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	service.supabaseUserURL = server.URL
+	service.httpClient = server.Client()
+	return &tokens
+}
+
+func googleTestClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub":            "google-user",
+		"iss":            "https://accounts.google.com",
+		"aud":            testGoogleClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"email":          "user@gmail.com",
+		"email_verified": true,
+		"name":           "Test User",
+	}
+}
+
+func TestVerifySupabaseToken(t *testing.T) {
+	service, key := newTestIdentityService(t)
+
+	identity, err := service.VerifySupabaseToken(signTestToken(t, jwt.SigningMethodRS256, key, supabaseTestClaims()))
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if identity.Subject != "supabase-user" || identity.Email != "user@example.com" || identity.Name != "Test User" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+	// A password sign-in alone does not show the email is confirmed
+	if identity.EmailVerified {
+		t.Error("email of a password sign-in reported as verified from the token")
+	}
+}
+
+func TestVerifySupabaseTokenEmailVerification(t *testing.T) {
+	service, key := newTestIdentityService(t)
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+		want   bool
+	}{
+		{"password sign-in", func(c jwt.MapClaims) {}, false},
+		{"google provider", func(c jwt.MapClaims) {
+			c["app_metadata"] = map[string]interface{}{"provider": "google", "providers": []interface{}{"google"}}
+			c["amr"] = []interface{}{map[string]interface{}{"method": "oauth", "timestamp": time.Now().Unix()}}
+		}, true},
+		{"magic link", func(c jwt.MapClaims) {
+			c["amr"] = []interface{}{map[string]interface{}{"method": "magiclink", "timestamp": time.Now().Unix()}}
+		}, true},
+		{"signup confirmation link", func(c jwt.MapClaims) {
+			c["amr"] = []interface{}{map[string]interface{}{"method": "email/signup", "timestamp": time.Now().Unix()}}
+		}, true},
+		{"otp", func(c jwt.MapClaims) {
+			c["amr"] = []interface{}{map[string]interface{}{"method": "otp", "timestamp": time.Now().Unix()}}
+		}, false},
+		{"only user metadata claims verification", func(c jwt.MapClaims) {
+			c["user_metadata"] = map[string]interface{}{"email_verified": true}
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := supabaseTestClaims()
+			tt.modify(claims)
+
+			identity, err := service.VerifySupabaseToken(signTestToken(t, jwt.SigningMethodRS256, key, claims))
+			if err != nil {
+				t.Fatalf("valid token rejected: %v", err)
+			}
+			if identity.EmailVerified != tt.want {
+				t.Errorf("EmailVerified = %v, want %v", identity.EmailVerified, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupabaseEmailConfirmed(t *testing.T) {
+	tests := []struct {
+		name             string
+		emailConfirmedAt interface{}
+		want             bool
+	}{
+		{"confirmed", "2026-01-02T03:04:05.123456Z", true},
+		{"unconfirmed", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, key := newTestIdentityService(t)
+			tokens := newTestSupabaseUserEndpoint(t, service, tt.emailConfirmedAt)
+
+			token := signTestToken(t, jwt.SigningMethodRS256, key, supabaseTestClaims())
+			if _, err := service.VerifySupabaseToken(token); err != nil {
+				t.Fatalf("valid token rejected: %v", err)
+			}
+			confirmed, err := service.SupabaseEmailConfirmed(token)
+			if err != nil {
+				t.Fatalf("SupabaseEmailConfirmed: %v", err)
+			}
+			if confirmed != tt.want {
+				t.Errorf("confirmed = %v, want %v", confirmed, tt.want)
+			}
+			if len(*tokens) != 1 || (*tokens)[0] != token {
+				t.Errorf("user endpoint called with %v, want the access token", *tokens)
+			}
+		})
+	}
+}
+
+func TestSupabaseEmailConfirmedFailsOnError(t *testing.T) {
+	service, key := newTestIdentityService(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"msg":"invalid JWT"}`, http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+	service.supabaseUserURL = server.URL
+	service.httpClient = server.Client()
+
+	if _, err := service.SupabaseEmailConfirmed(signTestToken(t, jwt.SigningMethodRS256, key, supabaseTestClaims())); err == nil {
+		t.Fatal("failed user lookup reported no error")
+	}
+}
+
+func TestVerifySupabaseTokenRejectsInvalidTokens(t *testing.T) {
+	service, key := newTestIdentityService(t)
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://other.supabase.co/auth/v1" }},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "anon" }},
+		{"missing email", func(c jwt.MapClaims) { delete(c, "email") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := supabaseTestClaims()
+			tt.modify(claims)
+
+			if _, err := service.VerifySupabaseToken(signTestToken(t, jwt.SigningMethodRS256, key, claims)); err == nil {
+				t.Fatal("invalid token accepted")
+			}
+		})
+	}
+}
+
+func TestVerifySupabaseTokenRejectsDisallowedAlgorithm(t *testing.T) {
+	service, key := newTestIdentityService(t)
+
+	// A token signed with HMAC using the public key as the secret must not be accepted
+	secret := []byte(base64.RawURLEncoding.EncodeToString(key.N.Bytes()))
+	if _, err := service.VerifySupabaseToken(signTestToken(t, jwt.SigningMethodHS256, secret, supabaseTestClaims())); err == nil {
+		t.Fatal("HS256 token accepted")
+	}
+}
+
+func TestVerifySupabaseTokenRejectsOtherKey(t *testing.T) {
+	service, _ := newTestIdentityService(t)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	if _, err := service.VerifySupabaseToken(signTestToken(t, jwt.SigningMethodRS256, otherKey, supabaseTestClaims())); err == nil {
+		t.Fatal("token signed with another key accepted")
+	}
+}
+
+func TestVerifyGoogleIDToken(t *testing.T) {
+	service, key := newTestIdentityService(t)
+
+	identity, err := service.VerifyGoogleIDToken(signTestToken(t, jwt.SigningMethodRS256, key, googleTestClaims()))
+	if err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if identity.GoogleID != "google-user" || identity.Email != "user@gmail.com" || !identity.EmailVerified {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+}
+
+func TestVerifyGoogleIDTokenRejectsInvalidTokens(t *testing.T) {
+	service, key := newTestIdentityService(t)
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://accounts.example.com" }},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other.apps.googleusercontent.com" }},
+		{"unverified email", func(c jwt.MapClaims) { c["email_verified"] = false }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := googleTestClaims()
+			tt.modify(claims)
+
+			if _, err := service.VerifyGoogleIDToken(signTestToken(t, jwt.SigningMethodRS256, key, claims)); err == nil {
+				t.Fatal("invalid token accepted")
+			}
+		})
+	}
+}
+
+func TestVerifyGoogleIDTokenRejectsDisallowedAlgorithm(t *testing.T) {
+	service, key := newTestIdentityService(t)
+
+	if _, err := service.VerifyGoogleIDToken(signTestToken(t, jwt.SigningMethodRS512, key, googleTestClaims())); err == nil {
+		t.Fatal("RS512 token accepted")
+	}
+}
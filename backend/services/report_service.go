@@ -0,0 +1,87 @@
+// services/report_service.go
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+// MaxReportPeriod is the longest period a health report can cover
+const MaxReportPeriod = 366 * 24 * time.Hour
+
+// ErrInvalidReportPeriod is returned for report periods that are empty or too long
+var ErrInvalidReportPeriod = errors.New("report period must be positive and at most one year")
+
+// ReportService builds reports from the user's health data
+type ReportService struct {
+	userRepo       *repository.UserRepository
+	assessmentRepo *repository.AssessmentRepository
+	activityRepo   *repository.ActivityRepository
+	foodRepo       *repository.FoodRepository
+}
+
+// NewReportService creates a new ReportService
+func NewReportService() *ReportService {
+	return &ReportService{
+		userRepo:       repository.NewUserRepository(),
+		assessmentRepo: repository.NewAssessmentRepository(),
+		activityRepo:   repository.NewActivityRepository(),
+		foodRepo:       repository.NewFoodRepository(),
+	}
+}
+
+// healthReport is the data rendered into the health report
+type healthReport struct {
+	User        *models.User
+	From        time.Time
+	To          time.Time // exclusive
+	GeneratedAt time.Time
+	Assessments []models.AssessmentResponse // oldest first
+	Activities  []models.ActivityLog        // oldest first
+	FoodLogs    []models.FoodLog            // oldest first
+}
+
+// HealthReportPDF renders the user's profile, assessments, activities and nutrition from
+// from up to (excluding) to as a PDF document to show a doctor
+func (s *ReportService) HealthReportPDF(userID int, from time.Time, to time.Time) ([]byte, error) {
+	if !from.Before(to) || to.Sub(from) > MaxReportPeriod {
+		return nil, ErrInvalidReportPeriod
+	}
+
+	user, err := s.userRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := models.ListOptions{From: &from, To: &to, Ascending: true}
+
+	assessments, _, err := s.assessmentRepo.GetAssessmentHistory(userID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	activities, _, err := s.activityRepo.GetUserActivities(userID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	foodLogs, _, err := s.foodRepo.GetUserFoodLogs(userID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &healthReport{
+		User:        user,
+		From:        from,
+		To:          to,
+		GeneratedAt: time.Now().UTC(),
+		Assessments: assessments,
+		Activities:  activities,
+		FoodLogs:    foodLogs,
+	}
+
+	return renderHealthReport(report)
+}
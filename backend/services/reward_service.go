@@ -0,0 +1,89 @@
+// services/reward_service.go
+package services
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+// redemptionCodeAlphabet leaves out characters that are easy to misread (0/O, 1/I/L)
+const redemptionCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// RewardService handles the rewards catalog and redemptions
+type RewardService struct {
+	rewardRepo *repository.RewardRepository
+	coinRepo   *repository.CoinRepository
+}
+
+// NewRewardService creates a new RewardService instance
+func NewRewardService() *RewardService {
+	return &RewardService{
+		rewardRepo: repository.NewRewardRepository(),
+		coinRepo:   repository.NewCoinRepository(),
+	}
+}
+
+// GetAvailableRewards retrieves the rewards that can be redeemed right now
+func (s *RewardService) GetAvailableRewards() ([]models.RewardItem, error) {
+	return s.rewardRepo.GetAvailableRewards()
+}
+
+// GetReward retrieves a reward item by ID
+func (s *RewardService) GetReward(rewardID int) (*models.RewardItem, error) {
+	return s.rewardRepo.GetRewardByID(rewardID)
+}
+
+// RedeemReward buys a reward with the user's coins and returns the issued voucher
+func (s *RewardService) RedeemReward(userID int, rewardID int) (*models.RedeemRewardResponse, error) {
+	// Make sure the user has a coin record to debit
+	if err := s.coinRepo.EnsureUserCoinsExists(userID); err != nil {
+		return nil, err
+	}
+
+	code, err := generateRedemptionCode()
+	if err != nil {
+		return nil, err
+	}
+
+	redemption, err := s.rewardRepo.RedeemReward(userID, rewardID, code)
+	if err != nil {
+		return nil, err
+	}
+
+	coins, err := s.coinRepo.GetUserCoins(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RedeemRewardResponse{
+		Redemption: *redemption,
+		Coins:      *coins,
+	}, nil
+}
+
+// GetUserRedemptions retrieves the rewards a user has redeemed
+func (s *RewardService) GetUserRedemptions(userID int) ([]models.RewardRedemption, error) {
+	return s.rewardRepo.GetUserRedemptions(userID)
+}
+
+// generateRedemptionCode returns a voucher code such as SGP-7K2M-QX9D
+func generateRedemptionCode() (string, error) {
+	code := []byte("SGP-XXXX-XXXX")
+	max := big.NewInt(int64(len(redemptionCodeAlphabet)))
+
+	for i := 4; i < len(code); i++ {
+		if code[i] == '-' {
+			continue
+		}
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = redemptionCodeAlphabet[n.Int64()]
+	}
+
+	return string(code), nil
+}
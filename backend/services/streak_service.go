@@ -0,0 +1,527 @@
+// services/streak_service.go
+package services
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+	"github.com/habdil/sigap-app/backend/repository"
+)
+
+const (
+	// defaultTimezone is used for users without a valid timezone
+	defaultTimezone = "Asia/Jakarta"
+	// minStreakMinutes is the shortest activity that counts towards a streak
+	minStreakMinutes = 10
+	// weeklyGoalDays is the number of active days a week needs for the weekly streak
+	weeklyGoalDays = 3
+	// streakFreezeCost is the price of a freeze day in coins
+	streakFreezeCost = 50
+)
+
+// streakMilestone is a streak length that pays a one-off bonus
+type streakMilestone struct {
+	Length int
+	Coins  int
+}
+
+// Daily milestones are counted in days, weekly milestones in weeks
+var (
+	dailyMilestones = []streakMilestone{
+		{3, 10}, {7, 25}, {14, 50}, {30, 100}, {60, 200}, {100, 400}, {365, 1000},
+	}
+	weeklyMilestones = []streakMilestone{
+		{4, 50}, {12, 150}, {26, 300}, {52, 700},
+	}
+)
+
+// StreakService tracks daily and weekly activity streaks and pays milestone bonuses
+type StreakService struct {
+	streakRepo *repository.StreakRepository
+	coinRepo   *repository.CoinRepository
+	userRepo   *repository.UserRepository
+}
+
+// NewStreakService creates a new StreakService
+func NewStreakService() *StreakService {
+	return &StreakService{
+		streakRepo: repository.NewStreakRepository(),
+		coinRepo:   repository.NewCoinRepository(),
+		userRepo:   repository.NewUserRepository(),
+	}
+}
+
+// streakState is everything needed to compute a user's streaks
+type streakState struct {
+	timezone string
+	active   map[int]bool
+	frozen   map[int]bool
+	summary  streakSummary
+	freeze   *models.StreakFreeze
+}
+
+// GetStreak returns the user's current streaks
+func (s *StreakService) GetStreak(userID int) (*models.StreakStatus, error) {
+	state, err := s.load(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := state.summary
+	status := &models.StreakStatus{
+		Timezone:           state.timezone,
+		CurrentStreak:      summary.Current,
+		LongestStreak:      summary.Longest,
+		ActiveToday:        summary.ActiveToday,
+		WeeklyStreak:       summary.Weekly,
+		WeeklyGoalDays:     weeklyGoalDays,
+		DaysActiveThisWeek: summary.DaysThisWeek,
+		FreezeAvailable:    state.freeze != nil,
+		FreezeCost:         streakFreezeCost,
+	}
+	if summary.Current > 0 {
+		status.StreakStartDate = formatDay(summary.Start)
+	}
+	if summary.LastActive >= 0 {
+		status.LastActiveDate = formatDay(summary.LastActive)
+	}
+	for _, milestone := range dailyMilestones {
+		if milestone.Length > summary.Current {
+			status.NextMilestone = milestone.Length
+			status.NextMilestoneBonus = milestone.Coins
+			break
+		}
+	}
+
+	return status, nil
+}
+
+// RecordActivity updates the streaks after an activity is logged. It uses the freeze
+// if one is needed to keep the daily streak alive and pays any milestone bonus reached.
+func (s *StreakService) RecordActivity(userID int) ([]models.StreakBonus, error) {
+	state, err := s.load(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if frozenDay := state.summary.FrozenDay; frozenDay >= 0 {
+		if _, err := s.streakRepo.UseFreeze(userID, dayTime(frozenDay)); err != nil {
+			return nil, err
+		}
+		state.frozen[frozenDay] = true
+	}
+
+	return s.settleBonuses(userID, state)
+}
+
+// SettleBonuses brings the milestone bonuses in line with the streaks after an activity
+// was edited or deleted, or its review changed whether it counts
+func (s *StreakService) SettleBonuses(userID int) ([]models.StreakBonus, error) {
+	state, err := s.load(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.settleBonuses(userID, state)
+}
+
+// PurchaseFreeze buys a freeze day with coins
+func (s *StreakService) PurchaseFreeze(userID int) (*models.StreakFreeze, error) {
+	_, location := s.userLocation(userID)
+
+	// Make sure the user has a coin record to debit
+	if err := s.coinRepo.EnsureUserCoinsExists(userID); err != nil {
+		return nil, err
+	}
+
+	return s.streakRepo.PurchaseFreeze(userID, streakFreezeCost, dayTime(dayNumber(time.Now(), location)))
+}
+
+// runMilestone identifies a milestone of one run of a streak kind
+type runMilestone struct {
+	kind      string
+	runStart  int
+	milestone int
+}
+
+// plannedBonus is a milestone bonus to pay, with the last day of the run it belongs to
+type plannedBonus struct {
+	Bonus  models.StreakBonus
+	RunEnd int
+}
+
+// bonusPlan is what settling the milestone bonuses takes: the paid bonuses to reverse
+// and the new bonuses to pay
+type bonusPlan struct {
+	Reverse []models.StreakBonus
+	Award   []plannedBonus
+}
+
+// settleBonuses pays the milestones the current streaks reached and reverses bonuses
+// whose run no longer reaches them, as planned by planBonuses. It returns the bonuses
+// paid.
+func (s *StreakService) settleBonuses(userID int, state *streakState) ([]models.StreakBonus, error) {
+	bonuses, err := s.streakRepo.GetActiveBonuses(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := planBonuses(userID, bonuses, state)
+
+	for _, bonus := range plan.Reverse {
+		if _, err := s.streakRepo.ReverseBonus(bonus); err != nil {
+			if errors.Is(err, repository.ErrInsufficientCoins) {
+				// The coins were spent already; the reversal is retried on the next settle
+				log.Printf("Cannot reverse streak bonus %d of user %d yet: %v", bonus.ID, userID, err)
+				continue
+			}
+			return nil, err
+		}
+	}
+
+	var awarded []models.StreakBonus
+	for _, planned := range plan.Award {
+		bonus := planned.Bonus
+		created, err := s.streakRepo.CreateBonus(&bonus, dayTime(planned.RunEnd))
+		if err != nil {
+			return awarded, err
+		}
+		if created {
+			awarded = append(awarded, bonus)
+		}
+	}
+
+	return awarded, nil
+}
+
+// planBonuses works out which of the paid bonuses to reverse and which milestones of the
+// current streaks to pay. A bonus belongs to the run containing the day it was reached
+// on and each milestone is paid once per run, so a run whose start moves, when its first
+// day is deleted or a backdated activity is imported, is not paid again. When two runs
+// merge, the later bonus of a milestone is reversed.
+func planBonuses(userID int, bonuses []models.StreakBonus, state *streakState) bonusPlan {
+	runs := map[string][]streakRun{
+		"daily":  dailyRuns(state.active, state.frozen),
+		"weekly": weeklyRuns(state.active),
+	}
+
+	var plan bonusPlan
+
+	paid := make(map[runMilestone]bool)
+	for _, bonus := range bonuses {
+		run, ok := findRun(runs[bonus.Kind], timeDay(bonus.ReachedOn))
+		if ok && run.length() >= bonus.Milestone {
+			key := runMilestone{bonus.Kind, run.start(), bonus.Milestone}
+			if !paid[key] {
+				paid[key] = true
+				continue
+			}
+		}
+		plan.Reverse = append(plan.Reverse, bonus)
+	}
+
+	award := func(kind string, start int, milestones []streakMilestone) {
+		run, ok := findRun(runs[kind], start)
+		if !ok {
+			return
+		}
+		for _, milestone := range milestones {
+			if milestone.Length > run.length() {
+				break
+			}
+			if paid[runMilestone{kind, run.start(), milestone.Length}] {
+				continue
+			}
+
+			plan.Award = append(plan.Award, plannedBonus{
+				Bonus: models.StreakBonus{
+					UserID:      userID,
+					Kind:        kind,
+					StreakStart: dayTime(run.start()),
+					ReachedOn:   dayTime(run.Steps[milestone.Length-1]),
+					Milestone:   milestone.Length,
+					Coins:       milestone.Coins,
+				},
+				RunEnd: run.End,
+			})
+		}
+	}
+
+	summary := state.summary
+	if summary.Current > 0 {
+		award("daily", summary.Start, dailyMilestones)
+	}
+	if summary.Weekly > 0 {
+		award("weekly", summary.WeeklyStart, weeklyMilestones)
+	}
+
+	return plan
+}
+
+// load reads the user's activity history and computes their streaks
+func (s *StreakService) load(userID int) (*streakState, error) {
+	timezone, location := s.userLocation(userID)
+
+	activeDays, err := s.streakRepo.GetActiveDays(userID, timezone, minStreakMinutes)
+	if err != nil {
+		return nil, err
+	}
+	frozenDays, err := s.streakRepo.GetFrozenDays(userID)
+	if err != nil {
+		return nil, err
+	}
+	freeze, err := s.streakRepo.GetUnusedFreeze(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	freezeFrom := -1
+	if freeze != nil {
+		freezeFrom = timeDay(freeze.PurchasedOn)
+	}
+
+	today := dayNumber(time.Now(), location)
+	active, frozen := daySet(activeDays), daySet(frozenDays)
+
+	return &streakState{
+		timezone: timezone,
+		active:   active,
+		frozen:   frozen,
+		summary:  computeStreaks(active, frozen, today, freezeFrom),
+		freeze:   freeze,
+	}, nil
+}
+
+// userLocation returns the user's timezone, falling back to the default one
+func (s *StreakService) userLocation(userID int) (string, *time.Location) {
+	return loadUserLocation(s.userRepo, userID)
+}
+
+// loadUserLocation returns a user's timezone, falling back to the default one
+func loadUserLocation(userRepo *repository.UserRepository, userID int) (string, *time.Location) {
+	timezone, err := userRepo.GetUserTimezone(userID)
+	if err == nil && timezone != "" {
+		if location, err := time.LoadLocation(timezone); err == nil {
+			return timezone, location
+		}
+	}
+
+	location, err := time.LoadLocation(defaultTimezone)
+	if err != nil {
+		return "UTC", time.UTC
+	}
+	return defaultTimezone, location
+}
+
+// streakSummary is the result of computeStreaks. Days are day numbers, -1 when unset.
+type streakSummary struct {
+	Current      int
+	Start        int
+	Longest      int
+	LastActive   int
+	ActiveToday  bool
+	FrozenDay    int // missed day the unused freeze has to cover to keep the streak
+	Weekly       int
+	WeeklyStart  int
+	DaysThisWeek int
+}
+
+// computeStreaks works out the streaks from the set of active and frozen days.
+// Only active days count towards the length; frozen days just keep the streak from
+// breaking. An unused freeze bought on freezeFrom (-1 for none) covers the most recent
+// missed day on or after that date. Today without activity does not break the streak yet.
+func computeStreaks(active, frozen map[int]bool, today int, freezeFrom int) streakSummary {
+	summary := streakSummary{Start: -1, LastActive: -1, FrozenDay: -1, WeeklyStart: -1}
+	covered := func(day int) bool { return active[day] || frozen[day] }
+
+	summary.ActiveToday = active[today]
+
+	// Daily streak, walking back from today
+	day := today
+	if !covered(day) {
+		day--
+	}
+	for {
+		if active[day] {
+			summary.Current++
+			summary.Start = day
+			day--
+			continue
+		}
+		if frozen[day] {
+			day--
+			continue
+		}
+		if summary.FrozenDay < 0 && freezeFrom >= 0 &&
+			day >= freezeFrom && day < today && covered(day-1) {
+			summary.FrozenDay = day
+			day--
+			continue
+		}
+		break
+	}
+	if summary.Current == 0 {
+		summary.Start = -1
+		summary.FrozenDay = -1
+	}
+
+	// Longest streak over the whole history, bridging frozen days
+	days := sortedDays(active)
+
+	run, previous := 0, 0
+	for i, d := range days {
+		if i > 0 && bridged(previous, d, frozen) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > summary.Longest {
+			summary.Longest = run
+		}
+		previous = d
+	}
+	if len(days) > 0 {
+		summary.LastActive = days[len(days)-1]
+	}
+	if summary.Current > summary.Longest {
+		summary.Longest = summary.Current
+	}
+
+	// Weekly streak: consecutive weeks (Monday to Sunday) with enough active days
+	perWeek := make(map[int]int)
+	for _, d := range days {
+		perWeek[weekStart(d)]++
+	}
+
+	week := weekStart(today)
+	summary.DaysThisWeek = perWeek[week]
+	if perWeek[week] < weeklyGoalDays {
+		// The current week can still reach the goal, so it does not break the streak
+		week -= 7
+	}
+	for perWeek[week] >= weeklyGoalDays {
+		summary.Weekly++
+		summary.WeeklyStart = week
+		week -= 7
+	}
+
+	return summary
+}
+
+// streakRun is a run of active days bridged by frozen days, or of consecutive weeks
+// meeting the weekly goal. Steps are the active days or the week starts, oldest first,
+// and End is the last day the run covers.
+type streakRun struct {
+	Steps []int
+	End   int
+}
+
+func (r streakRun) start() int  { return r.Steps[0] }
+func (r streakRun) length() int { return len(r.Steps) }
+
+// findRun returns the run that covers the day
+func findRun(runs []streakRun, day int) (streakRun, bool) {
+	for _, run := range runs {
+		if day >= run.start() && day <= run.End {
+			return run, true
+		}
+	}
+	return streakRun{}, false
+}
+
+// dailyRuns splits the whole history into runs of active days, as counted by computeStreaks
+func dailyRuns(active, frozen map[int]bool) []streakRun {
+	var runs []streakRun
+	for i, d := range sortedDays(active) {
+		if i > 0 && bridged(runs[len(runs)-1].End, d, frozen) {
+			run := &runs[len(runs)-1]
+			run.Steps = append(run.Steps, d)
+			run.End = d
+			continue
+		}
+		runs = append(runs, streakRun{Steps: []int{d}, End: d})
+	}
+	return runs
+}
+
+// weeklyRuns splits the whole history into runs of weeks meeting the weekly goal
+func weeklyRuns(active map[int]bool) []streakRun {
+	perWeek := make(map[int]int)
+	goalMet := make(map[int]bool)
+	for d := range active {
+		week := weekStart(d)
+		perWeek[week]++
+		if perWeek[week] >= weeklyGoalDays {
+			goalMet[week] = true
+		}
+	}
+
+	var runs []streakRun
+	for _, week := range sortedDays(goalMet) {
+		if len(runs) > 0 && runs[len(runs)-1].End+1 == week {
+			run := &runs[len(runs)-1]
+			run.Steps = append(run.Steps, week)
+			run.End = week + 6
+			continue
+		}
+		runs = append(runs, streakRun{Steps: []int{week}, End: week + 6})
+	}
+	return runs
+}
+
+// bridged reports whether every day between two active days is frozen
+func bridged(from, to int, frozen map[int]bool) bool {
+	for d := from + 1; d < to; d++ {
+		if !frozen[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// dayNumber returns the calendar day of t in location as days since the Unix epoch
+func dayNumber(t time.Time, location *time.Location) int {
+	year, month, day := t.In(location).Date()
+	return timeDay(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+// timeDay converts a date stored as UTC midnight into a day number
+func timeDay(t time.Time) int {
+	year, month, day := t.Date()
+	return int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+// dayTime converts a day number back into a UTC midnight date
+func dayTime(day int) time.Time {
+	return time.Unix(int64(day)*86400, 0).UTC()
+}
+
+func formatDay(day int) string {
+	return dayTime(day).Format("2006-01-02")
+}
+
+// weekStart returns the Monday of the week the day falls in
+func weekStart(day int) int {
+	weekday := int(dayTime(day).Weekday()) // Sunday = 0
+	return day - (weekday+6)%7
+}
+
+func sortedDays(set map[int]bool) []int {
+	days := make([]int, 0, len(set))
+	for d := range set {
+		days = append(days, d)
+	}
+	sort.Ints(days)
+	return days
+}
+
+func daySet(days []time.Time) map[int]bool {
+	set := make(map[int]bool, len(days))
+	for _, day := range days {
+		set[timeDay(day)] = true
+	}
+	return set
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// testDay returns the day number of a YYYY-MM-DD date
+func testDay(t *testing.T, date string) int {
+	t.Helper()
+
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		t.Fatalf("parse %q: %v", date, err)
+	}
+	return timeDay(parsed)
+}
+
+// testDays returns the set of the given dates. "A..B" stands for every day from A to B.
+func testDays(t *testing.T, dates ...string) map[int]bool {
+	t.Helper()
+
+	set := make(map[int]bool)
+	for _, date := range dates {
+		from, to := date, date
+		if len(date) == len("2006-01-02..2006-01-02") {
+			from, to = date[:10], date[12:]
+		}
+		for d := testDay(t, from); d <= testDay(t, to); d++ {
+			set[d] = true
+		}
+	}
+	return set
+}
+
+func formatDays(days []int) []string {
+	formatted := make([]string, len(days))
+	for i, d := range days {
+		formatted[i] = formatDay(d)
+	}
+	return formatted
+}
+
+func TestComputeStreaks(t *testing.T) {
+	tests := []struct {
+		name       string
+		active     []string
+		frozen     []string
+		freezeFrom string
+		current    int
+		start      string
+		longest    int
+		frozenDay  string
+		today      bool
+	}{
+		{name: "active through today", active: []string{"2026-03-06..2026-03-10"},
+			current: 5, start: "2026-03-06", longest: 5, today: true},
+		{name: "today without activity does not break the streak", active: []string{"2026-03-06..2026-03-09"},
+			current: 4, start: "2026-03-06", longest: 4},
+		{name: "missed day breaks the streak", active: []string{"2026-03-01..2026-03-05", "2026-03-08..2026-03-10"},
+			current: 3, start: "2026-03-08", longest: 5, today: true},
+		{name: "frozen day bridges the gap", active: []string{"2026-03-01..2026-03-05", "2026-03-07..2026-03-10"},
+			frozen: []string{"2026-03-06"}, current: 9, start: "2026-03-01", longest: 9, today: true},
+		{name: "unused freeze covers the missed day", active: []string{"2026-03-01..2026-03-05", "2026-03-07..2026-03-10"},
+			freezeFrom: "2026-03-04", current: 9, start: "2026-03-01", longest: 9, frozenDay: "2026-03-06", today: true},
+		{name: "freeze bought after the missed day", active: []string{"2026-03-01..2026-03-05", "2026-03-07..2026-03-10"},
+			freezeFrom: "2026-03-07", current: 4, start: "2026-03-07", longest: 5, today: true},
+		{name: "no activity", current: 0, longest: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			freezeFrom := -1
+			if tt.freezeFrom != "" {
+				freezeFrom = testDay(t, tt.freezeFrom)
+			}
+
+			got := computeStreaks(testDays(t, tt.active...), testDays(t, tt.frozen...), testDay(t, "2026-03-10"), freezeFrom)
+
+			if got.Current != tt.current || got.Longest != tt.longest || got.ActiveToday != tt.today {
+				t.Errorf("current %d, longest %d, active today %v; want %d, %d, %v",
+					got.Current, got.Longest, got.ActiveToday, tt.current, tt.longest, tt.today)
+			}
+			wantStart, wantFrozenDay := -1, -1
+			if tt.start != "" {
+				wantStart = testDay(t, tt.start)
+			}
+			if tt.frozenDay != "" {
+				wantFrozenDay = testDay(t, tt.frozenDay)
+			}
+			if got.Start != wantStart || got.FrozenDay != wantFrozenDay {
+				t.Errorf("start %d, frozen day %d; want %d, %d", got.Start, got.FrozenDay, wantStart, wantFrozenDay)
+			}
+		})
+	}
+}
+
+func TestComputeStreaksWeekly(t *testing.T) {
+	tests := []struct {
+		name         string
+		active       []string
+		weekly       int
+		weeklyStart  string
+		daysThisWeek int
+	}{
+		{
+			name: "across the year boundary",
+			active: []string{
+				"2025-12-15", "2025-12-17", "2025-12-19",
+				"2025-12-22", "2025-12-24", "2025-12-26",
+				"2025-12-30", "2025-12-31", "2026-01-02",
+				"2026-01-05",
+			},
+			weekly: 3, weeklyStart: "2025-12-15", daysThisWeek: 1,
+		},
+		{
+			name: "current week meeting the goal counts",
+			active: []string{
+				"2025-12-29", "2025-12-31", "2026-01-02",
+				"2026-01-05", "2026-01-06", "2026-01-07",
+			},
+			weekly: 2, weeklyStart: "2025-12-29", daysThisWeek: 3,
+		},
+		{
+			name: "week short of the goal breaks the streak",
+			active: []string{
+				"2025-12-22", "2025-12-24", "2025-12-26",
+				"2025-12-30", "2026-01-02",
+			},
+			weekly: 0, daysThisWeek: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeStreaks(testDays(t, tt.active...), nil, testDay(t, "2026-01-07"), -1)
+
+			wantStart := -1
+			if tt.weeklyStart != "" {
+				wantStart = testDay(t, tt.weeklyStart)
+			}
+			if got.Weekly != tt.weekly || got.WeeklyStart != wantStart || got.DaysThisWeek != tt.daysThisWeek {
+				t.Errorf("weekly %d from %d with %d days this week; want %d from %d with %d",
+					got.Weekly, got.WeeklyStart, got.DaysThisWeek, tt.weekly, wantStart, tt.daysThisWeek)
+			}
+		})
+	}
+}
+
+func TestDailyRuns(t *testing.T) {
+	tests := []struct {
+		name   string
+		active []string
+		frozen []string
+		want   [][]string
+	}{
+		{
+			name:   "frozen day bridges the gap",
+			active: []string{"2026-03-01", "2026-03-02", "2026-03-04"},
+			frozen: []string{"2026-03-03"},
+			want:   [][]string{{"2026-03-01", "2026-03-02", "2026-03-04"}},
+		},
+		{
+			name:   "missed day splits the run",
+			active: []string{"2026-03-01", "2026-03-02", "2026-03-04"},
+			want:   [][]string{{"2026-03-01", "2026-03-02"}, {"2026-03-04"}},
+		},
+		{
+			name:   "frozen days bridge a longer gap",
+			active: []string{"2026-03-01", "2026-03-04"},
+			frozen: []string{"2026-03-02", "2026-03-03"},
+			want:   [][]string{{"2026-03-01", "2026-03-04"}},
+		},
+		{
+			name:   "frozen day without activity after it",
+			active: []string{"2026-03-01"},
+			frozen: []string{"2026-03-02"},
+			want:   [][]string{{"2026-03-01"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := dailyRuns(testDays(t, tt.active...), testDays(t, tt.frozen...))
+
+			var got [][]string
+			for _, run := range runs {
+				got = append(got, formatDays(run.Steps))
+				if run.End != run.Steps[len(run.Steps)-1] {
+					t.Errorf("run %v ends on %s, want its last active day", formatDays(run.Steps), formatDay(run.End))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("runs %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeeklyRuns(t *testing.T) {
+	tests := []struct {
+		name   string
+		active []string
+		want   [][]string
+		ends   []string
+	}{
+		{
+			name: "across the year boundary",
+			active: []string{
+				"2025-12-22", "2025-12-24", "2025-12-26",
+				"2025-12-30", "2025-12-31", "2026-01-01",
+				"2026-01-05", "2026-01-06", "2026-01-07",
+			},
+			want: [][]string{{"2025-12-22", "2025-12-29", "2026-01-05"}},
+			ends: []string{"2026-01-11"},
+		},
+		{
+			name: "week short of the goal splits the run",
+			active: []string{
+				"2025-12-22", "2025-12-24", "2025-12-26",
+				"2025-12-30", "2026-01-01",
+				"2026-01-05", "2026-01-06", "2026-01-07",
+			},
+			want: [][]string{{"2025-12-22"}, {"2026-01-05"}},
+			ends: []string{"2025-12-28", "2026-01-11"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := weeklyRuns(testDays(t, tt.active...))
+
+			var got [][]string
+			var ends []string
+			for _, run := range runs {
+				got = append(got, formatDays(run.Steps))
+				ends = append(ends, formatDay(run.End))
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(ends, tt.ends) {
+				t.Errorf("runs %v ending %v, want %v ending %v", got, ends, tt.want, tt.ends)
+			}
+		})
+	}
+}
+
+func TestPlanBonuses(t *testing.T) {
+	// paidBonus is a bonus paid earlier, identified by ID
+	paidBonus := func(id int, kind string, milestone int, reachedOn string) models.StreakBonus {
+		reached, _ := time.Parse("2006-01-02", reachedOn)
+		return models.StreakBonus{ID: id, UserID: 1, Kind: kind, Milestone: milestone, ReachedOn: reached}
+	}
+
+	tests := []struct {
+		name    string
+		active  []string
+		frozen  []string
+		today   string
+		paid    []models.StreakBonus
+		reverse []int
+		award   []string
+	}{
+		{
+			name:   "new run pays the milestones it reached",
+			active: []string{"2026-03-14..2026-03-20"},
+			today:  "2026-03-20",
+			award: []string{
+				"daily 3 of run from 2026-03-14 reached 2026-03-16 until 2026-03-20",
+				"daily 7 of run from 2026-03-14 reached 2026-03-20 until 2026-03-20",
+			},
+		},
+		{
+			name:   "paid milestones are not paid again",
+			active: []string{"2026-03-14..2026-03-20"},
+			today:  "2026-03-20",
+			paid:   []models.StreakBonus{paidBonus(1, "daily", 3, "2026-03-16"), paidBonus(2, "daily", 7, "2026-03-20")},
+		},
+		{
+			name:   "frozen day bridges the gap",
+			active: []string{"2026-03-10..2026-03-12", "2026-03-14..2026-03-20"},
+			frozen: []string{"2026-03-13"},
+			today:  "2026-03-20",
+			award: []string{
+				"daily 3 of run from 2026-03-10 reached 2026-03-12 until 2026-03-20",
+				"daily 7 of run from 2026-03-10 reached 2026-03-17 until 2026-03-20",
+			},
+		},
+		{
+			name:   "start of the run deleted",
+			active: []string{"2026-03-15..2026-03-20"},
+			today:  "2026-03-20",
+			paid:   []models.StreakBonus{paidBonus(1, "daily", 3, "2026-03-16")},
+		},
+		{
+			// The run 03-10..03-20 paid 3 and 7; deleting 03-15 splits it. The first part
+			// still reaches 3, the second does not reach 7 and starts counting anew.
+			name:    "deleted day reverses only the lost milestones",
+			active:  []string{"2026-03-10..2026-03-14", "2026-03-16..2026-03-20"},
+			today:   "2026-03-20",
+			paid:    []models.StreakBonus{paidBonus(1, "daily", 3, "2026-03-12"), paidBonus(2, "daily", 7, "2026-03-16")},
+			reverse: []int{2},
+			award:   []string{"daily 3 of run from 2026-03-16 reached 2026-03-18 until 2026-03-20"},
+		},
+		{
+			// Restoring 03-15 merges the runs again: the reversed 7 is earned again and the
+			// second run's 3 duplicates the first one's
+			name:    "milestone earned again after its reversal",
+			active:  []string{"2026-03-10..2026-03-20"},
+			today:   "2026-03-20",
+			paid:    []models.StreakBonus{paidBonus(1, "daily", 3, "2026-03-12"), paidBonus(3, "daily", 3, "2026-03-18")},
+			reverse: []int{3},
+			award:   []string{"daily 7 of run from 2026-03-10 reached 2026-03-16 until 2026-03-20"},
+		},
+		{
+			name:    "bonus of a vanished run is reversed",
+			active:  []string{"2026-03-19..2026-03-20"},
+			today:   "2026-03-20",
+			paid:    []models.StreakBonus{paidBonus(1, "daily", 3, "2026-03-12")},
+			reverse: []int{1},
+		},
+		{
+			name: "weekly milestone across the year boundary",
+			active: []string{
+				"2025-12-08", "2025-12-10", "2025-12-12",
+				"2025-12-15", "2025-12-17", "2025-12-19",
+				"2025-12-22", "2025-12-24", "2025-12-26",
+				"2025-12-29", "2025-12-31", "2026-01-02",
+				"2026-01-05", "2026-01-07",
+			},
+			today: "2026-01-07",
+			award: []string{"weekly 4 of run from 2025-12-08 reached 2025-12-29 until 2026-01-04"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active, frozen := testDays(t, tt.active...), testDays(t, tt.frozen...)
+			state := &streakState{
+				active:  active,
+				frozen:  frozen,
+				summary: computeStreaks(active, frozen, testDay(t, tt.today), -1),
+			}
+
+			plan := planBonuses(1, tt.paid, state)
+
+			var reverse []int
+			for _, bonus := range plan.Reverse {
+				reverse = append(reverse, bonus.ID)
+			}
+			var award []string
+			for _, planned := range plan.Award {
+				bonus := planned.Bonus
+				if bonus.UserID != 1 {
+					t.Errorf("bonus for user %d, want 1", bonus.UserID)
+				}
+				award = append(award, fmt.Sprintf("%s %d of run from %s reached %s until %s",
+					bonus.Kind, bonus.Milestone, bonus.StreakStart.Format("2006-01-02"),
+					bonus.ReachedOn.Format("2006-01-02"), formatDay(planned.RunEnd)))
+			}
+			if !reflect.DeepEqual(reverse, tt.reverse) {
+				t.Errorf("reversed %v, want %v", reverse, tt.reverse)
+			}
+			if !reflect.DeepEqual(award, tt.award) {
+				t.Errorf("awarded %q, want %q", award, tt.award)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// storage/local.go
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalURLPath is the API path under which the local backend serves signed downloads
+const LocalURLPath = "/api/files/"
+
+// ErrInvalidSignature is returned for download links that were tampered with or expired
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// Local stores objects as files below a directory on the API server's disk. Downloads go
+// through the API at LocalURLPath, authorized by an HMAC signature in the URL.
+type Local struct {
+	dir        string
+	publicURL  string
+	signingKey []byte
+}
+
+// NewLocal creates a local backend storing files in dir. Signed URLs start with
+// publicURL, the external address of the API; when empty they are relative to the host.
+func NewLocal(dir string, publicURL string, signingKey []byte) *Local {
+	return &Local{
+		dir:        dir,
+		publicURL:  strings.TrimSuffix(publicURL, "/"),
+		signingKey: signingKey,
+	}
+}
+
+// Name identifies the backend
+func (l *Local) Name() string {
+	return BackendLocal
+}
+
+func (l *Local) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the object, replacing an existing one atomically
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// Get reads the object. The content type is derived from the key's extension.
+func (l *Local) Get(ctx context.Context, key string) (*Object, error) {
+	filePath, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &Object{Data: data, ContentType: contentType}, nil
+}
+
+// Delete removes the object
+func (l *Local) Delete(ctx context.Context, key string) error {
+	filePath, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL returns a download link under LocalURLPath carrying the expiry time and its
+// signature
+func (l *Local) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if _, err := cleanKey(key); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	query := url.Values{
+		"expires":   {expires},
+		"signature": {l.sign(key, expires)},
+	}
+
+	escaped := (&url.URL{Path: key}).EscapedPath()
+	return l.publicURL + LocalURLPath + escaped + "?" + query.Encode(), nil
+}
+
+// Verify checks the expiry and signature of a download link produced by SignedURL
+func (l *Local) Verify(key string, expires string, signature string) error {
+	if len(l.signingKey) == 0 {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(key, expires))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (l *Local) sign(key string, expires string) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
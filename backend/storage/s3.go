@@ -0,0 +1,313 @@
+// storage/s3.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config holds the connection settings of an S3-compatible server
+type S3Config struct {
+	// Endpoint is the server URL, such as https://s3.ap-southeast-1.amazonaws.com or
+	// http://localhost:9000 for MinIO
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// ForcePathStyle addresses the bucket in the path instead of the host name, as most
+	// self-hosted servers require
+	ForcePathStyle bool
+	Timeout        time.Duration
+}
+
+// S3 stores objects in a bucket of an S3-compatible server, signing requests with
+// AWS Signature Version 4
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time // clock the requests are signed with
+	err      error            // configuration error reported by every call
+}
+
+// NewS3 creates an S3 backend
+func NewS3(config S3Config) *S3 {
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	s := &S3{config: config, client: &http.Client{Timeout: config.Timeout}, now: time.Now}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	switch {
+	case config.Endpoint == "" || err != nil || endpoint.Host == "":
+		s.err = errors.New("S3_ENDPOINT is not a valid URL")
+	case config.Bucket == "":
+		s.err = errors.New("S3_BUCKET is not set")
+	case config.AccessKeyID == "" || config.SecretAccessKey == "":
+		s.err = errors.New("S3 credentials are not set")
+	}
+	s.endpoint = endpoint
+
+	return s
+}
+
+// Name identifies the backend
+func (s *S3) Name() string {
+	return BackendS3
+}
+
+// Put uploads the object
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return s.responseError(resp)
+	}
+	return nil
+}
+
+// Get downloads the object
+func (s *S3) Get(ctx context.Context, key string) (*Object, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, s.responseError(resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{Data: data, ContentType: resp.Header.Get("Content-Type")}, nil
+}
+
+// Delete removes the object. S3 reports success for keys that do not exist.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return s.responseError(resp)
+	}
+	return nil
+}
+
+// SignedURL returns a presigned GET URL. S3 accepts expiries of up to seven days.
+func (s *S3) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	objectURL, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+
+	const maxExpiry = 7 * 24 * time.Hour
+	if expiry > maxExpiry {
+		expiry = maxExpiry
+	}
+
+	now := s.now().UTC()
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.config.AccessKeyID + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format(amzDateFormat)},
+		"X-Amz-Expires":       {strconv.Itoa(int(expiry.Seconds()))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	objectURL.RawQuery = canonicalQuery(query)
+
+	headers := map[string]string{"host": objectURL.Host}
+	signature := s.signature(now, http.MethodGet, objectURL, headers, "UNSIGNED-PAYLOAD")
+
+	return objectURL.String() + "&X-Amz-Signature=" + signature, nil
+}
+
+const amzDateFormat = "20060102T150405Z"
+
+// do sends a request signed with the Authorization header
+func (s *S3) do(ctx context.Context, method string, key string, body []byte, contentType string) (*http.Response, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	objectURL, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, objectURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	payloadHash := sha256Hex(body)
+	headers := map[string]string{
+		"host":                 objectURL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           now.Format(amzDateFormat),
+	}
+	if contentType != "" {
+		headers["content-type"] = contentType
+	}
+
+	for name, value := range headers {
+		if name != "host" {
+			req.Header.Set(name, value)
+		}
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, s.scope(now), signedHeaders(headers),
+		s.signature(now, method, objectURL, headers, payloadHash)))
+
+	return s.client.Do(req)
+}
+
+// objectURL returns the URL of an object in path or virtual-hosted style
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	objectURL := *s.endpoint
+	if s.config.ForcePathStyle {
+		objectURL.Path = objectURL.Path + "/" + s.config.Bucket + "/" + key
+	} else {
+		objectURL.Host = s.config.Bucket + "." + objectURL.Host
+		objectURL.Path = objectURL.Path + "/" + key
+	}
+	objectURL.RawPath = uriEncode(objectURL.Path, false)
+	return &objectURL, nil
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+// signature computes the Signature Version 4 signature of a request
+func (s *S3) signature(now time.Time, method string, u *url.URL, headers map[string]string, payloadHash string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		u.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders(headers),
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		now.Format(amzDateFormat),
+		s.scope(now),
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// responseError turns an S3 error response into an error
+func (s *S3) responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+func signedHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ";")
+}
+
+// canonicalQuery encodes query parameters sorted by name, as Signature Version 4 requires
+func canonicalQuery(values url.Values) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range values[name] {
+			parts = append(parts, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters, and slashes unless
+// encodeSlash is set
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
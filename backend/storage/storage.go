@@ -0,0 +1,142 @@
+// storage/storage.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// Backend identifiers accepted by STORAGE_BACKEND
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// DefaultURLExpiry is how long signed URLs stay valid unless STORAGE_URL_EXPIRY says otherwise
+const DefaultURLExpiry = time.Hour
+
+var (
+	// ErrNotFound is returned for objects that do not exist
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidKey is returned for keys that are empty or try to leave the storage root
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object is a stored file with its content type
+type Object struct {
+	Data        []byte
+	ContentType string
+}
+
+// Storage stores uploaded files under slash-separated keys such as "food/12/abc.jpg".
+// Objects are private; clients download them through short-lived signed URLs.
+type Storage interface {
+	// Name identifies the backend
+	Name() string
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns ErrNotFound for keys that do not exist
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete succeeds for keys that do not exist
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that downloads the object until expiry has passed
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// New returns the backend configured by STORAGE_BACKEND ("local" or "s3", default "local").
+//
+// The local backend writes to STORAGE_LOCAL_DIR (default "uploads") and signs URLs under
+// STORAGE_PUBLIC_URL with STORAGE_SIGNING_KEY, falling back to JWT_SECRET. The S3 backend
+// talks to any S3-compatible server using S3_ENDPOINT, S3_REGION, S3_BUCKET,
+// S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY and S3_FORCE_PATH_STYLE.
+func New() Storage {
+	name := strings.ToLower(os.Getenv("STORAGE_BACKEND"))
+	if name == "" {
+		name = BackendLocal
+	}
+
+	switch name {
+	case BackendLocal:
+		signingKey := os.Getenv("STORAGE_SIGNING_KEY")
+		if signingKey == "" {
+			signingKey = os.Getenv("JWT_SECRET")
+		}
+		return NewLocal(envOrDefault("STORAGE_LOCAL_DIR", "uploads"), os.Getenv("STORAGE_PUBLIC_URL"), []byte(signingKey))
+	case BackendS3:
+		return NewS3(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          envOrDefault("S3_REGION", "us-east-1"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			ForcePathStyle:  os.Getenv("S3_FORCE_PATH_STYLE") == "true",
+		})
+	default:
+		log.Printf("Warning: unknown storage backend %q", name)
+		return &unavailable{err: fmt.Errorf("unknown storage backend %q", name)}
+	}
+}
+
+// URLExpiry returns the lifetime of signed URLs from STORAGE_URL_EXPIRY
+func URLExpiry() time.Duration {
+	value := os.Getenv("STORAGE_URL_EXPIRY")
+	if value == "" {
+		return DefaultURLExpiry
+	}
+	expiry, err := time.ParseDuration(value)
+	if err != nil || expiry <= 0 {
+		log.Printf("Warning: invalid STORAGE_URL_EXPIRY %q, using %s", value, DefaultURLExpiry)
+		return DefaultURLExpiry
+	}
+	return expiry
+}
+
+// cleanKey checks that a key is a relative slash-separated path without ".." elements
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || path.Clean(key) != key {
+		return "", ErrInvalidKey
+	}
+	for _, element := range strings.Split(key, "/") {
+		if element == ".." || element == "." {
+			return "", ErrInvalidKey
+		}
+	}
+	return key, nil
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// unavailable is returned for a misconfigured backend so uploads fail with a clear error
+type unavailable struct {
+	err error
+}
+
+func (u *unavailable) Name() string {
+	return "unavailable"
+}
+
+func (u *unavailable) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	return u.err
+}
+
+func (u *unavailable) Get(ctx context.Context, key string) (*Object, error) {
+	return nil, u.err
+}
+
+func (u *unavailable) Delete(ctx context.Context, key string) error {
+	return u.err
+}
+
+func (u *unavailable) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "", u.err
+}
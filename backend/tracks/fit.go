@@ -0,0 +1,247 @@
+// tracks/fit.go
+package tracks
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FIT message and field numbers from the Garmin FIT profile
+const (
+	fitMesgSession = 18
+	fitMesgRecord  = 20
+
+	fitFieldTimestamp        = 253
+	fitFieldSessionSport     = 5
+	fitFieldPositionLat      = 0
+	fitFieldPositionLong     = 1
+	fitFieldAltitude         = 2
+	fitFieldHeartRate        = 3
+	fitFieldDistance         = 5
+	fitFieldEnhancedAltitude = 78
+)
+
+// fitEpoch is the zero time of FIT timestamps, 1989-12-31 00:00:00 UTC
+var fitEpoch = time.Date(1989, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+var errInvalidFIT = errors.New("invalid FIT file")
+
+// fitSports maps the FIT sport enum to sport names
+var fitSports = map[uint64]string{
+	1:  "running",
+	2:  "cycling",
+	5:  "swimming",
+	11: "walking",
+	17: "hiking",
+}
+
+type fitFieldDef struct {
+	Num  byte
+	Size int
+}
+
+type fitDefinition struct {
+	Order       binary.ByteOrder
+	GlobalNum   uint16
+	Fields      []fitFieldDef
+	DevDataSize int
+}
+
+// parseFIT decodes the record and session messages of a FIT file. Other
+// messages and developer fields are skipped.
+func parseFIT(data []byte) (*recording, error) {
+	if len(data) < 12 || string(data[8:12]) != ".FIT" {
+		return nil, errInvalidFIT
+	}
+	headerSize := int(data[0])
+	dataSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	if headerSize < 12 || headerSize+dataSize > len(data) {
+		return nil, errInvalidFIT
+	}
+
+	rec := &recording{}
+	definitions := make(map[byte]*fitDefinition)
+	var lastTimestamp uint32
+
+	pos := headerSize
+	end := headerSize + dataSize
+	for pos < end {
+		header := data[pos]
+		pos++
+
+		var (
+			local        byte
+			compressedTS bool
+			timeOffset   uint32
+			isDefinition bool
+			hasDeveloper bool
+		)
+		if header&0x80 != 0 {
+			// Compressed timestamp header, always a data message
+			compressedTS = true
+			local = (header >> 5) & 0x03
+			timeOffset = uint32(header & 0x1F)
+		} else {
+			local = header & 0x0F
+			isDefinition = header&0x40 != 0
+			hasDeveloper = header&0x20 != 0
+		}
+
+		if isDefinition {
+			def, size, err := readFITDefinition(data[pos:end], hasDeveloper)
+			if err != nil {
+				return nil, err
+			}
+			definitions[local] = def
+			pos += size
+			continue
+		}
+
+		def, ok := definitions[local]
+		if !ok {
+			return nil, fmt.Errorf("%w: data message without definition", errInvalidFIT)
+		}
+
+		values := make(map[byte]uint64, len(def.Fields))
+		for _, field := range def.Fields {
+			if pos+field.Size > end {
+				return nil, fmt.Errorf("%w: truncated message", errInvalidFIT)
+			}
+			if value, ok := readFITValue(data[pos:pos+field.Size], def.Order); ok {
+				values[field.Num] = value
+			}
+			pos += field.Size
+		}
+		pos += def.DevDataSize
+		if pos > end {
+			return nil, fmt.Errorf("%w: truncated message", errInvalidFIT)
+		}
+
+		if compressedTS {
+			timestamp := lastTimestamp&^0x1F + timeOffset
+			if timeOffset < lastTimestamp&0x1F {
+				timestamp += 0x20
+			}
+			lastTimestamp = timestamp
+		} else if timestamp, ok := values[fitFieldTimestamp]; ok && fitValid(timestamp, 4) {
+			lastTimestamp = uint32(timestamp)
+		}
+
+		switch def.GlobalNum {
+		case fitMesgRecord:
+			rec.Samples = append(rec.Samples, fitSample(values, lastTimestamp))
+		case fitMesgSession:
+			if sport, ok := values[fitFieldSessionSport]; ok && rec.Sport == "" {
+				rec.Sport = fitSports[sport]
+			}
+		}
+	}
+
+	return rec, nil
+}
+
+// readFITDefinition reads a definition message and returns it with its size in bytes
+func readFITDefinition(data []byte, hasDeveloper bool) (*fitDefinition, int, error) {
+	if len(data) < 5 {
+		return nil, 0, fmt.Errorf("%w: truncated definition", errInvalidFIT)
+	}
+
+	def := &fitDefinition{Order: binary.LittleEndian}
+	if data[1] == 1 {
+		def.Order = binary.BigEndian
+	}
+	def.GlobalNum = def.Order.Uint16(data[2:4])
+
+	count := int(data[4])
+	pos := 5
+	if len(data) < pos+count*3 {
+		return nil, 0, fmt.Errorf("%w: truncated definition", errInvalidFIT)
+	}
+	for i := 0; i < count; i++ {
+		def.Fields = append(def.Fields, fitFieldDef{Num: data[pos], Size: int(data[pos+1])})
+		pos += 3
+	}
+
+	if hasDeveloper {
+		if len(data) < pos+1 {
+			return nil, 0, fmt.Errorf("%w: truncated definition", errInvalidFIT)
+		}
+		devCount := int(data[pos])
+		pos++
+		if len(data) < pos+devCount*3 {
+			return nil, 0, fmt.Errorf("%w: truncated definition", errInvalidFIT)
+		}
+		for i := 0; i < devCount; i++ {
+			def.DevDataSize += int(data[pos+1])
+			pos += 3
+		}
+	}
+
+	return def, pos, nil
+}
+
+// readFITValue reads an unsigned integer field of 1, 2 or 4 bytes. Other sizes
+// (strings and arrays) are not needed and are skipped.
+func readFITValue(data []byte, order binary.ByteOrder) (uint64, bool) {
+	switch len(data) {
+	case 1:
+		return uint64(data[0]), true
+	case 2:
+		return uint64(order.Uint16(data)), true
+	case 4:
+		return uint64(order.Uint32(data)), true
+	default:
+		return 0, false
+	}
+}
+
+// fitValid reports whether a value is not the FIT "invalid" marker for its size.
+// The unsigned markers are all ones; signed 32-bit fields use 0x7FFFFFFF.
+func fitValid(value uint64, size int) bool {
+	switch size {
+	case 1:
+		return value != 0xFF
+	case 2:
+		return value != 0xFFFF
+	default:
+		return value != 0xFFFFFFFF && value != 0x7FFFFFFF
+	}
+}
+
+// fitSample converts the fields of a record message into a sample
+func fitSample(values map[byte]uint64, timestamp uint32) sample {
+	s := sample{}
+	if timestamp != 0 {
+		s.Time = fitEpoch.Add(time.Duration(timestamp) * time.Second)
+	}
+
+	lat, latOK := values[fitFieldPositionLat]
+	lon, lonOK := values[fitFieldPositionLong]
+	if latOK && lonOK && fitValid(lat, 4) && fitValid(lon, 4) {
+		s.Lat = semicirclesToDegrees(lat)
+		s.Lon = semicirclesToDegrees(lon)
+		s.HasPosition = true
+	}
+
+	if altitude, ok := values[fitFieldEnhancedAltitude]; ok && fitValid(altitude, 4) {
+		s.Elevation, s.HasElevation = float64(altitude)/5-500, true
+	} else if altitude, ok := values[fitFieldAltitude]; ok && fitValid(altitude, 2) {
+		s.Elevation, s.HasElevation = float64(altitude)/5-500, true
+	}
+
+	if heartRate, ok := values[fitFieldHeartRate]; ok && fitValid(heartRate, 1) {
+		s.HeartRate = int(heartRate)
+	}
+	if distance, ok := values[fitFieldDistance]; ok && fitValid(distance, 4) {
+		s.Distance, s.HasDistance = float64(distance)/100, true
+	}
+
+	return s
+}
+
+// semicirclesToDegrees converts a FIT sint32 position to degrees
+func semicirclesToDegrees(value uint64) float64 {
+	return float64(int32(uint32(value))) * (180.0 / (1 << 31))
+}
@@ -0,0 +1,240 @@
+package tracks
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// fitBuilder writes FIT files for tests. Messages are encoded with the definitions
+// registered for their local message type.
+type fitBuilder struct {
+	body        bytes.Buffer
+	definitions map[byte]*fitDefinition
+}
+
+func newFITBuilder() *fitBuilder {
+	return &fitBuilder{definitions: make(map[byte]*fitDefinition)}
+}
+
+// define writes a definition message for a local message type
+func (b *fitBuilder) define(local byte, order binary.ByteOrder, global uint16, fields ...fitFieldDef) {
+	b.body.WriteByte(0x40 | local)
+	b.body.WriteByte(0) // reserved
+	if order == binary.BigEndian {
+		b.body.WriteByte(1)
+	} else {
+		b.body.WriteByte(0)
+	}
+	binary.Write(&b.body, order, global)
+	b.body.WriteByte(byte(len(fields)))
+	for _, field := range fields {
+		b.body.Write([]byte{field.Num, byte(field.Size), 0})
+	}
+	b.definitions[local] = &fitDefinition{Order: order, GlobalNum: global, Fields: fields}
+}
+
+// message writes a data message with a normal header
+func (b *fitBuilder) message(local byte, values ...uint64) {
+	b.body.WriteByte(local)
+	b.values(local, values)
+}
+
+// compressed writes a data message with a compressed timestamp header
+func (b *fitBuilder) compressed(local byte, timeOffset byte, values ...uint64) {
+	b.body.WriteByte(0x80 | local<<5 | timeOffset&0x1F)
+	b.values(local, values)
+}
+
+func (b *fitBuilder) values(local byte, values []uint64) {
+	def := b.definitions[local]
+	for i, field := range def.Fields {
+		switch field.Size {
+		case 1:
+			b.body.WriteByte(byte(values[i]))
+		case 2:
+			binary.Write(&b.body, def.Order, uint16(values[i]))
+		case 4:
+			binary.Write(&b.body, def.Order, uint32(values[i]))
+		}
+	}
+}
+
+// bytes returns the file with a 14-byte header. The CRCs are not checked by the parser
+// and left zero.
+func (b *fitBuilder) bytes() []byte {
+	var file bytes.Buffer
+	file.Write([]byte{14, 0x20, 0x08, 0x08})
+	binary.Write(&file, binary.LittleEndian, uint32(b.body.Len()))
+	file.WriteString(".FIT")
+	file.Write([]byte{0, 0})
+	file.Write(b.body.Bytes())
+	file.Write([]byte{0, 0})
+	return file.Bytes()
+}
+
+// degreesToSemicircles converts degrees to a FIT sint32 position
+func degreesToSemicircles(degrees float64) uint64 {
+	return uint64(uint32(int32(math.Round(degrees * (1 << 31) / 180))))
+}
+
+// fitTime returns the time of a FIT timestamp
+func fitTime(timestamp uint32) time.Time {
+	return fitEpoch.Add(time.Duration(timestamp) * time.Second)
+}
+
+func TestParseFIT(t *testing.T) {
+	// 30 seconds into a 32-second window, so the first compressed offset rolls over
+	const start uint32 = 1_000_000_030
+
+	b := newFITBuilder()
+	b.define(0, binary.LittleEndian, fitMesgRecord,
+		fitFieldDef{fitFieldTimestamp, 4},
+		fitFieldDef{fitFieldPositionLat, 4},
+		fitFieldDef{fitFieldPositionLong, 4},
+		fitFieldDef{fitFieldAltitude, 2},
+		fitFieldDef{fitFieldHeartRate, 1},
+		fitFieldDef{fitFieldDistance, 4},
+	)
+	b.message(0, uint64(start), degreesToSemicircles(-6.2), degreesToSemicircles(106.816666), (10+500)*5, 140, 0)
+
+	// Records with compressed timestamps carry no timestamp field
+	b.define(1, binary.BigEndian, fitMesgRecord,
+		fitFieldDef{fitFieldPositionLat, 4},
+		fitFieldDef{fitFieldPositionLong, 4},
+		fitFieldDef{fitFieldEnhancedAltitude, 4},
+		fitFieldDef{fitFieldHeartRate, 1},
+		fitFieldDef{fitFieldDistance, 4},
+	)
+	b.compressed(1, 2, degreesToSemicircles(-6.195), degreesToSemicircles(106.816666), (12+500)*5, 150, 55600)
+	b.compressed(1, 10, degreesToSemicircles(-6.19), degreesToSemicircles(106.816666), (14+500)*5, 155, 111200)
+
+	// A record whose fields all hold the invalid markers, as devices write while they
+	// have no fix or no heart rate contact
+	b.message(0, uint64(start+20), 0x7FFFFFFF, 0x7FFFFFFF, 0xFFFF, 0xFF, 0xFFFFFFFF)
+
+	b.define(2, binary.LittleEndian, fitMesgSession,
+		fitFieldDef{fitFieldTimestamp, 4},
+		fitFieldDef{fitFieldSessionSport, 1},
+	)
+	b.message(2, uint64(start+20), 1)
+
+	rec, err := parseFIT(b.bytes())
+	if err != nil {
+		t.Fatalf("parseFIT: %v", err)
+	}
+
+	if rec.Sport != "running" {
+		t.Errorf("sport %q, want running", rec.Sport)
+	}
+	if len(rec.Samples) != 4 {
+		t.Fatalf("got %d samples, want 4", len(rec.Samples))
+	}
+
+	wantTimes := []uint32{start, start + 4, start + 12, start + 20}
+	for i, want := range wantTimes {
+		if got := rec.Samples[i].Time; !got.Equal(fitTime(want)) {
+			t.Errorf("sample %d at %v, want %v", i, got, fitTime(want))
+		}
+	}
+
+	wantLats := []float64{-6.2, -6.195, -6.19}
+	for i, want := range wantLats {
+		s := rec.Samples[i]
+		if !s.HasPosition || math.Abs(s.Lat-want) > 1e-6 || math.Abs(s.Lon-106.816666) > 1e-6 {
+			t.Errorf("sample %d at %v,%v, want %v,106.816666", i, s.Lat, s.Lon, want)
+		}
+	}
+
+	first := rec.Samples[0]
+	if !first.HasElevation || first.Elevation != 10 || first.HeartRate != 140 || !first.HasDistance || first.Distance != 0 {
+		t.Errorf("unexpected first sample %+v", first)
+	}
+	second := rec.Samples[1]
+	if !second.HasElevation || second.Elevation != 12 || second.HeartRate != 150 || second.Distance != 556 {
+		t.Errorf("unexpected second sample %+v", second)
+	}
+
+	invalid := rec.Samples[3]
+	if invalid.HasPosition || invalid.HasElevation || invalid.HeartRate != 0 || invalid.HasDistance {
+		t.Errorf("invalid markers read as values: %+v", invalid)
+	}
+}
+
+func TestParseFITRejectsInvalidFiles(t *testing.T) {
+	valid := newFITBuilder()
+	valid.define(0, binary.LittleEndian, fitMesgRecord, fitFieldDef{fitFieldTimestamp, 4})
+	valid.message(0, 1_000_000_000)
+	file := valid.bytes()
+
+	withoutDefinition := newFITBuilder()
+	withoutDefinition.body.Write([]byte{0x00, 0, 0, 0, 0})
+
+	truncated := append([]byte(nil), file...)
+	binary.LittleEndian.PutUint32(truncated[4:8], uint32(len(file)))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"too short", file[:8]},
+		{"no FIT signature", append([]byte{14, 0x20, 0, 0, 0, 0, 0, 0}, []byte(".GPX\x00\x00")...)},
+		{"data size beyond the file", truncated},
+		{"data message without definition", withoutDefinition.bytes()},
+		{"truncated message", func() []byte {
+			b := newFITBuilder()
+			b.define(0, binary.LittleEndian, fitMesgRecord, fitFieldDef{fitFieldTimestamp, 4})
+			b.body.Write([]byte{0x00, 1, 2})
+			return b.bytes()
+		}()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseFIT(tt.data); !errors.Is(err, errInvalidFIT) {
+				t.Errorf("got error %v, want errInvalidFIT", err)
+			}
+		})
+	}
+}
+
+func TestFITValid(t *testing.T) {
+	tests := []struct {
+		value uint64
+		size  int
+		want  bool
+	}{
+		{0xFF, 1, false},
+		{0xFE, 1, true},
+		{0xFFFF, 2, false},
+		{0x7FFF, 2, true},
+		{0xFFFFFFFF, 4, false},
+		{0x7FFFFFFF, 4, false},
+		{0x80000000, 4, true},
+	}
+	for _, tt := range tests {
+		if got := fitValid(tt.value, tt.size); got != tt.want {
+			t.Errorf("fitValid(%#x, %d) = %v, want %v", tt.value, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestSemicirclesToDegrees(t *testing.T) {
+	tests := []struct {
+		value uint64
+		want  float64
+	}{
+		{0, 0},
+		{1 << 30, 90},
+		{uint64(uint32(0xC0000000)), -90},
+		{degreesToSemicircles(-6.2), -6.2},
+		{degreesToSemicircles(106.816666), 106.816666},
+	}
+	for _, tt := range tests {
+		if got := semicirclesToDegrees(tt.value); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("semicirclesToDegrees(%#x) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
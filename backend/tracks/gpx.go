@@ -0,0 +1,84 @@
+// tracks/gpx.go
+package tracks
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gpxFile is the part of a GPX 1.1 document we read. Heart rate comes from the
+// Garmin TrackPointExtension used by most watches and apps.
+type gpxFile struct {
+	Tracks []struct {
+		Type     string `xml:"type"`
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Lat       string `xml:"lat,attr"`
+	Lon       string `xml:"lon,attr"`
+	Elevation string `xml:"ele"`
+	Time      string `xml:"time"`
+	HeartRate string `xml:"extensions>TrackPointExtension>hr"`
+}
+
+func parseGPX(data []byte) (*recording, error) {
+	var doc gpxFile
+	if err := newXMLDecoder(data).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid GPX file: %v", err)
+	}
+
+	rec := &recording{}
+	for _, track := range doc.Tracks {
+		if rec.Sport == "" {
+			rec.Sport = normalizeSport(track.Type)
+		}
+		for _, segment := range track.Segments {
+			for _, point := range segment.Points {
+				s := sample{Time: parseXMLTime(point.Time)}
+
+				lat, latErr := strconv.ParseFloat(point.Lat, 64)
+				lon, lonErr := strconv.ParseFloat(point.Lon, 64)
+				if latErr == nil && lonErr == nil {
+					s.Lat, s.Lon, s.HasPosition = lat, lon, true
+				}
+				if elevation, err := strconv.ParseFloat(strings.TrimSpace(point.Elevation), 64); err == nil {
+					s.Elevation, s.HasElevation = elevation, true
+				}
+				if heartRate, err := strconv.Atoi(strings.TrimSpace(point.HeartRate)); err == nil {
+					s.HeartRate = heartRate
+				}
+
+				rec.Samples = append(rec.Samples, s)
+			}
+		}
+	}
+
+	return rec, nil
+}
+
+// newXMLDecoder returns a decoder that accepts the non UTF-8 charsets some exporters declare
+func newXMLDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+// parseXMLTime parses an ISO 8601 timestamp, returning the zero time if it is invalid
+func parseXMLTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
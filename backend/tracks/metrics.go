@@ -0,0 +1,231 @@
+// tracks/metrics.go
+package tracks
+
+import (
+	"math"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+const (
+	// earthRadiusM is the mean Earth radius used for haversine distances
+	earthRadiusM = 6371000.0
+	// elevationThresholdM filters GPS altitude noise out of the elevation gain
+	elevationThresholdM = 2.0
+	// minSplitM is the shortest trailing partial split that is reported
+	minSplitM = 10.0
+	// maxStoredPoints caps the route points kept per track
+	maxStoredPoints = 2000
+)
+
+// summarize computes the track summary and kilometre splits from the samples.
+// Distance comes from the device when the file carries it, otherwise it is
+// measured along the recorded positions. Duration is the elapsed time.
+func summarize(samples []sample) (*models.ActivityTrack, error) {
+	samples = sortSamples(samples)
+	if len(samples) < 2 {
+		return nil, ErrNoTrackPoints
+	}
+
+	first, last := samples[0], samples[len(samples)-1]
+	track := &models.ActivityTrack{
+		StartedAt:       first.Time,
+		EndedAt:         last.Time,
+		DurationSeconds: int(last.Time.Sub(first.Time).Seconds()),
+	}
+
+	distances := cumulativeDistances(samples)
+	totalM := distances[len(distances)-1]
+	track.DistanceKM = round(totalM/1000, 3)
+	track.AvgPace = pace(float64(track.DurationSeconds), totalM)
+
+	// Heart rate
+	heartRateSum, heartRateCount := 0, 0
+	for _, s := range samples {
+		if s.HeartRate > 0 {
+			heartRateSum += s.HeartRate
+			heartRateCount++
+			if s.HeartRate > track.HeartRateMax {
+				track.HeartRateMax = s.HeartRate
+			}
+		}
+	}
+	if heartRateCount > 0 {
+		track.HeartRateAvg = int(math.Round(float64(heartRateSum) / float64(heartRateCount)))
+	}
+
+	// Elevation gain per sample, with a threshold so GPS jitter is not counted as climbing
+	gains := make([]float64, len(samples))
+	reference, hasReference := 0.0, false
+	for i, s := range samples {
+		if !s.HasElevation {
+			continue
+		}
+		switch {
+		case !hasReference:
+			reference, hasReference = s.Elevation, true
+		case s.Elevation-reference >= elevationThresholdM:
+			gains[i] = s.Elevation - reference
+			track.ElevationGainM += gains[i]
+			reference = s.Elevation
+		case s.Elevation < reference:
+			reference = s.Elevation
+		}
+	}
+	track.ElevationGainM = round(track.ElevationGainM, 1)
+
+	track.Splits = splits(samples, distances, gains)
+	track.Points = routePoints(samples)
+
+	return track, nil
+}
+
+// cumulativeDistances returns the distance in metres covered at each sample
+func cumulativeDistances(samples []sample) []float64 {
+	distances := make([]float64, len(samples))
+
+	useDevice := false
+	for _, s := range samples {
+		if s.HasDistance && s.Distance > 0 {
+			useDevice = true
+			break
+		}
+	}
+
+	var previous *sample
+	for i := range samples {
+		s := &samples[i]
+		if i > 0 {
+			distances[i] = distances[i-1]
+		}
+
+		if useDevice {
+			// Devices can drop the field on some samples; never go backwards
+			if s.HasDistance && s.Distance > distances[i] {
+				distances[i] = s.Distance
+			}
+			continue
+		}
+
+		if !s.HasPosition {
+			continue
+		}
+		if previous != nil {
+			distances[i] += haversine(previous.Lat, previous.Lon, s.Lat, s.Lon)
+		}
+		previous = s
+	}
+
+	return distances
+}
+
+// splits cuts the track into kilometres, interpolating the time at each boundary
+func splits(samples []sample, distances []float64, gains []float64) []models.TrackSplit {
+	var result []models.TrackSplit
+
+	startTime := samples[0].Time
+	startM := 0.0
+	gain := 0.0
+
+	for i := 1; i < len(samples); i++ {
+		gain += gains[i]
+
+		for distances[i] >= startM+1000 {
+			boundary := startM + 1000
+			fraction := (boundary - distances[i-1]) / (distances[i] - distances[i-1])
+			interval := samples[i].Time.Sub(samples[i-1].Time)
+			boundaryTime := samples[i-1].Time.Add(time.Duration(fraction * float64(interval)))
+
+			seconds := boundaryTime.Sub(startTime).Seconds()
+			result = append(result, models.TrackSplit{
+				Index:           len(result) + 1,
+				DistanceKM:      1,
+				DurationSeconds: int(math.Round(seconds)),
+				AvgPace:         pace(seconds, 1000),
+				ElevationGainM:  round(gain, 1),
+			})
+
+			startTime, startM, gain = boundaryTime, boundary, 0
+		}
+	}
+
+	lastIndex := len(samples) - 1
+	if remaining := distances[lastIndex] - startM; remaining >= minSplitM {
+		seconds := samples[lastIndex].Time.Sub(startTime).Seconds()
+		result = append(result, models.TrackSplit{
+			Index:           len(result) + 1,
+			DistanceKM:      round(remaining/1000, 3),
+			DurationSeconds: int(math.Round(seconds)),
+			AvgPace:         pace(seconds, remaining),
+			ElevationGainM:  round(gain, 1),
+		})
+	}
+
+	return result
+}
+
+// routePoints returns the positioned samples, thinned out to at most maxStoredPoints
+func routePoints(samples []sample) []models.TrackPoint {
+	var positioned []sample
+	for _, s := range samples {
+		if s.HasPosition {
+			positioned = append(positioned, s)
+		}
+	}
+
+	step := 1
+	if len(positioned) > maxStoredPoints {
+		step = int(math.Ceil(float64(len(positioned)) / maxStoredPoints))
+	}
+
+	points := make([]models.TrackPoint, 0, len(positioned)/step+1)
+	for i := 0; i < len(positioned); i += step {
+		points = append(points, trackPoint(positioned[i]))
+	}
+	// Always keep the finish
+	if n := len(positioned); n > 0 && (n-1)%step != 0 {
+		points = append(points, trackPoint(positioned[n-1]))
+	}
+
+	return points
+}
+
+func trackPoint(s sample) models.TrackPoint {
+	point := models.TrackPoint{
+		Time:      s.Time,
+		Lat:       round(s.Lat, 6),
+		Lon:       round(s.Lon, 6),
+		HeartRate: s.HeartRate,
+	}
+	if s.HasElevation {
+		elevation := round(s.Elevation, 1)
+		point.Elevation = &elevation
+	}
+	return point
+}
+
+// haversine returns the great-circle distance in metres between two positions
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * earthRadiusM * math.Asin(math.Sqrt(a))
+}
+
+// pace returns minutes per kilometre, or 0 when no distance was covered
+func pace(seconds float64, metres float64) float64 {
+	if metres <= 0 {
+		return 0
+	}
+	return round((seconds/60)/(metres/1000), 2)
+}
+
+func round(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
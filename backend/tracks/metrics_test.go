@@ -0,0 +1,125 @@
+package tracks
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+func TestSplits(t *testing.T) {
+	start := time.Date(2026, time.March, 10, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		seconds   []int
+		distances []float64
+		gains     []float64
+		want      []models.TrackSplit
+	}{
+		{
+			// The first kilometre ends halfway between the samples at 900 and 1100 m
+			name:      "boundary between samples",
+			seconds:   []int{0, 300, 360, 700, 760},
+			distances: []float64{0, 900, 1100, 2000, 2100},
+			gains:     []float64{0, 5, 3, 0, 1},
+			want: []models.TrackSplit{
+				{Index: 1, DistanceKM: 1, DurationSeconds: 330, AvgPace: 5.5, ElevationGainM: 8},
+				{Index: 2, DistanceKM: 1, DurationSeconds: 370, AvgPace: 6.17},
+				{Index: 3, DistanceKM: 0.1, DurationSeconds: 60, AvgPace: 10, ElevationGainM: 1},
+			},
+		},
+		{
+			name:      "several boundaries between two samples",
+			seconds:   []int{0, 750},
+			distances: []float64{0, 2500},
+			gains:     []float64{0, 0},
+			want: []models.TrackSplit{
+				{Index: 1, DistanceKM: 1, DurationSeconds: 300, AvgPace: 5},
+				{Index: 2, DistanceKM: 1, DurationSeconds: 300, AvgPace: 5},
+				{Index: 3, DistanceKM: 0.5, DurationSeconds: 150, AvgPace: 5},
+			},
+		},
+		{
+			name:      "short trailing distance is not a split",
+			seconds:   []int{0, 300},
+			distances: []float64{0, 1005},
+			gains:     []float64{0, 0},
+			want: []models.TrackSplit{
+				{Index: 1, DistanceKM: 1, DurationSeconds: 299, AvgPace: 4.98},
+			},
+		},
+		{
+			name:      "standing still between samples",
+			seconds:   []int{0, 200, 500, 600},
+			distances: []float64{0, 500, 500, 1000},
+			gains:     []float64{0, 0, 0, 0},
+			want: []models.TrackSplit{
+				{Index: 1, DistanceKM: 1, DurationSeconds: 600, AvgPace: 10},
+			},
+		},
+		{
+			name:      "shorter than a kilometre",
+			seconds:   []int{0, 240},
+			distances: []float64{0, 800},
+			gains:     []float64{0, 2},
+			want: []models.TrackSplit{
+				{Index: 1, DistanceKM: 0.8, DurationSeconds: 240, AvgPace: 5, ElevationGainM: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples := make([]sample, len(tt.seconds))
+			for i, seconds := range tt.seconds {
+				samples[i] = sample{Time: start.Add(time.Duration(seconds) * time.Second)}
+			}
+
+			got := splits(samples, tt.distances, tt.gains)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splits = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCumulativeDistances(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []sample
+		want    []float64
+	}{
+		{
+			name: "device distance never goes backwards",
+			samples: []sample{
+				{Distance: 0, HasDistance: true},
+				{Distance: 400, HasDistance: true},
+				{},
+				{Distance: 350, HasDistance: true},
+				{Distance: 900, HasDistance: true},
+			},
+			want: []float64{0, 400, 400, 400, 900},
+		},
+		{
+			name: "samples without a position add no distance",
+			samples: []sample{
+				{Lat: 0, Lon: 0, HasPosition: true},
+				{},
+				{Lat: 0.01, Lon: 0, HasPosition: true},
+			},
+			want: []float64{0, 0, 1111.95},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cumulativeDistances(tt.samples)
+			for i := range got {
+				got[i] = round(got[i], 2)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("distances = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
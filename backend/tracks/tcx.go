@@ -0,0 +1,65 @@
+// tracks/tcx.go
+package tracks
+
+import "fmt"
+
+// tcxFile is the part of a Garmin Training Center (TCX) document we read
+type tcxFile struct {
+	Activities []struct {
+		Sport string `xml:"Sport,attr"`
+		Laps  []struct {
+			Tracks []struct {
+				Points []tcxPoint `xml:"Trackpoint"`
+			} `xml:"Track"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+type tcxPoint struct {
+	Time     string `xml:"Time"`
+	Position *struct {
+		Lat float64 `xml:"LatitudeDegrees"`
+		Lon float64 `xml:"LongitudeDegrees"`
+	} `xml:"Position"`
+	Altitude  *float64 `xml:"AltitudeMeters"`
+	Distance  *float64 `xml:"DistanceMeters"`
+	HeartRate *int     `xml:"HeartRateBpm>Value"`
+}
+
+func parseTCX(data []byte) (*recording, error) {
+	var doc tcxFile
+	if err := newXMLDecoder(data).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid TCX file: %v", err)
+	}
+
+	rec := &recording{}
+	for _, activity := range doc.Activities {
+		if rec.Sport == "" {
+			rec.Sport = normalizeSport(activity.Sport)
+		}
+		for _, lap := range activity.Laps {
+			for _, track := range lap.Tracks {
+				for _, point := range track.Points {
+					s := sample{Time: parseXMLTime(point.Time)}
+
+					if point.Position != nil {
+						s.Lat, s.Lon, s.HasPosition = point.Position.Lat, point.Position.Lon, true
+					}
+					if point.Altitude != nil {
+						s.Elevation, s.HasElevation = *point.Altitude, true
+					}
+					if point.Distance != nil {
+						s.Distance, s.HasDistance = *point.Distance, true
+					}
+					if point.HeartRate != nil {
+						s.HeartRate = *point.HeartRate
+					}
+
+					rec.Samples = append(rec.Samples, s)
+				}
+			}
+		}
+	}
+
+	return rec, nil
+}
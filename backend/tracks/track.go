@@ -0,0 +1,142 @@
+// tracks/track.go
+package tracks
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/habdil/sigap-app/backend/models"
+)
+
+// Supported source formats
+const (
+	FormatGPX = "gpx"
+	FormatTCX = "tcx"
+	FormatFIT = "fit"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for files that are not GPX, TCX or FIT
+	ErrUnsupportedFormat = errors.New("unsupported file format: expected GPX, TCX or FIT")
+	// ErrNoTrackPoints is returned when a file has fewer than two timestamped track points
+	ErrNoTrackPoints = errors.New("file contains no timestamped track points")
+)
+
+// sample is a track point as read from a file. Devices may leave out any
+// field except the time, and TCX and FIT files also carry the distance
+// measured by the device.
+type sample struct {
+	Time         time.Time
+	Lat, Lon     float64
+	HasPosition  bool
+	Elevation    float64
+	HasElevation bool
+	HeartRate    int
+	Distance     float64 // cumulative metres measured by the device
+	HasDistance  bool
+}
+
+// recording is the raw content of an activity file
+type recording struct {
+	Sport   string
+	Samples []sample
+}
+
+// Parse reads a GPX, TCX or FIT file and returns its track with distance,
+// duration, pace, elevation gain and kilometre splits filled in. The format
+// is taken from the file name and falls back to the file content.
+func Parse(filename string, data []byte) (*models.ActivityTrack, error) {
+	format := DetectFormat(filename, data)
+
+	var (
+		rec *recording
+		err error
+	)
+	switch format {
+	case FormatGPX:
+		rec, err = parseGPX(data)
+	case FormatTCX:
+		rec, err = parseTCX(data)
+	case FormatFIT:
+		rec, err = parseFIT(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	track, err := summarize(rec.Samples)
+	if err != nil {
+		return nil, err
+	}
+	track.SourceFormat = format
+	track.Sport = rec.Sport
+
+	return track, nil
+}
+
+// DetectFormat returns the format of an activity file, or "" if it is not recognised
+func DetectFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gpx":
+		return FormatGPX
+	case ".tcx":
+		return FormatTCX
+	case ".fit":
+		return FormatFIT
+	}
+
+	if len(data) >= 12 && string(data[8:12]) == ".FIT" {
+		return FormatFIT
+	}
+
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	switch {
+	case bytes.Contains(head, []byte("<gpx")):
+		return FormatGPX
+	case bytes.Contains(head, []byte("<TrainingCenterDatabase")):
+		return FormatTCX
+	}
+
+	return ""
+}
+
+// normalizeSport maps the sport names used by GPX, TCX and FIT files to one vocabulary
+func normalizeSport(sport string) string {
+	switch strings.ToLower(strings.TrimSpace(sport)) {
+	case "running", "run", "trail_running", "treadmill_running", "9":
+		return "running"
+	case "walking", "walk", "10":
+		return "walking"
+	case "hiking", "hike", "4":
+		return "hiking"
+	case "cycling", "biking", "bike", "ride", "1":
+		return "cycling"
+	case "swimming", "swim", "open_water_swimming":
+		return "swimming"
+	default:
+		return ""
+	}
+}
+
+// sortSamples orders samples by time and drops the ones without a timestamp
+func sortSamples(samples []sample) []sample {
+	timed := samples[:0]
+	for _, s := range samples {
+		if !s.Time.IsZero() {
+			timed = append(timed, s)
+		}
+	}
+	sort.SliceStable(timed, func(i, j int) bool {
+		return timed[i].Time.Before(timed[j].Time)
+	})
+	return timed
+}
@@ -0,0 +1,145 @@
+package tracks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return data
+}
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("parse %q: %v", value, err)
+	}
+	return parsed.UTC()
+}
+
+func TestParseGPX(t *testing.T) {
+	rec, err := parseGPX(readFixture(t, "run.gpx"))
+	if err != nil {
+		t.Fatalf("parseGPX: %v", err)
+	}
+
+	if rec.Sport != "running" {
+		t.Errorf("sport %q, want running", rec.Sport)
+	}
+
+	want := []sample{
+		{Time: mustTime(t, "2026-03-10T23:00:00Z"), Lat: -6.2, Lon: 106.816666, HasPosition: true, Elevation: 10, HasElevation: true, HeartRate: 140},
+		{Time: mustTime(t, "2026-03-10T23:03:00Z"), Lat: -6.195, Lon: 106.816666, HasPosition: true, Elevation: 13, HasElevation: true, HeartRate: 150},
+		// Offsets are converted to UTC
+		{Time: mustTime(t, "2026-03-10T23:05:30Z"), Lat: -6.191, Lon: 106.816666, HasPosition: true, Elevation: 12.5, HasElevation: true},
+		// An invalid time is left zero and the point dropped later
+		{Lat: -6.19, Lon: 106.816666, HasPosition: true, Elevation: 12, HasElevation: true},
+		{Time: mustTime(t, "2026-03-10T23:06:00Z"), Lat: -6.19, Lon: 106.816666, HasPosition: true, HeartRate: 160},
+	}
+	if len(rec.Samples) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(rec.Samples), len(want))
+	}
+	for i := range want {
+		if rec.Samples[i] != want[i] {
+			t.Errorf("sample %d = %+v, want %+v", i, rec.Samples[i], want[i])
+		}
+	}
+}
+
+func TestParseTCX(t *testing.T) {
+	rec, err := parseTCX(readFixture(t, "run.tcx"))
+	if err != nil {
+		t.Fatalf("parseTCX: %v", err)
+	}
+
+	if rec.Sport != "running" {
+		t.Errorf("sport %q, want running", rec.Sport)
+	}
+
+	want := []sample{
+		{Time: mustTime(t, "2026-03-10T23:00:00Z"), Lat: -6.2, Lon: 106.816666, HasPosition: true, Elevation: 10, HasElevation: true, HeartRate: 140, HasDistance: true},
+		// Trackpoints without a fix have no position but still carry the device distance
+		{Time: mustTime(t, "2026-03-10T23:05:00Z"), HeartRate: 150, Distance: 1000, HasDistance: true},
+		{Time: mustTime(t, "2026-03-10T23:10:30Z"), Lat: -6.182, Lon: 106.816666, HasPosition: true, Elevation: 14.5, HasElevation: true, Distance: 2000, HasDistance: true},
+	}
+	if len(rec.Samples) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(rec.Samples), len(want))
+	}
+	for i := range want {
+		if rec.Samples[i] != want[i] {
+			t.Errorf("sample %d = %+v, want %+v", i, rec.Samples[i], want[i])
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file          string
+		format        string
+		durationS     int
+		distanceKM    float64
+		heartRateAvg  int
+		elevationGain float64
+		splits        int
+		points        int
+	}{
+		// Distance measured along the positions: 0.01 degrees of latitude
+		{"run.gpx", FormatGPX, 360, 1.112, 150, 3, 2, 4},
+		// Distance taken from the device
+		{"run.tcx", FormatTCX, 630, 2, 145, 4.5, 2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			track, err := Parse(tt.file, readFixture(t, tt.file))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			if track.SourceFormat != tt.format || track.Sport != "running" {
+				t.Errorf("format %q, sport %q; want %q, running", track.SourceFormat, track.Sport, tt.format)
+			}
+			if track.DurationSeconds != tt.durationS || track.DistanceKM != tt.distanceKM {
+				t.Errorf("%d s over %v km, want %d s over %v km", track.DurationSeconds, track.DistanceKM, tt.durationS, tt.distanceKM)
+			}
+			if track.HeartRateAvg != tt.heartRateAvg || track.ElevationGainM != tt.elevationGain {
+				t.Errorf("heart rate %d, elevation gain %v; want %d, %v", track.HeartRateAvg, track.ElevationGainM, tt.heartRateAvg, tt.elevationGain)
+			}
+			if len(track.Splits) != tt.splits || len(track.Points) != tt.points {
+				t.Errorf("%d splits, %d points; want %d, %d", len(track.Splits), len(track.Points), tt.splits, tt.points)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	fit := newFITBuilder().bytes()
+
+	tests := []struct {
+		filename string
+		data     []byte
+		want     string
+	}{
+		{"run.GPX", nil, FormatGPX},
+		{"run.tcx", nil, FormatTCX},
+		{"run.fit", nil, FormatFIT},
+		{"upload", fit, FormatFIT},
+		{"upload", []byte(`<?xml version="1.0"?><gpx version="1.1">`), FormatGPX},
+		{"upload", []byte(`<?xml version="1.0"?><TrainingCenterDatabase>`), FormatTCX},
+		{"run.csv", []byte("time,lat,lon"), ""},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.filename, tt.data); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// defaultJWKSCacheTTL is used when the JWKS response has no Cache-Control max-age
+	defaultJWKSCacheTTL = time.Hour
+	// minJWKSRefreshInterval limits refetches triggered by unknown key IDs
+	minJWKSRefreshInterval = time.Minute
+)
+
+// JWKS fetches and caches the public keys published at a JSON Web Key Set URL.
+// Keys are refetched when the cache expires or when a token names an unknown key ID,
+// which picks up key rotation without a restart.
+type JWKS struct {
+	url    string
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]interface{}
+	expiresAt   time.Time
+	lastFetchAt time.Time
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewJWKS creates a JWKS cache for the given URL
+func NewJWKS(url string) *JWKS {
+	return &JWKS{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Keyfunc returns the verification key for a token, for use with jwt.Parse
+func (j *JWKS) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no key ID")
+	}
+
+	return j.key(kid)
+}
+
+// key looks up a key ID, refreshing the key set if needed
+func (j *JWKS) key(kid string) (interface{}, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	key, ok := j.keys[kid]
+
+	expired := now.After(j.expiresAt)
+	unknownKey := !ok && now.Sub(j.lastFetchAt) >= minJWKSRefreshInterval
+	if expired || unknownKey {
+		if err := j.refresh(now); err != nil {
+			// Keep serving cached keys if the endpoint is temporarily unavailable
+			if !ok {
+				return nil, err
+			}
+		}
+		key, ok = j.keys[kid]
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	return key, nil
+}
+
+// refresh downloads the key set; the caller must hold the lock
+func (j *JWKS) refresh(now time.Time) error {
+	j.lastFetchAt = now
+
+	if j.url == "" {
+		return errors.New("JWKS URL is not configured")
+	}
+
+	resp, err := j.client.Get(j.url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned status code %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys we cannot use rather than failing the whole set
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	j.keys = keys
+	j.expiresAt = now.Add(cacheMaxAge(resp.Header.Get("Cache-Control")))
+
+	return nil
+}
+
+// publicKey converts a JWK into a crypto public key
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// cacheMaxAge reads max-age from a Cache-Control header
+func cacheMaxAge(header string) time.Duration {
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	return defaultJWKSCacheTTL
+}
@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keyServer serves a JWKS whose keys can be swapped to simulate rotation
+type keyServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    []jsonWebKey
+	fetches atomic.Int32
+}
+
+func newKeyServer(t *testing.T) *keyServer {
+	t.Helper()
+
+	ks := &keyServer{}
+	ks.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ks.fetches.Add(1)
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": ks.keys})
+	}))
+	t.Cleanup(ks.Close)
+	return ks
+}
+
+func (ks *keyServer) setKeys(keys ...jsonWebKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = keys
+}
+
+func generateRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kid: kid,
+		Kty: "RSA",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func signToken(t *testing.T, kid string, key *rsa.PrivateKey, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func validClaims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		Subject:   "user-1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+}
+
+func parse(jwks *JWKS, token string) error {
+	_, err := jwt.Parse(token, jwks.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithExpirationRequired(),
+	)
+	return err
+}
+
+func TestJWKSVerifiesSignedToken(t *testing.T) {
+	key := generateRSAKey(t)
+	server := newKeyServer(t)
+	server.setKeys(rsaJWK("key-1", &key.PublicKey))
+	jwks := NewJWKS(server.URL)
+
+	if err := parse(jwks, signToken(t, "key-1", key, validClaims())); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+}
+
+func TestJWKSCachesKeys(t *testing.T) {
+	key := generateRSAKey(t)
+	server := newKeyServer(t)
+	server.setKeys(rsaJWK("key-1", &key.PublicKey))
+	jwks := NewJWKS(server.URL)
+
+	for i := 0; i < 3; i++ {
+		if err := parse(jwks, signToken(t, "key-1", key, validClaims())); err != nil {
+			t.Fatalf("valid token rejected: %v", err)
+		}
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Fatalf("fetched the key set %d times, want 1", fetches)
+	}
+}
+
+func TestJWKSRefetchesWhenCacheExpires(t *testing.T) {
+	key := generateRSAKey(t)
+	server := newKeyServer(t)
+	server.setKeys(rsaJWK("key-1", &key.PublicKey))
+	jwks := NewJWKS(server.URL)
+
+	if err := parse(jwks, signToken(t, "key-1", key, validClaims())); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	jwks.expiresAt = time.Now().Add(-time.Second)
+	if err := parse(jwks, signToken(t, "key-1", key, validClaims())); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Fatalf("fetched the key set %d times, want 2", fetches)
+	}
+}
+
+func TestJWKSUnknownKeyTriggersRefetch(t *testing.T) {
+	oldKey := generateRSAKey(t)
+	newKey := generateRSAKey(t)
+	server := newKeyServer(t)
+	server.setKeys(rsaJWK("key-1", &oldKey.PublicKey))
+	jwks := NewJWKS(server.URL)
+
+	if err := parse(jwks, signToken(t, "key-1", oldKey, validClaims())); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	// The provider rotates its key. Within the refresh interval the unknown key ID is
+	// rejected without hammering the endpoint.
+	server.setKeys(rsaJWK("key-2", &newKey.PublicKey))
+	if err := parse(jwks, signToken(t, "key-2", newKey, validClaims())); err == nil {
+		t.Fatal("token with unknown key accepted before the refresh interval passed")
+	}
+	if fetches := server.fetches.Load(); fetches != 1 {
+		t.Fatalf("fetched the key set %d times, want 1", fetches)
+	}
+
+	jwks.lastFetchAt = time.Now().Add(-minJWKSRefreshInterval)
+	if err := parse(jwks, signToken(t, "key-2", newKey, validClaims())); err != nil {
+		t.Fatalf("token signed with the rotated key rejected: %v", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Fatalf("fetched the key set %d times, want 2", fetches)
+	}
+}
+
+func TestJWKSRejectsExpiredToken(t *testing.T) {
+	key := generateRSAKey(t)
+	server := newKeyServer(t)
+	server.setKeys(rsaJWK("key-1", &key.PublicKey))
+	jwks := NewJWKS(server.URL)
+
+	claims := validClaims()
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	if err := parse(jwks, signToken(t, "key-1", key, claims)); err == nil {
+		t.Fatal("expired token accepted")
+	}
+}
+
+func TestJWKSRejectsWrongKey(t *testing.T) {
+	key := generateRSAKey(t)
+	otherKey := generateRSAKey(t)
+	server := newKeyServer(t)
+	server.setKeys(rsaJWK("key-1", &key.PublicKey))
+	jwks := NewJWKS(server.URL)
+
+	if err := parse(jwks, signToken(t, "key-1", otherKey, validClaims())); err == nil {
+		t.Fatal("token signed with another key accepted")
+	}
+}
+
+func TestJWKSRejectsTokenWithoutKeyID(t *testing.T) {
+	key := generateRSAKey(t)
+	server := newKeyServer(t)
+	server.setKeys(rsaJWK("key-1", &key.PublicKey))
+	jwks := NewJWKS(server.URL)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims())
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	if err := parse(jwks, signed); err == nil {
+		t.Fatal("token without key ID accepted")
+	}
+}
+
+func TestCacheMaxAge(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"public, max-age=300, must-revalidate", 300 * time.Second},
+		{"max-age=0", defaultJWKSCacheTTL},
+		{"no-cache", defaultJWKSCacheTTL},
+		{"", defaultJWKSCacheTTL},
+	}
+	for _, tt := range tests {
+		if got := cacheMaxAge(tt.header); got != tt.want {
+			t.Errorf("cacheMaxAge(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"os"
 	"time"
@@ -10,27 +14,33 @@ import (
 
 // Claims represents the JWT claims
 type Claims struct {
-	UserID int `json:"user_id"`
+	UserID    int    `json:"user_id"`
+	SessionID string `json:"sid"` // Refresh token family the access token belongs to
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for the user
-func GenerateToken(userID int) (string, error) {
-	// Get JWT expiry time from env
-	expiryStr := os.Getenv("JWT_EXPIRY")
-	if expiryStr == "" {
-		expiryStr = "24h" // Default to 24 hours
-	}
+// AccessTokenExpiry returns the lifetime of access tokens from JWT_EXPIRY
+func AccessTokenExpiry() (time.Duration, error) {
+	return durationFromEnv("JWT_EXPIRY", "15m")
+}
 
-	// Parse the expiry duration
-	expiry, err := time.ParseDuration(expiryStr)
+// RefreshTokenExpiry returns the lifetime of refresh tokens from REFRESH_TOKEN_EXPIRY
+func RefreshTokenExpiry() (time.Duration, error) {
+	return durationFromEnv("REFRESH_TOKEN_EXPIRY", "720h")
+}
+
+// GenerateToken generates a short-lived JWT access token for the user session
+func GenerateToken(userID int, sessionID string) (string, error) {
+	// Get JWT expiry time from env
+	expiry, err := AccessTokenExpiry()
 	if err != nil {
 		return "", err
 	}
 
 	// Set JWT claims
 	claims := Claims{
-		UserID: userID,
+		UserID:    userID,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -83,3 +93,40 @@ func VerifyToken(tokenString string) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// GenerateRefreshToken creates an opaque random refresh token and returns it with its hash.
+// Only the hash is stored in the database.
+func GenerateRefreshToken() (string, string, error) {
+	token, err := RandomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, HashToken(token), nil
+}
+
+// RandomToken returns n random bytes encoded as URL-safe base64
+func RandomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the SHA-256 hex digest of a token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// durationFromEnv parses a duration from the environment, using fallback when unset
+func durationFromEnv(key, fallback string) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		value = fallback
+	}
+
+	return time.ParseDuration(value)
+}